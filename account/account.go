@@ -21,6 +21,7 @@ package account
 import (
 	"github.com/OnyxPay/OnyxChain-crypto/keypair"
 	s "github.com/OnyxPay/OnyxChain-crypto/signature"
+	"github.com/OnyxPay/OnyxChain-crypto/vrf"
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/log"
 	"github.com/OnyxPay/OnyxChain/core/types"
@@ -32,6 +33,7 @@ type Account struct {
 	PublicKey  keypair.PublicKey
 	Address    common.Address
 	SigScheme  s.SignatureScheme
+	Signer     Signer //external signer holding the private key, PrivateKey is nil if set
 }
 
 func NewAccount(encrypt string) *Account {
@@ -93,6 +95,26 @@ func (this *Account) Scheme() s.SignatureScheme {
 	return this.SigScheme
 }
 
+//Sign return the serialized signature of data, signed by external signer if set
+func (this *Account) Sign(data []byte) ([]byte, error) {
+	if this.Signer != nil {
+		return this.Signer.Sign(data)
+	}
+	sig, err := s.Sign(this.SigScheme, this.PrivateKey, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.Serialize(sig)
+}
+
+//Vrf return the vrf value and proof of data, computed by external signer if set
+func (this *Account) Vrf(data []byte) ([]byte, []byte, error) {
+	if this.Signer != nil {
+		return this.Signer.Vrf(data)
+	}
+	return vrf.Vrf(this.PrivateKey, data)
+}
+
 //AccountMetadata all account info without private key
 type AccountMetadata struct {
 	IsDefault bool   //Is default account
@@ -106,4 +128,5 @@ type AccountMetadata struct {
 	Key       []byte //PrivateKey in encrypted
 	EncAlg    string //Encrypt alg of private key
 	Hash      string //Hash alg
+	HDPath    string //Derivation path if derived from HD wallet
 }
@@ -36,6 +36,10 @@ import (
 type Client interface {
 	//NewAccount create a new account.
 	NewAccount(label string, typeCode keypair.KeyType, curveCode byte, sigScheme s.SignatureScheme, passwd []byte) (*Account, error)
+	//NewHDWallet save the mnemonic encrypted by passwd to wallet, path is the BIP44 account level path
+	NewHDWallet(mnemonic, path string, passwd []byte) error
+	//DeriveAccount derive the next account from the mnemonic of wallet
+	DeriveAccount(label string, typeCode keypair.KeyType, curveCode byte, sigScheme s.SignatureScheme, passwd []byte) (*Account, error)
 	//ImportAccount import a already exist account to wallet
 	ImportAccount(accMeta *AccountMetadata) error
 	//GetAccountByAddress return account object by address
@@ -170,6 +174,78 @@ func (this *ClientImpl) NewAccount(label string, typeCode keypair.KeyType, curve
 	}, nil
 }
 
+func (this *ClientImpl) NewHDWallet(mnemonic, path string, passwd []byte) error {
+	if len(passwd) == 0 {
+		return fmt.Errorf("password cannot empty")
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.walletData.HDWallet != nil {
+		return fmt.Errorf("wallet already has mnemonic")
+	}
+	hd, err := NewHDWallet(mnemonic, path, passwd, this.walletData.Scrypt)
+	if err != nil {
+		return err
+	}
+	this.walletData.HDWallet = hd
+	err = this.save()
+	if err != nil {
+		this.walletData.HDWallet = nil
+		return fmt.Errorf("save error:%s", err)
+	}
+	return nil
+}
+
+func (this *ClientImpl) DeriveAccount(label string, typeCode keypair.KeyType, curveCode byte, sigScheme s.SignatureScheme, passwd []byte) (*Account, error) {
+	this.lock.Lock()
+	hd := this.walletData.HDWallet
+	if hd == nil {
+		this.lock.Unlock()
+		return nil, fmt.Errorf("wallet has no mnemonic")
+	}
+	path := hd.AccountPath(typeCode, hd.NextIndex)
+	prvkey, pubkey, err := hd.DeriveKeyPair(path, typeCode, curveCode, passwd)
+	if err != nil {
+		this.lock.Unlock()
+		return nil, err
+	}
+	//index is consumed even if the account exists, so the next derive can move on
+	hd.NextIndex++
+	address := types.AddressFromPubKey(pubkey)
+	addressBase58 := address.ToBase58()
+	if _, ok := this.accAddrs[addressBase58]; ok {
+		err = this.save()
+		this.lock.Unlock()
+		if err != nil {
+			return nil, fmt.Errorf("save error:%s", err)
+		}
+		return nil, fmt.Errorf("account:%s of path:%s already exists", addressBase58, path)
+	}
+	this.lock.Unlock()
+
+	prvSecret, err := keypair.EncryptWithCustomScrypt(prvkey, addressBase58, passwd, this.walletData.Scrypt)
+	if err != nil {
+		return nil, fmt.Errorf("encryptPrivateKey error:%s", err)
+	}
+	accData := &AccountData{}
+	accData.Label = label
+	accData.SetKeyPair(prvSecret)
+	accData.SigSch = sigScheme.Name()
+	accData.PubKey = hex.EncodeToString(keypair.SerializePublicKey(pubkey))
+	accData.HDPath = path
+
+	err = this.addAccountData(accData)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		PrivateKey: prvkey,
+		PublicKey:  pubkey,
+		Address:    address,
+		SigScheme:  sigScheme,
+	}, nil
+}
+
 func (this *ClientImpl) addAccountData(accData *AccountData) error {
 	if !this.checkSigScheme(accData.Alg, accData.SigSch) {
 		return fmt.Errorf("sigScheme:%s does not match KeyType:%s", accData.SigSch, accData.Alg)
@@ -341,6 +417,7 @@ func (this *ClientImpl) getAccountMetadata(accData *AccountData) *AccountMetadat
 	accMeta.Hash = accData.Hash
 	accMeta.Curve = accData.Param["curve"]
 	accMeta.Salt = accData.Salt
+	accMeta.HDPath = accData.HDPath
 	return accMeta
 }
 
@@ -38,6 +38,7 @@ type AccountData struct {
 	SigSch    string `json:"signatureScheme"`
 	IsDefault bool   `json:"isDefault"`
 	Lock      bool   `json:"lock"`
+	HDPath    string `json:"hdPath,omitempty"` //Derivation path if derived from HD wallet
 }
 
 func (this *AccountData) SetKeyPair(keyinfo *keypair.ProtectedKey) {
@@ -72,6 +73,7 @@ type WalletData struct {
 	Scrypt     *keypair.ScryptParam `json:"scrypt"`
 	Identities []Identity           `json:"identities,omitempty"`
 	Accounts   []*AccountData       `json:"accounts,omitempty"`
+	HDWallet   *HDWallet            `json:"hdWallet,omitempty"`
 	Extra      string               `json:"extra,omitempty"`
 }
 
@@ -99,6 +101,9 @@ func (this *WalletData) Clone() *WalletData {
 		w.Accounts[i] = &ac
 	}
 	w.Identities = this.Identities
+	if this.HDWallet != nil {
+		w.HDWallet = this.HDWallet.Clone()
+	}
 	w.Extra = this.Extra
 	return &w
 }
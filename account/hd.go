@@ -0,0 +1,297 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package account
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	HD_DEFAULT_PATH     = "m/44'/1024'/0'" //Default BIP44 account level path of HD wallet
+	HD_HARDENED_OFFSET  = 0x80000000
+	HD_MNEMONIC_ENTROPY = 128 //Entropy bits of new mnemonic, 12 words
+	HD_SALT_SIZE        = 16
+)
+
+//Master key of SLIP-0010 derivation for each curve
+const (
+	HD_P256_SEED    = "Nist256p1 seed"
+	HD_SM2_SEED     = "Sm2p256v1 seed"
+	HD_ED25519_SEED = "ed25519 seed"
+)
+
+//HDWallet is the mnemonic of wallet, accounts are derived from its seed along the BIP44 path.
+type HDWallet struct {
+	Mnemonic  []byte               `json:"mnemonic"` //Mnemonic encrypted by password
+	Salt      []byte               `json:"salt"`
+	Scrypt    *keypair.ScryptParam `json:"scrypt"`
+	Path      string               `json:"path"`      //Account level path, change and index are appended on derive
+	NextIndex uint32               `json:"nextIndex"` //Index of next derived account
+}
+
+//NewMnemonic return a random BIP39 mnemonic
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(HD_MNEMONIC_ENTROPY)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+//NewHDWallet return a HD wallet which mnemonic is encrypted by passwd
+func NewHDWallet(mnemonic, path string, passwd []byte, param *keypair.ScryptParam) (*HDWallet, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, fmt.Errorf("invalid mnemonic")
+	}
+	if path == "" {
+		path = HD_DEFAULT_PATH
+	}
+	_, err := ParseHDPath(path)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, HD_SALT_SIZE)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	if param == nil {
+		param = keypair.GetScryptParameters()
+	}
+	hd := &HDWallet{
+		Salt:   salt,
+		Scrypt: param,
+		Path:   path,
+	}
+	hd.Mnemonic, err = hd.crypt([]byte(mnemonic), passwd, true)
+	if err != nil {
+		return nil, err
+	}
+	return hd, nil
+}
+
+//GetMnemonic return the decrypted mnemonic
+func (this *HDWallet) GetMnemonic(passwd []byte) (string, error) {
+	mnemonic, err := this.crypt(this.Mnemonic, passwd, false)
+	if err != nil {
+		return "", err
+	}
+	return string(mnemonic), nil
+}
+
+//AccountPath return the full derivation path of account index. Ed25519 only supports hardened derivation.
+func (this *HDWallet) AccountPath(typeCode keypair.KeyType, index uint32) string {
+	if typeCode == keypair.PK_EDDSA {
+		return fmt.Sprintf("%s/0'/%d'", this.Path, index)
+	}
+	return fmt.Sprintf("%s/0/%d", this.Path, index)
+}
+
+//DeriveKeyPair derive the key pair of path from the seed of mnemonic
+func (this *HDWallet) DeriveKeyPair(path string, typeCode keypair.KeyType, curveCode byte, passwd []byte) (keypair.PrivateKey, keypair.PublicKey, error) {
+	mnemonic, err := this.GetMnemonic(passwd)
+	if err != nil {
+		return nil, nil, err
+	}
+	seed := bip39.NewSeed(mnemonic, "")
+	return DeriveKeyPair(seed, path, typeCode, curveCode)
+}
+
+func (this *HDWallet) crypt(data, passwd []byte, encrypt bool) ([]byte, error) {
+	dkey, err := scrypt.Key(passwd, this.Salt, this.Scrypt.N, this.Scrypt.R, this.Scrypt.P, 64)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(dkey[32:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := dkey[:gcm.NonceSize()]
+	if encrypt {
+		return gcm.Seal(nil, nonce, data, []byte("mnemonic")), nil
+	}
+	plain, err := gcm.Open(nil, nonce, data, []byte("mnemonic"))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt mnemonic error, password may be wrong")
+	}
+	return plain, nil
+}
+
+func (this *HDWallet) Clone() *HDWallet {
+	hd := *this
+	sp := *this.Scrypt
+	hd.Scrypt = &sp
+	return &hd
+}
+
+//ParseHDPath parse BIP32 path like m/44'/1024'/0'/0/0 to child indexes
+func ParseHDPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || parts[0] != "m" {
+		return nil, fmt.Errorf("invalid hd path:%s", path)
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "H")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || index >= HD_HARDENED_OFFSET {
+			return nil, fmt.Errorf("invalid hd path:%s", path)
+		}
+		if hardened {
+			index += HD_HARDENED_OFFSET
+		}
+		indexes = append(indexes, uint32(index))
+	}
+	return indexes, nil
+}
+
+//DeriveKeyPair derive key pair of path from seed following SLIP-0010. ECDSA on P-256, SM2 and Ed25519 are supported.
+func DeriveKeyPair(seed []byte, path string, typeCode keypair.KeyType, curveCode byte) (keypair.PrivateKey, keypair.PublicKey, error) {
+	indexes, err := ParseHDPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf []byte
+	switch {
+	case typeCode == keypair.PK_ECDSA && curveCode == keypair.P256, typeCode == keypair.PK_SM2 && curveCode == keypair.SM2P256V1:
+		curve, err := keypair.GetCurve(curveCode)
+		if err != nil {
+			return nil, nil, err
+		}
+		seedKey := HD_P256_SEED
+		if typeCode == keypair.PK_SM2 {
+			seedKey = HD_SM2_SEED
+		}
+		key, _, err := deriveHDKey(seedKey, curve, seed, indexes)
+		if err != nil {
+			return nil, nil, err
+		}
+		buf = append([]byte{byte(typeCode), curveCode}, key...)
+		buf = append(buf, compressPoint(curve, key)...)
+	case typeCode == keypair.PK_EDDSA && curveCode == keypair.ED25519:
+		key, _, err := deriveHDKey(HD_ED25519_SEED, nil, seed, indexes)
+		if err != nil {
+			return nil, nil, err
+		}
+		buf = append([]byte{byte(typeCode), curveCode}, ed25519.NewKeyFromSeed(key)...)
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type:%d curve:%d of hd wallet", typeCode, curveCode)
+	}
+	pri, err := keypair.DeserializePrivateKey(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pri, pri.Public(), nil
+}
+
+//deriveHDKey return the private key and chain code of indexes, curve is nil for ed25519
+func deriveHDKey(seedKey string, curve elliptic.Curve, seed []byte, indexes []uint32) ([]byte, []byte, error) {
+	key, chainCode := hmacSplit([]byte(seedKey), seed)
+	if curve != nil {
+		for !isValidScalar(curve, key) {
+			key, chainCode = hmacSplit([]byte(seedKey), append([]byte{1}, chainCode...))
+		}
+	}
+	for _, index := range indexes {
+		var data []byte
+		if index >= HD_HARDENED_OFFSET {
+			data = append([]byte{0}, key...)
+		} else if curve == nil {
+			return nil, nil, fmt.Errorf("ed25519 only supports hardened derivation")
+		} else {
+			data = compressPoint(curve, key)
+		}
+		data = appendUint32(data, index)
+		for {
+			il, ir := hmacSplit(chainCode, data)
+			if curve == nil {
+				key, chainCode = il, ir
+				break
+			}
+			if isValidScalar(curve, il) {
+				child := new(big.Int).SetBytes(il)
+				child.Add(child, new(big.Int).SetBytes(key))
+				child.Mod(child, curve.Params().N)
+				if child.Sign() != 0 {
+					key, chainCode = padScalar(child.Bytes()), ir
+					break
+				}
+			}
+			data = appendUint32(append([]byte{1}, ir...), index)
+		}
+	}
+	return key, chainCode, nil
+}
+
+func hmacSplit(key, data []byte) ([]byte, []byte) {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+func isValidScalar(curve elliptic.Curve, key []byte) bool {
+	k := new(big.Int).SetBytes(key)
+	return k.Sign() != 0 && k.Cmp(curve.Params().N) < 0
+}
+
+func padScalar(key []byte) []byte {
+	if len(key) >= 32 {
+		return key
+	}
+	return append(make([]byte, 32-len(key)), key...)
+}
+
+func compressPoint(curve elliptic.Curve, key []byte) []byte {
+	x, y := curve.ScalarBaseMult(key)
+	buf := make([]byte, 33)
+	buf[0] = 0x02 + byte(y.Bit(0))
+	xb := x.Bytes()
+	copy(buf[33-len(xb):], xb)
+	return buf
+}
+
+func appendUint32(data []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(data, buf[:]...)
+}
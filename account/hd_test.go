@@ -0,0 +1,158 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package account
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	s "github.com/OnyxPay/OnyxChain-crypto/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHDPath(t *testing.T) {
+	indexes, err := ParseHDPath("m/44'/1024'/0'/0/1")
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{44 + HD_HARDENED_OFFSET, 1024 + HD_HARDENED_OFFSET, HD_HARDENED_OFFSET, 0, 1}, indexes)
+
+	for _, path := range []string{"", "44'/0", "m/a", "m/2147483648", "m//1"} {
+		_, err = ParseHDPath(path)
+		assert.NotNil(t, err, path)
+	}
+}
+
+//test vector 1 of SLIP-0010
+func TestDeriveHDKey(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	testCases := []struct {
+		seedKey   string
+		curve     elliptic.Curve
+		indexes   []uint32
+		key       string
+		chainCode string
+	}{
+		{HD_P256_SEED, elliptic.P256(), nil,
+			"612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2",
+			"beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea"},
+		{HD_P256_SEED, elliptic.P256(), []uint32{HD_HARDENED_OFFSET, 1},
+			"284e9d38d07d21e4e281b645089a94f4cf5a5a81369acf151a1c3a57f18b2129",
+			"4187afff1aafa8445010097fb99d23aee9f599450c7bd140b6826ac22ba21d0c"},
+		{HD_ED25519_SEED, nil, nil,
+			"2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7",
+			"90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb"},
+		{HD_ED25519_SEED, nil, []uint32{HD_HARDENED_OFFSET},
+			"68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			"8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69"},
+	}
+	for _, tc := range testCases {
+		key, chainCode, err := deriveHDKey(tc.seedKey, tc.curve, seed, tc.indexes)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.key, hex.EncodeToString(key))
+		assert.Equal(t, tc.chainCode, hex.EncodeToString(chainCode))
+	}
+
+	_, _, err := deriveHDKey(HD_ED25519_SEED, nil, seed, []uint32{1})
+	assert.NotNil(t, err)
+}
+
+func TestHDWallet(t *testing.T) {
+	mnemonic, err := NewMnemonic()
+	assert.Nil(t, err)
+	_, err = NewHDWallet("abandon abandon", "", testPasswd, &lowSecurityParam)
+	assert.NotNil(t, err)
+
+	hd, err := NewHDWallet(mnemonic, "", testPasswd, &lowSecurityParam)
+	assert.Nil(t, err)
+	assert.Equal(t, HD_DEFAULT_PATH, hd.Path)
+	m, err := hd.GetMnemonic(testPasswd)
+	assert.Nil(t, err)
+	assert.Equal(t, mnemonic, m)
+	_, err = hd.GetMnemonic([]byte("wrong"))
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "m/44'/1024'/0'/0/3", hd.AccountPath(keypair.PK_ECDSA, 3))
+	assert.Equal(t, "m/44'/1024'/0'/0'/3'", hd.AccountPath(keypair.PK_EDDSA, 3))
+
+	for _, kt := range []struct {
+		typeCode  keypair.KeyType
+		curveCode byte
+	}{
+		{keypair.PK_ECDSA, keypair.P256},
+		{keypair.PK_SM2, keypair.SM2P256V1},
+		{keypair.PK_EDDSA, keypair.ED25519},
+	} {
+		path := hd.AccountPath(kt.typeCode, 0)
+		_, pub1, err := hd.DeriveKeyPair(path, kt.typeCode, kt.curveCode, testPasswd)
+		assert.Nil(t, err)
+		_, pub2, err := hd.DeriveKeyPair(path, kt.typeCode, kt.curveCode, testPasswd)
+		assert.Nil(t, err)
+		assert.True(t, keypair.ComparePublicKey(pub1, pub2))
+		_, pub3, err := hd.DeriveKeyPair(hd.AccountPath(kt.typeCode, 1), kt.typeCode, kt.curveCode, testPasswd)
+		assert.Nil(t, err)
+		assert.False(t, keypair.ComparePublicKey(pub1, pub3))
+	}
+	_, _, err = hd.DeriveKeyPair(hd.AccountPath(keypair.PK_ECDSA, 0), keypair.PK_ECDSA, keypair.P384, testPasswd)
+	assert.NotNil(t, err)
+}
+
+func TestClientDeriveAccount(t *testing.T) {
+	path1 := "./wallet_hd_test1.dat"
+	path2 := "./wallet_hd_test2.dat"
+	defer os.Remove(path1)
+	defer os.Remove(path2)
+	wallet1, err := Open(path1)
+	assert.Nil(t, err)
+	_, err = wallet1.DeriveAccount("", keypair.PK_ECDSA, keypair.P256, s.SHA256withECDSA, testPasswd)
+	assert.NotNil(t, err)
+
+	mnemonic, err := NewMnemonic()
+	assert.Nil(t, err)
+	err = wallet1.NewHDWallet(mnemonic, "", testPasswd)
+	assert.Nil(t, err)
+	err = wallet1.NewHDWallet(mnemonic, "", testPasswd)
+	assert.NotNil(t, err)
+	acc1, err := wallet1.DeriveAccount("hd1", keypair.PK_ECDSA, keypair.P256, s.SHA256withECDSA, testPasswd)
+	assert.Nil(t, err)
+	acc2, err := wallet1.DeriveAccount("hd2", keypair.PK_ECDSA, keypair.P256, s.SHA256withECDSA, testPasswd)
+	assert.Nil(t, err)
+	assert.NotEqual(t, acc1.Address, acc2.Address)
+	assert.Equal(t, "m/44'/1024'/0'/0/1", wallet1.GetAccountMetadataByLabel("hd2").HDPath)
+
+	//restore from mnemonic
+	wallet2, err := Open(path2)
+	assert.Nil(t, err)
+	err = wallet2.NewHDWallet(mnemonic, "", testPasswd)
+	assert.Nil(t, err)
+	acc, err := wallet2.DeriveAccount("", keypair.PK_ECDSA, keypair.P256, s.SHA256withECDSA, testPasswd)
+	assert.Nil(t, err)
+	assert.Equal(t, acc1.Address, acc.Address)
+
+	//reload
+	wallet2, err = Open(path2)
+	assert.Nil(t, err)
+	acc, err = wallet2.DeriveAccount("", keypair.PK_ECDSA, keypair.P256, s.SHA256withECDSA, testPasswd)
+	assert.Nil(t, err)
+	assert.Equal(t, acc2.Address, acc.Address)
+	accTmp, err := wallet2.GetAccountByAddress(acc.Address.ToBase58(), testPasswd)
+	assert.Nil(t, err)
+	assert.Equal(t, acc.Address, accTmp.Address)
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package account
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	s "github.com/OnyxPay/OnyxChain-crypto/signature"
+	"github.com/OnyxPay/OnyxChain-crypto/vrf"
+)
+
+const (
+	REMOTE_SIGNER_PUBKEY = "pubkey"
+	REMOTE_SIGNER_SIGN   = "sign"
+	REMOTE_SIGNER_VRF    = "vrf"
+
+	REMOTE_SIGNER_TIMEOUT  = 10 * time.Second
+	REMOTE_SIGNER_MAX_BODY = 1024 * 1024
+	UNIX_SOCKET_PREFIX     = "unix://"
+	AUTH_TOKEN_PREFIX      = "Bearer "
+)
+
+//RemoteSignerRequest is the request of remote signer protocol, data is in hex
+type RemoteSignerRequest struct {
+	Method string `json:"method"`
+	Data   string `json:"data"`
+}
+
+//RemoteSignerResponse is the response of remote signer protocol. Result is the hex of serialized public key,
+//signature or vrf value, Proof is the hex of vrf proof and Scheme is the signature scheme of public key
+type RemoteSignerResponse struct {
+	Error  string `json:"error,omitempty"`
+	Result string `json:"result,omitempty"`
+	Proof  string `json:"proof,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+//RemoteSigner sign data by the signer process reached over local http socket, so the private key never
+//lives in the node process. Every request carries the token shared with the signer process, and the signer
+//must only be reachable from localhost, since the token is sent in plain http
+type RemoteSigner struct {
+	url    string
+	token  string
+	client *http.Client
+	pubKey keypair.PublicKey
+	scheme s.SignatureScheme
+}
+
+//NewRemoteSigner connect to remote signer of address and fetch its public key. Address is unix:///path/to/socket
+//or http on loopback interface like http://127.0.0.1:port, token is the secret shared with the signer process
+func NewRemoteSigner(address, token string) (*RemoteSigner, error) {
+	if token == "" {
+		return nil, fmt.Errorf("remote signer token is empty")
+	}
+	signer := &RemoteSigner{
+		token:  token,
+		client: &http.Client{Timeout: REMOTE_SIGNER_TIMEOUT},
+	}
+	if strings.HasPrefix(address, UNIX_SOCKET_PREFIX) {
+		path := strings.TrimPrefix(address, UNIX_SOCKET_PREFIX)
+		signer.url = "http://unix/"
+		signer.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		}
+	} else {
+		if !isLoopbackURL(address) {
+			return nil, fmt.Errorf("remote signer %s is not on loopback interface", address)
+		}
+		signer.url = address
+	}
+
+	rsp, err := signer.call(REMOTE_SIGNER_PUBKEY, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get public key error:%s", err)
+	}
+	data, err := hex.DecodeString(rsp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key error:%s", err)
+	}
+	signer.pubKey, err = keypair.DeserializePublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("deserialize public key error:%s", err)
+	}
+	signer.scheme, err = s.GetScheme(rsp.Scheme)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature scheme:%s", rsp.Scheme)
+	}
+	return signer, nil
+}
+
+//isLoopbackURL return whether the http url is on loopback interface
+func isLoopbackURL(address string) bool {
+	u, err := url.Parse(address)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+//NewRemoteAccount return account of remote signer of address
+func NewRemoteAccount(address, token string) (*Account, error) {
+	signer, err := NewRemoteSigner(address, token)
+	if err != nil {
+		return nil, err
+	}
+	return NewSignerAccount(signer), nil
+}
+
+func (this *RemoteSigner) PubKey() keypair.PublicKey {
+	return this.pubKey
+}
+
+func (this *RemoteSigner) Scheme() s.SignatureScheme {
+	return this.scheme
+}
+
+//Sign return the signature of data by remote signer, the signature is verified before return
+func (this *RemoteSigner) Sign(data []byte) ([]byte, error) {
+	rsp, err := this.call(REMOTE_SIGNER_SIGN, data)
+	if err != nil {
+		return nil, err
+	}
+	sigData, err := hex.DecodeString(rsp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("decode signature error:%s", err)
+	}
+	sig, err := s.Deserialize(sigData)
+	if err != nil {
+		return nil, fmt.Errorf("deserialize signature error:%s", err)
+	}
+	if !s.Verify(this.pubKey, data, sig) {
+		return nil, fmt.Errorf("invalid signature from remote signer")
+	}
+	return sigData, nil
+}
+
+//Vrf return the vrf value and proof of data by remote signer, the proof is verified before return
+func (this *RemoteSigner) Vrf(data []byte) ([]byte, []byte, error) {
+	rsp, err := this.call(REMOTE_SIGNER_VRF, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err := hex.DecodeString(rsp.Result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode vrf value error:%s", err)
+	}
+	proof, err := hex.DecodeString(rsp.Proof)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode vrf proof error:%s", err)
+	}
+	ok, err := vrf.Verify(this.pubKey, data, value, proof)
+	if err != nil || !ok {
+		return nil, nil, fmt.Errorf("invalid vrf proof from remote signer")
+	}
+	return value, proof, nil
+}
+
+func (this *RemoteSigner) call(method string, data []byte) (*RemoteSignerResponse, error) {
+	req, err := json.Marshal(&RemoteSignerRequest{
+		Method: method,
+		Data:   hex.EncodeToString(data),
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, this.url, bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", AUTH_TOKEN_PREFIX+this.token)
+	httpRsp, err := this.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer %s error:%s", method, err)
+	}
+	defer httpRsp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(httpRsp.Body, REMOTE_SIGNER_MAX_BODY))
+	if err != nil {
+		return nil, fmt.Errorf("read remote signer response error:%s", err)
+	}
+	rsp := &RemoteSignerResponse{}
+	if err := json.Unmarshal(body, rsp); err != nil {
+		return nil, fmt.Errorf("unmarshal remote signer response error:%s", err)
+	}
+	if rsp.Error != "" {
+		return nil, fmt.Errorf("remote signer %s error:%s", method, rsp.Error)
+	}
+	return rsp, nil
+}
+
+//NewRemoteSignerHandler return http handler serving remote signer protocol with signer, the signer process
+//holding the private key serves it on local socket. Requests without the token are rejected
+func NewRemoteSignerHandler(signer Signer, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rsp *RemoteSignerResponse
+		w.Header().Set("Content-Type", "application/json")
+		auth := r.Header.Get("Authorization")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(AUTH_TOKEN_PREFIX+token)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			rsp = &RemoteSignerResponse{Error: "unauthorized"}
+		} else {
+			rsp = handleRemoteSignerRequest(signer, w, r)
+		}
+		json.NewEncoder(w).Encode(rsp)
+	})
+}
+
+func handleRemoteSignerRequest(signer Signer, w http.ResponseWriter, r *http.Request) *RemoteSignerResponse {
+	if r.Method != http.MethodPost {
+		return &RemoteSignerResponse{Error: "invalid http method"}
+	}
+	req := &RemoteSignerRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, REMOTE_SIGNER_MAX_BODY)).Decode(req); err != nil {
+		return &RemoteSignerResponse{Error: fmt.Sprintf("invalid request:%s", err)}
+	}
+	data, err := hex.DecodeString(req.Data)
+	if err != nil {
+		return &RemoteSignerResponse{Error: fmt.Sprintf("invalid data:%s", err)}
+	}
+	switch req.Method {
+	case REMOTE_SIGNER_PUBKEY:
+		return &RemoteSignerResponse{
+			Result: hex.EncodeToString(keypair.SerializePublicKey(signer.PubKey())),
+			Scheme: signer.Scheme().Name(),
+		}
+	case REMOTE_SIGNER_SIGN:
+		sig, err := signer.Sign(data)
+		if err != nil {
+			return &RemoteSignerResponse{Error: err.Error()}
+		}
+		return &RemoteSignerResponse{Result: hex.EncodeToString(sig)}
+	case REMOTE_SIGNER_VRF:
+		value, proof, err := signer.Vrf(data)
+		if err != nil {
+			return &RemoteSignerResponse{Error: err.Error()}
+		}
+		return &RemoteSignerResponse{Result: hex.EncodeToString(value), Proof: hex.EncodeToString(proof)}
+	default:
+		return &RemoteSignerResponse{Error: fmt.Sprintf("unsupport method:%s", req.Method)}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package account
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/OnyxPay/OnyxChain-crypto/vrf"
+	"github.com/OnyxPay/OnyxChain/core/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "secret"
+
+func TestRemoteSigner(t *testing.T) {
+	local := NewAccount("")
+	server := httptest.NewServer(NewRemoteSignerHandler(local, testToken))
+	defer server.Close()
+
+	acc, err := NewRemoteAccount(server.URL, testToken)
+	assert.Nil(t, err)
+	assert.Nil(t, acc.PrivateKey)
+	assert.Equal(t, local.Address, acc.Address)
+	assert.Equal(t, local.SigScheme, acc.SigScheme)
+	assert.Equal(t, keypair.SerializePublicKey(local.PublicKey), keypair.SerializePublicKey(acc.PublicKey))
+
+	data := []byte("hello")
+	sig, err := signature.Sign(acc, data)
+	assert.Nil(t, err)
+	assert.Nil(t, signature.Verify(local.PublicKey, data, sig))
+
+	value, proof, err := acc.Vrf(data)
+	assert.Nil(t, err)
+	ok, err := vrf.Verify(local.PublicKey, data, value, proof)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestRemoteSignerWrongKey(t *testing.T) {
+	local := NewAccount("")
+	server := httptest.NewServer(NewRemoteSignerHandler(local, testToken))
+	defer server.Close()
+
+	signer, err := NewRemoteSigner(server.URL, testToken)
+	assert.Nil(t, err)
+	//remote signer changes its key, signatures should be rejected
+	signer.pubKey = NewAccount("").PublicKey
+	_, err = signer.Sign([]byte("hello"))
+	assert.NotNil(t, err)
+}
+
+func TestRemoteSignerUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "signer.sock")
+	listener, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	local := NewAccount("")
+	go http.Serve(listener, NewRemoteSignerHandler(local, testToken))
+	defer listener.Close()
+
+	acc, err := NewRemoteAccount(UNIX_SOCKET_PREFIX+path, testToken)
+	assert.Nil(t, err)
+	assert.Equal(t, local.Address, acc.Address)
+	data := []byte("hello")
+	sig, err := acc.Sign(data)
+	assert.Nil(t, err)
+	assert.Nil(t, signature.Verify(local.PublicKey, data, sig))
+}
+
+func TestRemoteSignerAuth(t *testing.T) {
+	local := NewAccount("")
+	server := httptest.NewServer(NewRemoteSignerHandler(local, testToken))
+	defer server.Close()
+
+	_, err := NewRemoteSigner(server.URL, "wrong")
+	assert.NotNil(t, err)
+	_, err = NewRemoteSigner(server.URL, "")
+	assert.NotNil(t, err)
+	_, err = NewRemoteSigner("http://10.0.0.1:20000", testToken)
+	assert.NotNil(t, err)
+}
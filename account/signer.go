@@ -0,0 +1,48 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package account
+
+import (
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	s "github.com/OnyxPay/OnyxChain-crypto/signature"
+	"github.com/OnyxPay/OnyxChain/core/types"
+)
+
+//Signer sign data with the private key of an account. The key may be held by local keystore or remote signer,
+//so the users of signer never touch the private key
+type Signer interface {
+	//PubKey return the public key of signer
+	PubKey() keypair.PublicKey
+	//Scheme return the signature scheme of signer
+	Scheme() s.SignatureScheme
+	//Sign return the serialized signature of data
+	Sign(data []byte) ([]byte, error)
+	//Vrf return the vrf value and proof of data
+	Vrf(data []byte) ([]byte, []byte, error)
+}
+
+//NewSignerAccount return account of signer, the account has no private key
+func NewSignerAccount(signer Signer) *Account {
+	return &Account{
+		PublicKey: signer.PubKey(),
+		Address:   types.AddressFromPubKey(signer.PubKey()),
+		SigScheme: signer.Scheme(),
+		Signer:    signer,
+	}
+}
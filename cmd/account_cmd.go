@@ -55,9 +55,12 @@ You can use ./OnyxChain account --help command to view help information of walle
 					utils.AccountDefaultFlag,
 					utils.AccountLabelFlag,
 					utils.IdentityFlag,
+					utils.AccountMnemonicFlag,
+					utils.AccountHDPathFlag,
 					utils.WalletFileFlag,
 				},
 				Description: ` Add a new account to wallet.
+   With --mnemonic, a new BIP39 mnemonic is generated and saved encrypted in wallet, and accounts are derived from it along the BIP44 path. Only P-256 of ecdsa, sm2 and ed25519 can be derived.
    OnyxChain support three type of key: ecdsa, sm2 and ed25519, and support 224、256、384、521 bits length of key in ecdsa, but only support 256 bits length of key in sm2 and ed25519.
    OnyxChain support multiple signature scheme.
    For ECDSA support SHA224withECDSA、SHA256withECDSA、SHA384withECDSA、SHA512withEdDSA、SHA3-224withECDSA、SHA3-256withECDSA、SHA3-384withECDSA、SHA3-512withECDSA、RIPEMD160withECDSA;
@@ -88,6 +91,22 @@ You can use ./OnyxChain account --help command to view help information of walle
    3 ed25519|   25519 256    | SHA512withEdDSA
    -------------------------------------------------`,
 			},
+			{
+				Action:    accountDerive,
+				Name:      "derive",
+				Usage:     "Derive new accounts from the mnemonic of wallet",
+				ArgsUsage: "[sub-command options]",
+				Flags: []cli.Flag{
+					utils.AccountQuantityFlag,
+					utils.AccountTypeFlag,
+					utils.AccountKeylenFlag,
+					utils.AccountSigSchemeFlag,
+					utils.AccountDefaultFlag,
+					utils.AccountLabelFlag,
+					utils.WalletFileFlag,
+				},
+				Description: `Derive new accounts from the mnemonic of wallet, which is created by 'account add --mnemonic' or 'account import --mnemonic'. Accounts are derived by index in order.`,
+			},
 			{
 				Action:    accountList,
 				Name:      "list",
@@ -132,8 +151,15 @@ You can use ./OnyxChain account --help command to view help information of walle
 					utils.WalletFileFlag,
 					utils.AccountSourceFileFlag,
 					utils.AccountWIFFlag,
+					utils.AccountMnemonicFlag,
+					utils.AccountHDPathFlag,
+					utils.AccountQuantityFlag,
+					utils.AccountTypeFlag,
+					utils.AccountKeylenFlag,
+					utils.AccountSigSchemeFlag,
+					utils.AccountDefaultFlag,
 				},
-				Description: "Import accounts of wallet to another. If not specific accounts in args, all account in source will be import",
+				Description: "Import accounts of wallet to another. If not specific accounts in args, all account in source will be import. With --mnemonic, restore accounts from an input mnemonic instead of source wallet",
 			},
 			{
 				Action:    accountExport,
@@ -166,6 +192,10 @@ func accountCreate(ctx *cli.Context) error {
 		PrintInfoMsg("	curve: %s", curveMap[optionCurve].name)
 		PrintInfoMsg("	signature scheme: %s", schemeMap[optionScheme].name)
 	}
+	optionMnemonic := ctx.Bool(utils.GetFlagName(utils.AccountMnemonicFlag))
+	if optionMnemonic && ctx.Bool(utils.IdentityFlag.Name) {
+		return fmt.Errorf("cannot create ONX ID with mnemonic")
+	}
 	optionFile := checkFileName(ctx)
 	optionNumber := checkNumber(ctx)
 	optionLabel := checkLabel(ctx)
@@ -194,12 +224,38 @@ func accountCreate(ctx *cli.Context) error {
 		PrintInfoMsg("Bind public key:%s", id.Control[0].Public)
 		return nil
 	}
+	newAccount := wallet.NewAccount
+	if optionMnemonic {
+		mnemonic, err := account.NewMnemonic()
+		if err != nil {
+			return fmt.Errorf("new mnemonic error:%s", err)
+		}
+		err = wallet.NewHDWallet(mnemonic, ctx.String(utils.GetFlagName(utils.AccountHDPathFlag)), pass)
+		if err != nil {
+			return fmt.Errorf("save mnemonic error:%s", err)
+		}
+		PrintInfoMsg("Mnemonic:%s", mnemonic)
+		PrintWarnMsg("Please write down the mnemonic and keep it safe, it is the only way to restore the accounts.")
+		newAccount = wallet.DeriveAccount
+	}
+	err = createAccounts(newAccount, wallet, optionLabel, optionNumber, keyType, curve, scheme, pass)
+	if err != nil {
+		return err
+	}
+	PrintInfoMsg("Create account successfully.")
+	return nil
+}
+
+type newAccountFunc func(label string, typeCode keypair.KeyType, curveCode byte, sigScheme signature.SignatureScheme, passwd []byte) (*account.Account, error)
+
+func createAccounts(newAccount newAccountFunc, wallet account.Client, optionLabel string, optionNumber int,
+	keyType keypair.KeyType, curve byte, scheme signature.SignatureScheme, pass []byte) error {
 	for i := 0; i < optionNumber; i++ {
 		label := optionLabel
 		if label != "" && optionNumber > 1 {
 			label = fmt.Sprintf("%s%d", label, i+1)
 		}
-		acc, err := wallet.NewAccount(label, keyType, curve, scheme, pass)
+		acc, err := newAccount(label, keyType, curve, scheme, pass)
 		if err != nil {
 			return fmt.Errorf("new account error:%s", err)
 		}
@@ -208,9 +264,78 @@ func accountCreate(ctx *cli.Context) error {
 		PrintInfoMsg("Address:%s", acc.Address.ToBase58())
 		PrintInfoMsg("Public key:%s", hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey)))
 		PrintInfoMsg("Signature scheme:%s", acc.SigScheme.Name())
+		if meta := wallet.GetAccountMetadataByAddress(acc.Address.ToBase58()); meta != nil && meta.HDPath != "" {
+			PrintInfoMsg("HD path:%s", meta.HDPath)
+		}
 	}
+	return nil
+}
 
-	PrintInfoMsg("Create account successfully.")
+func checkKeyOptions(ctx *cli.Context, reader *bufio.Reader) (keypair.KeyType, byte, signature.SignatureScheme) {
+	optionType := ""
+	optionCurve := ""
+	optionScheme := ""
+	if !ctx.IsSet(utils.GetFlagName(utils.AccountDefaultFlag)) {
+		optionType = checkType(ctx, reader)
+		optionCurve = checkCurve(ctx, reader, &optionType)
+		optionScheme = checkScheme(ctx, reader, &optionType)
+	} else {
+		PrintInfoMsg("Use default setting '-t ecdsa -b 256 -s SHA256withECDSA'")
+	}
+	return keyTypeMap[optionType].code, curveMap[optionCurve].code, schemeMap[optionScheme].code
+}
+
+//derive new accounts from mnemonic of wallet
+func accountDerive(ctx *cli.Context) error {
+	reader := bufio.NewReader(os.Stdin)
+	keyType, curve, scheme := checkKeyOptions(ctx, reader)
+	optionNumber := checkNumber(ctx)
+	optionLabel := checkLabel(ctx)
+	wallet, err := common.OpenWallet(ctx)
+	if err != nil {
+		return err
+	}
+	if wallet.GetWalletData().HDWallet == nil {
+		return fmt.Errorf("wallet has no mnemonic, use 'account add --mnemonic' or 'account import --mnemonic' first")
+	}
+	pass, err := common.GetPasswd(ctx)
+	if err != nil {
+		return err
+	}
+	defer common.ClearPasswd(pass)
+	err = createAccounts(wallet.DeriveAccount, wallet, optionLabel, optionNumber, keyType, curve, scheme, pass)
+	if err != nil {
+		return err
+	}
+	PrintInfoMsg("Derive account successfully.")
+	return nil
+}
+
+//restore HD wallet from input mnemonic and derive accounts
+func accountImportMnemonic(ctx *cli.Context, wallet account.Client) error {
+	reader := bufio.NewReader(os.Stdin)
+	keyType, curve, scheme := checkKeyOptions(ctx, reader)
+	optionNumber := checkNumber(ctx)
+	fmt.Printf("Mnemonic:")
+	mnemonic, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("input mnemonic error:%s", err)
+	}
+	PrintInfoMsg("Please input a password to encrypt the mnemonic and derived key(s)")
+	pass, err := password.GetConfirmedPassword()
+	if err != nil {
+		return err
+	}
+	defer common.ClearPasswd(pass)
+	err = wallet.NewHDWallet(mnemonic, ctx.String(utils.GetFlagName(utils.AccountHDPathFlag)), pass)
+	if err != nil {
+		return fmt.Errorf("restore mnemonic error:%s", err)
+	}
+	err = createAccounts(wallet.DeriveAccount, wallet, "", optionNumber, keyType, curve, scheme, pass)
+	if err != nil {
+		return err
+	}
+	PrintInfoMsg("Import accounts from mnemonic successfully.")
 	return nil
 }
 
@@ -264,6 +389,9 @@ func accountList(ctx *cli.Context) error {
 		PrintInfoMsg("	Curve: %v", accMeta.Curve)
 		PrintInfoMsg("	Key length: %v bits", len(accMeta.Key)*8)
 		PrintInfoMsg("	Public key: %v", accMeta.PubKey)
+		if accMeta.HDPath != "" {
+			PrintInfoMsg("	HD path: %v", accMeta.HDPath)
+		}
 		PrintInfoMsg("	Signature scheme: %v\n", accMeta.SigSch)
 	}
 	return nil
@@ -387,6 +515,13 @@ func accountDelete(ctx *cli.Context) error {
 }
 
 func accountImport(ctx *cli.Context) error {
+	if ctx.Bool(utils.GetFlagName(utils.AccountMnemonicFlag)) {
+		wallet, err := account.Open(checkFileName(ctx))
+		if err != nil {
+			return err
+		}
+		return accountImportMnemonic(ctx, wallet)
+	}
 	source := ctx.String(utils.GetFlagName(utils.AccountSourceFileFlag))
 	if source == "" {
 		PrintErrorMsg("Missing source wallet path argument to import.")
@@ -53,6 +53,23 @@ var AssetCommand = cli.Command{
 				utils.WalletFileFlag,
 			},
 		},
+		{
+			Action:      batchTransfer,
+			Name:        "batchtransfer",
+			Usage:       "Transfer onx or oxg to accounts of payout file",
+			ArgsUsage:   " ",
+			Description: "Transfer onx or oxg to accounts of payout file. The transfers are validated by pre-execution and split into multiple transactions if exceed max transaction size or gas limit",
+			Flags: []cli.Flag{
+				utils.RPCPortFlag,
+				utils.TransactionGasPriceFlag,
+				utils.TransactionGasLimitFlag,
+				utils.TransactionAssetFlag,
+				utils.TransactionFromFlag,
+				utils.TransactionPayoutFileFlag,
+				utils.ForceSendTxFlag,
+				utils.WalletFileFlag,
+			},
+		},
 		{
 			Action:    approve,
 			Name:      "approve",
@@ -191,8 +208,14 @@ func transfer(ctx *cli.Context) error {
 		}
 	}
 
-	gasPrice := ctx.Uint64(utils.TransactionGasPriceFlag.Name)
-	gasLimit := ctx.Uint64(utils.TransactionGasLimitFlag.Name)
+	mutable, err := utils.TransferTx(0, 0, asset, fromAddr, toAddr, amount)
+	if err != nil {
+		return err
+	}
+	gasPrice, gasLimit, err := cmdcom.GetGasPriceAndLimit(ctx, mutable)
+	if err != nil {
+		return err
+	}
 
 	networkId, err := utils.GetNetworkId()
 	if err != nil {
@@ -292,6 +315,81 @@ func getAllowance(ctx *cli.Context) error {
 	return nil
 }
 
+func batchTransfer(ctx *cli.Context) error {
+	SetRpcPort(ctx)
+	if !ctx.IsSet(utils.GetFlagName(utils.TransactionFromFlag)) ||
+		!ctx.IsSet(utils.GetFlagName(utils.TransactionPayoutFileFlag)) {
+		PrintErrorMsg("Missing %s or %s argument.", utils.TransactionFromFlag.Name, utils.TransactionPayoutFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+
+	asset := ctx.String(utils.GetFlagName(utils.TransactionAssetFlag))
+	if asset == "" {
+		asset = utils.ASSET_ONX
+	}
+	from := ctx.String(utils.TransactionFromFlag.Name)
+	fromAddr, err := cmdcom.ParseAddress(from, ctx)
+	if err != nil {
+		return err
+	}
+	items, err := utils.ReadPayoutFile(ctx.String(utils.GetFlagName(utils.TransactionPayoutFileFlag)))
+	if err != nil {
+		return err
+	}
+	states, err := utils.ParseTransferItems(asset, fromAddr, items)
+	if err != nil {
+		return err
+	}
+
+	force := ctx.Bool(utils.GetFlagName(utils.ForceSendTxFlag))
+	if !force {
+		err = utils.CheckBatchBalance(asset, states)
+		if err != nil {
+			PrintErrorMsg("%s", err)
+			PrintInfoMsg("\nTip:")
+			PrintInfoMsg("  If you want to send transaction compulsively, please using %s flag.", utils.GetFlagName(utils.ForceSendTxFlag))
+			return nil
+		}
+	}
+
+	gasPrice := ctx.Uint64(utils.GetFlagName(utils.TransactionGasPriceFlag))
+	var gasLimit uint64
+	if ctx.IsSet(utils.GetFlagName(utils.TransactionGasLimitFlag)) {
+		gasLimit = ctx.Uint64(utils.GetFlagName(utils.TransactionGasLimitFlag))
+	}
+	networkId, err := utils.GetNetworkId()
+	if err != nil {
+		return err
+	}
+	if networkId == config.NETWORK_ID_SOLO_NET {
+		gasPrice = 0
+	}
+	txs, err := utils.BatchTransferTx(gasPrice, gasLimit, asset, states, true)
+	if err != nil {
+		return fmt.Errorf("batch transfer error:%s", err)
+	}
+
+	signer, err := cmdcom.GetAccount(ctx, fromAddr)
+	if err != nil {
+		return err
+	}
+	PrintInfoMsg("Batch transfer %s", strings.ToUpper(asset))
+	PrintInfoMsg("  From:%s", fromAddr)
+	PrintInfoMsg("  Transfers:%d", len(states))
+	PrintInfoMsg("  Transactions:%d", len(txs))
+	for i, mutable := range txs {
+		txHash, err := utils.InvokeSmartContract(signer, mutable)
+		if err != nil {
+			return fmt.Errorf("send transaction %d error:%s", i, err)
+		}
+		PrintInfoMsg("  TxHash:%s", txHash)
+	}
+	PrintInfoMsg("\nTip:")
+	PrintInfoMsg("  Using './onyxchain info status <txhash>' to query transaction status.")
+	return nil
+}
+
 func approve(ctx *cli.Context) error {
 	SetRpcPort(ctx)
 	asset := ctx.String(utils.GetFlagName(utils.ApproveAssetFlag))
@@ -25,6 +25,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/config"
 	"github.com/OnyxPay/OnyxChain/common/password"
+	"github.com/OnyxPay/OnyxChain/core/types"
 	"github.com/urfave/cli"
 	"strconv"
 )
@@ -173,3 +174,26 @@ func ClearPasswd(passwd []byte) {
 		passwd[i] = 0
 	}
 }
+
+//GetGasPriceAndLimit return the gas price and gas limit of transaction. Values not set by flags
+//are taken from the gas estimation of the node, the tx need not be signed
+func GetGasPriceAndLimit(ctx *cli.Context, tx *types.MutableTransaction) (uint64, uint64, error) {
+	gasPriceFlag := utils.GetFlagName(utils.TransactionGasPriceFlag)
+	gasLimitFlag := utils.GetFlagName(utils.TransactionGasLimitFlag)
+	gasPrice := ctx.Uint64(gasPriceFlag)
+	gasLimit := ctx.Uint64(gasLimitFlag)
+	if ctx.IsSet(gasPriceFlag) && ctx.IsSet(gasLimitFlag) {
+		return gasPrice, gasLimit, nil
+	}
+	estimate, err := utils.EstimateGas(tx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("estimate gas error:%s", err)
+	}
+	if !ctx.IsSet(gasPriceFlag) {
+		gasPrice = estimate.GasPrice
+	}
+	if !ctx.IsSet(gasLimitFlag) {
+		gasLimit = estimate.GasLimit
+	}
+	return gasPrice, gasLimit, nil
+}
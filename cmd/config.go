@@ -36,6 +36,10 @@ func SetOnyxChainConfig(ctx *cli.Context) (*config.OnyxChainConfig, error) {
 		return nil, fmt.Errorf("setGenesis error:%s", err)
 	}
 	setCommonConfig(ctx, cfg.Common)
+	err = setCheckpoints(ctx, cfg.Common)
+	if err != nil {
+		return nil, fmt.Errorf("setCheckpoints error:%s", err)
+	}
 	setConsensusConfig(ctx, cfg.Consensus)
 	setP2PNodeConfig(ctx, cfg.P2PNode)
 	setRpcConfig(ctx, cfg.Rpc)
@@ -50,6 +54,15 @@ func SetOnyxChainConfig(ctx *cli.Context) (*config.OnyxChainConfig, error) {
 		cfg.P2PNode.NetworkMagic = config.GetNetworkMagic(cfg.P2PNode.NetworkId)
 		cfg.Common.GasPrice = 0
 	}
+	if cfg.Common.PruneBlocks > 0 {
+		if cfg.Common.PruneBlocks < config.MIN_PRUNE_BLOCKS {
+			return nil, fmt.Errorf("prune blocks should not be less than %d", config.MIN_PRUNE_BLOCKS)
+		}
+		//vbft loads chain config from history blocks
+		if cfg.Consensus.EnableConsensus && cfg.Genesis.ConsensusType == config.CONSENSUS_TYPE_VBFT {
+			return nil, fmt.Errorf("vbft consensus is not supported by pruned node")
+		}
+	}
 	if cfg.P2PNode.NetworkId == config.NETWORK_ID_MAIN_NET ||
 		cfg.P2PNode.NetworkId == config.NETWORK_ID_POLARIS_NET {
 		defNetworkId, err := cfg.GetDefaultNetworkId()
@@ -62,6 +75,9 @@ func SetOnyxChainConfig(ctx *cli.Context) (*config.OnyxChainConfig, error) {
 			cfg.P2PNode.NetworkName = config.GetNetworkName(defNetworkId)
 		}
 	}
+	if _, err := cfg.GetCheckpoints(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
@@ -108,6 +124,16 @@ func setGenesis(ctx *cli.Context, cfg *config.OnyxChainConfig) error {
 		if cfg.Genesis.DBFT.GenBlockTime <= 0 {
 			cfg.Genesis.DBFT.GenBlockTime = config.DEFAULT_GEN_BLOCK_TIME
 		}
+	case config.CONSENSUS_TYPE_SBFT:
+		if len(cfg.Genesis.SBFT.Bookkeepers) < config.SBFT_MIN_NODE_NUM {
+			return fmt.Errorf("SBFT consensus at least need %d bookkeepers in config", config.SBFT_MIN_NODE_NUM)
+		}
+		if cfg.Genesis.SBFT.GenBlockTime <= 0 {
+			cfg.Genesis.SBFT.GenBlockTime = config.DEFAULT_GEN_BLOCK_TIME
+		}
+		if cfg.Genesis.SBFT.ViewChangeTimeout <= 0 {
+			cfg.Genesis.SBFT.ViewChangeTimeout = config.DEFAULT_VIEW_CHANGE_TIMEOUT
+		}
 	case config.CONSENSUS_TYPE_VBFT:
 		err = governance.CheckVBFTConfig(cfg.Genesis.VBFT)
 		if err != nil {
@@ -126,11 +152,28 @@ func setGenesis(ctx *cli.Context, cfg *config.OnyxChainConfig) error {
 func setCommonConfig(ctx *cli.Context, cfg *config.CommonConfig) {
 	cfg.LogLevel = ctx.Uint(utils.GetFlagName(utils.LogLevelFlag))
 	cfg.EnableEventLog = !ctx.Bool(utils.GetFlagName(utils.DisableEventLogFlag))
+	cfg.EnableEventIndex = ctx.Bool(utils.GetFlagName(utils.EnableEventIndexFlag))
+	cfg.EnableStateProof = ctx.Bool(utils.GetFlagName(utils.EnableStateProofFlag))
+	cfg.PruneBlocks = uint32(ctx.Uint(utils.GetFlagName(utils.PruneBlocksFlag)))
 	cfg.GasLimit = ctx.Uint64(utils.GetFlagName(utils.GasLimitFlag))
 	cfg.GasPrice = ctx.Uint64(utils.GetFlagName(utils.GasPriceFlag))
+	cfg.MaxTxPerPayer = ctx.Uint(utils.GetFlagName(utils.MaxTxPerPayerFlag))
 	cfg.DataDir = ctx.String(utils.GetFlagName(utils.DataDirFlag))
 }
 
+func setCheckpoints(ctx *cli.Context, cfg *config.CommonConfig) error {
+	if !ctx.IsSet(utils.GetFlagName(utils.CheckpointsFileFlag)) {
+		return nil
+	}
+	cpFile := ctx.String(utils.GetFlagName(utils.CheckpointsFileFlag))
+	err := utils.GetJsonObjectFromFile(cpFile, &cfg.Checkpoints)
+	if err != nil {
+		return err
+	}
+	log.Infof("Load %d checkpoints from %s", len(cfg.Checkpoints), cpFile)
+	return nil
+}
+
 func setConsensusConfig(ctx *cli.Context, cfg *config.ConsensusConfig) {
 	cfg.EnableConsensus = ctx.Bool(utils.GetFlagName(utils.EnableConsensusFlag))
 	cfg.MaxTxInBlock = ctx.Uint(utils.GetFlagName(utils.MaxTxInBlockFlag))
@@ -148,6 +191,9 @@ func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
 	cfg.MaxConnInBound = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundFlag))
 	cfg.MaxConnOutBound = ctx.Uint(utils.GetFlagName(utils.MaxConnOutBoundFlag))
 	cfg.MaxConnInBoundForSingleIP = ctx.Uint(utils.GetFlagName(utils.MaxConnInBoundForSingleIPFlag))
+	cfg.NodeKeyPath = ctx.String(utils.GetFlagName(utils.NodeKeyFileFlag))
+	cfg.DHTPort = ctx.Uint(utils.GetFlagName(utils.DHTPortFlag))
+	cfg.DiscoveryOnly = ctx.Bool(utils.GetFlagName(utils.DiscoveryOnlyFlag))
 
 	rsvfile := ctx.String(utils.GetFlagName(utils.ReservedPeersFileFlag))
 	if cfg.ReservedPeersOnly {
@@ -163,6 +209,9 @@ func setP2PNodeConfig(ctx *cli.Context, cfg *config.P2PNodeConfig) {
 		for i := 0; i < len(cfg.ReservedCfg.ReservedPeers); i++ {
 			log.Info("reserved addr: " + cfg.ReservedCfg.ReservedPeers[i])
 		}
+		for i := 0; i < len(cfg.ReservedCfg.ReservedKeys); i++ {
+			log.Info("reserved key: " + cfg.ReservedCfg.ReservedKeys[i])
+		}
 		for i := 0; i < len(cfg.ReservedCfg.MaskPeers); i++ {
 			log.Info("mask addr: " + cfg.ReservedCfg.MaskPeers[i])
 		}
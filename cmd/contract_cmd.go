@@ -25,6 +25,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/cmd/utils"
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/core/payload"
 	httpcom "github.com/OnyxPay/OnyxChain/http/base/common"
 	"github.com/urfave/cli"
 	"io/ioutil"
@@ -35,9 +36,9 @@ var (
 	ContractCommand = cli.Command{
 		Name:        "contract",
 		Action:      cli.ShowSubcommandHelp,
-		Usage:       "Deploy or invoke smart contract",
+		Usage:       "Deploy, invoke or debug smart contract",
 		ArgsUsage:   " ",
-		Description: `Smart contract operations support the deployment of NeoVM smart contract, and the pre-execution and execution of NeoVM smart contract.`,
+		Description: `Smart contract operations support the deployment of NeoVM and WasmVM smart contract, and the pre-execution, execution and debugging of NeoVM smart contract.`,
 		Subcommands: []cli.Command{
 			{
 				Action:    deployContract,
@@ -50,6 +51,7 @@ var (
 					utils.TransactionGasLimitFlag,
 					utils.ContractStorageFlag,
 					utils.ContractCodeFileFlag,
+					utils.ContractVmTypeFlag,
 					utils.ContractNameFlag,
 					utils.ContractVersionFlag,
 					utils.ContractAuthorFlag,
@@ -109,6 +111,25 @@ var (
 					utils.AccountAddressFlag,
 				},
 			},
+			{
+				Action: debugContract,
+				Name:   "debug",
+				Usage:  "Debug NeoVM smart contract",
+				ArgsUsage: `Invoke the contract code with params in a local debugger. Parameters are same as invoke command.
+
+  The contract runs on an empty in-memory ledger, or on the ledger of data dir if --datadir is specified.
+  Node should be stopped before debugging on its data dir, and the changes of execution are never saved.
+  With --dap flag, the debugger serves Debug Adapter Protocol for IDE, the source of contract is its disassembly.
+`,
+				Flags: []cli.Flag{
+					utils.ContractCodeFileFlag,
+					utils.ContractParamsFlag,
+					utils.ContractDebugDAPFlag,
+					utils.DataDirFlag,
+					utils.ConfigFlag,
+					utils.NetworkIdFlag,
+				},
+			},
 		},
 	}
 )
@@ -123,6 +144,15 @@ func deployContract(ctx *cli.Context) error {
 	}
 
 	store := ctx.Bool(utils.GetFlagName(utils.ContractStorageFlag))
+	var vmType payload.VmType
+	switch strings.ToLower(ctx.String(utils.GetFlagName(utils.ContractVmTypeFlag))) {
+	case "", "neovm":
+		vmType = payload.NEOVM_TYPE
+	case "wasmvm":
+		vmType = payload.WASMVM_TYPE
+	default:
+		return fmt.Errorf("unsupported vm type:%s", ctx.String(utils.GetFlagName(utils.ContractVmTypeFlag)))
+	}
 	codeFile := ctx.String(utils.GetFlagName(utils.ContractCodeFileFlag))
 	if "" == codeFile {
 		return fmt.Errorf("please specific code file")
@@ -151,7 +181,7 @@ func deployContract(ctx *cli.Context) error {
 	cversion := fmt.Sprintf("%s", version)
 
 	if ctx.IsSet(utils.GetFlagName(utils.ContractPrepareDeployFlag)) {
-		preResult, err := utils.PrepareDeployContract(store, code, name, cversion, author, email, desc)
+		preResult, err := utils.PrepareDeployContract(store, vmType, code, name, cversion, author, email, desc)
 		if err != nil {
 			return fmt.Errorf("PrepareDeployContract error:%s", err)
 		}
@@ -168,7 +198,7 @@ func deployContract(ctx *cli.Context) error {
 		return fmt.Errorf("get signer account error:%s", err)
 	}
 
-	txHash, err := utils.DeployContract(gasPrice, gasLimit, signer, store, code, name, cversion, author, email, desc)
+	txHash, err := utils.DeployContract(gasPrice, gasLimit, signer, store, vmType, code, name, cversion, author, email, desc)
 	if err != nil {
 		return fmt.Errorf("DeployContract error:%s", err)
 	}
@@ -330,8 +360,14 @@ func invokeContract(ctx *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("get signer account error:%s", err)
 	}
-	gasPrice := ctx.Uint64(utils.GetFlagName(utils.TransactionGasPriceFlag))
-	gasLimit := ctx.Uint64(utils.GetFlagName(utils.TransactionGasLimitFlag))
+	mutable, err := httpcom.NewNeovmInvokeTransaction(0, 0, contractAddr, params)
+	if err != nil {
+		return err
+	}
+	gasPrice, gasLimit, err := cmdcom.GetGasPriceAndLimit(ctx, mutable)
+	if err != nil {
+		return err
+	}
 	networkId, err := utils.GetNetworkId()
 	if err != nil {
 		return err
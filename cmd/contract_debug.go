@@ -0,0 +1,265 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/OnyxPay/OnyxChain/cmd/utils"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/core/store/ledgerstore"
+	"github.com/OnyxPay/OnyxChain/smartcontract/debugger"
+	"github.com/urfave/cli"
+)
+
+const debugHelp = `Commands:
+  break, b <offset>     Set breakpoint at hex offset of contract
+  delete, d <offset>    Delete breakpoint at hex offset of contract
+  continue, c           Continue until next breakpoint
+  step, s               Step into next instruction
+  next, n               Step over next instruction
+  out, o                Step out of current function
+  stack, bt             Print call stack
+  eval, e [frame]       Print evaluation stack of frame
+  alt, a [frame]        Print alt stack of frame
+  storage, st [frame]   Print storage of contract of frame
+  list, l [frame]       Print disassembly of frame
+  help, h               Print this help
+  quit, q               Terminate debugging`
+
+func debugContract(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+	if !ctx.IsSet(utils.GetFlagName(utils.ContractCodeFileFlag)) {
+		PrintErrorMsg("Missing %s argument.", utils.ContractCodeFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	codeFile := ctx.String(utils.GetFlagName(utils.ContractCodeFileFlag))
+	codeStr, err := ioutil.ReadFile(codeFile)
+	if err != nil {
+		return fmt.Errorf("read code:%s error:%s", codeFile, err)
+	}
+	code, err := common.HexToBytes(strings.TrimSpace(string(codeStr)))
+	if err != nil {
+		return fmt.Errorf("contract code convert hex to bytes error:%s", err)
+	}
+	params, err := utils.ParseParams(ctx.String(utils.GetFlagName(utils.ContractParamsFlag)))
+	if err != nil {
+		return fmt.Errorf("parseParams error:%s", err)
+	}
+	debugConfig := &debugger.Config{Code: code, Params: params}
+
+	if ctx.IsSet(utils.GetFlagName(utils.DataDirFlag)) {
+		cfg, err := SetOnyxChainConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("SetOnyxChainConfig error:%s", err)
+		}
+		dbDir := utils.GetStoreDirPath(cfg.Common.DataDir, cfg.P2PNode.NetworkName)
+		if !common.FileExisted(dbDir) {
+			return fmt.Errorf("data dir:%s doesn't exist", dbDir)
+		}
+		bookKeepers, genesisBlock, err := getSnapshotGenesisBlock()
+		if err != nil {
+			return err
+		}
+		ledgerStore, err := ledgerstore.NewLedgerStore(dbDir, config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId))
+		if err != nil {
+			return fmt.Errorf("NewLedgerStore error:%s", err)
+		}
+		defer ledgerStore.Close()
+		err = ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookKeepers)
+		if err != nil {
+			return fmt.Errorf("init ledger error:%s", err)
+		}
+		debugConfig.Ledger = ledgerStore
+		debugConfig.Overlay = ledgerStore.NewOverlayDB()
+		PrintInfoMsg("Debug on ledger of block height:%d", ledgerStore.GetCurrentBlockHeight())
+	}
+
+	if ctx.IsSet(utils.GetFlagName(utils.ContractDebugDAPFlag)) {
+		address := ctx.String(utils.GetFlagName(utils.ContractDebugDAPFlag))
+		PrintInfoMsg("Debug adapter listening on %s", address)
+		return debugger.ListenDAP(address, debugConfig)
+	}
+
+	session, err := debugger.NewSession(debugConfig)
+	if err != nil {
+		return err
+	}
+	PrintInfoMsg("Contract address:%s", session.Contract.ToHexString())
+	PrintInfoMsg(debugHelp)
+	printDebugEvent(session, session.Start(true))
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("(debug) ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			session.Terminate()
+			return nil
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		exited, err := debugCommand(session, args)
+		if err != nil {
+			PrintErrorMsg("%s", err)
+		}
+		if exited {
+			return nil
+		}
+	}
+}
+
+//debugCommand execute a console command, and return true if the execution is exited
+func debugCommand(session *debugger.Session, args []string) (bool, error) {
+	var event *debugger.Event
+	switch args[0] {
+	case "break", "b", "delete", "d":
+		if len(args) < 2 {
+			return false, fmt.Errorf("missing offset")
+		}
+		offset, err := strconv.ParseUint(strings.TrimPrefix(args[1], "0x"), 16, 32)
+		if err != nil {
+			return false, fmt.Errorf("invalid offset:%s", args[1])
+		}
+		if args[0] == "break" || args[0] == "b" {
+			session.SetBreakpoint(session.Contract, int(offset))
+		} else {
+			session.ClearBreakpoint(session.Contract, int(offset))
+		}
+		PrintInfoMsg("Breakpoints:%s", formatOffsets(session.Breakpoints(session.Contract)))
+		return false, nil
+	case "continue", "c":
+		event = session.Continue()
+	case "step", "s":
+		event = session.StepInto()
+	case "next", "n":
+		event = session.StepOver()
+	case "out", "o":
+		event = session.StepOut()
+	case "stack", "bt":
+		for i, frame := range session.CallStack() {
+			PrintInfoMsg("#%d %s:%04X", i, frame.Contract.ToHexString(), frame.Offset)
+		}
+		return false, nil
+	case "eval", "e", "alt", "a", "storage", "st", "list", "l":
+		frame, err := debugFrame(session, args)
+		if err != nil {
+			return false, err
+		}
+		switch args[0] {
+		case "eval", "e":
+			printStackItems(frame.EvaluationStack())
+		case "alt", "a":
+			printStackItems(frame.AltStack())
+		case "storage", "st":
+			items, err := session.Storage(frame.Contract)
+			if err != nil {
+				return false, err
+			}
+			for _, item := range items {
+				PrintInfoMsg("%s: %s", item.Key, item.Value)
+			}
+		default:
+			instrs, err := debugger.Disassemble(frame.Code)
+			if err != nil {
+				return false, err
+			}
+			for _, instr := range instrs {
+				mark := " "
+				if instr.Offset == frame.Offset {
+					mark = ">"
+				}
+				PrintInfoMsg("%s %04X  %s", mark, instr.Offset, instr)
+			}
+		}
+		return false, nil
+	case "help", "h":
+		PrintInfoMsg(debugHelp)
+		return false, nil
+	case "quit", "q":
+		session.Terminate()
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command:%s", args[0])
+	}
+	printDebugEvent(session, event)
+	return event.Reason == debugger.STOP_EXIT, nil
+}
+
+func debugFrame(session *debugger.Session, args []string) (*debugger.Frame, error) {
+	frames := session.CallStack()
+	index := 0
+	if len(args) > 1 {
+		i, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid frame:%s", args[1])
+		}
+		index = i
+	}
+	if index < 0 || index >= len(frames) {
+		return nil, fmt.Errorf("frame %d not in call stack", index)
+	}
+	return frames[index], nil
+}
+
+func printDebugEvent(session *debugger.Session, event *debugger.Event) {
+	if event.Reason == debugger.STOP_EXIT {
+		PrintInfoMsg("Execution exited, gas consumed:%d", session.GasConsumed())
+		if event.Err != nil {
+			PrintErrorMsg("Execution failed:%s", event.Err)
+		} else if event.Result != nil {
+			PrintInfoMsg("Return:%s", event.Result)
+		}
+		return
+	}
+	instr := ""
+	frames := session.CallStack()
+	if len(frames) > 0 {
+		instrs, _ := debugger.Disassemble(frames[0].Code)
+		for _, v := range instrs {
+			if v.Offset == event.Offset {
+				instr = v.String()
+			}
+		}
+	}
+	PrintInfoMsg("Stopped(%s) at %s:%04X  %s", event.Reason, event.Contract.ToHexString(), event.Offset, instr)
+}
+
+func printStackItems(items []*debugger.StackItem) {
+	for i, item := range items {
+		PrintInfoMsg("%d: %s", i, item)
+	}
+}
+
+func formatOffsets(offsets []int) string {
+	strs := make([]string, 0, len(offsets))
+	for _, offset := range offsets {
+		strs = append(strs, fmt.Sprintf("%04X", offset))
+	}
+	return strings.Join(strs, " ")
+}
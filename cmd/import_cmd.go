@@ -47,6 +47,10 @@ var ImportCommand = cli.Command{
 		utils.ConfigFlag,
 		utils.NetworkIdFlag,
 		utils.DisableEventLogFlag,
+		utils.EnableEventIndexFlag,
+		utils.EnableStateProofFlag,
+		utils.PruneBlocksFlag,
+		utils.CheckpointsFileFlag,
 	},
 	Description: "Note that import cmd doesn't support testmode",
 }
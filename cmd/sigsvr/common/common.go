@@ -27,6 +27,9 @@ import (
 
 var DefWalletStore *store.WalletStore
 
+//DefMultiSigStore is the store of pending multi-signature transactions
+var DefMultiSigStore *store.MultiSigStore
+
 type CliRpcRequest struct {
 	Qid     string          `json:"qid"`
 	Params  json.RawMessage `json:"params"`
@@ -26,7 +26,13 @@ func init() {
 	DefCliRpcSvr.RegHandler("sigdata", handlers.SigData)
 	DefCliRpcSvr.RegHandler("sigrawtx", handlers.SigRawTransaction)
 	DefCliRpcSvr.RegHandler("sigmutilrawtx", handlers.SigMutilRawTransaction)
+	DefCliRpcSvr.RegHandler("regmultisigtx", handlers.RegMultiSigTx)
+	DefCliRpcSvr.RegHandler("listmultisigtx", handlers.ListMultiSigTx)
+	DefCliRpcSvr.RegHandler("getmultisigtx", handlers.GetMultiSigTx)
+	DefCliRpcSvr.RegHandler("sigmultisigtx", handlers.SigMultiSigTx)
+	DefCliRpcSvr.RegHandler("delmultisigtx", handlers.DelMultiSigTx)
 	DefCliRpcSvr.RegHandler("sigtransfertx", handlers.SigTransferTransaction)
+	DefCliRpcSvr.RegHandler("sigbatchtransfertx", handlers.SigBatchTransferTransaction)
 	DefCliRpcSvr.RegHandler("signeovminvoketx", handlers.SigNeoVMInvokeTx)
 	DefCliRpcSvr.RegHandler("signeovminvokeabitx", handlers.SigNeoVMInvokeAbiTx)
 	DefCliRpcSvr.RegHandler("signativeinvoketx", handlers.SigNativeInvokeTx)
@@ -0,0 +1,490 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	clisvrcom "github.com/OnyxPay/OnyxChain/cmd/sigsvr/common"
+	"github.com/OnyxPay/OnyxChain/cmd/sigsvr/store"
+	cliutil "github.com/OnyxPay/OnyxChain/cmd/utils"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/constants"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/signature"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	bcomn "github.com/OnyxPay/OnyxChain/http/base/common"
+	"github.com/OnyxPay/OnyxChain/smartcontract/debugger"
+)
+
+type RegMultiSigTxReq struct {
+	RawTx   string   `json:"raw_tx"`
+	M       int      `json:"m"`
+	PubKeys []string `json:"pub_keys"`
+}
+
+type RegMultiSigTxRsp struct {
+	Id      string   `json:"id"`
+	Address string   `json:"address"`
+	Signers []string `json:"signers"`
+}
+
+type MultiSigTxIdReq struct {
+	Id string `json:"id"`
+}
+
+type ListMultiSigTxReq struct {
+	Address string `json:"address"`
+}
+
+type ListMultiSigTxRsp struct {
+	Txs []*store.PendingMultiSigTx `json:"txs"`
+}
+
+type GetMultiSigTxRsp struct {
+	*store.PendingMultiSigTx
+	Tx   *bcomn.Transactions `json:"tx"`
+	Code string              `json:"code,omitempty"` //disassembled invoke code
+}
+
+type SigMultiSigTxReq struct {
+	Id      string `json:"id"`
+	PubKey  string `json:"pub_key"`  //public key of external signature, empty to sign with account of request
+	SigData string `json:"sig_data"` //external signature of tx hash
+	Submit  bool   `json:"submit"`   //send the transaction to OnyxChain when signatures are enough
+}
+
+type SigMultiSigTxRsp struct {
+	Id       string   `json:"id"`
+	Signers  []string `json:"signers"`
+	Complete bool     `json:"complete"`
+	SignedTx string   `json:"signed_tx,omitempty"`
+	TxHash   string   `json:"tx_hash,omitempty"`
+}
+
+//RegMultiSigTx register a transaction of multi-signature address, co-signers sign it by id later.
+//The empty payer of unsigned tx is set to the multi-signature address, which changes the tx hash, so
+//external signatures should sign the hash of the registered tx, that is the returned id
+func RegMultiSigTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcResponse) {
+	if clisvrcom.DefMultiSigStore == nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		resp.ErrorInfo = "multi-signature store not enabled"
+		return
+	}
+	rawReq := &RegMultiSigTxReq{}
+	err := json.Unmarshal(req.Params, rawReq)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		return
+	}
+	pubKeys, err := parseMultiSigPubKeys(rawReq.M, rawReq.PubKeys)
+	if err != nil {
+		log.Infof("Cli Qid:%s RegMultiSigTx parse pub keys error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		resp.ErrorInfo = err.Error()
+		return
+	}
+	mutTx, err := parseMutableTx(rawReq.RawTx)
+	if err != nil {
+		log.Infof("Cli Qid:%s RegMultiSigTx parse tx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_TX
+		return
+	}
+	address, err := types.AddressFromMultiPubKeys(pubKeys, rawReq.M)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		return
+	}
+	if mutTx.Payer == common.ADDRESS_EMPTY {
+		if len(mutTx.Sigs) != 0 {
+			resp.ErrorCode = clisvrcom.CLIERR_INVALID_TX
+			resp.ErrorInfo = "payer of signed tx cannot be empty"
+			return
+		}
+		mutTx.Payer = address
+	}
+	rawTx, err := serializeMutableTx(mutTx)
+	if err != nil {
+		log.Infof("Cli Qid:%s RegMultiSigTx serialize tx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	txHash := mutTx.Hash()
+	pending := &store.PendingMultiSigTx{
+		Id:        txHash.ToHexString(),
+		Address:   address.ToBase58(),
+		M:         uint16(rawReq.M),
+		PubKeys:   rawReq.PubKeys,
+		Signers:   encodePubKeys(cliutil.GetMultiSigners(mutTx, pubKeys)),
+		RawTx:     rawTx,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	clisvrcom.DefMultiSigStore.Lock()
+	defer clisvrcom.DefMultiSigStore.Unlock()
+	exist, err := clisvrcom.DefMultiSigStore.Get(pending.Id)
+	if err != nil {
+		log.Infof("Cli Qid:%s RegMultiSigTx get tx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	if exist != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		resp.ErrorInfo = "tx already registered"
+		return
+	}
+	err = clisvrcom.DefMultiSigStore.Put(pending)
+	if err != nil {
+		log.Infof("Cli Qid:%s RegMultiSigTx put tx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	resp.Result = &RegMultiSigTxRsp{
+		Id:      pending.Id,
+		Address: pending.Address,
+		Signers: pending.Signers,
+	}
+}
+
+//ListMultiSigTx return the registered multi-signature transactions, filtered by address if not empty
+func ListMultiSigTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcResponse) {
+	if clisvrcom.DefMultiSigStore == nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		resp.ErrorInfo = "multi-signature store not enabled"
+		return
+	}
+	rawReq := &ListMultiSigTxReq{}
+	if len(req.Params) > 0 {
+		err := json.Unmarshal(req.Params, rawReq)
+		if err != nil {
+			resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+			return
+		}
+	}
+	txs, err := clisvrcom.DefMultiSigStore.List()
+	if err != nil {
+		log.Infof("Cli Qid:%s ListMultiSigTx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	result := make([]*store.PendingMultiSigTx, 0, len(txs))
+	for _, tx := range txs {
+		if rawReq.Address != "" && rawReq.Address != tx.Address {
+			continue
+		}
+		result = append(result, tx)
+	}
+	resp.Result = &ListMultiSigTxRsp{Txs: result}
+}
+
+//GetMultiSigTx return the registered multi-signature transaction with decoded payload
+func GetMultiSigTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcResponse) {
+	pending, errCode := getPendingMultiSigTx(req)
+	if errCode != clisvrcom.CLIERR_OK {
+		resp.ErrorCode = errCode
+		return
+	}
+	mutTx, err := parseMutableTx(pending.RawTx)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	tx, err := mutTx.IntoImmutable()
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	rsp := &GetMultiSigTxRsp{
+		PendingMultiSigTx: pending,
+		Tx:                bcomn.TransArryByteToHexString(tx),
+	}
+	if invoke, ok := tx.Payload.(*payload.InvokeCode); ok {
+		instrs, err := debugger.Disassemble(invoke.Code)
+		if err == nil {
+			rsp.Code = debugger.Listing(instrs)
+		}
+	}
+	resp.Result = rsp
+}
+
+//SigMultiSigTx add a signature of co-signer to the registered transaction, and send the transaction if
+//signatures are enough and submit is required
+func SigMultiSigTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcResponse) {
+	if clisvrcom.DefMultiSigStore == nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		resp.ErrorInfo = "multi-signature store not enabled"
+		return
+	}
+	rawReq := &SigMultiSigTxReq{}
+	err := json.Unmarshal(req.Params, rawReq)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		return
+	}
+	clisvrcom.DefMultiSigStore.Lock()
+	defer clisvrcom.DefMultiSigStore.Unlock()
+	pending, err := clisvrcom.DefMultiSigStore.Get(rawReq.Id)
+	if err != nil {
+		log.Infof("Cli Qid:%s SigMultiSigTx get tx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	if pending == nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		resp.ErrorInfo = "tx not found"
+		return
+	}
+	if pending.TxHash != "" {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		resp.ErrorInfo = "tx already submitted"
+		return
+	}
+	pubKeys, err := parseMultiSigPubKeys(int(pending.M), pending.PubKeys)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	mutTx, err := parseMutableTx(pending.RawTx)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+
+	if !pending.Complete() {
+		if rawReq.SigData != "" {
+			err = addExternalMultiSig(mutTx, pending.M, pubKeys, rawReq.PubKey, rawReq.SigData)
+			if err != nil {
+				log.Infof("Cli Qid:%s SigMultiSigTx add signature error:%s", req.Qid, err)
+				resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+				resp.ErrorInfo = err.Error()
+				return
+			}
+		} else {
+			signer, err := req.GetAccount()
+			if err != nil {
+				log.Infof("Cli Qid:%s SigMultiSigTx GetAccount:%s", req.Qid, err)
+				resp.ErrorCode = clisvrcom.CLIERR_ACCOUNT_UNLOCK
+				return
+			}
+			err = cliutil.MultiSigTransaction(mutTx, pending.M, pubKeys, signer)
+			if err != nil {
+				log.Infof("Cli Qid:%s SigMultiSigTx MultiSigTransaction error:%s", req.Qid, err)
+				resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+				resp.ErrorInfo = err.Error()
+				return
+			}
+		}
+		pending.Signers = encodePubKeys(cliutil.GetMultiSigners(mutTx, pubKeys))
+		pending.RawTx, err = serializeMutableTx(mutTx)
+		if err != nil {
+			resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+			return
+		}
+	}
+	//save the signatures before submitting, so they are kept if submitting failed
+	err = clisvrcom.DefMultiSigStore.Put(pending)
+	if err != nil {
+		log.Infof("Cli Qid:%s SigMultiSigTx put tx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+	if pending.Complete() && rawReq.Submit {
+		err = submitMultiSigTx(mutTx, pending)
+		if err != nil {
+			log.Infof("Cli Qid:%s SigMultiSigTx submit tx error:%s", req.Qid, err)
+			resp.ErrorCode = clisvrcom.CLIERR_INVALID_TX
+			resp.ErrorInfo = err.Error()
+		}
+	}
+	rsp := &SigMultiSigTxRsp{
+		Id:       pending.Id,
+		Signers:  pending.Signers,
+		Complete: pending.Complete(),
+		TxHash:   pending.TxHash,
+	}
+	if rsp.Complete {
+		rsp.SignedTx = pending.RawTx
+	}
+	resp.Result = rsp
+}
+
+//submitMultiSigTx send the complete transaction to OnyxChain, and save its hash to the pending transaction
+func submitMultiSigTx(mutTx *types.MutableTransaction, pending *store.PendingMultiSigTx) error {
+	tx, err := mutTx.IntoImmutable()
+	if err != nil {
+		return err
+	}
+	txHash, err := cliutil.SendRawTransaction(tx)
+	if err != nil {
+		return err
+	}
+	pending.TxHash = txHash
+	return clisvrcom.DefMultiSigStore.Put(pending)
+}
+
+//DelMultiSigTx remove the registered transaction, the account of request should be one of co-signers
+func DelMultiSigTx(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcResponse) {
+	if clisvrcom.DefMultiSigStore == nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		resp.ErrorInfo = "multi-signature store not enabled"
+		return
+	}
+	clisvrcom.DefMultiSigStore.Lock()
+	defer clisvrcom.DefMultiSigStore.Unlock()
+	pending, errCode := getPendingMultiSigTx(req)
+	if errCode != clisvrcom.CLIERR_OK {
+		resp.ErrorCode = errCode
+		return
+	}
+	signer, err := req.GetAccount()
+	if err != nil {
+		log.Infof("Cli Qid:%s DelMultiSigTx GetAccount:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_ACCOUNT_UNLOCK
+		return
+	}
+	signerKey := hex.EncodeToString(keypair.SerializePublicKey(signer.PublicKey))
+	isCoSigner := false
+	for _, pk := range pending.PubKeys {
+		if pk == signerKey {
+			isCoSigner = true
+			break
+		}
+	}
+	if !isCoSigner {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		resp.ErrorInfo = "account is not co-signer of tx"
+		return
+	}
+	err = clisvrcom.DefMultiSigStore.Delete(pending.Id)
+	if err != nil {
+		log.Infof("Cli Qid:%s DelMultiSigTx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+		return
+	}
+}
+
+func getPendingMultiSigTx(req *clisvrcom.CliRpcRequest) (*store.PendingMultiSigTx, int) {
+	if clisvrcom.DefMultiSigStore == nil {
+		return nil, clisvrcom.CLIERR_INTERNAL_ERR
+	}
+	rawReq := &MultiSigTxIdReq{}
+	err := json.Unmarshal(req.Params, rawReq)
+	if err != nil {
+		return nil, clisvrcom.CLIERR_INVALID_PARAMS
+	}
+	pending, err := clisvrcom.DefMultiSigStore.Get(rawReq.Id)
+	if err != nil {
+		log.Infof("Cli Qid:%s get multi-signature tx error:%s", req.Qid, err)
+		return nil, clisvrcom.CLIERR_INTERNAL_ERR
+	}
+	if pending == nil {
+		return nil, clisvrcom.CLIERR_INVALID_PARAMS
+	}
+	return pending, clisvrcom.CLIERR_OK
+}
+
+func addExternalMultiSig(mutTx *types.MutableTransaction, m uint16, pubKeys []keypair.PublicKey, pkStr, sigStr string) error {
+	pkData, err := hex.DecodeString(pkStr)
+	if err != nil {
+		return fmt.Errorf("invalid pub key")
+	}
+	pk, err := keypair.DeserializePublicKey(pkData)
+	if err != nil {
+		return fmt.Errorf("invalid pub key")
+	}
+	isCoSigner := false
+	for _, key := range pubKeys {
+		if keypair.ComparePublicKey(key, pk) {
+			isCoSigner = true
+			break
+		}
+	}
+	if !isCoSigner {
+		return fmt.Errorf("pub key is not co-signer of tx")
+	}
+	sigData, err := hex.DecodeString(sigStr)
+	if err != nil {
+		return fmt.Errorf("invalid sig data")
+	}
+	txHash := mutTx.Hash()
+	err = signature.Verify(pk, txHash.ToArray(), sigData)
+	if err != nil {
+		return fmt.Errorf("verify signature error:%s", err)
+	}
+	cliutil.AddMultiSignature(mutTx, m, pubKeys, pk, sigData)
+	return nil
+}
+
+func parseMultiSigPubKeys(m int, pkStrs []string) ([]keypair.PublicKey, error) {
+	numkeys := len(pkStrs)
+	if m <= 0 || numkeys < m || numkeys <= 1 || numkeys > constants.MULTI_SIG_MAX_PUBKEY_SIZE {
+		return nil, fmt.Errorf("invalid m:%d of %d pub keys", m, numkeys)
+	}
+	pubKeys := make([]keypair.PublicKey, 0, numkeys)
+	for _, pkStr := range pkStrs {
+		pkData, err := hex.DecodeString(pkStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pub key:%s", pkStr)
+		}
+		pk, err := keypair.DeserializePublicKey(pkData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pub key:%s", pkStr)
+		}
+		pubKeys = append(pubKeys, pk)
+	}
+	return pubKeys, nil
+}
+
+func encodePubKeys(pubKeys []keypair.PublicKey) []string {
+	pkStrs := make([]string, 0, len(pubKeys))
+	for _, pk := range pubKeys {
+		pkStrs = append(pkStrs, hex.EncodeToString(keypair.SerializePublicKey(pk)))
+	}
+	return pkStrs
+}
+
+func parseMutableTx(rawTx string) (*types.MutableTransaction, error) {
+	rawTxData, err := hex.DecodeString(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := types.TransactionFromRawBytes(rawTxData)
+	if err != nil {
+		return nil, err
+	}
+	return tx.IntoMutable()
+}
+
+func serializeMutableTx(mutTx *types.MutableTransaction) (string, error) {
+	tx, err := mutTx.IntoImmutable()
+	if err != nil {
+		return "", err
+	}
+	sink := common.ZeroCopySink{}
+	err = tx.Serialization(&sink)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sink.Bytes()), nil
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/OnyxPay/OnyxChain-crypto/signature"
+	clisvrcom "github.com/OnyxPay/OnyxChain/cmd/sigsvr/common"
+	"github.com/OnyxPay/OnyxChain/cmd/sigsvr/store"
+	"github.com/OnyxPay/OnyxChain/cmd/utils"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiSigTx(t *testing.T) {
+	testMultiSigStorePath := "multisig_data_tmp"
+	var err error
+	clisvrcom.DefMultiSigStore, err = store.NewMultiSigStore(testMultiSigStorePath)
+	if err != nil {
+		t.Errorf("NewMultiSigStore error:%s", err)
+		return
+	}
+	defer func() {
+		clisvrcom.DefMultiSigStore.Close()
+		clisvrcom.DefMultiSigStore = nil
+		os.RemoveAll(testMultiSigStorePath)
+	}()
+
+	acc1, err := clisvrcom.DefWalletStore.NewAccountData(keypair.PK_ECDSA, keypair.P256, signature.SHA256withECDSA, pwd)
+	assert.Nil(t, err)
+	clisvrcom.DefWalletStore.AddAccountData(acc1)
+	acc2, err := clisvrcom.DefWalletStore.NewAccountData(keypair.PK_ECDSA, keypair.P256, signature.SHA256withECDSA, pwd)
+	assert.Nil(t, err)
+	clisvrcom.DefWalletStore.AddAccountData(acc2)
+
+	pkData, _ := hex.DecodeString(acc1.PubKey)
+	acc1PubKey, _ := keypair.DeserializePublicKey(pkData)
+	pkData, _ = hex.DecodeString(acc2.PubKey)
+	acc2PubKey, _ := keypair.DeserializePublicKey(pkData)
+
+	m := 2
+	fromAddr, err := types.AddressFromMultiPubKeys([]keypair.PublicKey{acc1PubKey, acc2PubKey}, m)
+	assert.Nil(t, err)
+	mutTx, err := utils.TransferTx(0, 0, "onyx", fromAddr.ToBase58(), acc1.Address, 10)
+	assert.Nil(t, err)
+	tx, err := mutTx.IntoImmutable()
+	assert.Nil(t, err)
+	sink := common.ZeroCopySink{}
+	err = tx.Serialization(&sink)
+	assert.Nil(t, err)
+
+	data, _ := json.Marshal(&RegMultiSigTxReq{
+		RawTx:   hex.EncodeToString(sink.Bytes()),
+		M:       m,
+		PubKeys: []string{acc1.PubKey, acc2.PubKey},
+	})
+	req := &clisvrcom.CliRpcRequest{Qid: "t", Method: "regmultisigtx", Params: data}
+	resp := &clisvrcom.CliRpcResponse{}
+	RegMultiSigTx(req, resp)
+	if resp.ErrorCode != clisvrcom.CLIERR_OK {
+		t.Errorf("RegMultiSigTx failed,ErrorCode:%d ErrorString:%s", resp.ErrorCode, resp.ErrorInfo)
+		return
+	}
+	regRsp := resp.Result.(*RegMultiSigTxRsp)
+	assert.Equal(t, fromAddr.ToBase58(), regRsp.Address)
+	assert.Equal(t, 0, len(regRsp.Signers))
+
+	resp = &clisvrcom.CliRpcResponse{}
+	RegMultiSigTx(req, resp)
+	assert.Equal(t, clisvrcom.CLIERR_INVALID_PARAMS, resp.ErrorCode)
+
+	data, _ = json.Marshal(&ListMultiSigTxReq{Address: fromAddr.ToBase58()})
+	resp = &clisvrcom.CliRpcResponse{}
+	ListMultiSigTx(&clisvrcom.CliRpcRequest{Qid: "t", Params: data}, resp)
+	assert.Equal(t, clisvrcom.CLIERR_OK, resp.ErrorCode)
+	assert.Equal(t, 1, len(resp.Result.(*ListMultiSigTxRsp).Txs))
+
+	data, _ = json.Marshal(&MultiSigTxIdReq{Id: regRsp.Id})
+	resp = &clisvrcom.CliRpcResponse{}
+	GetMultiSigTx(&clisvrcom.CliRpcRequest{Qid: "t", Params: data}, resp)
+	assert.Equal(t, clisvrcom.CLIERR_OK, resp.ErrorCode)
+
+	data, _ = json.Marshal(&SigMultiSigTxReq{Id: regRsp.Id})
+	req = &clisvrcom.CliRpcRequest{Qid: "t", Method: "sigmultisigtx", Params: data, Account: acc1.Address, Pwd: string(pwd)}
+	resp = &clisvrcom.CliRpcResponse{}
+	SigMultiSigTx(req, resp)
+	if resp.ErrorCode != clisvrcom.CLIERR_OK {
+		t.Errorf("SigMultiSigTx failed,ErrorCode:%d ErrorString:%s", resp.ErrorCode, resp.ErrorInfo)
+		return
+	}
+	sigRsp := resp.Result.(*SigMultiSigTxRsp)
+	assert.Equal(t, []string{acc1.PubKey}, sigRsp.Signers)
+	assert.False(t, sigRsp.Complete)
+
+	//external signature of acc2
+	acc2Data, err := clisvrcom.DefWalletStore.GetAccountByAddress(acc2.Address, pwd)
+	assert.Nil(t, err)
+	//the payer is set to the multi-signature address by registration, sign the hash of registered tx
+	txHash, err := common.Uint256FromHexString(regRsp.Id)
+	assert.Nil(t, err)
+	sigData, err := acc2Data.Sign(txHash.ToArray())
+	assert.Nil(t, err)
+	data, _ = json.Marshal(&SigMultiSigTxReq{
+		Id:      regRsp.Id,
+		PubKey:  acc2.PubKey,
+		SigData: hex.EncodeToString(sigData),
+		Submit:  true,
+	})
+	resp = &clisvrcom.CliRpcResponse{}
+	SigMultiSigTx(&clisvrcom.CliRpcRequest{Qid: "t", Params: data}, resp)
+	//no node to submit to, the signature is kept
+	assert.Equal(t, clisvrcom.CLIERR_INVALID_TX, resp.ErrorCode)
+	sigRsp = resp.Result.(*SigMultiSigTxRsp)
+	assert.True(t, sigRsp.Complete)
+	assert.NotEqual(t, "", sigRsp.SignedTx)
+	assert.Equal(t, "", sigRsp.TxHash)
+	pending, err := clisvrcom.DefMultiSigStore.Get(regRsp.Id)
+	assert.Nil(t, err)
+	assert.True(t, pending.Complete())
+	assert.Equal(t, 2, len(pending.Signers))
+
+	data, _ = json.Marshal(&MultiSigTxIdReq{Id: regRsp.Id})
+	resp = &clisvrcom.CliRpcResponse{}
+	DelMultiSigTx(&clisvrcom.CliRpcRequest{Qid: "t", Params: data, Account: acc2.Address, Pwd: string(pwd)}, resp)
+	assert.Equal(t, clisvrcom.CLIERR_OK, resp.ErrorCode)
+	pending, err = clisvrcom.DefMultiSigStore.Get(regRsp.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, pending)
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	clisvrcom "github.com/OnyxPay/OnyxChain/cmd/sigsvr/common"
+	cliutil "github.com/OnyxPay/OnyxChain/cmd/utils"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
+	"strconv"
+)
+
+type BatchTransferItem struct {
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+}
+
+type SigBatchTransferTransactionReq struct {
+	GasPrice  uint64               `json:"gas_price"`
+	GasLimit  uint64               `json:"gas_limit"`
+	Asset     string               `json:"asset"`
+	From      string               `json:"from"`
+	Transfers []*BatchTransferItem `json:"transfers"`
+	Payer     string               `json:"payer"`
+	PreExec   bool                 `json:"pre_exec"` //validate the transactions by pre-execution of node before signing
+}
+
+type SigBatchTransferTransactionRsp struct {
+	SignedTxs []string `json:"signed_txs"`
+}
+
+func SigBatchTransferTransaction(req *clisvrcom.CliRpcRequest, resp *clisvrcom.CliRpcResponse) {
+	rawReq := &SigBatchTransferTransactionReq{}
+	err := json.Unmarshal(req.Params, rawReq)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		return
+	}
+	fromAddr, err := common.AddressFromBase58(rawReq.From)
+	if err != nil {
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		resp.ErrorInfo = "invalid from address"
+		return
+	}
+	states := make([]*onx.State, 0, len(rawReq.Transfers))
+	for _, item := range rawReq.Transfers {
+		toAddr, err := common.AddressFromBase58(item.To)
+		if err != nil {
+			resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+			resp.ErrorInfo = "invalid to address:" + item.To
+			return
+		}
+		amount, err := strconv.ParseUint(item.Amount, 10, 64)
+		if err != nil {
+			resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+			resp.ErrorInfo = "amount should be string type of integer"
+			return
+		}
+		states = append(states, &onx.State{From: fromAddr, To: toAddr, Value: amount})
+	}
+	if rawReq.PreExec {
+		err = cliutil.CheckBatchBalance(rawReq.Asset, states)
+		if err != nil {
+			log.Infof("Cli Qid:%s SigBatchTransferTransaction CheckBatchBalance error:%s", req.Qid, err)
+			resp.ErrorCode = clisvrcom.CLIERR_INVALID_TX
+			resp.ErrorInfo = err.Error()
+			return
+		}
+	}
+	txs, err := cliutil.BatchTransferTx(rawReq.GasPrice, rawReq.GasLimit, rawReq.Asset, states, rawReq.PreExec)
+	if err != nil {
+		log.Infof("Cli Qid:%s SigBatchTransferTransaction BatchTransferTx error:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+		resp.ErrorInfo = err.Error()
+		return
+	}
+	var payerAddress common.Address
+	if rawReq.Payer != "" {
+		payerAddress, err = common.AddressFromBase58(rawReq.Payer)
+		if err != nil {
+			log.Infof("Cli Qid:%s SigBatchTransferTransaction AddressFromBase58 error:%s", req.Qid, err)
+			resp.ErrorCode = clisvrcom.CLIERR_INVALID_PARAMS
+			return
+		}
+	}
+
+	signer, err := req.GetAccount()
+	if err != nil {
+		log.Infof("Cli Qid:%s SigBatchTransferTransaction GetAccount:%s", req.Qid, err)
+		resp.ErrorCode = clisvrcom.CLIERR_ACCOUNT_UNLOCK
+		return
+	}
+	if signer == nil {
+		resp.ErrorCode = clisvrcom.CLIERR_ACCOUNT_UNLOCK
+		return
+	}
+	signedTxs := make([]string, 0, len(txs))
+	for _, mutable := range txs {
+		mutable.Payer = payerAddress
+		err = cliutil.SignTransaction(signer, mutable)
+		if err != nil {
+			log.Infof("Cli Qid:%s SigBatchTransferTransaction SignTransaction error:%s", req.Qid, err)
+			resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+			return
+		}
+		tx, err := mutable.IntoImmutable()
+		if err != nil {
+			log.Infof("Cli Qid:%s SigBatchTransferTransaction tx IntoInmmutable error:%s", req.Qid, err)
+			resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+			return
+		}
+		sink := common.ZeroCopySink{}
+		err = tx.Serialization(&sink)
+		if err != nil {
+			log.Infof("Cli Qid:%s SigBatchTransferTransaction tx Serialize error:%s", req.Qid, err)
+			resp.ErrorCode = clisvrcom.CLIERR_INTERNAL_ERR
+			return
+		}
+		signedTxs = append(signedTxs, hex.EncodeToString(sink.Bytes()))
+	}
+	resp.Result = &SigBatchTransferTransactionRsp{
+		SignedTxs: signedTxs,
+	}
+}
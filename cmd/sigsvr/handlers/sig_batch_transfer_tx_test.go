@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/OnyxPay/OnyxChain/account"
+	clisvrcom "github.com/OnyxPay/OnyxChain/cmd/sigsvr/common"
+	"testing"
+)
+
+func TestSigBatchTransferTransaction(t *testing.T) {
+	defAcc, err := testWallet.GetDefaultAccount(pwd)
+	if err != nil {
+		t.Errorf("GetDefaultAccount error:%s", err)
+		return
+	}
+	transfers := make([]*BatchTransferItem, 0)
+	for i := 0; i < 3; i++ {
+		transfers = append(transfers, &BatchTransferItem{
+			To:     account.NewAccount("").Address.ToBase58(),
+			Amount: "10",
+		})
+	}
+	sigReq := &SigBatchTransferTransactionReq{
+		GasLimit:  20000,
+		GasPrice:  0,
+		Asset:     "onyx",
+		From:      defAcc.Address.ToBase58(),
+		Transfers: transfers,
+	}
+	data, err := json.Marshal(sigReq)
+	if err != nil {
+		t.Errorf("json.Marshal SigBatchTransferTransactionReq error:%s", err)
+	}
+	req := &clisvrcom.CliRpcRequest{
+		Qid:     "t",
+		Method:  "sigbatchtransfertx",
+		Params:  data,
+		Account: defAcc.Address.ToBase58(),
+		Pwd:     string(pwd),
+	}
+	rsp := &clisvrcom.CliRpcResponse{}
+	SigBatchTransferTransaction(req, rsp)
+	if rsp.ErrorCode != 0 {
+		t.Errorf("SigBatchTransferTransaction failed. ErrorCode:%d", rsp.ErrorCode)
+		return
+	}
+	if len(rsp.Result.(*SigBatchTransferTransactionRsp).SignedTxs) != 1 {
+		t.Errorf("SigBatchTransferTransaction should return one transaction")
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	MULTISIG_TX_PREFIX = 0x01
+)
+
+//PendingMultiSigTx is a transaction of multi-signature address waiting for the signatures of co-signers
+type PendingMultiSigTx struct {
+	Id        string   `json:"id"`         //hash of transaction, not changed by signatures
+	Address   string   `json:"address"`    //multi-signature address
+	M         uint16   `json:"m"`          //signatures required
+	PubKeys   []string `json:"pub_keys"`   //public keys of co-signers in hex
+	Signers   []string `json:"signers"`    //public keys already signed in hex
+	RawTx     string   `json:"raw_tx"`     //transaction with the collected signatures in hex
+	CreatedAt int64    `json:"created_at"` //unix time of registration
+	TxHash    string   `json:"tx_hash"`    //hash of transaction if submitted
+}
+
+//Complete return whether the signatures of transaction are enough
+func (this *PendingMultiSigTx) Complete() bool {
+	return len(this.Signers) >= int(this.M)
+}
+
+//MultiSigStore persist the pending multi-signature transactions of sigsvr
+type MultiSigStore struct {
+	db   *leveldb.DB
+	lock sync.Mutex
+}
+
+func NewMultiSigStore(path string) (*MultiSigStore, error) {
+	lvlOpts := &opt.Options{
+		NoSync: false,
+		Filter: filter.NewBloomFilter(10),
+	}
+	db, err := leveldb.OpenFile(path, lvlOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &MultiSigStore{db: db}, nil
+}
+
+func GetMultiSigTxKey(id string) []byte {
+	return append([]byte{MULTISIG_TX_PREFIX}, []byte(id)...)
+}
+
+//Lock lock the store, callers updating a transaction should hold the lock between get and put
+func (this *MultiSigStore) Lock() {
+	this.lock.Lock()
+}
+
+func (this *MultiSigStore) Unlock() {
+	this.lock.Unlock()
+}
+
+func (this *MultiSigStore) Put(tx *PendingMultiSigTx) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("json.Marshal error:%s", err)
+	}
+	return this.db.Put(GetMultiSigTxKey(tx.Id), data, nil)
+}
+
+//Get return the pending transaction of id, nil if not exist
+func (this *MultiSigStore) Get(id string) (*PendingMultiSigTx, error) {
+	data, err := this.db.Get(GetMultiSigTxKey(id), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	tx := &PendingMultiSigTx{}
+	err = json.Unmarshal(data, tx)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error:%s", err)
+	}
+	return tx, nil
+}
+
+//List return the pending transactions ordered by registration time
+func (this *MultiSigStore) List() ([]*PendingMultiSigTx, error) {
+	iter := this.db.NewIterator(util.BytesPrefix([]byte{MULTISIG_TX_PREFIX}), nil)
+	defer iter.Release()
+	txs := make([]*PendingMultiSigTx, 0)
+	for iter.Next() {
+		tx := &PendingMultiSigTx{}
+		err := json.Unmarshal(iter.Value(), tx)
+		if err != nil {
+			return nil, fmt.Errorf("json.Unmarshal error:%s", err)
+		}
+		txs = append(txs, tx)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].CreatedAt < txs[j].CreatedAt
+	})
+	return txs, nil
+}
+
+func (this *MultiSigStore) Delete(id string) error {
+	return this.db.Delete(GetMultiSigTxKey(id), nil)
+}
+
+func (this *MultiSigStore) Close() error {
+	return this.db.Close()
+}
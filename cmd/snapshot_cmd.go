@@ -0,0 +1,212 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"compress/zlib"
+	"fmt"
+	"os"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/OnyxPay/OnyxChain/cmd/utils"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/core/genesis"
+	"github.com/OnyxPay/OnyxChain/core/store/ledgerstore"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/urfave/cli"
+)
+
+var SnapshotCommand = cli.Command{
+	Name:      "snapshot",
+	Usage:     "Export or import state snapshot",
+	ArgsUsage: "[arguments...]",
+	Description: `State snapshot contains block headers and states of current block. A new node can import a snapshot
+to an empty data dir, and continue syncing from the snapshot height without executing history blocks.`,
+	Subcommands: []cli.Command{
+		{
+			Action:    exportSnapshot,
+			Name:      "export",
+			Usage:     "Export state snapshot of current block in DB to a file",
+			ArgsUsage: "[sub-command options]",
+			Flags: []cli.Flag{
+				utils.SnapshotFileFlag,
+				utils.DataDirFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+			},
+			Description: "Node should be stopped before export",
+		},
+		{
+			Action:    importSnapshot,
+			Name:      "import",
+			Usage:     "Import state snapshot from a file to an empty DB",
+			ArgsUsage: "[sub-command options]",
+			Flags: []cli.Flag{
+				utils.SnapshotFileFlag,
+				utils.SnapshotDigestFlag,
+				utils.DataDirFlag,
+				utils.ConfigFlag,
+				utils.NetworkIdFlag,
+			},
+			Description: `Blocks before the snapshot height only have headers after import. The digest should be
+obtained from a trusted source, since the states are not signed by bookkeepers.`,
+		},
+	},
+}
+
+func getSnapshotGenesisBlock() ([]keypair.PublicKey, *types.Block, error) {
+	bookKeepers, err := config.DefConfig.GetBookkeepers()
+	if err != nil {
+		return nil, nil, fmt.Errorf("GetBookkeepers error:%s", err)
+	}
+	genesisBlock, err := genesis.BuildGenesisBlock(bookKeepers, config.DefConfig.Genesis)
+	if err != nil {
+		return nil, nil, fmt.Errorf("BuildGenesisBlock error %s", err)
+	}
+	return bookKeepers, genesisBlock, nil
+}
+
+func exportSnapshot(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	cfg, err := SetOnyxChainConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOnyxChainConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	dbDir := utils.GetStoreDirPath(cfg.Common.DataDir, cfg.P2PNode.NetworkName)
+	if !common.FileExisted(dbDir) {
+		return fmt.Errorf("data dir:%s doesn't exist", dbDir)
+	}
+	bookKeepers, genesisBlock, err := getSnapshotGenesisBlock()
+	if err != nil {
+		return err
+	}
+	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
+	ledgerStore, err := ledgerstore.NewLedgerStore(dbDir, stateHashHeight)
+	if err != nil {
+		return fmt.Errorf("NewLedgerStore error:%s", err)
+	}
+	defer ledgerStore.Close()
+	err = ledgerStore.InitLedgerStoreWithGenesisBlock(genesisBlock, bookKeepers)
+	if err != nil {
+		return fmt.Errorf("init ledger error:%s", err)
+	}
+
+	ef, err := os.OpenFile(snapshotFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		return fmt.Errorf("open file:%s error:%s", snapshotFile, err)
+	}
+	defer ef.Close()
+	fWriter := bufio.NewWriter(ef)
+	zWriter := zlib.NewWriter(fWriter)
+
+	PrintInfoMsg("Start export snapshot.")
+	height, blockHash, digest, err := ledgerStore.ExportSnapshot(zWriter)
+	if err != nil {
+		return fmt.Errorf("ExportSnapshot error:%s", err)
+	}
+	err = zWriter.Close()
+	if err != nil {
+		return fmt.Errorf("export compress error:%s", err)
+	}
+	err = fWriter.Flush()
+	if err != nil {
+		return fmt.Errorf("export flush file error:%s", err)
+	}
+	PrintInfoMsg("Export snapshot successfully.")
+	PrintInfoMsg("BlockHeight:%d", height)
+	PrintInfoMsg("BlockHash:%s", blockHash.ToHexString())
+	PrintInfoMsg("Digest:%s", digest.ToHexString())
+	PrintInfoMsg("Snapshot file:%s", snapshotFile)
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	log.InitLog(log.InfoLog)
+
+	cfg, err := SetOnyxChainConfig(ctx)
+	if err != nil {
+		PrintErrorMsg("SetOnyxChainConfig error:%s", err)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	snapshotFile := ctx.String(utils.GetFlagName(utils.SnapshotFileFlag))
+	if snapshotFile == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotFileFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	digestStr := ctx.String(utils.GetFlagName(utils.SnapshotDigestFlag))
+	if digestStr == "" {
+		PrintErrorMsg("Missing %s argument.", utils.SnapshotDigestFlag.Name)
+		cli.ShowSubcommandHelp(ctx)
+		return nil
+	}
+	digest, err := common.Uint256FromHexString(digestStr)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot digest:%s", err)
+	}
+	dbDir := utils.GetStoreDirPath(cfg.Common.DataDir, cfg.P2PNode.NetworkName)
+	if common.FileExisted(dbDir) {
+		return fmt.Errorf("data dir:%s already exists, snapshot can only be imported to a new data dir", dbDir)
+	}
+	_, genesisBlock, err := getSnapshotGenesisBlock()
+	if err != nil {
+		return err
+	}
+
+	ifile, err := os.OpenFile(snapshotFile, os.O_RDONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("OpenFile error:%s", err)
+	}
+	defer ifile.Close()
+	zReader, err := zlib.NewReader(bufio.NewReader(ifile))
+	if err != nil {
+		return fmt.Errorf("snapshot file decompress error:%s", err)
+	}
+	defer zReader.Close()
+
+	stateHashHeight := config.GetStateHashCheckHeight(cfg.P2PNode.NetworkId)
+	ledgerStore, err := ledgerstore.NewLedgerStore(dbDir, stateHashHeight)
+	if err != nil {
+		return fmt.Errorf("NewLedgerStore error:%s", err)
+	}
+	PrintInfoMsg("Start import snapshot.")
+	height, blockHash, err := ledgerStore.ImportSnapshot(genesisBlock, digest, zReader)
+	ledgerStore.Close()
+	if err != nil {
+		os.RemoveAll(dbDir)
+		return fmt.Errorf("ImportSnapshot error:%s", err)
+	}
+	PrintInfoMsg("Import snapshot completed.")
+	PrintInfoMsg("BlockHeight:%d", height)
+	PrintInfoMsg("BlockHash:%s", blockHash.ToHexString())
+	return nil
+}
@@ -100,6 +100,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ConfigFlag,
 			utils.LogLevelFlag,
 			utils.DisableEventLogFlag,
+			utils.EnableEventIndexFlag,
+			utils.EnableStateProofFlag,
+			utils.PruneBlocksFlag,
+			utils.CheckpointsFileFlag,
 			utils.DataDirFlag,
 		},
 	},
@@ -109,6 +113,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WalletFileFlag,
 			utils.AccountAddressFlag,
 			utils.AccountPassFlag,
+			utils.RemoteSignerFlag,
+			utils.RemoteSignerTokenFlag,
 			utils.AccountDefaultFlag,
 			utils.AccountKeylenFlag,
 			utils.AccountSetDefaultFlag,
@@ -120,6 +126,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.AccountChangePasswdFlag,
 			utils.AccountSourceFileFlag,
 			utils.AccountWIFFlag,
+			utils.AccountMnemonicFlag,
+			utils.AccountHDPathFlag,
 			utils.AccountLowSecurityFlag,
 			utils.AccountMultiMFlag,
 			utils.AccountMultiPubKeyFlag,
@@ -138,9 +146,11 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GasPriceFlag,
 			utils.GasLimitFlag,
+			utils.MaxTxPerPayerFlag,
 			utils.TxpoolPreExecDisableFlag,
 			utils.DisableSyncVerifyTxFlag,
 			utils.DisableBroadcastNetTxFlag,
+			utils.DisableTxPoolJournalFlag,
 		},
 	},
 	{
@@ -148,6 +158,9 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.ReservedPeersOnlyFlag,
 			utils.ReservedPeersFileFlag,
+			utils.NodeKeyFileFlag,
+			utils.DHTPortFlag,
+			utils.DiscoveryOnlyFlag,
 			utils.NetworkIdFlag,
 			utils.NodePortFlag,
 			utils.DualPortSupportFlag,
@@ -204,6 +217,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ContractPrepareInvokeFlag,
 			utils.ContractParamsFlag,
 			utils.ContractReturnTypeFlag,
+			utils.ContractDebugDAPFlag,
 		},
 	},
 	{
@@ -215,6 +229,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TransactionFromFlag,
 			utils.TransactionToFlag,
 			utils.TransactionAmountFlag,
+			utils.TransactionPayoutFileFlag,
 			utils.TransactionHashFlag,
 			utils.TransferFromSenderFlag,
 			utils.ApproveAssetFlag,
@@ -253,6 +268,13 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ImportEndHeightFlag,
 		},
 	},
+	{
+		Name: "SNAPSHOT",
+		Flags: []cli.Flag{
+			utils.SnapshotFileFlag,
+			utils.SnapshotDigestFlag,
+		},
+	},
 	{
 		Name: "MISC",
 	},
@@ -0,0 +1,204 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
+)
+
+const (
+	MAX_BATCH_TX_SIZE = types.MAX_TX_SIZE / 2 //max size of unsigned batch transfer transaction, leave room for signatures
+)
+
+//TransferItem is a transfer entry of payout file
+type TransferItem struct {
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+}
+
+//ReadPayoutFile read transfer items from json or csv payout file.
+//Json file is an array of {"to":"address","amount":"1.5"}, csv file has address,amount on each line
+func ReadPayoutFile(file string) ([]*TransferItem, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("open file:%s error:%s", file, err)
+	}
+	defer f.Close()
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		items := make([]*TransferItem, 0)
+		err = json.NewDecoder(f).Decode(&items)
+		if err != nil {
+			return nil, fmt.Errorf("json decode file:%s error:%s", file, err)
+		}
+		return items, nil
+	}
+	return ReadPayoutCSV(f)
+}
+
+//ReadPayoutCSV read transfer items from csv, lines start with # are ignored
+func ReadPayoutCSV(r io.Reader) ([]*TransferItem, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	items := make([]*TransferItem, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv error:%s", err)
+		}
+		items = append(items, &TransferItem{
+			To:     strings.TrimSpace(record[0]),
+			Amount: strings.TrimSpace(record[1]),
+		})
+	}
+	return items, nil
+}
+
+//ParseTransferItems return transfer states of asset from address to the items, amount of items is float number
+func ParseTransferItems(asset, from string, items []*TransferItem) ([]*onx.State, error) {
+	fromAddr, err := common.AddressFromBase58(from)
+	if err != nil {
+		return nil, fmt.Errorf("from address:%s invalid:%s", from, err)
+	}
+	states := make([]*onx.State, 0, len(items))
+	for i, item := range items {
+		toAddr, err := common.AddressFromBase58(item.To)
+		if err != nil {
+			return nil, fmt.Errorf("item %d to address:%s invalid:%s", i, item.To, err)
+		}
+		var amount uint64
+		switch strings.ToLower(asset) {
+		case ASSET_ONX:
+			amount = ParseOnx(item.Amount)
+		case ASSET_OXG:
+			amount = ParseOxg(item.Amount)
+		default:
+			return nil, fmt.Errorf("unsupport asset:%s", asset)
+		}
+		if amount == 0 {
+			return nil, fmt.Errorf("item %d amount:%s invalid", i, item.Amount)
+		}
+		if err := CheckAssetAmount(asset, amount); err != nil {
+			return nil, fmt.Errorf("item %d %s", i, err)
+		}
+		states = append(states, &onx.State{
+			From:  fromAddr,
+			To:    toAddr,
+			Value: amount,
+		})
+	}
+	return states, nil
+}
+
+//CheckBatchBalance check the balance of every sender is enough for the sum of its transfers
+func CheckBatchBalance(asset string, states []*onx.State) error {
+	totals := make(map[common.Address]uint64)
+	senders := make([]common.Address, 0)
+	for _, state := range states {
+		total, ok := totals[state.From]
+		if !ok {
+			senders = append(senders, state.From)
+		}
+		total, overflow := common.SafeAdd(total, state.Value)
+		if overflow {
+			return fmt.Errorf("total amount of %s overflow", state.From.ToBase58())
+		}
+		totals[state.From] = total
+	}
+	for _, sender := range senders {
+		balance, err := GetAccountBalance(sender.ToBase58(), asset)
+		if err != nil {
+			return err
+		}
+		if balance < totals[sender] {
+			return fmt.Errorf("account:%s balance:%d not enough for total amount:%d", sender.ToBase58(), balance, totals[sender])
+		}
+	}
+	return nil
+}
+
+//BatchTransferTx return transfer transactions of all the states. The states are split into multiple transactions
+//when a transaction would exceed MAX_BATCH_TX_SIZE. If preExec is true, every transaction is validated by
+//pre-execution and split again when its gas exceeds gasLimit, a zero gasLimit is set to the estimated gas.
+func BatchTransferTx(gasPrice, gasLimit uint64, asset string, states []*onx.State, preExec bool) ([]*types.MutableTransaction, error) {
+	if len(states) == 0 {
+		return nil, fmt.Errorf("no transfer")
+	}
+	txs := make([]*types.MutableTransaction, 0)
+	err := batchTransferTx(gasPrice, gasLimit, asset, states, preExec, &txs)
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+func batchTransferTx(gasPrice, gasLimit uint64, asset string, states []*onx.State, preExec bool,
+	txs *[]*types.MutableTransaction) error {
+	split := func(reason string) error {
+		if len(states) == 1 {
+			return fmt.Errorf("transfer to %s amount %d: %s", states[0].To.ToBase58(), states[0].Value, reason)
+		}
+		mid := len(states) / 2
+		if err := batchTransferTx(gasPrice, gasLimit, asset, states[:mid], preExec, txs); err != nil {
+			return err
+		}
+		return batchTransferTx(gasPrice, gasLimit, asset, states[mid:], preExec, txs)
+	}
+	mutable, err := TransferStatesTx(gasPrice, gasLimit, asset, states)
+	if err != nil {
+		return err
+	}
+	size, err := mutable.UnsignedSize()
+	if err != nil {
+		return fmt.Errorf("serialize transaction error:%s", err)
+	}
+	if size > MAX_BATCH_TX_SIZE {
+		return split(fmt.Sprintf("transaction size %d exceed %d", size, MAX_BATCH_TX_SIZE))
+	}
+	if _, err := mutable.IntoImmutable(); err != nil {
+		return fmt.Errorf("convert immutable transaction error:%s", err)
+	}
+	if preExec {
+		estimate, err := EstimateGas(mutable)
+		if err != nil {
+			return split(fmt.Sprintf("pre-execute error:%s", err))
+		}
+		if gasLimit == 0 {
+			mutable.GasLimit = estimate.GasLimit
+		} else if estimate.GasLimit > gasLimit {
+			return split(fmt.Sprintf("gas %d exceed gas limit %d", estimate.GasLimit, gasLimit))
+		}
+	}
+	*txs = append(*txs, mutable)
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/account"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPayoutCSV(t *testing.T) {
+	data := "# address,amount\nAXmQDzzvpEtPkNwBEFsREzApTTDZFW6frD, 10\nAGc9NrdF5MuMJpkFfZ4BXr8ivQKG1U2uBr,0.5\n"
+	items, err := ReadPayoutCSV(strings.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, []*TransferItem{
+		{To: "AXmQDzzvpEtPkNwBEFsREzApTTDZFW6frD", Amount: "10"},
+		{To: "AGc9NrdF5MuMJpkFfZ4BXr8ivQKG1U2uBr", Amount: "0.5"},
+	}, items)
+
+	_, err = ReadPayoutCSV(strings.NewReader("AXmQDzzvpEtPkNwBEFsREzApTTDZFW6frD\n"))
+	assert.NotNil(t, err)
+}
+
+func TestParseTransferItems(t *testing.T) {
+	from := account.NewAccount("").Address
+	to := account.NewAccount("").Address
+	states, err := ParseTransferItems(ASSET_OXG, from.ToBase58(), []*TransferItem{{To: to.ToBase58(), Amount: "0.5"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []*onx.State{{From: from, To: to, Value: 500000000}}, states)
+
+	_, err = ParseTransferItems(ASSET_ONX, from.ToBase58(), []*TransferItem{{To: to.ToBase58(), Amount: "0.5"}})
+	assert.NotNil(t, err)
+	_, err = ParseTransferItems(ASSET_ONX, from.ToBase58(), []*TransferItem{{To: "invalid", Amount: "1"}})
+	assert.NotNil(t, err)
+}
+
+func TestBatchTransferTxSplit(t *testing.T) {
+	from := account.NewAccount("").Address
+	states := make([]*onx.State, 0)
+	for i := 0; i < 20000; i++ {
+		to := common.AddressFromVmCode([]byte{byte(i), byte(i >> 8)})
+		states = append(states, &onx.State{From: from, To: to, Value: 1})
+	}
+	txs, err := BatchTransferTx(0, 20000, ASSET_ONX, states, false)
+	assert.Nil(t, err)
+	assert.True(t, len(txs) > 1)
+	for _, mutable := range txs {
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		assert.True(t, len(tx.Raw) <= MAX_BATCH_TX_SIZE)
+	}
+}
@@ -21,6 +21,7 @@ package utils
 import (
 	"strings"
 
+	"github.com/OnyxPay/OnyxChain/account"
 	"github.com/OnyxPay/OnyxChain/common/config"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
 	"github.com/urfave/cli"
@@ -28,6 +29,7 @@ import (
 
 const (
 	DEFAULT_EXPORT_FILE   = "./OnxBlocks.dat"
+	DEFAULT_SNAPSHOT_FILE = "./OnxSnapshot.dat"
 	DEFAULT_ABI_PATH      = "./abi"
 	DEFAULT_EXPORT_HEIGHT = 0
 	DEFAULT_WALLET_PATH   = "./wallet_data"
@@ -48,6 +50,23 @@ var (
 		Name:  "disable-event-log",
 		Usage: "Discard event log output by smart contract execution",
 	}
+	EnableEventIndexFlag = cli.BoolFlag{
+		Name:  "enable-event-index",
+		Usage: "Index event log by contract address and topic to speed up event query",
+	}
+	EnableStateProofFlag = cli.BoolFlag{
+		Name:  "enable-state-proof",
+		Usage: "Keep the state trie of contracts and storage to serve storage proof and transaction trace. The state root is computed locally, not signed by bookkeepers",
+	}
+	PruneBlocksFlag = cli.UintFlag{
+		Name:  "prune-blocks",
+		Usage: "Keep block body, event log and state trie roots of the recent `<number>` blocks only, 0 means no pruning. Not supported by vbft consensus node.",
+		Value: 0,
+	}
+	CheckpointsFileFlag = cli.StringFlag{
+		Name:  "checkpoints",
+		Usage: "Json `<file>` of extra checkpoints, e.g. [{\"Height\":100000,\"Hash\":\"...\"}]. Headers below the last checkpoint are not verified by signatures",
+	}
 	WalletFileFlag = cli.StringFlag{
 		Name:  "wallet,w",
 		Value: config.DEFAULT_WALLET_FILE_NAME,
@@ -99,6 +118,11 @@ var (
 		Usage: "Min gas price `<value>` of transaction to be accepted by tx pool.",
 		Value: config.DEFAULT_GAS_PRICE,
 	}
+	MaxTxPerPayerFlag = cli.UintFlag{
+		Name:  "max-tx-per-payer",
+		Usage: "Max transaction `<number>` of a payer in tx pool, 0 means no limit.",
+		Value: config.DEFAULT_MAX_TX_PER_PAYER,
+	}
 
 	//Test Mode setting
 	EnableTestModeFlag = cli.BoolFlag{
@@ -121,6 +145,10 @@ var (
 		Usage: "Reserved peers `<file>`",
 		Value: config.DEFAULT_RESERVED_FILE,
 	}
+	NodeKeyFileFlag = cli.StringFlag{
+		Name:  "nodekey",
+		Usage: "Node identity key `<file>` of p2p network. If not specified, <datadir>/nodekey is used and created if not exist",
+	}
 	NetworkIdFlag = cli.UintFlag{
 		Name:  "networkid",
 		Usage: "Network id `<number>`. 1=onyxchain main net, 2=polaris test net, 3=testmode, and other for custom network",
@@ -131,6 +159,14 @@ var (
 		Usage: "P2P network port `<number>`",
 		Value: config.DEFAULT_NODE_PORT,
 	}
+	DHTPortFlag = cli.UintFlag{
+		Name:  "dht-port",
+		Usage: "UDP port `<number>` of node discovery. Same as --nodeport if not specified",
+	}
+	DiscoveryOnlyFlag = cli.BoolFlag{
+		Name:  "discovery-only",
+		Usage: "Run node discovery only without ledger and p2p sync, used by boot nodes",
+	}
 	DualPortSupportFlag = cli.BoolFlag{
 		Name:  "dual-port",
 		Usage: "Enable a dual network, P2P network for transaction messages and for consensus messages.",
@@ -217,6 +253,14 @@ var (
 		Name:  "account,a",
 		Usage: "Account `<address>` when the OnyxChain node starts. If not specific, using default account instead",
 	}
+	RemoteSignerFlag = cli.StringFlag{
+		Name:  "remotesigner",
+		Usage: "Remote signer `<address>` holding the consensus key instead of wallet, unix:///path/to/socket or http://127.0.0.1:port",
+	}
+	RemoteSignerTokenFlag = cli.StringFlag{
+		Name:  "remotesigner-token",
+		Usage: "`<file>` of the token shared with remote signer. The signer must only be reachable from localhost",
+	}
 	AccountDefaultFlag = cli.BoolFlag{
 		Name:  "default,d",
 		Usage: "Default settings to create a new account (equal to '-t ecdsa -b 256 -s SHA256withECDSA')",
@@ -270,6 +314,15 @@ var (
 		Name:  "wif",
 		Usage: "Import WIF keys from the source file specified by --source option",
 	}
+	AccountMnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Create accounts from a new mnemonic, or restore accounts from an input mnemonic on import",
+	}
+	AccountHDPathFlag = cli.StringFlag{
+		Name:  "hdpath",
+		Usage: "BIP44 account level `<path>` of mnemonic, change and index of account are appended on derive",
+		Value: account.HD_DEFAULT_PATH,
+	}
 	AccountMultiMFlag = cli.UintFlag{
 		Name:  "m",
 		Usage: "Min signature `<number>` of multi signature address",
@@ -297,6 +350,11 @@ var (
 		Name:  "code",
 		Usage: "File path of contract code `<path>`",
 	}
+	ContractVmTypeFlag = cli.StringFlag{
+		Name:  "vmtype",
+		Usage: "Virtual machine `<type>` of contract code. neovm, wasmvm",
+		Value: "neovm",
+	}
 	ContractNameFlag = cli.StringFlag{
 		Name:  "name",
 		Usage: "Specifies contract name to `<name>`",
@@ -336,6 +394,10 @@ var (
 		Name:  "return",
 		Usage: "Return `<type>` of contract. bytearray(hexstring), string, integer, boolean",
 	}
+	ContractDebugDAPFlag = cli.StringFlag{
+		Name:  "dap",
+		Usage: "Serve debug adapter protocol on `<address>` instead of interactive console. e.g. 127.0.0.1:4711",
+	}
 
 	//information cmd settings
 	BlockHashInfoFlag = cli.StringFlag{
@@ -365,6 +427,10 @@ var (
 		Name:  "amount",
 		Usage: "Transfer `<amount>`. Float number",
 	}
+	TransactionPayoutFileFlag = cli.StringFlag{
+		Name:  "file",
+		Usage: "Payout `<file>` of batch transfer. Json array of {\"to\",\"amount\"} or csv of address,amount lines",
+	}
 	TransactionHashFlag = cli.StringFlag{
 		Name:  "hash",
 		Usage: "Transaction `<hash>`",
@@ -483,6 +549,17 @@ var (
 		Value: "m",
 	}
 
+	//Snapshot setting
+	SnapshotFileFlag = cli.StringFlag{
+		Name:  "snapshot-file",
+		Usage: "Snapshot `<file>` path",
+		Value: DEFAULT_SNAPSHOT_FILE,
+	}
+	SnapshotDigestFlag = cli.StringFlag{
+		Name:  "snapshot-digest",
+		Usage: "Trusted `<digest>` of snapshot printed by export, the snapshot is rejected if mismatched",
+	}
+
 	//PreExecute switcher
 	TxpoolPreExecDisableFlag = cli.BoolFlag{
 		Name:  "disable-tx-pool-pre-exec",
@@ -500,6 +577,11 @@ var (
 		Usage: "Disable broadcast tx from network in tx pool",
 	}
 
+	DisableTxPoolJournalFlag = cli.BoolFlag{
+		Name:  "disable-tx-pool-journal",
+		Usage: "Disable journal of tx pool, the transactions in tx pool are lost when node restarts",
+	}
+
 	NonOptionFlag = cli.StringFlag{
 		Name:  "option",
 		Usage: "this command does not need option, please run directly",
@@ -25,7 +25,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/OnyxPay/OnyxChain-crypto/keypair"
-	sig "github.com/OnyxPay/OnyxChain-crypto/signature"
 	"github.com/OnyxPay/OnyxChain/account"
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/constants"
@@ -226,6 +225,11 @@ func TransferTx(gasPrice, gasLimit uint64, asset, from, to string, amount uint64
 		To:    toAddr,
 		Value: amount,
 	})
+	return TransferStatesTx(gasPrice, gasLimit, asset, sts)
+}
+
+//TransferStatesTx return transfer transaction of onx|oxg with multiple states
+func TransferStatesTx(gasPrice, gasLimit uint64, asset string, sts []*onx.State) (*types.MutableTransaction, error) {
 	var version byte
 	var contractAddr common.Address
 	switch strings.ToLower(asset) {
@@ -355,23 +359,29 @@ func MultiSigTransaction(mutTx *types.MutableTransaction, m uint16, pubKeys []ke
 		mutTx.Payer = payer
 	}
 
-	if len(mutTx.Sigs) == 0 {
-		mutTx.Sigs = make([]types.Sig, 0)
-	}
-
 	txHash := mutTx.Hash()
 	sigData, err := Sign(txHash.ToArray(), signer)
 	if err != nil {
 		return fmt.Errorf("sign error:%s", err)
 	}
+	AddMultiSignature(mutTx, m, pubKeys, signer.PublicKey, sigData)
+	return nil
+}
 
+//AddMultiSignature add signature of pubKey to the multi-signature of pubKeys, signature already added is ignored.
+//The signature should be verified by caller
+func AddMultiSignature(mutTx *types.MutableTransaction, m uint16, pubKeys []keypair.PublicKey, pubKey keypair.PublicKey, sigData []byte) {
+	if len(mutTx.Sigs) == 0 {
+		mutTx.Sigs = make([]types.Sig, 0)
+	}
+	txHash := mutTx.Hash()
 	hasMutilSig := false
 	for i, sigs := range mutTx.Sigs {
 		if !pubKeysEqual(sigs.PubKeys, pubKeys) {
 			continue
 		}
 		hasMutilSig = true
-		if hasAlreadySig(txHash.ToArray(), signer.PublicKey, sigs.SigData) {
+		if hasAlreadySig(txHash.ToArray(), pubKey, sigs.SigData) {
 			break
 		}
 		sigs.SigData = append(sigs.SigData, sigData)
@@ -381,11 +391,27 @@ func MultiSigTransaction(mutTx *types.MutableTransaction, m uint16, pubKeys []ke
 	if !hasMutilSig {
 		mutTx.Sigs = append(mutTx.Sigs, types.Sig{
 			PubKeys: pubKeys,
-			M:       uint16(m),
+			M:       m,
 			SigData: [][]byte{sigData},
 		})
 	}
-	return nil
+}
+
+//GetMultiSigners return the public keys which have signed the multi-signature of pubKeys
+func GetMultiSigners(mutTx *types.MutableTransaction, pubKeys []keypair.PublicKey) []keypair.PublicKey {
+	txHash := mutTx.Hash()
+	signers := make([]keypair.PublicKey, 0)
+	for _, sigs := range mutTx.Sigs {
+		if !pubKeysEqual(sigs.PubKeys, pubKeys) {
+			continue
+		}
+		for _, pk := range pubKeys {
+			if hasAlreadySig(txHash.ToArray(), pk, sigs.SigData) {
+				signers = append(signers, pk)
+			}
+		}
+	}
+	return signers
 }
 
 func hasAlreadySig(data []byte, pk keypair.PublicKey, sigDatas [][]byte) bool {
@@ -424,15 +450,11 @@ func pubKeysEqual(pks1, pks2 []keypair.PublicKey) bool {
 	return true
 }
 
-//Sign sign return the signature to the data of private key
+//Sign sign return the signature to the data by signer of account
 func Sign(data []byte, signer *account.Account) ([]byte, error) {
-	s, err := sig.Sign(signer.SigScheme, signer.PrivateKey, data, nil)
-	if err != nil {
-		return nil, err
-	}
-	sigData, err := sig.Serialize(s)
+	sigData, err := signer.Sign(data)
 	if err != nil {
-		return nil, fmt.Errorf("sig.Serialize error:%s", err)
+		return nil, fmt.Errorf("sign error:%s", err)
 	}
 	return sigData, nil
 }
@@ -474,6 +496,29 @@ func PrepareSendRawTransaction(txData string) (*cstates.PreExecResult, error) {
 	return preResult, nil
 }
 
+//EstimateGas return the recommended gas limit, minimum gas price and fee of transaction, tx need not be signed
+func EstimateGas(tx *types.MutableTransaction) (*httpcom.GasEstimate, error) {
+	imTx, err := tx.IntoImmutable()
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	err = imTx.Serialize(&buffer)
+	if err != nil {
+		return nil, fmt.Errorf("tx serialize error:%s", err)
+	}
+	data, onxErr := sendRpcRequest("estimategas", []interface{}{hex.EncodeToString(buffer.Bytes())})
+	if onxErr != nil {
+		return nil, onxErr.Error
+	}
+	estimate := &httpcom.GasEstimate{}
+	err = json.Unmarshal(data, estimate)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal GasEstimate:%s error:%s", data, err)
+	}
+	return estimate, nil
+}
+
 //GetSmartContractEvent return smart contract event execute by invoke transaction by hex string code
 func GetSmartContractEvent(txHash string) (*rpccommon.ExecuteNotify, error) {
 	data, onxErr := sendRpcRequest("getsmartcodeevent", []interface{}{txHash})
@@ -597,6 +642,7 @@ func DeployContract(
 	gasLimit uint64,
 	signer *account.Account,
 	needStorage bool,
+	vmType payload.VmType,
 	code,
 	cname,
 	cversion,
@@ -608,7 +654,7 @@ func DeployContract(
 	if err != nil {
 		return "", fmt.Errorf("hex.DecodeString error:%s", err)
 	}
-	mutable := NewDeployCodeTransaction(gasPrice, gasLimit, c, needStorage, cname, cversion, cauthor, cemail, cdesc)
+	mutable := NewDeployCodeTransaction(gasPrice, gasLimit, c, needStorage, vmType, cname, cversion, cauthor, cemail, cdesc)
 
 	err = SignTransaction(signer, mutable)
 	if err != nil {
@@ -627,6 +673,7 @@ func DeployContract(
 
 func PrepareDeployContract(
 	needStorage bool,
+	vmType payload.VmType,
 	code,
 	cname,
 	cversion,
@@ -637,7 +684,7 @@ func PrepareDeployContract(
 	if err != nil {
 		return nil, fmt.Errorf("hex.DecodeString error:%s", err)
 	}
-	mutable := NewDeployCodeTransaction(0, 0, c, needStorage, cname, cversion, cauthor, cemail, cdesc)
+	mutable := NewDeployCodeTransaction(0, 0, c, needStorage, vmType, cname, cversion, cauthor, cemail, cdesc)
 	tx, _ := mutable.IntoImmutable()
 	var buffer bytes.Buffer
 	err = tx.Serialize(&buffer)
@@ -682,7 +729,7 @@ func InvokeWasmVMContract(
 	if err != nil {
 		return "", err
 	}
-	tx, err := httpcom.NewSmartContractTransaction(gasPrice, gasLimit, invokeCode)
+	tx, err := httpcom.NewWasmVMInvokeTransaction(gasPrice, gasLimit, invokeCode)
 	if err != nil {
 		return "", err
 	}
@@ -784,12 +831,13 @@ func PrepareInvokeNativeContract(
 }
 
 //NewDeployCodeTransaction return a smart contract deploy transaction instance
-func NewDeployCodeTransaction(gasPrice, gasLimit uint64, code []byte, needStorage bool,
+func NewDeployCodeTransaction(gasPrice, gasLimit uint64, code []byte, needStorage bool, vmType payload.VmType,
 	cname, cversion, cauthor, cemail, cdesc string) *types.MutableTransaction {
 
 	deployPayload := &payload.DeployCode{
 		Code:        code,
 		NeedStorage: needStorage,
+		VmType:      vmType,
 		Name:        cname,
 		Version:     cversion,
 		Author:      cauthor,
@@ -799,7 +847,7 @@ func NewDeployCodeTransaction(gasPrice, gasLimit uint64, code []byte, needStorag
 	tx := &types.MutableTransaction{
 		Version:  VERSION_TRANSACTION,
 		TxType:   types.Deploy,
-		Nonce:    uint32(time.Now().Unix()),
+		Nonce:    rand.Uint32(),
 		Payload:  deployPayload,
 		GasPrice: gasPrice,
 		GasLimit: gasLimit,
@@ -44,10 +44,14 @@ const (
 	DBFT_MIN_NODE_NUM        = 4 //min node number of dbft consensus
 	SOLO_MIN_NODE_NUM        = 1 //min node number of solo consensus
 	VBFT_MIN_NODE_NUM        = 4 //min node number of vbft consensus
+	SBFT_MIN_NODE_NUM        = 4 //min node number of sbft consensus
+
+	MIN_PRUNE_BLOCKS = 1000 //min number of recent blocks kept by pruned node
 
 	CONSENSUS_TYPE_DBFT = "dbft"
 	CONSENSUS_TYPE_SOLO = "solo"
 	CONSENSUS_TYPE_VBFT = "vbft"
+	CONSENSUS_TYPE_SBFT = "sbft"
 
 	DEFAULT_LOG_LEVEL                       = log.InfoLog
 	DEFAULT_MAX_LOG_SIZE                    = 100 //MByte
@@ -70,6 +74,8 @@ const (
 	DEFUALT_CLI_RPC_ADDRESS                 = "127.0.0.1"
 	DEFAULT_GAS_LIMIT                       = 20000
 	DEFAULT_GAS_PRICE                       = 500
+	DEFAULT_MAX_TX_PER_PAYER                = 1000
+	DEFAULT_VIEW_CHANGE_TIMEOUT             = 3 //second
 
 	DEFAULT_DATA_DIR      = "./Chain"
 	DEFAULT_RESERVED_FILE = "./peers.rsv"
@@ -114,6 +120,20 @@ func GetStateHashCheckHeight(id uint32) uint32 {
 	return STATE_HASH_CHECK_HEIGHT[id]
 }
 
+//Checkpoint is a block which is known to be on the canonical chain. Headers linked to
+//a checkpoint are trusted without checking the bookkeeper signatures
+type Checkpoint struct {
+	Height uint32
+	Hash   string //Hex string of the block hash
+}
+
+//CHECKPOINTS is the hard-coded checkpoints of each network. No checkpoint is published for the networks yet,
+//so a node syncs and verifies every header from genesis unless checkpoints are added by the --checkpoints config file
+var CHECKPOINTS = map[uint32][]*Checkpoint{
+	NETWORK_ID_MAIN_NET:    {}, //Network main, no checkpoint yet
+	NETWORK_ID_POLARIS_NET: {}, //Network polaris, no checkpoint yet
+}
+
 func GetNetworkName(id uint32) string {
 	name, ok := NETWORK_NAME[id]
 	if ok {
@@ -190,6 +210,7 @@ var TestNetConfig = &GenesisConfig{
 	},
 	DBFT: &DBFTConfig{},
 	SOLO: &SOLOConfig{},
+	SBFT: &SBFTConfig{},
 }
 
 var MainNetConfig = &GenesisConfig{
@@ -257,6 +278,7 @@ var MainNetConfig = &GenesisConfig{
 	},
 	DBFT: &DBFTConfig{},
 	SOLO: &SOLOConfig{},
+	SBFT: &SBFTConfig{},
 }
 
 var DefConfig = NewOnyxChainConfig()
@@ -267,6 +289,7 @@ type GenesisConfig struct {
 	VBFT          *VBFTConfig
 	DBFT          *DBFTConfig
 	SOLO          *SOLOConfig
+	SBFT          *SBFTConfig
 }
 
 func NewGenesisConfig() *GenesisConfig {
@@ -276,6 +299,7 @@ func NewGenesisConfig() *GenesisConfig {
 		VBFT:          &VBFTConfig{},
 		DBFT:          &DBFTConfig{},
 		SOLO:          &SOLOConfig{},
+		SBFT:          &SBFTConfig{},
 	}
 }
 
@@ -486,14 +510,27 @@ type SOLOConfig struct {
 	Bookkeepers  []string
 }
 
+//SBFTConfig is the genesis config of simplified bft consensus.
+//ViewChangeTimeout is the extra seconds a backup waits for every view before asking for a new primary
+type SBFTConfig struct {
+	GenBlockTime      uint
+	ViewChangeTimeout uint
+	Bookkeepers       []string
+}
+
 type CommonConfig struct {
-	LogLevel       uint
-	NodeType       string
-	EnableEventLog bool
-	SystemFee      map[string]int64
-	GasLimit       uint64
-	GasPrice       uint64
-	DataDir        string
+	LogLevel         uint
+	NodeType         string
+	EnableEventLog   bool
+	EnableEventIndex bool
+	EnableStateProof bool
+	SystemFee        map[string]int64
+	GasLimit         uint64
+	GasPrice         uint64
+	MaxTxPerPayer    uint
+	PruneBlocks      uint32 //Number of recent blocks whose body and event notifies are kept, 0 means no pruning
+	Checkpoints      []*Checkpoint
+	DataDir          string
 }
 
 type ConsensusConfig struct {
@@ -503,6 +540,7 @@ type ConsensusConfig struct {
 
 type P2PRsvConfig struct {
 	ReservedPeers []string `json:"reserved"`
+	ReservedKeys  []string `json:"reservedKeys"` //Hex encoded node keys of reserved peers
 	MaskPeers     []string `json:"mask"`
 }
 
@@ -519,11 +557,14 @@ type P2PNodeConfig struct {
 	CertPath                  string
 	KeyPath                   string
 	CAPath                    string
+	NodeKeyPath               string //Node identity key file, <DataDir>/nodekey if empty
 	HttpInfoPort              uint
 	MaxHdrSyncReqs            uint
 	MaxConnInBound            uint
 	MaxConnOutBound           uint
 	MaxConnInBoundForSingleIP uint
+	DHTPort                   uint //Udp port of node discovery, same as NodePort if 0
+	DiscoveryOnly             bool //Run node discovery only, for boot nodes
 }
 
 type RpcConfig struct {
@@ -567,6 +608,7 @@ func NewOnyxChainConfig() *OnyxChainConfig {
 			EnableEventLog: DEFAULT_ENABLE_EVENT_LOG,
 			SystemFee:      make(map[string]int64),
 			GasLimit:       DEFAULT_GAS_LIMIT,
+			MaxTxPerPayer:  DEFAULT_MAX_TX_PER_PAYER,
 			DataDir:        DEFAULT_DATA_DIR,
 		},
 		Consensus: &ConsensusConfig{
@@ -586,11 +628,14 @@ func NewOnyxChainConfig() *OnyxChainConfig {
 			CertPath:                  "",
 			KeyPath:                   "",
 			CAPath:                    "",
+			NodeKeyPath:               "",
 			HttpInfoPort:              DEFAULT_HTTP_INFO_PORT,
 			MaxHdrSyncReqs:            DEFAULT_MAX_SYNC_HEADER,
 			MaxConnInBound:            DEFAULT_MAX_CONN_IN_BOUND,
 			MaxConnOutBound:           DEFAULT_MAX_CONN_OUT_BOUND,
 			MaxConnInBoundForSingleIP: DEFAULT_MAX_CONN_IN_BOUND_FOR_SINGLE_IP,
+			DHTPort:                   0,
+			DiscoveryOnly:             false,
 		},
 		Rpc: &RpcConfig{
 			EnableHttpJsonRpc: true,
@@ -625,6 +670,8 @@ func (this *OnyxChainConfig) GetBookkeepers() ([]keypair.PublicKey, error) {
 		bookKeepers = this.Genesis.DBFT.Bookkeepers
 	case CONSENSUS_TYPE_SOLO:
 		bookKeepers = this.Genesis.SOLO.Bookkeepers
+	case CONSENSUS_TYPE_SBFT:
+		bookKeepers = this.Genesis.SBFT.Bookkeepers
 	default:
 		return nil, fmt.Errorf("Does not support %s consensus", this.Genesis.ConsensusType)
 	}
@@ -642,6 +689,25 @@ func (this *OnyxChainConfig) GetBookkeepers() ([]keypair.PublicKey, error) {
 	return pubKeys, nil
 }
 
+//GetCheckpoints return the hard-coded checkpoints of the network together with the configured ones
+func (this *OnyxChainConfig) GetCheckpoints() (map[uint32]common.Uint256, error) {
+	checkpoints := make(map[uint32]common.Uint256)
+	cps := make([]*Checkpoint, 0, len(CHECKPOINTS[this.P2PNode.NetworkId])+len(this.Common.Checkpoints))
+	cps = append(cps, CHECKPOINTS[this.P2PNode.NetworkId]...)
+	cps = append(cps, this.Common.Checkpoints...)
+	for _, cp := range cps {
+		hash, err := common.Uint256FromHexString(cp.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hash of checkpoint %d:%s", cp.Height, err)
+		}
+		if old, ok := checkpoints[cp.Height]; ok && old != hash {
+			return nil, fmt.Errorf("conflict checkpoints at height %d", cp.Height)
+		}
+		checkpoints[cp.Height] = hash
+	}
+	return checkpoints, nil
+}
+
 func (this *OnyxChainConfig) GetDefaultNetworkId() (uint32, error) {
 	defaultNetworkId, err := this.getDefNetworkIDFromGenesisConfig(this.Genesis)
 	if err != nil {
@@ -672,6 +738,8 @@ func (this *OnyxChainConfig) getDefNetworkIDFromGenesisConfig(genCfg *GenesisCon
 		configData, err = json.Marshal(genCfg.VBFT)
 	case CONSENSUS_TYPE_DBFT:
 		configData, err = json.Marshal(genCfg.DBFT)
+	case CONSENSUS_TYPE_SBFT:
+		configData, err = json.Marshal(genCfg.SBFT)
 	case CONSENSUS_TYPE_SOLO:
 		return NETWORK_ID_SOLO_NET, nil
 	default:
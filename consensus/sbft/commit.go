@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"io"
+
+	"github.com/OnyxPay/OnyxChain/common"
+)
+
+//Commit carries the block signature of a bookkeeper which has seen the proposal prepared
+type Commit struct {
+	msgData   ConsensusMessageData
+	BlockHash common.Uint256
+	Signature []byte
+}
+
+func (cm *Commit) Serialization(sink *common.ZeroCopySink) error {
+	cm.msgData.Serialization(sink)
+	sink.WriteHash(cm.BlockHash)
+	sink.WriteVarBytes(cm.Signature)
+	return nil
+}
+
+//read data to reader
+func (cm *Commit) Deserialization(source *common.ZeroCopySource) error {
+	err := cm.msgData.Deserialization(source)
+	if err != nil {
+		return err
+	}
+
+	var eof bool
+	cm.BlockHash, eof = source.NextHash()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	sign, _, irregular, eof := source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	cm.Signature = sign
+
+	return nil
+}
+
+func (cm *Commit) Type() ConsensusMessageType {
+	return cm.ConsensusMessageData().Type
+}
+
+func (cm *Commit) ViewNumber() byte {
+	return cm.msgData.ViewNumber
+}
+
+func (cm *Commit) ConsensusMessageData() *ConsensusMessageData {
+	return &(cm.msgData)
+}
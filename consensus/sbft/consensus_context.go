@@ -0,0 +1,324 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/OnyxPay/OnyxChain/account"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/core/ledger"
+	"github.com/OnyxPay/OnyxChain/core/signature"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/core/vote"
+	msg "github.com/OnyxPay/OnyxChain/p2pserver/message/types"
+)
+
+const ContextVersion uint32 = 0
+
+type ConsensusContext struct {
+	State           ConsensusState
+	PrevHash        common.Uint256
+	Height          uint32
+	ViewNumber      byte
+	Bookkeepers     []keypair.PublicKey
+	Owner           keypair.PublicKey
+	BookkeeperIndex int
+	PrimaryIndex    uint32
+	ExpectedView    []byte
+	ViewChanges     []*ViewChange
+
+	Proposal   *Proposal
+	BlockHash  common.Uint256
+	Prepares   []*Prepare
+	Commits    []*Commit
+	Locked     *Proposal
+	LockedHash common.Uint256
+
+	header *types.Block
+}
+
+//F return the max number of faulty bookkeepers the consensus can tolerate
+func (ctx *ConsensusContext) F() int {
+	return (len(ctx.Bookkeepers) - 1) / 3
+}
+
+//M return the quorum size
+func (ctx *ConsensusContext) M() int {
+	return len(ctx.Bookkeepers) - ctx.F()
+}
+
+func (ctx *ConsensusContext) PrimaryOf(viewNum byte) uint32 {
+	return (ctx.Height + uint32(viewNum)) % uint32(len(ctx.Bookkeepers))
+}
+
+func (ctx *ConsensusContext) IsBookkeeper() bool {
+	return ctx.BookkeeperIndex >= 0
+}
+
+func (ctx *ConsensusContext) Reset(bkAccount *account.Account) {
+	preHash := ledger.DefLedger.GetCurrentBlockHash()
+	height := ledger.DefLedger.GetCurrentBlockHeight()
+
+	var err error
+	ctx.Bookkeepers, err = vote.GetValidators([]*types.Transaction{})
+	if err != nil {
+		log.Error("[ConsensusContext] GetValidators failed", err)
+	}
+
+	bookkeeperLen := len(ctx.Bookkeepers)
+	ctx.State = Initial
+	ctx.PrevHash = preHash
+	ctx.Height = height + 1
+	ctx.ViewNumber = 0
+	ctx.BookkeeperIndex = -1
+	ctx.ExpectedView = make([]byte, bookkeeperLen)
+	ctx.ViewChanges = make([]*ViewChange, bookkeeperLen)
+	ctx.Commits = make([]*Commit, bookkeeperLen)
+	ctx.Locked = nil
+	ctx.LockedHash = common.UINT256_EMPTY
+	ctx.PrimaryIndex = ctx.PrimaryOf(0)
+	ctx.resetView()
+
+	log.Debugf("bookkeepers number: %d", bookkeeperLen)
+	for i := 0; i < bookkeeperLen; i++ {
+		if keypair.ComparePublicKey(bkAccount.PublicKey, ctx.Bookkeepers[i]) {
+			log.Debugf("this node is bookkeeper %d", i)
+			ctx.BookkeeperIndex = i
+			ctx.Owner = ctx.Bookkeepers[i]
+			break
+		}
+	}
+}
+
+//ChangeView move the context to a new view, the locked proposal and commits survive the change
+func (ctx *ConsensusContext) ChangeView(viewNum byte) {
+	ctx.State = Initial
+	ctx.ViewNumber = viewNum
+	ctx.PrimaryIndex = ctx.PrimaryOf(viewNum)
+	if ctx.IsBookkeeper() && ctx.ExpectedView[ctx.BookkeeperIndex] < viewNum {
+		ctx.ExpectedView[ctx.BookkeeperIndex] = viewNum
+	}
+	ctx.resetView()
+}
+
+func (ctx *ConsensusContext) resetView() {
+	ctx.Proposal = nil
+	ctx.BlockHash = common.UINT256_EMPTY
+	ctx.Prepares = make([]*Prepare, len(ctx.Bookkeepers))
+	ctx.header = nil
+}
+
+//SetProposal accept the proposal of current view
+func (ctx *ConsensusContext) SetProposal(proposal *Proposal) {
+	ctx.Proposal = proposal
+	ctx.header = ctx.MakeHeader(proposal)
+	ctx.BlockHash = ctx.header.Hash()
+}
+
+func (ctx *ConsensusContext) MakeHeader(proposal *Proposal) *types.Block {
+	txHash := make([]common.Uint256, 0, len(proposal.Transactions))
+	for _, t := range proposal.Transactions {
+		txHash = append(txHash, t.Hash())
+	}
+	txRoot := common.ComputeMerkleRoot(txHash)
+	blockRoot := ledger.DefLedger.GetBlockRootWithNewTxRoots(ctx.Height, []common.Uint256{txRoot})
+	header := &types.Header{
+		Version:          ContextVersion,
+		PrevBlockHash:    ctx.PrevHash,
+		TransactionsRoot: txRoot,
+		BlockRoot:        blockRoot,
+		Timestamp:        proposal.Timestamp,
+		Height:           ctx.Height,
+		ConsensusData:    proposal.Nonce,
+		NextBookkeeper:   proposal.NextBookkeeper,
+	}
+	return &types.Block{
+		Header:       header,
+		Transactions: []*types.Transaction{},
+	}
+}
+
+//MakeBlock build the block of current proposal with the commit signatures in bookkeeper order
+func (ctx *ConsensusContext) MakeBlock() *types.Block {
+	header := *ctx.header.Header
+	header.Bookkeepers = ctx.Bookkeepers
+	header.SigData = make([][]byte, 0, ctx.M())
+	for _, commit := range ctx.Commits {
+		if len(header.SigData) == ctx.M() {
+			break
+		}
+		if commit != nil && commit.BlockHash == ctx.BlockHash {
+			header.SigData = append(header.SigData, commit.Signature)
+		}
+	}
+	return &types.Block{
+		Header:       &header,
+		Transactions: ctx.Proposal.Transactions,
+	}
+}
+
+func (ctx *ConsensusContext) GetPrepareCount() int {
+	count := 0
+	for _, pre := range ctx.Prepares {
+		if pre != nil && pre.BlockHash == ctx.BlockHash {
+			count += 1
+		}
+	}
+	return count
+}
+
+func (ctx *ConsensusContext) GetCommitCount() int {
+	count := 0
+	for _, commit := range ctx.Commits {
+		if commit != nil && commit.BlockHash == ctx.BlockHash {
+			count += 1
+		}
+	}
+	return count
+}
+
+//PrepareCertificate collect the prepare signatures of current proposal
+func (ctx *ConsensusContext) PrepareCertificate() []SignaturesData {
+	sigs := make([]SignaturesData, 0, len(ctx.Prepares))
+	for i, pre := range ctx.Prepares {
+		if pre != nil && pre.BlockHash == ctx.BlockHash {
+			sigs = append(sigs, SignaturesData{Index: uint16(i), Signature: pre.Signature})
+		}
+	}
+	return sigs
+}
+
+//Lock remember the prepared proposal of current view together with its prepare certificate
+func (ctx *ConsensusContext) Lock() {
+	ctx.Locked = &Proposal{
+		Timestamp:      ctx.Proposal.Timestamp,
+		Nonce:          ctx.Proposal.Nonce,
+		NextBookkeeper: ctx.Proposal.NextBookkeeper,
+		Transactions:   ctx.Proposal.Transactions,
+		Signature:      ctx.Proposal.Signature,
+		JustifyView:    ctx.ViewNumber,
+		Justify:        ctx.PrepareCertificate(),
+	}
+	ctx.Locked.msgData.Type = ProposalMsg
+	ctx.Locked.msgData.ViewNumber = ctx.ViewNumber
+	ctx.LockedHash = ctx.BlockHash
+}
+
+//VerifyJustify check the prepare certificate of a locked proposal and return the block hash of it
+func (ctx *ConsensusContext) VerifyJustify(proposal *Proposal) (common.Uint256, error) {
+	blockHash := ctx.MakeHeader(proposal).Hash()
+	data := PrepareHash(blockHash, proposal.JustifyView)
+	signed := make(map[uint16]bool)
+	for _, sig := range proposal.Justify {
+		if int(sig.Index) >= len(ctx.Bookkeepers) || signed[sig.Index] {
+			continue
+		}
+		if err := signature.Verify(ctx.Bookkeepers[sig.Index], data, sig.Signature); err != nil {
+			continue
+		}
+		signed[sig.Index] = true
+	}
+	if len(signed) < ctx.M() {
+		return blockHash, fmt.Errorf("prepare certificate of view %d has %d valid signatures, need %d",
+			proposal.JustifyView, len(signed), ctx.M())
+	}
+	return blockHash, nil
+}
+
+//HighestLocked return the locked proposal of the highest view among the view changes for viewNum and self
+func (ctx *ConsensusContext) HighestLocked(viewNum byte) *Proposal {
+	locked := ctx.Locked
+	for _, cv := range ctx.ViewChanges {
+		if cv == nil || cv.NewViewNumber < viewNum || cv.Locked == nil {
+			continue
+		}
+		if locked == nil || cv.Locked.JustifyView > locked.JustifyView {
+			if _, err := ctx.VerifyJustify(cv.Locked); err != nil {
+				log.Warnf("[ConsensusContext] invalid locked proposal in view change: %s", err)
+				continue
+			}
+			locked = cv.Locked
+		}
+	}
+	return locked
+}
+
+func (ctx *ConsensusContext) MakePayload(message ConsensusMessage) *msg.ConsensusPayload {
+	message.ConsensusMessageData().ViewNumber = ctx.ViewNumber
+	sink := common.NewZeroCopySink(nil)
+	message.Serialization(sink)
+	return &msg.ConsensusPayload{
+		Version:         ContextVersion,
+		PrevHash:        ctx.PrevHash,
+		Height:          ctx.Height,
+		BookkeeperIndex: uint16(ctx.BookkeeperIndex),
+		Timestamp:       uint32(time.Now().Unix()),
+		Data:            sink.Bytes(),
+		Owner:           ctx.Owner,
+	}
+}
+
+func (ctx *ConsensusContext) MakeProposal() *msg.ConsensusPayload {
+	ctx.Proposal.msgData.Type = ProposalMsg
+	return ctx.MakePayload(ctx.Proposal)
+}
+
+func (ctx *ConsensusContext) MakePrepare(pre *Prepare) *msg.ConsensusPayload {
+	pre.msgData.Type = PrepareMsg
+	return ctx.MakePayload(pre)
+}
+
+func (ctx *ConsensusContext) MakeCommit(cm *Commit) *msg.ConsensusPayload {
+	cm.msgData.Type = CommitMsg
+	return ctx.MakePayload(cm)
+}
+
+func (ctx *ConsensusContext) MakeViewChange() *msg.ConsensusPayload {
+	cv := &ViewChange{
+		NewViewNumber: ctx.ExpectedView[ctx.BookkeeperIndex],
+		Locked:        ctx.Locked,
+	}
+	cv.msgData.Type = ViewChangeMsg
+	return ctx.MakePayload(cv)
+}
+
+func (ctx *ConsensusContext) GetStateDetail() string {
+	return fmt.Sprintf("Initial: %t, Primary: %t, Backup: %t, ProposalSent: %t, ProposalRecved: %t, CommitSent: %t, BlockGenerated: %t, ",
+		ctx.State.HasFlag(Initial),
+		ctx.State.HasFlag(Primary),
+		ctx.State.HasFlag(Backup),
+		ctx.State.HasFlag(ProposalSent),
+		ctx.State.HasFlag(ProposalRecved),
+		ctx.State.HasFlag(CommitSent),
+		ctx.State.HasFlag(BlockGenerated))
+}
+
+//PrepareHash return the data signed by a prepare vote, it binds the block hash to the view
+func PrepareHash(blockHash common.Uint256, viewNum byte) []byte {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteHash(blockHash)
+	sink.WriteByte(viewNum)
+	hash := sha256.Sum256(sink.Bytes())
+	return hash[:]
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"errors"
+	"io"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/log"
+)
+
+type ConsensusMessage interface {
+	Serialization(sink *common.ZeroCopySink) error
+	Deserialization(source *common.ZeroCopySource) error
+	Type() ConsensusMessageType
+	ViewNumber() byte
+	ConsensusMessageData() *ConsensusMessageData
+}
+
+type ConsensusMessageData struct {
+	Type       ConsensusMessageType
+	ViewNumber byte
+}
+
+func DeserializeMessage(data []byte) (ConsensusMessage, error) {
+	if len(data) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	msgType := ConsensusMessageType(data[0])
+
+	source := common.NewZeroCopySource(data)
+	switch msgType {
+	case ProposalMsg:
+		proposal := &Proposal{}
+		err := proposal.Deserialization(source)
+		if err != nil {
+			log.Error("[DeserializeMessage] ProposalMsg Deserialize Error: ", err.Error())
+			return nil, err
+		}
+		return proposal, nil
+	case PrepareMsg:
+		prepare := &Prepare{}
+		err := prepare.Deserialization(source)
+		if err != nil {
+			log.Error("[DeserializeMessage] PrepareMsg Deserialize Error: ", err.Error())
+			return nil, err
+		}
+		return prepare, nil
+	case CommitMsg:
+		commit := &Commit{}
+		err := commit.Deserialization(source)
+		if err != nil {
+			log.Error("[DeserializeMessage] CommitMsg Deserialize Error: ", err.Error())
+			return nil, err
+		}
+		return commit, nil
+	case ViewChangeMsg:
+		cv := &ViewChange{}
+		err := cv.Deserialization(source)
+		if err != nil {
+			log.Error("[DeserializeMessage] ViewChangeMsg Deserialize Error: ", err.Error())
+			return nil, err
+		}
+		return cv, nil
+	}
+
+	return nil, errors.New("The message is invalid.")
+}
+
+func (cd *ConsensusMessageData) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteByte(byte(cd.Type))
+	sink.WriteByte(cd.ViewNumber)
+}
+
+//read data to reader
+func (cd *ConsensusMessageData) Deserialization(source *common.ZeroCopySource) error {
+	temp, eof := source.NextByte()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	cd.Type = ConsensusMessageType(temp)
+	cd.ViewNumber, eof = source.NextByte()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+
+	return nil
+}
+
+type SignaturesData struct {
+	Signature []byte
+	Index     uint16
+}
+
+func serializeSignatures(sink *common.ZeroCopySink, sigs []SignaturesData) {
+	sink.WriteVarUint(uint64(len(sigs)))
+	for _, sign := range sigs {
+		sink.WriteVarBytes(sign.Signature)
+		sink.WriteUint16(sign.Index)
+	}
+}
+
+func deserializeSignatures(source *common.ZeroCopySource) ([]SignaturesData, error) {
+	length, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return nil, common.ErrIrregularData
+	}
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	var sigs []SignaturesData
+	for i := uint64(0); i < length; i++ {
+		sig := SignaturesData{}
+		sig.Signature, _, irregular, eof = source.NextVarBytes()
+		if irregular {
+			return nil, common.ErrIrregularData
+		}
+		sig.Index, eof = source.NextUint16()
+		if eof {
+			return nil, io.ErrUnexpectedEOF
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"io"
+
+	"github.com/OnyxPay/OnyxChain/common"
+)
+
+//Prepare is the vote of a backup for the proposal of current view, Signature signs PrepareHash
+type Prepare struct {
+	msgData   ConsensusMessageData
+	BlockHash common.Uint256
+	Signature []byte
+}
+
+func (pre *Prepare) Serialization(sink *common.ZeroCopySink) error {
+	pre.msgData.Serialization(sink)
+	sink.WriteHash(pre.BlockHash)
+	sink.WriteVarBytes(pre.Signature)
+	return nil
+}
+
+//read data to reader
+func (pre *Prepare) Deserialization(source *common.ZeroCopySource) error {
+	err := pre.msgData.Deserialization(source)
+	if err != nil {
+		return err
+	}
+
+	var eof bool
+	pre.BlockHash, eof = source.NextHash()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	sign, _, irregular, eof := source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	pre.Signature = sign
+
+	return nil
+}
+
+func (pre *Prepare) Type() ConsensusMessageType {
+	return pre.ConsensusMessageData().Type
+}
+
+func (pre *Prepare) ViewNumber() byte {
+	return pre.msgData.ViewNumber
+}
+
+func (pre *Prepare) ConsensusMessageData() *ConsensusMessageData {
+	return &(pre.msgData)
+}
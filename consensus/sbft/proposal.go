@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/types"
+)
+
+//Proposal is the block proposed by the primary of a view. A block locked in a former view
+//is proposed again together with the prepare certificate of that view in Justify
+type Proposal struct {
+	msgData        ConsensusMessageData
+	Timestamp      uint32
+	Nonce          uint64
+	NextBookkeeper common.Address
+	Transactions   []*types.Transaction
+	Signature      []byte
+	JustifyView    byte
+	Justify        []SignaturesData
+}
+
+func (pr *Proposal) Serialization(sink *common.ZeroCopySink) error {
+	pr.msgData.Serialization(sink)
+	sink.WriteUint32(pr.Timestamp)
+	sink.WriteVarUint(pr.Nonce)
+	sink.WriteAddress(pr.NextBookkeeper)
+	sink.WriteVarUint(uint64(len(pr.Transactions)))
+	for _, t := range pr.Transactions {
+		if err := t.Serialization(sink); err != nil {
+			return fmt.Errorf("[Proposal] transactions serialization failed: %s", err)
+		}
+	}
+	sink.WriteVarBytes(pr.Signature)
+	sink.WriteByte(pr.JustifyView)
+	serializeSignatures(sink, pr.Justify)
+
+	return nil
+}
+
+func (pr *Proposal) Deserialization(source *common.ZeroCopySource) error {
+	pr.msgData = ConsensusMessageData{}
+	err := pr.msgData.Deserialization(source)
+	if err != nil {
+		return err
+	}
+
+	var eof bool
+	pr.Timestamp, eof = source.NextUint32()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	nonce, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	pr.Nonce = nonce
+	pr.NextBookkeeper, eof = source.NextAddress()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+
+	length, _, irregular, eof := source.NextVarUint()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	pr.Transactions = nil
+	for i := uint64(0); i < length; i++ {
+		var t types.Transaction
+		if err := t.Deserialization(source); err != nil {
+			return fmt.Errorf("[Proposal] transactions deserialization failed: %s", err)
+		}
+		pr.Transactions = append(pr.Transactions, &t)
+	}
+
+	pr.Signature, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	pr.JustifyView, eof = source.NextByte()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	pr.Justify, err = deserializeSignatures(source)
+	return err
+}
+
+//IsLocked report whether the proposal carries the block locked in a former view
+func (pr *Proposal) IsLocked() bool {
+	return len(pr.Justify) != 0
+}
+
+func (pr *Proposal) Type() ConsensusMessageType {
+	return pr.ConsensusMessageData().Type
+}
+
+func (pr *Proposal) ViewNumber() byte {
+	return pr.msgData.ViewNumber
+}
+
+func (pr *Proposal) ConsensusMessageData() *ConsensusMessageData {
+	return &(pr.msgData)
+}
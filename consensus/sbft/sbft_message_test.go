@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func serializeMessage(t *testing.T, msg ConsensusMessage) []byte {
+	sink := common.NewZeroCopySink(nil)
+	err := msg.Serialization(sink)
+	assert.Nil(t, err)
+	return sink.Bytes()
+}
+
+func TestProposalSerialization(t *testing.T) {
+	proposal := &Proposal{
+		Timestamp:      1554709823,
+		Nonce:          123456,
+		NextBookkeeper: common.Address{1, 2, 3},
+		Signature:      []byte{4, 5, 6},
+		JustifyView:    1,
+		Justify: []SignaturesData{
+			{Index: 0, Signature: []byte{7}},
+			{Index: 2, Signature: []byte{8}},
+		},
+	}
+	proposal.msgData = ConsensusMessageData{Type: ProposalMsg, ViewNumber: 2}
+
+	msg, err := DeserializeMessage(serializeMessage(t, proposal))
+	assert.Nil(t, err)
+	assert.Equal(t, proposal, msg)
+	assert.True(t, msg.(*Proposal).IsLocked())
+}
+
+func TestPrepareCommitSerialization(t *testing.T) {
+	pre := &Prepare{
+		BlockHash: common.Uint256{1, 2},
+		Signature: []byte{3, 4},
+	}
+	pre.msgData = ConsensusMessageData{Type: PrepareMsg, ViewNumber: 1}
+	msg, err := DeserializeMessage(serializeMessage(t, pre))
+	assert.Nil(t, err)
+	assert.Equal(t, pre, msg)
+
+	cm := &Commit{
+		BlockHash: common.Uint256{5, 6},
+		Signature: []byte{7, 8},
+	}
+	cm.msgData = ConsensusMessageData{Type: CommitMsg, ViewNumber: 3}
+	msg, err = DeserializeMessage(serializeMessage(t, cm))
+	assert.Nil(t, err)
+	assert.Equal(t, cm, msg)
+}
+
+func TestViewChangeSerialization(t *testing.T) {
+	cv := &ViewChange{NewViewNumber: 2}
+	cv.msgData = ConsensusMessageData{Type: ViewChangeMsg, ViewNumber: 1}
+	msg, err := DeserializeMessage(serializeMessage(t, cv))
+	assert.Nil(t, err)
+	assert.Equal(t, cv, msg)
+
+	cv.Locked = &Proposal{
+		Timestamp:   1554709823,
+		Signature:   []byte{1},
+		JustifyView: 1,
+		Justify:     []SignaturesData{{Index: 1, Signature: []byte{2}}},
+	}
+	cv.Locked.msgData = ConsensusMessageData{Type: ProposalMsg, ViewNumber: 1}
+	data := serializeMessage(t, cv)
+	msg, err = DeserializeMessage(data)
+	assert.Nil(t, err)
+	assert.Equal(t, cv, msg)
+
+	_, err = DeserializeMessage(data[:len(data)-1])
+	assert.NotNil(t, err)
+}
+
+func TestQuorum(t *testing.T) {
+	for _, c := range []struct{ n, f, m int }{{4, 1, 3}, {5, 1, 4}, {7, 2, 5}, {10, 3, 7}} {
+		ctx := &ConsensusContext{Bookkeepers: make([]keypair.PublicKey, c.n)}
+		assert.Equal(t, c.f, ctx.F())
+		assert.Equal(t, c.m, ctx.M())
+	}
+}
+
+func TestPrepareHash(t *testing.T) {
+	hash := common.Uint256{1}
+	assert.Equal(t, PrepareHash(hash, 1), PrepareHash(hash, 1))
+	assert.NotEqual(t, PrepareHash(hash, 1), PrepareHash(hash, 2))
+	assert.NotEqual(t, PrepareHash(hash, 1), hash[:])
+}
@@ -18,13 +18,700 @@
 
 package sbft
 
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/OnyxPay/OnyxChain-eventbus/actor"
+	"github.com/OnyxPay/OnyxChain/account"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	actorTypes "github.com/OnyxPay/OnyxChain/consensus/actor"
+	"github.com/OnyxPay/OnyxChain/core/genesis"
+	"github.com/OnyxPay/OnyxChain/core/ledger"
+	"github.com/OnyxPay/OnyxChain/core/signature"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/core/vote"
+	"github.com/OnyxPay/OnyxChain/events"
+	"github.com/OnyxPay/OnyxChain/events/message"
+	p2pmsg "github.com/OnyxPay/OnyxChain/p2pserver/message/types"
+	"github.com/OnyxPay/OnyxChain/validator/increment"
+)
+
+//SbftService is a simplified bft consensus for small consortium networks.
+//The primary of a view proposes a block, bookkeepers prepare it and commit it
+//with their block signatures once a quorum has prepared. A bookkeeper which has seen
+//a quorum of prepares locks the block and only votes for other blocks locked in a later view
 type SbftService struct {
+	context           ConsensusContext
+	Account           *account.Account
+	timer             *time.Timer
+	timerHeight       uint32
+	timerView         byte
+	blockReceivedTime time.Time
+	viewChangeTimeout time.Duration
+	started           bool
+	ledger            *ledger.Ledger
+	incrValidator     *increment.IncrementValidator
+	poolActor         *actorTypes.TxPoolActor
+	p2p               *actorTypes.P2PActor
+
+	pid *actor.PID
+	sub *events.ActorSubscriber
+}
+
+func NewSbftService(bkAccount *account.Account, txpool, p2p *actor.PID) (*SbftService, error) {
+	service := &SbftService{
+		Account:           bkAccount,
+		timer:             time.NewTimer(time.Second * 15),
+		viewChangeTimeout: config.DEFAULT_VIEW_CHANGE_TIMEOUT * time.Second,
+		started:           false,
+		ledger:            ledger.DefLedger,
+		incrValidator:     increment.NewIncrementValidator(20),
+		poolActor:         &actorTypes.TxPoolActor{Pool: txpool},
+		p2p:               &actorTypes.P2PActor{P2P: p2p},
+	}
+
+	if !service.timer.Stop() {
+		<-service.timer.C
+	}
+
+	go func() {
+		for {
+			select {
+			case <-service.timer.C:
+				log.Debug("******Get a timeout notice")
+				service.pid.Tell(&actorTypes.TimeOut{})
+			}
+		}
+	}()
+
+	props := actor.FromProducer(func() actor.Actor {
+		return service
+	})
+
+	pid, err := actor.SpawnNamed(props, "consensus_sbft")
+	service.pid = pid
+
+	service.sub = events.NewActorSubscriber(pid)
+	return service, err
+}
+
+func (this *SbftService) Receive(context actor.Context) {
+	if _, ok := context.Message().(*actorTypes.StartConsensus); this.started == false && ok == false {
+		return
+	}
+
+	switch msg := context.Message().(type) {
+	case *actor.Restarting:
+		log.Warn("sbft actor restarting")
+	case *actor.Stopping:
+		log.Warn("sbft actor stopping")
+	case *actor.Stopped:
+		log.Warn("sbft actor stopped")
+	case *actor.Started:
+		log.Warn("sbft actor started")
+	case *actor.Restart:
+		log.Warn("sbft actor restart")
+	case *actorTypes.StartConsensus:
+		this.start()
+	case *actorTypes.StopConsensus:
+		this.incrValidator.Clean()
+		this.halt()
+	case *actorTypes.TimeOut:
+		log.Info("sbft receive timeout")
+		this.Timeout()
+	case *message.SaveBlockCompleteMsg:
+		log.Infof("sbft actor receives block complete event. block height=%d, numtx=%d",
+			msg.Block.Header.Height, len(msg.Block.Transactions))
+		this.incrValidator.AddBlock(msg.Block)
+		this.handleBlockPersistCompleted(msg.Block)
+	case *p2pmsg.ConsensusPayload:
+		this.NewConsensusPayload(msg)
+
+	default:
+		log.Info("sbft actor: Unknown msg ", msg, "type", reflect.TypeOf(msg))
+	}
+}
+
+func (this *SbftService) GetPID() *actor.PID {
+	return this.pid
 }
 
-func (ss *SbftService) Halt() error {
+func (this *SbftService) Start() error {
+	this.pid.Tell(&actorTypes.StartConsensus{})
 	return nil
 }
 
-func (ds *SbftService) Start() error {
+func (this *SbftService) Halt() error {
+	this.pid.Tell(&actorTypes.StopConsensus{})
 	return nil
 }
+
+func (ss *SbftService) start() {
+	ss.started = true
+
+	sbftCfg := config.DefConfig.Genesis.SBFT
+	if sbftCfg.GenBlockTime > config.MIN_GEN_BLOCK_TIME {
+		genesis.GenBlockTime = time.Duration(sbftCfg.GenBlockTime) * time.Second
+	} else {
+		log.Warn("The Generate block time should be longer than 2 seconds, so set it to be default 6 seconds.")
+	}
+	if sbftCfg.ViewChangeTimeout > 0 {
+		ss.viewChangeTimeout = time.Duration(sbftCfg.ViewChangeTimeout) * time.Second
+	}
+
+	ss.sub.Subscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+
+	ss.InitializeConsensus(0)
+}
+
+func (ss *SbftService) halt() error {
+	log.Info("SBFT Stop")
+	if ss.timer != nil {
+		ss.timer.Stop()
+	}
+
+	if ss.started {
+		ss.sub.Unsubscribe(message.TOPIC_SAVE_BLOCK_COMPLETE)
+	}
+	return nil
+}
+
+func (ss *SbftService) handleBlockPersistCompleted(block *types.Block) {
+	log.Infof("persist block: %x", block.Hash())
+	ss.p2p.Broadcast(block.Hash())
+
+	ss.InitializeConsensus(0)
+}
+
+//backupTimeout is how long a backup waits for the block of a view before it asks for a view change
+func (ss *SbftService) backupTimeout(viewNum byte) time.Duration {
+	timeout := ss.viewChangeTimeout * (time.Duration(viewNum) + 1)
+	if viewNum == 0 {
+		timeout += genesis.GenBlockTime
+	}
+	return timeout
+}
+
+func (ss *SbftService) resetTimer(timeout time.Duration) {
+	ss.timer.Stop()
+	ss.timer.Reset(timeout)
+}
+
+func (ss *SbftService) InitializeConsensus(viewNum byte) {
+	log.Debug("[InitializeConsensus] viewNum: ", viewNum)
+
+	if viewNum == 0 {
+		ss.context.Reset(ss.Account)
+	} else {
+		if ss.context.State.HasFlag(BlockGenerated) {
+			return
+		}
+		ss.context.ChangeView(viewNum)
+	}
+
+	if !ss.context.IsBookkeeper() {
+		log.Info("You aren't bookkeeper")
+		return
+	}
+
+	ss.timerHeight = ss.context.Height
+	ss.timerView = viewNum
+	if ss.context.BookkeeperIndex == int(ss.context.PrimaryIndex) {
+		//primary peer
+		ss.context.State |= Primary
+		span := time.Now().Sub(ss.blockReceivedTime)
+		if viewNum > 0 || span > genesis.GenBlockTime {
+			ss.resetTimer(0)
+		} else {
+			ss.resetTimer(genesis.GenBlockTime - span)
+		}
+	} else {
+		//backup peer
+		ss.context.State = Backup
+		ss.resetTimer(ss.backupTimeout(viewNum))
+	}
+}
+
+func (ss *SbftService) Timeout() {
+	if ss.timerHeight != ss.context.Height || ss.timerView != ss.context.ViewNumber {
+		return
+	}
+	if ss.context.State.HasFlag(BlockGenerated) {
+		return
+	}
+
+	log.Info("Timeout: height: ", ss.timerHeight, " View: ", ss.timerView, " State: ", ss.context.GetStateDetail())
+
+	if ss.context.State.HasFlag(Primary) && !ss.context.State.HasFlag(ProposalSent) {
+		if err := ss.sendProposal(); err != nil {
+			log.Errorf("[Timeout] send proposal failed: %s", err)
+			ss.RequestChangeView()
+			return
+		}
+		ss.resetTimer(ss.viewChangeTimeout * (time.Duration(ss.timerView) + 1))
+	} else {
+		ss.RequestChangeView()
+	}
+}
+
+func (ss *SbftService) NewConsensusPayload(payload *p2pmsg.ConsensusPayload) {
+	if !ss.context.IsBookkeeper() {
+		return
+	}
+	//if payload from current peer, ignore it
+	if int(payload.BookkeeperIndex) == ss.context.BookkeeperIndex {
+		return
+	}
+
+	//if payload is not same height with current contex, ignore it
+	if payload.Version != ContextVersion || payload.PrevHash != ss.context.PrevHash || payload.Height != ss.context.Height {
+		log.Debug("unmatched height")
+		return
+	}
+
+	if ss.context.State.HasFlag(BlockGenerated) {
+		log.Debug("has flag 'BlockGenerated'")
+		return
+	}
+
+	if int(payload.BookkeeperIndex) >= len(ss.context.Bookkeepers) {
+		log.Debug("bookkeeper index out of range")
+		return
+	}
+
+	if !keypair.ComparePublicKey(payload.Owner, ss.context.Bookkeepers[payload.BookkeeperIndex]) {
+		log.Debug("payload owner is not the bookkeeper of index")
+		return
+	}
+
+	msg, err := DeserializeMessage(payload.Data)
+	if err != nil {
+		log.Error(fmt.Sprintf("DeserializeMessage failed: %s\n", err))
+		return
+	}
+
+	//commits and view changes are valid across views
+	if msg.ViewNumber() != ss.context.ViewNumber && msg.Type() != ViewChangeMsg && msg.Type() != CommitMsg {
+		return
+	}
+
+	err = payload.Verify()
+	if err != nil {
+		log.Warn(err.Error())
+		return
+	}
+
+	switch msg.Type() {
+	case ViewChangeMsg:
+		if cv, ok := msg.(*ViewChange); ok {
+			ss.ViewChangeReceived(payload, cv)
+		}
+	case ProposalMsg:
+		if pr, ok := msg.(*Proposal); ok {
+			ss.ProposalReceived(payload, pr)
+		}
+	case PrepareMsg:
+		if pre, ok := msg.(*Prepare); ok {
+			ss.PrepareReceived(payload, pre)
+		}
+	case CommitMsg:
+		if cm, ok := msg.(*Commit); ok {
+			ss.CommitReceived(payload, cm)
+		}
+	default:
+		log.Warn("unknown consensus message type")
+	}
+}
+
+//newProposal pack the transactions of txnpool into a new block proposal
+func (ss *SbftService) newProposal() (*Proposal, error) {
+	header, err := ss.ledger.GetHeaderByHash(ss.context.PrevHash)
+	if err != nil {
+		return nil, fmt.Errorf("GetHeader PrevHash:%x error:%s", ss.context.PrevHash, err)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("cannot GetHeaderByHash by PrevHash:%x", ss.context.PrevHash)
+	}
+
+	proposal := &Proposal{}
+	now := uint32(time.Now().Unix())
+	blockTime := header.Timestamp + 1
+	if blockTime > now {
+		proposal.Timestamp = blockTime
+	} else {
+		proposal.Timestamp = now
+	}
+	proposal.Nonce = common.GetNonce()
+
+	height := ss.context.Height - 1
+	validHeight := height
+	start, end := ss.incrValidator.BlockRange()
+	if height+1 == end {
+		validHeight = start
+	} else {
+		ss.incrValidator.Clean()
+		log.Infof("incr validator block height %v != ledger block height %v", int(end)-1, height)
+	}
+
+	log.Infof("current block height %v, increment validator block cache range: [%d, %d)", height, start, end)
+	txs := ss.poolActor.GetTxnPool(true, validHeight)
+
+	proposal.Transactions = make([]*types.Transaction, 0, len(txs))
+	for _, txEntry := range txs {
+		if err := ss.incrValidator.Verify(txEntry.Tx, validHeight); err == nil {
+			proposal.Transactions = append(proposal.Transactions, txEntry.Tx)
+		}
+	}
+
+	proposal.NextBookkeeper, err = ss.nextBookkeeper(proposal.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+func (ss *SbftService) nextBookkeeper(txs []*types.Transaction) (common.Address, error) {
+	nextBookkeepers, err := vote.GetValidators(txs)
+	if err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("GetValidators failed: %s", err)
+	}
+	nextBookkeeper, err := types.AddressFromBookkeepers(nextBookkeepers)
+	if err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("GetBookkeeperAddress failed: %s", err)
+	}
+	return nextBookkeeper, nil
+}
+
+//sendProposal propose the highest locked block known by the primary, or a new block if there is none
+func (ss *SbftService) sendProposal() error {
+	var proposal *Proposal
+	if locked := ss.context.HighestLocked(ss.context.ViewNumber); locked != nil {
+		log.Infof("propose block locked in view %d: height: %d View: %d", locked.JustifyView, ss.context.Height, ss.context.ViewNumber)
+		proposal = &Proposal{
+			Timestamp:      locked.Timestamp,
+			Nonce:          locked.Nonce,
+			NextBookkeeper: locked.NextBookkeeper,
+			Transactions:   locked.Transactions,
+			JustifyView:    locked.JustifyView,
+			Justify:        locked.Justify,
+		}
+	} else {
+		var err error
+		proposal, err = ss.newProposal()
+		if err != nil {
+			return err
+		}
+	}
+
+	ss.context.SetProposal(proposal)
+	sig, err := signature.Sign(ss.Account, ss.context.BlockHash[:])
+	if err != nil {
+		return fmt.Errorf("sign proposal failed: %s", err)
+	}
+	proposal.Signature = sig
+	ss.context.State |= ProposalSent
+
+	log.Info("Send proposal: height: ", ss.context.Height, " View: ", ss.context.ViewNumber, " tx: ", len(proposal.Transactions))
+	ss.SignAndRelay(ss.context.MakeProposal())
+	ss.blockReceivedTime = time.Now()
+
+	ss.sendPrepare()
+	return nil
+}
+
+func (ss *SbftService) ProposalReceived(payload *p2pmsg.ConsensusPayload, proposal *Proposal) {
+	log.Info(fmt.Sprintf("Proposal Received: height=%d View=%d index=%d tx=%d", payload.Height, proposal.ViewNumber(), payload.BookkeeperIndex, len(proposal.Transactions)))
+
+	if !ss.context.State.HasFlag(Backup) || ss.context.State.HasFlag(ProposalRecved) {
+		return
+	}
+
+	if uint32(payload.BookkeeperIndex) != ss.context.PrimaryIndex {
+		return
+	}
+
+	header, err := ss.ledger.GetHeaderByHash(ss.context.PrevHash)
+	if err != nil {
+		log.Errorf("ProposalReceived GetHeader failed with PrevHash:%x", ss.context.PrevHash)
+		return
+	}
+	if header == nil {
+		log.Errorf("ProposalReceived cannot GetHeaderByHash by PrevHash:%x", ss.context.PrevHash)
+		return
+	}
+	if proposal.Timestamp <= header.Timestamp || proposal.Timestamp > uint32(time.Now().Add(time.Minute*10).Unix()) {
+		log.Info(fmt.Sprintf("Proposal Received: Timestamp incorrect: %d", proposal.Timestamp))
+		return
+	}
+
+	var blockHash common.Uint256
+	if proposal.IsLocked() {
+		if proposal.JustifyView >= proposal.ViewNumber() {
+			log.Warnf("ProposalReceived locked view %d is not before view %d", proposal.JustifyView, proposal.ViewNumber())
+			return
+		}
+		blockHash, err = ss.context.VerifyJustify(proposal)
+		if err != nil {
+			log.Warn("ProposalReceived verify prepare certificate failed.", err)
+			return
+		}
+	} else {
+		blockHash = ss.context.MakeHeader(proposal).Hash()
+	}
+
+	//a locked bookkeeper only votes for its locked block, or a block locked in a later view
+	if locked := ss.context.Locked; locked != nil && ss.context.LockedHash != blockHash {
+		if !proposal.IsLocked() || proposal.JustifyView <= locked.JustifyView {
+			log.Warnf("ProposalReceived proposal %x conflicts with block %x locked in view %d",
+				blockHash, ss.context.LockedHash, locked.JustifyView)
+			return
+		}
+	}
+
+	err = signature.Verify(ss.context.Bookkeepers[payload.BookkeeperIndex], blockHash[:], proposal.Signature)
+	if err != nil {
+		log.Warn("ProposalReceived VerifySignature failed.", err)
+		return
+	}
+
+	//transactions of a locked block were verified by a quorum already
+	if !proposal.IsLocked() {
+		if err := ss.verifyTransactions(proposal.Transactions); err != nil {
+			log.Error("ProposalReceived new transaction verification failed, will not sent prepare", err)
+			return
+		}
+		nextBookkeeper, err := ss.nextBookkeeper(proposal.Transactions)
+		if err != nil {
+			log.Errorf("[ProposalReceived] %s", err)
+			return
+		}
+		if nextBookkeeper != proposal.NextBookkeeper {
+			log.Error("[ProposalReceived] Unmatched NextBookkeeper")
+			return
+		}
+	}
+
+	ss.context.SetProposal(proposal)
+	ss.context.State |= ProposalRecved
+	ss.blockReceivedTime = time.Now()
+
+	ss.sendPrepare()
+	//commits may arrive before the proposal
+	if err := ss.CheckCommits(); err != nil {
+		log.Error("CheckCommits failed", err)
+	}
+	log.Info("Proposal finished")
+}
+
+func (ss *SbftService) verifyTransactions(txs []*types.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	height := ss.context.Height - 1
+	start, end := ss.incrValidator.BlockRange()
+
+	validHeight := height
+	if height+1 == end {
+		validHeight = start
+	} else {
+		ss.incrValidator.Clean()
+		log.Infof("incr validator block height %v != ledger block height %v", int(end)-1, height)
+	}
+
+	if err := ss.poolActor.VerifyBlock(txs, validHeight); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err := ss.incrValidator.Verify(tx, validHeight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ss *SbftService) sendPrepare() {
+	sig, err := signature.Sign(ss.Account, PrepareHash(ss.context.BlockHash, ss.context.ViewNumber))
+	if err != nil {
+		log.Error("[SbftService] signing prepare failed", err)
+		return
+	}
+	pre := &Prepare{
+		BlockHash: ss.context.BlockHash,
+		Signature: sig,
+	}
+	ss.context.Prepares[ss.context.BookkeeperIndex] = pre
+	ss.SignAndRelay(ss.context.MakePrepare(pre))
+
+	ss.CheckPrepares()
+}
+
+func (ss *SbftService) PrepareReceived(payload *p2pmsg.ConsensusPayload, pre *Prepare) {
+	log.Info(fmt.Sprintf("Prepare Received: height=%d View=%d index=%d", payload.Height, pre.ViewNumber(), payload.BookkeeperIndex))
+
+	//if the prepare already exist, needn't handle again
+	if ss.context.Prepares[payload.BookkeeperIndex] != nil {
+		return
+	}
+
+	data := PrepareHash(pre.BlockHash, pre.ViewNumber())
+	if err := signature.Verify(ss.context.Bookkeepers[payload.BookkeeperIndex], data, pre.Signature); err != nil {
+		log.Warn("PrepareReceived VerifySignature failed.", err)
+		return
+	}
+	ss.context.Prepares[payload.BookkeeperIndex] = pre
+
+	ss.CheckPrepares()
+}
+
+//CheckPrepares lock the proposal and commit it when a quorum has prepared it
+func (ss *SbftService) CheckPrepares() {
+	if ss.context.Proposal == nil || ss.context.State.HasFlag(CommitSent) {
+		return
+	}
+	if ss.context.GetPrepareCount() < ss.context.M() {
+		return
+	}
+
+	ss.context.Lock()
+	sig, err := signature.Sign(ss.Account, ss.context.BlockHash[:])
+	if err != nil {
+		log.Error("[SbftService] signing block failed", err)
+		return
+	}
+	cm := &Commit{
+		BlockHash: ss.context.BlockHash,
+		Signature: sig,
+	}
+	ss.context.Commits[ss.context.BookkeeperIndex] = cm
+	ss.context.State |= CommitSent
+
+	log.Info("send commit")
+	ss.SignAndRelay(ss.context.MakeCommit(cm))
+
+	if err := ss.CheckCommits(); err != nil {
+		log.Error("CheckCommits failed", err)
+	}
+}
+
+func (ss *SbftService) CommitReceived(payload *p2pmsg.ConsensusPayload, cm *Commit) {
+	log.Info(fmt.Sprintf("Commit Received: height=%d View=%d index=%d", payload.Height, cm.ViewNumber(), payload.BookkeeperIndex))
+
+	if old := ss.context.Commits[payload.BookkeeperIndex]; old != nil && old.BlockHash == cm.BlockHash {
+		return
+	}
+
+	err := signature.Verify(ss.context.Bookkeepers[payload.BookkeeperIndex], cm.BlockHash[:], cm.Signature)
+	if err != nil {
+		log.Warn("CommitReceived VerifySignature failed.", err)
+		return
+	}
+	ss.context.Commits[payload.BookkeeperIndex] = cm
+
+	if err := ss.CheckCommits(); err != nil {
+		log.Error("CheckCommits failed", err)
+	}
+}
+
+//CheckCommits save the block when a quorum of block signatures is collected
+func (ss *SbftService) CheckCommits() error {
+	if ss.context.Proposal == nil || ss.context.State.HasFlag(BlockGenerated) {
+		return nil
+	}
+	if ss.context.GetCommitCount() < ss.context.M() {
+		return nil
+	}
+
+	block := ss.context.MakeBlock()
+	hash := block.Hash()
+	isExist, err := ss.ledger.IsContainBlock(hash)
+	if err != nil {
+		log.Errorf("DefLedger.IsContainBlock Hash:%x error:%s", hash, err)
+		return err
+	}
+	if !isExist {
+		result, err := ss.ledger.ExecuteBlock(block)
+		if err != nil {
+			return fmt.Errorf("CheckCommits ExecuteBlock Height:%d error:%s", block.Header.Height, err)
+		}
+		err = ss.ledger.SubmitBlock(block, result)
+		if err != nil {
+			return fmt.Errorf("CheckCommits SubmitBlock Height:%d error:%s", block.Header.Height, err)
+		}
+	}
+	ss.context.State |= BlockGenerated
+	log.Infof("block generated: height=%d View=%d hash=%x", block.Header.Height, ss.context.ViewNumber, hash)
+	return nil
+}
+
+func (ss *SbftService) ViewChangeReceived(payload *p2pmsg.ConsensusPayload, cv *ViewChange) {
+	log.Info(fmt.Sprintf("View Change Received: height=%d View=%d index=%d nv=%d", payload.Height, cv.ViewNumber(), payload.BookkeeperIndex, cv.NewViewNumber))
+
+	if cv.NewViewNumber <= ss.context.ExpectedView[payload.BookkeeperIndex] {
+		return
+	}
+
+	ss.context.ExpectedView[payload.BookkeeperIndex] = cv.NewViewNumber
+	ss.context.ViewChanges[payload.BookkeeperIndex] = cv
+
+	ss.CheckExpectedView()
+}
+
+//CheckExpectedView join a view asked by f+1 bookkeepers, and move to a view asked by a quorum
+func (ss *SbftService) CheckExpectedView() {
+	if ss.context.State.HasFlag(BlockGenerated) {
+		return
+	}
+
+	views := make([]byte, len(ss.context.ExpectedView))
+	copy(views, ss.context.ExpectedView)
+	sort.Slice(views, func(i, j int) bool { return views[i] > views[j] })
+
+	//at least one honest bookkeeper has asked for joinView
+	joinView := views[ss.context.F()]
+	if joinView > ss.context.ExpectedView[ss.context.BookkeeperIndex] {
+		log.Infof("join view change: height=%d View=%d nv=%d", ss.context.Height, ss.context.ViewNumber, joinView)
+		ss.context.ExpectedView[ss.context.BookkeeperIndex] = joinView
+		ss.SignAndRelay(ss.context.MakeViewChange())
+	}
+
+	newView := views[ss.context.M()-1]
+	if newView > ss.context.ViewNumber {
+		log.Infof("change view: height=%d View=%d nv=%d", ss.context.Height, ss.context.ViewNumber, newView)
+		ss.InitializeConsensus(newView)
+	}
+}
+
+func (ss *SbftService) RequestChangeView() {
+	if ss.context.State.HasFlag(BlockGenerated) {
+		return
+	}
+	expected := ss.context.ExpectedView[ss.context.BookkeeperIndex]
+	if expected == math.MaxUint8 {
+		log.Warnf("Request change view: height=%d reach max view number", ss.context.Height)
+		return
+	}
+	ss.context.ExpectedView[ss.context.BookkeeperIndex] = expected + 1
+	log.Info(fmt.Sprintf("Request change view: height=%d View=%d nv=%d state=%s", ss.context.Height,
+		ss.context.ViewNumber, expected+1, ss.context.GetStateDetail()))
+
+	ss.resetTimer(ss.viewChangeTimeout * time.Duration(expected+1-ss.context.ViewNumber))
+
+	ss.SignAndRelay(ss.context.MakeViewChange())
+	ss.CheckExpectedView()
+}
+
+func (ss *SbftService) SignAndRelay(payload *p2pmsg.ConsensusPayload) {
+	buf := new(bytes.Buffer)
+	payload.SerializeUnsigned(buf)
+	payload.Signature, _ = signature.Sign(ss.Account, buf.Bytes())
+
+	ss.p2p.Broadcast(payload)
+}
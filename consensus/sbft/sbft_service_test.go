@@ -0,0 +1,432 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/OnyxPay/OnyxChain-eventbus/actor"
+	"github.com/OnyxPay/OnyxChain/account"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	actorTypes "github.com/OnyxPay/OnyxChain/consensus/actor"
+	"github.com/OnyxPay/OnyxChain/core/genesis"
+	"github.com/OnyxPay/OnyxChain/core/ledger"
+	"github.com/OnyxPay/OnyxChain/core/signature"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/validator/increment"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDataDir = "test"
+
+var (
+	testAccounts []*account.Account
+	testPID      *actor.PID
+)
+
+//discardActor drop the messages relayed by the service under test
+type discardActor struct{}
+
+func (this *discardActor) Receive(context actor.Context) {}
+
+func TestMain(m *testing.M) {
+	log.Init(log.PATH, log.Stdout)
+	config.DefConfig.Genesis.ConsensusType = config.CONSENSUS_TYPE_SBFT
+	bookkeepers := make([]keypair.PublicKey, 0, 4)
+	for i := 0; i < 4; i++ {
+		acc := account.NewAccount("")
+		testAccounts = append(testAccounts, acc)
+		bookkeepers = append(bookkeepers, acc.PublicKey)
+	}
+
+	os.RemoveAll(testDataDir)
+	var err error
+	ledger.DefLedger, err = ledger.NewLedger(testDataDir, 0)
+	if err != nil {
+		fmt.Printf("NewLedger error %s\n", err)
+		os.Exit(1)
+	}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	if err != nil {
+		fmt.Printf("BuildGenesisBlock error %s\n", err)
+		os.Exit(1)
+	}
+	err = ledger.DefLedger.Init(bookkeepers, block)
+	if err != nil {
+		fmt.Printf("Init ledger error %s\n", err)
+		os.Exit(1)
+	}
+	testPID = actor.Spawn(actor.FromProducer(func() actor.Actor { return &discardActor{} }))
+
+	code := m.Run()
+	ledger.DefLedger.Close()
+	os.RemoveAll(testDataDir)
+	os.Exit(code)
+}
+
+//newTestService return the service of bookkeeper self at view 0 of the next block
+func newTestService(self int) *SbftService {
+	ss := &SbftService{
+		Account:           testAccounts[self],
+		timer:             time.NewTimer(time.Hour),
+		viewChangeTimeout: time.Hour,
+		ledger:            ledger.DefLedger,
+		incrValidator:     increment.NewIncrementValidator(20),
+		poolActor:         &actorTypes.TxPoolActor{Pool: testPID},
+		p2p:               &actorTypes.P2PActor{P2P: testPID},
+	}
+	ss.InitializeConsensus(0)
+	return ss
+}
+
+//testBackup return a bookkeeper which is not the primary of views at the next block
+func testBackup(views ...byte) int {
+	height := ledger.DefLedger.GetCurrentBlockHeight() + 1
+	for i := range testAccounts {
+		primary := false
+		for _, view := range views {
+			if uint32(i) == (height+uint32(view))%uint32(len(testAccounts)) {
+				primary = true
+			}
+		}
+		if !primary {
+			return i
+		}
+	}
+	return -1
+}
+
+//testOthers return the bookkeepers except self
+func testOthers(self int) []int {
+	others := make([]int, 0, len(testAccounts)-1)
+	for i := range testAccounts {
+		if i != self {
+			others = append(others, i)
+		}
+	}
+	return others
+}
+
+func testSign(t *testing.T, from int, data []byte) []byte {
+	sig, err := signature.Sign(testAccounts[from], data)
+	assert.Nil(t, err)
+	return sig
+}
+
+//sendMessage feed the message of bookkeeper from at view to the service
+func sendMessage(t *testing.T, ss *SbftService, from int, view byte, message ConsensusMessage, typ ConsensusMessageType) {
+	message.ConsensusMessageData().Type = typ
+	ctx := ss.context
+	ctx.ViewNumber = view
+	ctx.BookkeeperIndex = from
+	ctx.Owner = testAccounts[from].PublicKey
+	payload := ctx.MakePayload(message)
+	buf := new(bytes.Buffer)
+	assert.Nil(t, payload.SerializeUnsigned(buf))
+	payload.Signature = testSign(t, from, buf.Bytes())
+	ss.NewConsensusPayload(payload)
+}
+
+func newTestProposal(t *testing.T, ss *SbftService, nonce uint64) *Proposal {
+	header, err := ledger.DefLedger.GetHeaderByHash(ss.context.PrevHash)
+	assert.Nil(t, err)
+	nextBookkeeper, err := ss.nextBookkeeper(nil)
+	assert.Nil(t, err)
+	return &Proposal{
+		Timestamp:      header.Timestamp + 1,
+		Nonce:          nonce,
+		NextBookkeeper: nextBookkeeper,
+		Transactions:   []*types.Transaction{},
+	}
+}
+
+//signProposal sign the block of proposal by bookkeeper from, and return the block hash
+func signProposal(t *testing.T, ss *SbftService, proposal *Proposal, from int) common.Uint256 {
+	blockHash := ss.context.MakeHeader(proposal).Hash()
+	proposal.Signature = testSign(t, from, blockHash[:])
+	return blockHash
+}
+
+func newTestPrepare(t *testing.T, from int, blockHash common.Uint256, view byte) *Prepare {
+	return &Prepare{BlockHash: blockHash, Signature: testSign(t, from, PrepareHash(blockHash, view))}
+}
+
+func newTestJustify(t *testing.T, blockHash common.Uint256, view byte, signers ...int) []SignaturesData {
+	sigs := make([]SignaturesData, 0, len(signers))
+	for _, i := range signers {
+		sigs = append(sigs, SignaturesData{Index: uint16(i), Signature: testSign(t, i, PrepareHash(blockHash, view))})
+	}
+	return sigs
+}
+
+//lockTestBlock let the backup ss receive a new block at view 0 and lock it with the prepares of others
+func lockTestBlock(t *testing.T, ss *SbftService) common.Uint256 {
+	primary := int(ss.context.PrimaryIndex)
+	proposal := newTestProposal(t, ss, 1)
+	blockHash := signProposal(t, ss, proposal, primary)
+	sendMessage(t, ss, primary, 0, proposal, ProposalMsg)
+	assert.True(t, ss.context.State.HasFlag(ProposalRecved))
+	for _, from := range testOthers(ss.context.BookkeeperIndex)[:ss.context.M()-1] {
+		sendMessage(t, ss, from, 0, newTestPrepare(t, from, blockHash, 0), PrepareMsg)
+	}
+	assert.Equal(t, blockHash, ss.context.LockedHash)
+	return blockHash
+}
+
+func TestCheckPrepares(t *testing.T) {
+	type vote struct {
+		otherBlock bool //prepare another block
+		signView   byte //view signed by prepare
+		msgView    byte //view of prepare message
+	}
+	tests := []struct {
+		name   string
+		votes  []vote
+		locked bool
+	}{
+		{name: "M-1 prepares", votes: []vote{{}}, locked: false},
+		{name: "M prepares", votes: []vote{{}, {}}, locked: true},
+		{name: "prepare of other block", votes: []vote{{}, {otherBlock: true}}, locked: false},
+		{name: "prepare signed for other view", votes: []vote{{}, {signView: 1}}, locked: false},
+		{name: "prepare message of other view", votes: []vote{{}, {signView: 1, msgView: 1}}, locked: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			self := testBackup(0)
+			ss := newTestService(self)
+			primary := int(ss.context.PrimaryIndex)
+			proposal := newTestProposal(t, ss, 1)
+			blockHash := signProposal(t, ss, proposal, primary)
+			sendMessage(t, ss, primary, 0, proposal, ProposalMsg)
+			assert.True(t, ss.context.State.HasFlag(ProposalRecved))
+			assert.Equal(t, blockHash, ss.context.BlockHash)
+			assert.Equal(t, 1, ss.context.GetPrepareCount())
+
+			others := testOthers(self)
+			for i, v := range tt.votes {
+				hash := blockHash
+				if v.otherBlock {
+					hash = common.Uint256{1}
+				}
+				sendMessage(t, ss, others[i], v.msgView, newTestPrepare(t, others[i], hash, v.signView), PrepareMsg)
+			}
+			assert.Equal(t, tt.locked, ss.context.Locked != nil)
+			assert.Equal(t, tt.locked, ss.context.State.HasFlag(CommitSent))
+			if tt.locked {
+				assert.Equal(t, blockHash, ss.context.LockedHash)
+				assert.Equal(t, ss.context.M(), len(ss.context.Locked.Justify))
+				assert.NotNil(t, ss.context.Commits[self])
+			}
+		})
+	}
+}
+
+func TestCheckCommits(t *testing.T) {
+	tests := []struct {
+		name      string
+		votes     []bool //whether the commit is for another block
+		generated bool
+	}{
+		{name: "M-1 commits", votes: []bool{false}, generated: false},
+		{name: "commit of other block", votes: []bool{false, true}, generated: false},
+		//the block is saved, keep it the last case
+		{name: "M commits", votes: []bool{false, false}, generated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			self := testBackup(0)
+			ss := newTestService(self)
+			height := ledger.DefLedger.GetCurrentBlockHeight()
+			blockHash := lockTestBlock(t, ss)
+			assert.True(t, ss.context.State.HasFlag(CommitSent))
+
+			others := testOthers(self)
+			for i, otherBlock := range tt.votes {
+				hash := blockHash
+				if otherBlock {
+					hash = common.Uint256{1}
+				}
+				cm := &Commit{BlockHash: hash, Signature: testSign(t, others[i], hash[:])}
+				sendMessage(t, ss, others[i], 0, cm, CommitMsg)
+			}
+			assert.Equal(t, tt.generated, ss.context.State.HasFlag(BlockGenerated))
+			if tt.generated {
+				assert.Equal(t, height+1, ledger.DefLedger.GetCurrentBlockHeight())
+				assert.Equal(t, blockHash, ledger.DefLedger.GetCurrentBlockHash())
+			} else {
+				assert.Equal(t, height, ledger.DefLedger.GetCurrentBlockHeight())
+			}
+		})
+	}
+}
+
+func TestVerifyJustify(t *testing.T) {
+	ss := newTestService(0)
+	proposal := newTestProposal(t, ss, 1)
+	blockHash := ss.context.MakeHeader(proposal).Hash()
+	outOfRange := newTestJustify(t, blockHash, 0, 0, 1)
+	outOfRange = append(outOfRange, SignaturesData{Index: 4, Signature: outOfRange[0].Signature})
+	tests := []struct {
+		name        string
+		justifyView byte
+		justify     []SignaturesData
+		valid       bool
+	}{
+		{name: "M signatures", justify: newTestJustify(t, blockHash, 0, 0, 1, 2), valid: true},
+		{name: "M-1 signatures", justify: newTestJustify(t, blockHash, 0, 0, 1), valid: false},
+		{name: "duplicated signer", justify: newTestJustify(t, blockHash, 0, 0, 1, 1), valid: false},
+		{name: "signer out of range", justify: outOfRange, valid: false},
+		{name: "signed for other view", justifyView: 1, justify: newTestJustify(t, blockHash, 0, 0, 1, 2), valid: false},
+		{name: "signed for other block", justify: newTestJustify(t, common.Uint256{1}, 0, 0, 1, 2), valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locked := *proposal
+			locked.JustifyView = tt.justifyView
+			locked.Justify = tt.justify
+			hash, err := ss.context.VerifyJustify(&locked)
+			assert.Equal(t, blockHash, hash)
+			assert.Equal(t, tt.valid, err == nil)
+		})
+	}
+}
+
+func TestProposalReceivedLocked(t *testing.T) {
+	//the backup locks a block A at view 0, and receives the proposal of view 2
+	tests := []struct {
+		name     string
+		proposal func(t *testing.T, ss *SbftService, lockedHash common.Uint256) *Proposal
+		accepted bool
+	}{
+		{
+			name: "new block conflicts with lock",
+			proposal: func(t *testing.T, ss *SbftService, lockedHash common.Uint256) *Proposal {
+				return newTestProposal(t, ss, 2)
+			},
+			accepted: false,
+		},
+		{
+			name: "re-proposal of locked block",
+			proposal: func(t *testing.T, ss *SbftService, lockedHash common.Uint256) *Proposal {
+				locked := *ss.context.Locked
+				return &locked
+			},
+			accepted: true,
+		},
+		{
+			name: "re-proposal of locked block with M-1 prepares",
+			proposal: func(t *testing.T, ss *SbftService, lockedHash common.Uint256) *Proposal {
+				locked := *ss.context.Locked
+				locked.Justify = locked.Justify[:ss.context.M()-1]
+				return &locked
+			},
+			accepted: false,
+		},
+		{
+			name: "re-proposal of block locked in proposal view",
+			proposal: func(t *testing.T, ss *SbftService, lockedHash common.Uint256) *Proposal {
+				locked := *ss.context.Locked
+				locked.JustifyView = 2
+				locked.Justify = newTestJustify(t, lockedHash, 2, 0, 1, 2)
+				return &locked
+			},
+			accepted: false,
+		},
+		{
+			name: "other block locked in the view of lock",
+			proposal: func(t *testing.T, ss *SbftService, lockedHash common.Uint256) *Proposal {
+				proposal := newTestProposal(t, ss, 2)
+				blockHash := ss.context.MakeHeader(proposal).Hash()
+				proposal.JustifyView = 0
+				proposal.Justify = newTestJustify(t, blockHash, 0, 0, 1, 2)
+				return proposal
+			},
+			accepted: false,
+		},
+		{
+			name: "other block locked in later view",
+			proposal: func(t *testing.T, ss *SbftService, lockedHash common.Uint256) *Proposal {
+				proposal := newTestProposal(t, ss, 2)
+				blockHash := ss.context.MakeHeader(proposal).Hash()
+				proposal.JustifyView = 1
+				proposal.Justify = newTestJustify(t, blockHash, 1, 0, 1, 2)
+				return proposal
+			},
+			accepted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			self := testBackup(0, 2)
+			ss := newTestService(self)
+			lockedHash := lockTestBlock(t, ss)
+			ss.InitializeConsensus(2)
+			assert.True(t, ss.context.State.HasFlag(Backup))
+			assert.NotNil(t, ss.context.Locked)
+
+			primary := int(ss.context.PrimaryIndex)
+			proposal := tt.proposal(t, ss, lockedHash)
+			blockHash := signProposal(t, ss, proposal, primary)
+			sendMessage(t, ss, primary, 2, proposal, ProposalMsg)
+			assert.Equal(t, tt.accepted, ss.context.State.HasFlag(ProposalRecved))
+			if tt.accepted {
+				assert.Equal(t, blockHash, ss.context.BlockHash)
+				assert.NotNil(t, ss.context.Prepares[self])
+			}
+		})
+	}
+}
+
+func TestCheckExpectedView(t *testing.T) {
+	//4 bookkeepers, f = 1, M = 3
+	tests := []struct {
+		name         string
+		self         byte   //expected view of self
+		others       []byte //expected views of others
+		wantExpected byte
+		wantView     byte
+	}{
+		{name: "f view changes are not joined", self: 0, others: []byte{3, 0, 0}, wantExpected: 0, wantView: 0},
+		{name: "f+1 view changes are joined", self: 0, others: []byte{3, 3, 0}, wantExpected: 3, wantView: 0},
+		{name: "join the lowest view of f+1", self: 0, others: []byte{3, 2, 0}, wantExpected: 2, wantView: 0},
+		{name: "M-1 view changes keep view", self: 1, others: []byte{1, 0, 0}, wantExpected: 1, wantView: 0},
+		{name: "M view changes change view", self: 1, others: []byte{1, 1, 0}, wantExpected: 1, wantView: 1},
+		{name: "change to the view asked by M", self: 2, others: []byte{3, 1, 0}, wantExpected: 2, wantView: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			self := 0
+			ss := newTestService(self)
+			ss.context.ExpectedView[self] = tt.self
+			for i, from := range testOthers(self) {
+				ss.context.ExpectedView[from] = tt.others[i]
+			}
+			ss.CheckExpectedView()
+			assert.Equal(t, tt.wantExpected, ss.context.ExpectedView[self])
+			assert.Equal(t, tt.wantView, ss.context.ViewNumber)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sbft
+
+import (
+	"io"
+
+	"github.com/OnyxPay/OnyxChain/common"
+)
+
+//ViewChange asks for a new primary, Locked is the proposal the sender has seen prepared
+//with its prepare certificate, nil if there is none
+type ViewChange struct {
+	msgData       ConsensusMessageData
+	NewViewNumber byte
+	Locked        *Proposal
+}
+
+func (cv *ViewChange) Serialization(sink *common.ZeroCopySink) error {
+	cv.msgData.Serialization(sink)
+	sink.WriteByte(cv.NewViewNumber)
+	sink.WriteBool(cv.Locked != nil)
+	if cv.Locked != nil {
+		return cv.Locked.Serialization(sink)
+	}
+	return nil
+}
+
+//read data to reader
+func (cv *ViewChange) Deserialization(source *common.ZeroCopySource) error {
+	err := cv.msgData.Deserialization(source)
+	if err != nil {
+		return err
+	}
+
+	viewNum, eof := source.NextByte()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	cv.NewViewNumber = viewNum
+
+	locked, irregular, eof := source.NextBool()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	cv.Locked = nil
+	if locked {
+		cv.Locked = &Proposal{}
+		return cv.Locked.Deserialization(source)
+	}
+	return nil
+}
+
+func (cv *ViewChange) Type() ConsensusMessageType {
+	return cv.ConsensusMessageData().Type
+}
+
+func (cv *ViewChange) ViewNumber() byte {
+	return cv.msgData.ViewNumber
+}
+
+func (cv *ViewChange) ConsensusMessageData() *ConsensusMessageData {
+	return &(cv.msgData)
+}
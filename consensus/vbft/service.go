@@ -516,7 +516,8 @@ func (self *Server) initialize() error {
 
 func (self *Server) start() error {
 	// check if server pubkey support VRF
-	if !vrf.ValidatePrivateKey(self.account.PrivateKey) || !vrf.ValidatePublicKey(self.account.PublicKey) {
+	if !vrf.ValidatePublicKey(self.account.PublicKey) ||
+		(self.account.Signer == nil && !vrf.ValidatePrivateKey(self.account.PrivateKey)) {
 		return fmt.Errorf("server %d consensus start failed: invalid account key for VRF", self.Index)
 	}
 
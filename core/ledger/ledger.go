@@ -72,6 +72,14 @@ func (self *Ledger) AddBlock(block *types.Block, stateMerkleRoot common.Uint256)
 	return err
 }
 
+func (self *Ledger) AddCheckpointBlocks(blocks []*types.Block) error {
+	err := self.ldgStore.AddCheckpointBlocks(blocks)
+	if err != nil {
+		log.Errorf("Ledger AddCheckpointBlocks count:%d error:%s", len(blocks), err)
+	}
+	return err
+}
+
 func (self *Ledger) ExecuteBlock(b *types.Block) (store.ExecuteResult, error) {
 	return self.ldgStore.ExecuteBlock(b)
 }
@@ -133,6 +141,14 @@ func (self *Ledger) GetCurrentHeaderHash() common.Uint256 {
 	return self.ldgStore.GetCurrentHeaderHash()
 }
 
+func (self *Ledger) GetCheckpoints() map[uint32]common.Uint256 {
+	return self.ldgStore.GetCheckpoints()
+}
+
+func (self *Ledger) GetLastCheckpoint() uint32 {
+	return self.ldgStore.GetLastCheckpoint()
+}
+
 func (self *Ledger) IsContainTransaction(txHash common.Uint256) (bool, error) {
 	return self.ldgStore.IsContainTransaction(txHash)
 }
@@ -149,6 +165,14 @@ func (self *Ledger) GetBookkeeperState() (*states.BookkeeperState, error) {
 	return self.ldgStore.GetBookkeeperState()
 }
 
+func (self *Ledger) GetStorageProof(codeHash common.Address, key []byte, height uint32) (*store.StorageProof, error) {
+	storageKey := &states.StorageKey{
+		ContractAddress: codeHash,
+		Key:             key,
+	}
+	return self.ldgStore.GetStorageProof(storageKey, height)
+}
+
 func (self *Ledger) GetStorageItem(codeHash common.Address, key []byte) ([]byte, error) {
 	storageKey := &states.StorageKey{
 		ContractAddress: codeHash,
@@ -176,6 +200,18 @@ func (self *Ledger) PreExecuteContract(tx *types.Transaction) (*cstate.PreExecRe
 	return self.ldgStore.PreExecuteContract(tx)
 }
 
+func (self *Ledger) PreExecuteContractWithTrace(tx *types.Transaction) (*cstate.PreExecResult, error) {
+	return self.ldgStore.PreExecuteContractWithTrace(tx)
+}
+
+func (self *Ledger) TraceTransaction(txHash common.Uint256) (*cstate.PreExecResult, error) {
+	return self.ldgStore.TraceTransaction(txHash)
+}
+
+func (self *Ledger) EstimateGas(tx *types.Transaction) (*cstate.GasEstimate, error) {
+	return self.ldgStore.EstimateGas(tx)
+}
+
 func (self *Ledger) GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error) {
 	return self.ldgStore.GetEventNotifyByTx(tx)
 }
@@ -184,6 +220,10 @@ func (self *Ledger) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify
 	return self.ldgStore.GetEventNotifyByBlock(height)
 }
 
+func (self *Ledger) GetEventNotifyLogs(filter *event.EventFilter) ([]*event.NotifyEventLog, error) {
+	return self.ldgStore.GetEventNotifyLogs(filter)
+}
+
 func (self *Ledger) Close() error {
 	return self.ldgStore.Close()
 }
@@ -27,10 +27,26 @@ import (
 	"github.com/OnyxPay/OnyxChain/common/serialization"
 )
 
+// VmType is the virtual machine a deployed contract runs on
+type VmType byte
+
+const (
+	NEOVM_TYPE  VmType = 0
+	WASMVM_TYPE VmType = 1
+)
+
+// vm flags share the byte formerly holding the NeedStorage bool, so legacy
+// NeoVM deploy payloads (flag 0 or 1) keep their encoding and hash
+const (
+	VM_FLAG_NEED_STORAGE byte = 1 << 0
+	VM_FLAG_WASMVM       byte = 1 << 1
+)
+
 // DeployCode is an implementation of transaction payload for deploy smartcontract
 type DeployCode struct {
 	Code        []byte
 	NeedStorage bool
+	VmType      VmType
 	Name        string
 	Version     string
 	Author      string
@@ -47,6 +63,29 @@ func (dc *DeployCode) Address() common.Address {
 	return dc.address
 }
 
+func (dc *DeployCode) vmFlags() byte {
+	var flags byte
+	if dc.NeedStorage {
+		flags |= VM_FLAG_NEED_STORAGE
+	}
+	if dc.VmType == WASMVM_TYPE {
+		flags |= VM_FLAG_WASMVM
+	}
+	return flags
+}
+
+func (dc *DeployCode) setVmFlags(flags byte) error {
+	if flags&^(VM_FLAG_NEED_STORAGE|VM_FLAG_WASMVM) != 0 {
+		return fmt.Errorf("invalid vm flags: %d", flags)
+	}
+	dc.NeedStorage = flags&VM_FLAG_NEED_STORAGE != 0
+	dc.VmType = NEOVM_TYPE
+	if flags&VM_FLAG_WASMVM != 0 {
+		dc.VmType = WASMVM_TYPE
+	}
+	return nil
+}
+
 func (dc *DeployCode) Serialize(w io.Writer) error {
 	var err error
 
@@ -55,9 +94,9 @@ func (dc *DeployCode) Serialize(w io.Writer) error {
 		return fmt.Errorf("DeployCode Code Serialize failed: %s", err)
 	}
 
-	err = serialization.WriteBool(w, dc.NeedStorage)
+	err = serialization.WriteByte(w, dc.vmFlags())
 	if err != nil {
-		return fmt.Errorf("DeployCode NeedStorage Serialize failed: %s", err)
+		return fmt.Errorf("DeployCode VmFlags Serialize failed: %s", err)
 	}
 
 	err = serialization.WriteString(w, dc.Name)
@@ -95,9 +134,12 @@ func (dc *DeployCode) Deserialize(r io.Reader) error {
 	}
 	dc.Code = code
 
-	dc.NeedStorage, err = serialization.ReadBool(r)
+	flags, err := serialization.ReadByte(r)
 	if err != nil {
-		return fmt.Errorf("DeployCode NeedStorage Deserialize failed: %s", err)
+		return fmt.Errorf("DeployCode VmFlags Deserialize failed: %s", err)
+	}
+	if err = dc.setVmFlags(flags); err != nil {
+		return fmt.Errorf("DeployCode VmFlags Deserialize failed: %s", err)
 	}
 
 	dc.Name, err = serialization.ReadString(r)
@@ -136,7 +178,7 @@ func (dc *DeployCode) ToArray() []byte {
 
 func (dc *DeployCode) Serialization(sink *common.ZeroCopySink) error {
 	sink.WriteVarBytes(dc.Code)
-	sink.WriteBool(dc.NeedStorage)
+	sink.WriteByte(dc.vmFlags())
 	sink.WriteString(dc.Name)
 	sink.WriteString(dc.Version)
 	sink.WriteString(dc.Author)
@@ -154,8 +196,9 @@ func (dc *DeployCode) Deserialization(source *common.ZeroCopySource) error {
 		return common.ErrIrregularData
 	}
 
-	dc.NeedStorage, irregular, eof = source.NextBool()
-	if irregular {
+	var flags byte
+	flags, eof = source.NextByte()
+	if dc.setVmFlags(flags) != nil {
 		return common.ErrIrregularData
 	}
 
@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,3 +41,33 @@ func TestDeployCode_Serialize(t *testing.T) {
 	err := deploy2.Deserialize(buf)
 	assert.NotNil(t, err)
 }
+
+func TestDeployCode_SerializeVmType(t *testing.T) {
+	deploy := DeployCode{
+		Code:        []byte{0, 'a', 's', 'm'},
+		NeedStorage: true,
+		VmType:      WASMVM_TYPE,
+	}
+
+	sink := common.NewZeroCopySink(nil)
+	deploy.Serialization(sink)
+	var deploy2 DeployCode
+	err := deploy2.Deserialization(common.NewZeroCopySource(sink.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, WASMVM_TYPE, deploy2.VmType)
+	assert.True(t, deploy2.NeedStorage)
+
+	// legacy neovm payloads store the NeedStorage bool in the flags byte
+	legacy := DeployCode{Code: []byte{1, 2, 3}, NeedStorage: true}
+	buf := bytes.NewBuffer(nil)
+	legacy.Serialize(buf)
+	assert.Equal(t, byte(1), buf.Bytes()[4])
+	var legacy2 DeployCode
+	assert.Nil(t, legacy2.Deserialize(buf))
+	assert.Equal(t, NEOVM_TYPE, legacy2.VmType)
+
+	bs := sink.Bytes()
+	bs[5] = 0x80
+	err = deploy2.Deserialization(common.NewZeroCopySource(bs))
+	assert.Equal(t, common.ErrIrregularData, err)
+}
@@ -26,12 +26,7 @@ import (
 
 // Sign returns the signature of data using privKey
 func Sign(signer Signer, data []byte) ([]byte, error) {
-	signature, err := s.Sign(signer.Scheme(), signer.PrivKey(), data, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return s.Serialize(signature)
+	return signer.Sign(data)
 }
 
 // Verify check the signature of data using pubKey
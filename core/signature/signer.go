@@ -24,9 +24,10 @@ import (
 )
 
 // Signer is the abstract interface of user's information(Keys) for signing data.
+// The private key is never exposed, it may be held by local keystore or remote signer.
 type Signer interface {
-	//get signer's private key
-	PrivKey() keypair.PrivateKey
+	//sign data with signer's private key, return the serialized signature
+	Sign(data []byte) ([]byte, error)
 
 	//get signer's public key
 	PubKey() keypair.PublicKey
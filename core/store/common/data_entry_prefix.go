@@ -27,6 +27,9 @@ const (
 	DATA_HEADER                            = 0x01 //Block hash => block hash key prefix
 	DATA_TRANSACTION                       = 0x02 //Transction hash = > transaction key prefix
 	DATA_STATE_MERKLE_ROOT                 = 0x21 // block height => write set hash + state merkle root
+	DATA_STATE_TRIE_NODE                   = 0x22 //State trie node hash => state trie node key prefix
+	DATA_STATE_TRIE_ROOT                   = 0x23 //Block height => state trie root key prefix
+	DATA_STATE_TRIE_VALUE                  = 0x24 //Value hash => state value of state trie key prefix
 
 	// Transaction
 	ST_BOOKKEEPER DataEntryPrefix = 0x03 //BookKeeper state key prefix
@@ -44,5 +47,11 @@ const (
 	SYS_BLOCK_MERKLE_TREE  DataEntryPrefix = 0x13 // Block merkle tree root key prefix
 	SYS_STATE_MERKLE_TREE  DataEntryPrefix = 0x20 // state merkle tree root key prefix
 
-	EVENT_NOTIFY DataEntryPrefix = 0x14 //Event notify key prefix
+	EVENT_NOTIFY         DataEntryPrefix = 0x14 //Event notify key prefix
+	EVENT_INDEX_CONTRACT DataEntryPrefix = 0x15 //Contract address => event notify position key prefix
+	EVENT_INDEX_TOPIC    DataEntryPrefix = 0x16 //Contract address and topic => event notify position key prefix
+	SYS_EVENT_INDEX      DataEntryPrefix = 0x17 //Height of last indexed block key prefix
+	SYS_PRUNE_HEIGHT     DataEntryPrefix = 0x18 //Lowest height of unpruned block key prefix
+	SYS_STATE_TRIE       DataEntryPrefix = 0x19 //Height of last state trie key prefix
+	SYS_STATE_TRIE_VER   DataEntryPrefix = 0x1a //Version and start height of state trie key prefix
 )
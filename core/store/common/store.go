@@ -26,6 +26,8 @@ import (
 )
 
 var ErrNotFound = errors.New("not found")
+var ErrPruned = errors.New("pruned")
+var ErrRangeTooLarge = errors.New("height range too large")
 
 //Store iterator for iterate store
 type StoreIterator interface {
@@ -124,6 +124,9 @@ func (this *BlockStore) GetBlock(blockHash common.Uint256) (*types.Block, error)
 	for _, txHash := range txHashes {
 		tx, _, err := this.GetTransaction(txHash)
 		if err != nil {
+			if err == scom.ErrPruned {
+				return nil, err
+			}
 			return nil, fmt.Errorf("GetTransaction %s error %s", txHash.ToHexString(), err)
 		}
 		if tx == nil {
@@ -382,6 +385,10 @@ func (this *BlockStore) loadTransaction(txHash common.Uint256) (*types.Transacti
 	if eof {
 		return nil, 0, io.ErrUnexpectedEOF
 	}
+	//pruned transaction only keeps the block height
+	if source.Len() == 0 {
+		return nil, height, scom.ErrPruned
+	}
 	tx = new(types.Transaction)
 	err = tx.Deserialization(source)
 	if err != nil {
@@ -390,6 +397,46 @@ func (this *BlockStore) loadTransaction(txHash common.Uint256) (*types.Transacti
 	return tx, height, nil
 }
 
+//PruneBlock discard the transactions of block and keep the header. The hash and height of pruned transactions
+//are kept, so that they still can not be replayed
+func (this *BlockStore) PruneBlock(blockHash common.Uint256) error {
+	header, txHashes, err := this.loadHeaderWithTx(blockHash)
+	if err != nil {
+		return err
+	}
+	if this.enableCache {
+		this.cache.RemoveBlock(blockHash)
+	}
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, header.Height)
+	for _, txHash := range txHashes {
+		if this.enableCache {
+			this.cache.RemoveTransaction(txHash)
+		}
+		this.store.BatchPut(this.getTransactionKey(txHash), value)
+	}
+	return nil
+}
+
+//SavePruneHeight persist the lowest height of unpruned block
+func (this *BlockStore) SavePruneHeight(height uint32) {
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, height)
+	this.store.BatchPut(this.getPruneHeightKey(), value)
+}
+
+//GetPruneHeight return the lowest height of unpruned block
+func (this *BlockStore) GetPruneHeight() (uint32, error) {
+	data, err := this.store.Get(this.getPruneHeightKey())
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 4 {
+		return 0, fmt.Errorf("invalid prune height")
+	}
+	return binary.LittleEndian.Uint32(data), nil
+}
+
 //IsContainTransaction return whether the transaction is in store
 func (this *BlockStore) ContainTransaction(txHash common.Uint256) (bool, error) {
 	key := this.getTransactionKey(txHash)
@@ -484,6 +531,10 @@ func (this *BlockStore) getVersionKey() []byte {
 	return []byte{byte(scom.SYS_VERSION)}
 }
 
+func (this *BlockStore) getPruneHeightKey() []byte {
+	return []byte{byte(scom.SYS_PRUNE_HEIGHT)}
+}
+
 func (this *BlockStore) getHeaderIndexListKey(startHeight uint32) []byte {
 	key := bytes.NewBuffer(nil)
 	key.WriteByte(byte(scom.IX_HEADER_HASH_LIST))
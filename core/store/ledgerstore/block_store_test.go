@@ -26,6 +26,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/account"
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/core/payload"
+	scom "github.com/OnyxPay/OnyxChain/core/store/common"
 	"github.com/OnyxPay/OnyxChain/core/types"
 	"github.com/OnyxPay/OnyxChain/core/utils"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
@@ -354,6 +355,78 @@ func TestBlock(t *testing.T) {
 	}
 }
 
+func TestPruneBlock(t *testing.T) {
+	acc1 := account.NewAccount("")
+	acc2 := account.NewAccount("")
+	header := &types.Header{
+		Version:       123,
+		PrevBlockHash: common.Uint256{},
+		Timestamp:     uint32(uint32(time.Date(2017, time.February, 23, 0, 0, 0, 0, time.UTC).Unix())),
+		Height:        uint32(3),
+	}
+	tx1, err := transferTx(acc1.Address, acc2.Address, 20)
+	if err != nil {
+		t.Errorf("TestPruneBlock transferTx error:%s", err)
+		return
+	}
+	block := &types.Block{
+		Header:       header,
+		Transactions: []*types.Transaction{tx1},
+	}
+	blockHash := block.Hash()
+	tx1Hash := tx1.Hash()
+
+	testBlockStore.NewBatch()
+	err = testBlockStore.SaveBlock(block)
+	if err != nil {
+		t.Errorf("SaveBlock error %s", err)
+		return
+	}
+	err = testBlockStore.CommitTo()
+	if err != nil {
+		t.Errorf("CommitTo error %s", err)
+		return
+	}
+
+	testBlockStore.NewBatch()
+	err = testBlockStore.PruneBlock(blockHash)
+	if err != nil {
+		t.Errorf("PruneBlock error %s", err)
+		return
+	}
+	testBlockStore.SavePruneHeight(header.Height + 1)
+	err = testBlockStore.CommitTo()
+	if err != nil {
+		t.Errorf("CommitTo error %s", err)
+		return
+	}
+
+	pruneHeight, err := testBlockStore.GetPruneHeight()
+	if err != nil || pruneHeight != header.Height+1 {
+		t.Errorf("TestPruneBlock prune height %d error %v", pruneHeight, err)
+		return
+	}
+	if _, err = testBlockStore.GetBlock(blockHash); err != scom.ErrPruned {
+		t.Errorf("TestPruneBlock GetBlock error %v should be pruned", err)
+		return
+	}
+	tx, height, err := testBlockStore.GetTransaction(tx1Hash)
+	if err != scom.ErrPruned || tx != nil || height != header.Height {
+		t.Errorf("TestPruneBlock GetTransaction height %d error %v", height, err)
+		return
+	}
+	exist, err := testBlockStore.ContainTransaction(tx1Hash)
+	if err != nil || !exist {
+		t.Errorf("TestPruneBlock pruned transaction %x should exist", tx1Hash)
+		return
+	}
+	h, err := testBlockStore.GetHeader(blockHash)
+	if err != nil || h.Hash() != blockHash {
+		t.Errorf("TestPruneBlock GetHeader error %v", err)
+		return
+	}
+}
+
 func transferTx(from, to common.Address, amount uint64) (*types.Transaction, error) {
 	buf := bytes.NewBuffer(nil)
 	var sts []onx.State
@@ -20,9 +20,12 @@ package ledgerstore
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"sort"
+
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/log"
 	"github.com/OnyxPay/OnyxChain/common/serialization"
@@ -104,6 +107,24 @@ func (this *EventStore) GetEventNotifyByTx(txHash common.Uint256) (*event.Execut
 
 //GetEventNotifyByBlock return all event notify of transaction in block
 func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error) {
+	txHashs, err := this.getEventTxHashsByBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	evtNotifies := make([]*event.ExecuteNotify, 0)
+	for _, txHash := range txHashs {
+		evtNotify, err := this.GetEventNotifyByTx(txHash)
+		if err != nil {
+			log.Errorf("getEventNotifyByTx Height:%d by txhash:%s error:%s", height, txHash.ToHexString(), err)
+			continue
+		}
+		evtNotifies = append(evtNotifies, evtNotify)
+	}
+	return evtNotifies, nil
+}
+
+//getEventTxHashsByBlock return transaction hashes of block in the order of block
+func (this *EventStore) getEventTxHashsByBlock(height uint32) ([]common.Uint256, error) {
 	key, err := this.getEventNotifyByBlockKey(height)
 	if err != nil {
 		return nil, err
@@ -117,21 +138,228 @@ func (this *EventStore) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNo
 	if err != nil {
 		return nil, fmt.Errorf("ReadUint32 error %s", err)
 	}
-	evtNotifies := make([]*event.ExecuteNotify, 0)
+	txHashs := make([]common.Uint256, 0, size)
 	for i := uint32(0); i < size; i++ {
 		var txHash common.Uint256
 		err = txHash.Deserialize(reader)
 		if err != nil {
 			return nil, fmt.Errorf("txHash.Deserialize error %s", err)
 		}
-		evtNotify, err := this.GetEventNotifyByTx(txHash)
-		if err != nil {
-			log.Errorf("getEventNotifyByTx Height:%d by txhash:%s error:%s", height, txHash.ToHexString(), err)
+		txHashs = append(txHashs, txHash)
+	}
+	return txHashs, nil
+}
+
+//SaveEventIndexByBlock index event notify of block by contract address and topic. notifies are in the order of block transactions
+func (this *EventStore) SaveEventIndexByBlock(height uint32, notifies []*event.ExecuteNotify) {
+	for txIndex, notify := range notifies {
+		if notify == nil {
 			continue
 		}
-		evtNotifies = append(evtNotifies, evtNotify)
+		txHash := notify.TxHash.ToArray()
+		for notifyIndex, info := range notify.Notify {
+			pos := getEventPosition(height, uint32(txIndex), uint32(notifyIndex))
+			this.store.BatchPut(this.getEventIndexKey(info.ContractAddress, pos), txHash)
+			if topic, ok := event.NotifyTopic(info.States); ok {
+				this.store.BatchPut(this.getEventTopicIndexKey(info.ContractAddress, topic, pos), txHash)
+			}
+		}
 	}
-	return evtNotifies, nil
+}
+
+//PruneEventNotifyByBlock discard event notifies of block, and the event index of them if pruneIndex is set
+func (this *EventStore) PruneEventNotifyByBlock(height uint32, pruneIndex bool) error {
+	txHashs, err := this.getEventTxHashsByBlock(height)
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	for txIndex, txHash := range txHashs {
+		if pruneIndex {
+			notify, err := this.GetEventNotifyByTx(txHash)
+			if err != nil && err != scom.ErrNotFound {
+				return err
+			}
+			if notify != nil {
+				for notifyIndex, info := range notify.Notify {
+					pos := getEventPosition(height, uint32(txIndex), uint32(notifyIndex))
+					this.store.BatchDelete(this.getEventIndexKey(info.ContractAddress, pos))
+					if topic, ok := event.NotifyTopic(info.States); ok {
+						this.store.BatchDelete(this.getEventTopicIndexKey(info.ContractAddress, topic, pos))
+					}
+				}
+			}
+		}
+		this.store.BatchDelete(this.getEventNotifyByTxKey(txHash))
+	}
+	key, err := this.getEventNotifyByBlockKey(height)
+	if err != nil {
+		return err
+	}
+	this.store.BatchDelete(key)
+	return nil
+}
+
+//SaveEventIndexHeight persist the height of last indexed block
+func (this *EventStore) SaveEventIndexHeight(height uint32) {
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, height)
+	this.store.BatchPut([]byte{byte(scom.SYS_EVENT_INDEX)}, value)
+}
+
+//GetEventIndexHeight return the height of last indexed block
+func (this *EventStore) GetEventIndexHeight() (uint32, error) {
+	data, err := this.store.Get([]byte{byte(scom.SYS_EVENT_INDEX)})
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 4 {
+		return 0, fmt.Errorf("invalid event index height")
+	}
+	return binary.LittleEndian.Uint32(data), nil
+}
+
+//GetEventLogsByIndex return event notifies of filter contracts and topics by the event index in chain order
+func (this *EventStore) GetEventLogsByIndex(filter *event.EventFilter) ([]*event.NotifyEventLog, error) {
+	max := uint64(filter.Offset) + uint64(filter.Limit)
+	var prefixes [][]byte
+	contracts := make(map[common.Address]bool)
+	for _, addr := range filter.Contracts {
+		if contracts[addr] {
+			continue
+		}
+		contracts[addr] = true
+		if len(filter.Topics) == 0 {
+			prefixes = append(prefixes, this.getEventIndexKey(addr, nil))
+			continue
+		}
+		topics := make(map[string]bool)
+		for _, topic := range filter.Topics {
+			if !topics[topic] {
+				topics[topic] = true
+				prefixes = append(prefixes, this.getEventTopicIndexKey(addr, topic, nil))
+			}
+		}
+	}
+
+	logs := make([]*event.NotifyEventLog, 0)
+	for _, prefix := range prefixes {
+		start := append(append([]byte{}, prefix...), getEventPosition(filter.FromHeight, 0, 0)...)
+		iter := this.store.NewRangeIterator(start, getPrefixLimit(prefix))
+		count := uint64(0)
+		for count < max && iter.Next() {
+			key := iter.Key()
+			if len(key) != len(prefix)+EVENT_POSITION_SIZE {
+				continue
+			}
+			pos := key[len(prefix):]
+			height := binary.BigEndian.Uint32(pos[0:4])
+			if height > filter.ToHeight {
+				break
+			}
+			txHash, err := common.Uint256ParseFromBytes(iter.Value())
+			if err != nil {
+				iter.Release()
+				return nil, fmt.Errorf("parse event index tx hash error %s", err)
+			}
+			logs = append(logs, &event.NotifyEventLog{
+				Height:      height,
+				TxIndex:     binary.BigEndian.Uint32(pos[4:8]),
+				TxHash:      txHash,
+				NotifyIndex: binary.BigEndian.Uint32(pos[8:12]),
+			})
+			count++
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].Height != logs[j].Height {
+			return logs[i].Height < logs[j].Height
+		}
+		if logs[i].TxIndex != logs[j].TxIndex {
+			return logs[i].TxIndex < logs[j].TxIndex
+		}
+		return logs[i].NotifyIndex < logs[j].NotifyIndex
+	})
+	if uint64(len(logs)) <= uint64(filter.Offset) {
+		return []*event.NotifyEventLog{}, nil
+	}
+	if uint64(len(logs)) > max {
+		logs = logs[:max]
+	}
+	logs = logs[filter.Offset:]
+
+	notifies := make(map[common.Uint256]*event.ExecuteNotify)
+	for _, l := range logs {
+		notify, ok := notifies[l.TxHash]
+		if !ok {
+			var err error
+			notify, err = this.GetEventNotifyByTx(l.TxHash)
+			if err != nil {
+				return nil, fmt.Errorf("GetEventNotifyByTx %s error %s", l.TxHash.ToHexString(), err)
+			}
+			notifies[l.TxHash] = notify
+		}
+		if int(l.NotifyIndex) >= len(notify.Notify) {
+			return nil, fmt.Errorf("event index of tx %s out of range", l.TxHash.ToHexString())
+		}
+		l.Notify = notify.Notify[l.NotifyIndex]
+	}
+	return logs, nil
+}
+
+//GetEventLogsByScan return event notifies match the filter by scanning every block in height range,
+//scom.ErrRangeTooLarge if the range exceed MAX_EVENT_SCAN_RANGE
+func (this *EventStore) GetEventLogsByScan(filter *event.EventFilter) ([]*event.NotifyEventLog, error) {
+	if filter.FromHeight <= filter.ToHeight && filter.ToHeight-filter.FromHeight >= MAX_EVENT_SCAN_RANGE {
+		return nil, scom.ErrRangeTooLarge
+	}
+	logs := make([]*event.NotifyEventLog, 0)
+	skip := filter.Offset
+	for height := uint64(filter.FromHeight); height <= uint64(filter.ToHeight); height++ {
+		txHashs, err := this.getEventTxHashsByBlock(uint32(height))
+		if err != nil {
+			if err == scom.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		for txIndex, txHash := range txHashs {
+			notify, err := this.GetEventNotifyByTx(txHash)
+			if err != nil {
+				if err == scom.ErrNotFound {
+					continue
+				}
+				return nil, err
+			}
+			for notifyIndex, info := range notify.Notify {
+				if !filter.Match(info) {
+					continue
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				logs = append(logs, &event.NotifyEventLog{
+					Height:      uint32(height),
+					TxIndex:     uint32(txIndex),
+					TxHash:      txHash,
+					NotifyIndex: uint32(notifyIndex),
+					Notify:      info,
+				})
+				if uint32(len(logs)) >= filter.Limit {
+					return logs, nil
+				}
+			}
+		}
+	}
+	return logs, nil
 }
 
 //CommitTo event store batch to store
@@ -200,6 +428,47 @@ func (this *EventStore) getEventNotifyByBlockKey(height uint32) ([]byte, error)
 	return key, nil
 }
 
+//EVENT_POSITION_SIZE is the size of event position: block height, transaction index and notify index
+const EVENT_POSITION_SIZE = 12
+
+//getEventPosition encode event position in big endian to keep the chain order of index keys
+func getEventPosition(height, txIndex, notifyIndex uint32) []byte {
+	pos := make([]byte, EVENT_POSITION_SIZE)
+	binary.BigEndian.PutUint32(pos[0:4], height)
+	binary.BigEndian.PutUint32(pos[4:8], txIndex)
+	binary.BigEndian.PutUint32(pos[8:12], notifyIndex)
+	return pos
+}
+
+//getPrefixLimit return the smallest key greater than all keys with the prefix
+func getPrefixLimit(prefix []byte) []byte {
+	limit := make([]byte, len(prefix))
+	copy(limit, prefix)
+	for i := len(limit) - 1; i >= 0; i-- {
+		if limit[i] < 0xff {
+			limit[i]++
+			return limit[:i+1]
+		}
+	}
+	return nil
+}
+
+func (this *EventStore) getEventIndexKey(contract common.Address, pos []byte) []byte {
+	key := make([]byte, 0, 1+common.ADDR_LEN+len(pos))
+	key = append(key, byte(scom.EVENT_INDEX_CONTRACT))
+	key = append(key, contract[:]...)
+	return append(key, pos...)
+}
+
+func (this *EventStore) getEventTopicIndexKey(contract common.Address, topic string, pos []byte) []byte {
+	topicHash := sha256.Sum256([]byte(topic))
+	key := make([]byte, 0, 1+common.ADDR_LEN+len(topicHash)+len(pos))
+	key = append(key, byte(scom.EVENT_INDEX_TOPIC))
+	key = append(key, contract[:]...)
+	key = append(key, topicHash[:]...)
+	return append(key, pos...)
+}
+
 func (this *EventStore) getEventNotifyByTxKey(txHash common.Uint256) []byte {
 	data := txHash.ToArray()
 	key := make([]byte, 1+len(data))
@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	scom "github.com/OnyxPay/OnyxChain/core/store/common"
+	"github.com/OnyxPay/OnyxChain/smartcontract/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventIndex(t *testing.T) {
+	eventStore, err := NewEventStore("test/event")
+	if err != nil {
+		t.Errorf("NewEventStore error %s", err)
+		return
+	}
+	defer eventStore.Close()
+
+	contract1 := common.Address{1}
+	contract2 := common.Address{2}
+	eventStore.NewBatch()
+	for height := uint32(1); height <= 3; height++ {
+		notifies := make([]*event.ExecuteNotify, 0)
+		txHashs := make([]common.Uint256, 0)
+		for i := 0; i < 2; i++ {
+			txHash := common.Uint256{byte(height), byte(i)}
+			notify := &event.ExecuteNotify{
+				TxHash: txHash,
+				State:  event.CONTRACT_STATE_SUCCESS,
+				Notify: []*event.NotifyEventInfo{
+					{ContractAddress: contract1, States: []interface{}{"transfer", height}},
+					{ContractAddress: contract2, States: []interface{}{"approve", height}},
+					{ContractAddress: contract1, States: []interface{}{"approve", height}},
+				},
+			}
+			err = eventStore.SaveEventNotifyByTx(txHash, notify)
+			if err != nil {
+				t.Errorf("SaveEventNotifyByTx error %s", err)
+				return
+			}
+			notifies = append(notifies, notify)
+			txHashs = append(txHashs, txHash)
+		}
+		err = eventStore.SaveEventNotifyByBlock(height, txHashs)
+		if err != nil {
+			t.Errorf("SaveEventNotifyByBlock error %s", err)
+			return
+		}
+		eventStore.SaveEventIndexByBlock(height, notifies)
+		eventStore.SaveEventIndexHeight(height)
+	}
+	err = eventStore.CommitTo()
+	if err != nil {
+		t.Errorf("CommitTo error %s", err)
+		return
+	}
+
+	indexHeight, err := eventStore.GetEventIndexHeight()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), indexHeight)
+
+	filters := []*event.EventFilter{
+		{FromHeight: 1, ToHeight: 3, Contracts: []common.Address{contract1}, Limit: 100},
+		{FromHeight: 2, ToHeight: 3, Contracts: []common.Address{contract1, contract2}, Limit: 100},
+		{FromHeight: 1, ToHeight: 2, Contracts: []common.Address{contract1}, Topics: []string{"approve"}, Limit: 100},
+		{FromHeight: 1, ToHeight: 3, Contracts: []common.Address{contract2, contract1}, Offset: 3, Limit: 4},
+		{FromHeight: 1, ToHeight: 3, Contracts: []common.Address{contract2}, Offset: 10, Limit: 4},
+	}
+	for _, filter := range filters {
+		indexLogs, err := eventStore.GetEventLogsByIndex(filter)
+		assert.Nil(t, err)
+		scanLogs, err := eventStore.GetEventLogsByScan(filter)
+		assert.Nil(t, err)
+		assert.Equal(t, scanLogs, indexLogs)
+	}
+	_, err = eventStore.GetEventLogsByScan(&event.EventFilter{FromHeight: 1, ToHeight: MAX_EVENT_SCAN_RANGE, Limit: 100})
+	assert.Nil(t, err)
+	_, err = eventStore.GetEventLogsByScan(&event.EventFilter{FromHeight: 1, ToHeight: MAX_EVENT_SCAN_RANGE + 1, Limit: 100})
+	assert.Equal(t, scom.ErrRangeTooLarge, err)
+
+	logs, err := eventStore.GetEventLogsByIndex(filters[2])
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(logs))
+	for _, l := range logs {
+		assert.Equal(t, contract1, l.Notify.ContractAddress)
+		assert.Equal(t, uint32(2), l.NotifyIndex)
+	}
+
+	logs, err = eventStore.GetEventLogsByIndex(filters[3])
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(logs))
+	assert.Equal(t, uint32(1), logs[0].Height)
+	assert.Equal(t, uint32(1), logs[0].TxIndex)
+	assert.Equal(t, uint32(0), logs[0].NotifyIndex)
+}
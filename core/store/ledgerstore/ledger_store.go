@@ -29,6 +29,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/OnyxPay/OnyxChain-crypto/keypair"
@@ -55,11 +56,20 @@ import (
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
 	sstate "github.com/OnyxPay/OnyxChain/smartcontract/states"
 	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	"github.com/OnyxPay/OnyxChain/smartcontract/trace"
+	"github.com/OnyxPay/OnyxChain/vm/wasmvm/exec"
 )
 
 const (
-	SYSTEM_VERSION          = byte(1)      //Version of ledger store
-	HEADER_INDEX_BATCH_SIZE = uint32(2000) //Bath size of saving header index
+	SYSTEM_VERSION            = byte(1)       //Version of ledger store
+	HEADER_INDEX_BATCH_SIZE   = uint32(2000)  //Bath size of saving header index
+	EVENT_INDEX_BATCH_SIZE    = uint32(1000)  //Batch size of building event index
+	PRUNE_BATCH_SIZE          = uint32(1000)  //Batch size of pruning blocks
+	STATE_TRIE_BATCH_SIZE     = 10000         //Batch size of building state trie
+	STATE_TRIE_VERSION        = byte(1)       //Version of state trie, 1 contains contracts and storage items
+	STATE_TRIE_PRUNE_INTERVAL = uint32(10000) //Interval of block heights to prune state trie
+	MAX_EVENT_SCAN_RANGE      = uint32(10000) //Max height range of scanning blocks for event logs
+	GAS_ESTIMATE_MARGIN       = uint64(10)    //Percentage of execution gas added to estimated gas limit
 )
 
 var (
@@ -78,6 +88,7 @@ type LedgerStoreImp struct {
 	storedIndexCount     uint32                           //record the count of have saved block index
 	currBlockHeight      uint32                           //Current block height
 	currBlockHash        common.Uint256                   //Current block hash
+	pruneHeight          uint32                           //Lowest height of unpruned block
 	headerCache          map[common.Uint256]*types.Header //BlockHash => Header
 	headerIndex          map[uint32]common.Uint256        //Header index, Mapping header height => block hash
 	savingBlockSemaphore chan bool
@@ -85,6 +96,10 @@ type LedgerStoreImp struct {
 	vbftPeerInfoblock    map[string]uint32 //pubInfo save pubkey,peerindex
 	lock                 sync.RWMutex
 	stateHashCheckHeight uint32
+	checkpoints          map[uint32]common.Uint256 //Checkpoint height => block hash
+	lastCheckpoint       uint32                    //Height of the highest checkpoint
+	stateTriePruning     uint32                    //Whether the state trie is being pruned in background
+	stateTriePruneWait   sync.WaitGroup
 }
 
 //NewLedgerStore return LedgerStoreImp instance
@@ -97,6 +112,11 @@ func NewLedgerStore(dataDir string, stateHashHeight uint32) (*LedgerStoreImp, er
 		savingBlockSemaphore: make(chan bool, 1),
 		stateHashCheckHeight: stateHashHeight,
 	}
+	checkpoints, err := config.DefConfig.GetCheckpoints()
+	if err != nil {
+		return nil, fmt.Errorf("GetCheckpoints error %s", err)
+	}
+	ledgerStore.setCheckpoints(checkpoints)
 
 	blockStore, err := NewBlockStore(fmt.Sprintf("%s%s%s", dataDir, string(os.PathSeparator), DBDirBlock), true)
 	if err != nil {
@@ -245,6 +265,22 @@ func (this *LedgerStoreImp) init() error {
 	if err != nil {
 		return fmt.Errorf("recoverStore error %s", err)
 	}
+	err = this.loadPruneHeight()
+	if err != nil {
+		return fmt.Errorf("loadPruneHeight error %s", err)
+	}
+	err = this.pruneStore()
+	if err != nil {
+		return fmt.Errorf("pruneStore error %s", err)
+	}
+	err = this.buildEventIndex()
+	if err != nil {
+		return fmt.Errorf("buildEventIndex error %s", err)
+	}
+	err = this.buildStateTrie()
+	if err != nil {
+		return fmt.Errorf("buildStateTrie error %s", err)
+	}
 	return nil
 }
 
@@ -309,7 +345,7 @@ func (this *LedgerStoreImp) recoverStore() error {
 		if err != nil {
 			return fmt.Errorf("save to state store height:%d error:%s", i, err)
 		}
-		err = this.saveBlockToEventStore(block)
+		err = this.saveBlockToEventStore(block, result.Notify)
 		if err != nil {
 			return fmt.Errorf("save to event store height:%d error:%s", i, err)
 		}
@@ -416,27 +452,115 @@ func (this *LedgerStoreImp) getHeaderCache(blockHash common.Uint256) *types.Head
 	return header
 }
 
-func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
-	if header.Height == 0 {
-		return vbftPeerInfo, nil
+func (this *LedgerStoreImp) setCheckpoints(checkpoints map[uint32]common.Uint256) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.checkpoints = checkpoints
+	this.lastCheckpoint = 0
+	for height := range checkpoints {
+		if height > this.lastCheckpoint {
+			this.lastCheckpoint = height
+		}
+	}
+}
+
+//GetCheckpoints return the checkpoints of the network, mapping block height => block hash
+func (this *LedgerStoreImp) GetCheckpoints() map[uint32]common.Uint256 {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	checkpoints := make(map[uint32]common.Uint256, len(this.checkpoints))
+	for height, hash := range this.checkpoints {
+		checkpoints[height] = hash
 	}
-	var prevHeader *types.Header
+	return checkpoints
+}
+
+//GetLastCheckpoint return the height of the highest checkpoint, 0 means no checkpoint
+func (this *LedgerStoreImp) GetLastCheckpoint() uint32 {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.lastCheckpoint
+}
+
+func (this *LedgerStoreImp) getCheckpoint(height uint32) (common.Uint256, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	hash, ok := this.checkpoints[height]
+	return hash, ok
+}
+
+//verifyHeaderLink check the header follows its previous header, and return the previous header
+func (this *LedgerStoreImp) verifyHeaderLink(header *types.Header) (*types.Header, error) {
 	prevHeaderHash := header.PrevBlockHash
 	prevHeader, err := this.GetHeaderByHash(prevHeaderHash)
 	if err != nil && err != scom.ErrNotFound {
-		return vbftPeerInfo, fmt.Errorf("get prev header error %s", err)
+		return nil, fmt.Errorf("get prev header error %s", err)
 	}
 	if prevHeader == nil {
-		return vbftPeerInfo, fmt.Errorf("cannot find pre header by blockHash %s", prevHeaderHash.ToHexString())
+		return nil, fmt.Errorf("cannot find pre header by blockHash %s", prevHeaderHash.ToHexString())
 	}
+	return prevHeader, this.checkHeaderLink(header, prevHeader)
+}
 
+func (this *LedgerStoreImp) checkHeaderLink(header, prevHeader *types.Header) error {
 	if prevHeader.Height+1 != header.Height {
-		return vbftPeerInfo, fmt.Errorf("block height is incorrect")
+		return fmt.Errorf("block height is incorrect")
 	}
 
 	if prevHeader.Timestamp >= header.Timestamp {
-		return vbftPeerInfo, fmt.Errorf("block timestamp is incorrect")
+		return fmt.Errorf("block timestamp is incorrect")
+	}
+	if cpHash, ok := this.getCheckpoint(header.Height); ok && cpHash != header.Hash() {
+		return fmt.Errorf("block hash mismatch checkpoint at height %d", header.Height)
+	}
+	return nil
+}
+
+//updateVbftPeerInfo return the peers of the new chain config carried by the header
+func updateVbftPeerInfo(header *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
+	blkInfo, err := vconfig.VbftBlock(header)
+	if err != nil {
+		return vbftPeerInfo, err
 	}
+	if blkInfo.NewChainConfig != nil {
+		peerInfo := make(map[string]uint32)
+		for _, p := range blkInfo.NewChainConfig.Peers {
+			peerInfo[p.ID] = p.Index
+		}
+		return peerInfo, nil
+	}
+	return vbftPeerInfo, nil
+}
+
+//trustHeader check the header without verifying the signatures. Only used for the headers below a checkpoint
+func (this *LedgerStoreImp) trustHeader(header *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
+	if header.Height == 0 {
+		return vbftPeerInfo, nil
+	}
+	_, err := this.verifyHeaderLink(header)
+	if err != nil {
+		return vbftPeerInfo, err
+	}
+	if strings.ToLower(config.DefConfig.Genesis.ConsensusType) == "vbft" {
+		return updateVbftPeerInfo(header, vbftPeerInfo)
+	}
+	return vbftPeerInfo, nil
+}
+
+func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
+	if header.Height == 0 {
+		return vbftPeerInfo, nil
+	}
+	prevHeader, err := this.verifyHeaderLink(header)
+	if err != nil {
+		return vbftPeerInfo, err
+	}
+	return verifyHeaderSig(header, prevHeader, vbftPeerInfo)
+}
+
+//verifyHeaderSig check the header is signed by the bookkeepers, and return the peers of vbft after the header
+func verifyHeaderSig(header, prevHeader *types.Header, vbftPeerInfo map[string]uint32) (map[string]uint32, error) {
+	var err error
 	consensusType := strings.ToLower(config.DefConfig.Genesis.ConsensusType)
 	if consensusType == "vbft" {
 		//check bookkeeppers
@@ -458,18 +582,7 @@ func (this *LedgerStoreImp) verifyHeader(header *types.Header, vbftPeerInfo map[
 			log.Errorf("VerifyMultiSignature:%s,Bookkeepers:%d,pubkey:%d,heigh:%d", err, len(header.Bookkeepers), len(vbftPeerInfo), header.Height)
 			return vbftPeerInfo, err
 		}
-		blkInfo, err := vconfig.VbftBlock(header)
-		if err != nil {
-			return vbftPeerInfo, err
-		}
-		if blkInfo.NewChainConfig != nil {
-			peerInfo := make(map[string]uint32)
-			for _, p := range blkInfo.NewChainConfig.Peers {
-				peerInfo[p.ID] = p.Index
-			}
-			return peerInfo, nil
-		}
-		return vbftPeerInfo, nil
+		return updateVbftPeerInfo(header, vbftPeerInfo)
 	} else {
 		address, err := types.AddressFromBookkeepers(header.Bookkeepers)
 		if err != nil {
@@ -505,11 +618,15 @@ func (this *LedgerStoreImp) AddHeader(header *types.Header) error {
 	return nil
 }
 
-//AddHeaders bath add header.
+//AddHeaders bath add header. If the headers are linked one by one and end with a checkpoint,
+//their signatures are not verified
 func (this *LedgerStoreImp) AddHeaders(headers []*types.Header) error {
 	sort.Slice(headers, func(i, j int) bool {
 		return headers[i].Height < headers[j].Height
 	})
+	if this.isCheckpointLinked(headers) {
+		return this.addCheckpointHeaders(headers)
+	}
 	var err error
 	for _, header := range headers {
 		err = this.AddHeader(header)
@@ -520,6 +637,41 @@ func (this *LedgerStoreImp) AddHeaders(headers []*types.Header) error {
 	return nil
 }
 
+//isCheckpointLinked return whether the sorted headers are linked one by one, and the last one is a checkpoint
+func (this *LedgerStoreImp) isCheckpointLinked(headers []*types.Header) bool {
+	if len(headers) == 0 {
+		return false
+	}
+	last := headers[len(headers)-1]
+	cpHash, ok := this.getCheckpoint(last.Height)
+	if !ok || cpHash != last.Hash() {
+		return false
+	}
+	for i := len(headers) - 1; i > 0; i-- {
+		if headers[i].Height != headers[i-1].Height+1 || headers[i].PrevBlockHash != headers[i-1].Hash() {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *LedgerStoreImp) addCheckpointHeaders(headers []*types.Header) error {
+	var err error
+	for _, header := range headers {
+		nextHeaderHeight := this.GetCurrentHeaderHeight() + 1
+		if header.Height != nextHeaderHeight {
+			return fmt.Errorf("header height %d not equal next header height %d", header.Height, nextHeaderHeight)
+		}
+		this.vbftPeerInfoheader, err = this.trustHeader(header, this.vbftPeerInfoheader)
+		if err != nil {
+			return fmt.Errorf("trustHeader error %s", err)
+		}
+		this.addHeaderCache(header)
+		this.setHeaderIndex(header.Height, header.Hash())
+	}
+	return nil
+}
+
 func (this *LedgerStoreImp) GetStateMerkleRoot(height uint32) (common.Uint256, error) {
 	return this.stateStore.GetStateMerkleRoot(height)
 }
@@ -595,6 +747,98 @@ func (this *LedgerStoreImp) AddBlock(block *types.Block, stateMerkleRoot common.
 	return nil
 }
 
+//AddCheckpointBlocks add the consecutive blocks not higher than the last checkpoint to store.
+//The blocks should match the header index, which has been linked to a checkpoint. So the
+//signatures are not verified and the blocks are written to block store in one batch
+func (this *LedgerStoreImp) AddCheckpointBlocks(blocks []*types.Block) error {
+	this.getSavingBlockLock()
+	defer this.releaseSavingBlockLock()
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Header.Height < blocks[j].Header.Height
+	})
+	currBlockHeight := this.GetCurrentBlockHeight()
+	for len(blocks) > 0 && blocks[0].Header.Height <= currBlockHeight {
+		blocks = blocks[1:]
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	lastCheckpoint := this.GetLastCheckpoint()
+	vbftPeerInfo := this.vbftPeerInfoblock
+	isVbft := strings.ToLower(config.DefConfig.Genesis.ConsensusType) == "vbft"
+	var err error
+	for i, block := range blocks {
+		blockHeight := block.Header.Height
+		if blockHeight != currBlockHeight+uint32(i)+1 {
+			return fmt.Errorf("block height %d not equal next block height %d", blockHeight, currBlockHeight+uint32(i)+1)
+		}
+		if blockHeight > lastCheckpoint {
+			return fmt.Errorf("block height %d higher than last checkpoint %d", blockHeight, lastCheckpoint)
+		}
+		if block.Hash() != this.getHeaderIndex(blockHeight) {
+			return fmt.Errorf("block hash mismatch header index at height %d", blockHeight)
+		}
+		if isVbft {
+			vbftPeerInfo, err = updateVbftPeerInfo(block.Header, vbftPeerInfo)
+			if err != nil {
+				return fmt.Errorf("updateVbftPeerInfo height:%d error %s", blockHeight, err)
+			}
+		}
+	}
+
+	this.blockStore.NewBatch()
+	for _, block := range blocks {
+		err = this.saveBlockToBlockStore(block)
+		if err != nil {
+			return fmt.Errorf("save to block store height:%d error:%s", block.Header.Height, err)
+		}
+	}
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return fmt.Errorf("blockStore.CommitTo height:%d error %s", blocks[len(blocks)-1].Header.Height, err)
+	}
+	this.vbftPeerInfoblock = vbftPeerInfo
+
+	//same as recoverStore, the state is executed block by block after the block store committed
+	for _, block := range blocks {
+		blockHash := block.Hash()
+		blockHeight := block.Header.Height
+		this.stateStore.NewBatch()
+		this.eventStore.NewBatch()
+		result, err := this.executeBlock(block)
+		if err != nil {
+			return err
+		}
+		err = this.saveBlockToStateStore(block, result)
+		if err != nil {
+			return fmt.Errorf("save to state store height:%d error:%s", blockHeight, err)
+		}
+		err = this.saveBlockToEventStore(block, result.Notify)
+		if err != nil {
+			return fmt.Errorf("save to event store height:%d error:%s", blockHeight, err)
+		}
+		err = this.eventStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("eventStore.CommitTo height:%d error %s", blockHeight, err)
+		}
+		err = this.stateStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("stateStore.CommitTo height:%d error %s", blockHeight, err)
+		}
+		this.setCurrentBlock(blockHeight, blockHash)
+		this.delHeaderCache(blockHash)
+
+		if events.DefActorPublisher != nil {
+			events.DefActorPublisher.Publish(
+				message.TOPIC_SAVE_BLOCK_COMPLETE,
+				&message.SaveBlockCompleteMsg{
+					Block: block,
+				})
+		}
+	}
+	return this.pruneStore()
+}
+
 func (this *LedgerStoreImp) saveBlockToBlockStore(block *types.Block) error {
 	blockHash := block.Hash()
 	blockHeight := block.Header.Height
@@ -634,7 +878,7 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 	cache := storage.NewCacheDB(overlay)
 	for _, tx := range block.Transactions {
 		cache.Reset()
-		notify, e := this.handleTransaction(overlay, cache, block, tx)
+		notify, e := this.handleTransaction(overlay, cache, block, tx, nil)
 		if e != nil {
 			err = e
 			return
@@ -642,6 +886,14 @@ func (this *LedgerStoreImp) executeBlock(block *types.Block) (result store.Execu
 
 		result.Notify = append(result.Notify, notify)
 	}
+	if block.Header.Height != 0 {
+		notifies, e := this.stateStore.HandleScheduledEntries(this, overlay, cache, block)
+		if e != nil {
+			err = e
+			return
+		}
+		result.Notify = append(result.Notify, notifies...)
+	}
 
 	result.Hash = overlay.ChangeHash()
 	result.WriteSet = overlay.GetWriteSet()
@@ -726,16 +978,22 @@ func (this *LedgerStoreImp) saveBlockToStateStore(block *types.Block, result sto
 		}
 	})
 
+	if this.isStateProofEnabled() {
+		err = this.stateStore.UpdateStateTrie(blockHeight, result.WriteSet)
+		if err != nil {
+			return fmt.Errorf("UpdateStateTrie error %s", err)
+		}
+	}
+
 	return nil
 }
 
-func (this *LedgerStoreImp) saveBlockToEventStore(block *types.Block) error {
+func (this *LedgerStoreImp) saveBlockToEventStore(block *types.Block, notifies []*event.ExecuteNotify) error {
 	blockHash := block.Hash()
 	blockHeight := block.Header.Height
-	txs := make([]common.Uint256, 0)
-	for _, tx := range block.Transactions {
-		txHash := tx.Hash()
-		txs = append(txs, txHash)
+	txs := make([]common.Uint256, 0, len(notifies))
+	for _, notify := range notifies {
+		txs = append(txs, notify.TxHash)
 	}
 	if len(txs) > 0 {
 		err := this.eventStore.SaveEventNotifyByBlock(block.Header.Height, txs)
@@ -743,6 +1001,10 @@ func (this *LedgerStoreImp) saveBlockToEventStore(block *types.Block) error {
 			return fmt.Errorf("SaveEventNotifyByBlock error %s", err)
 		}
 	}
+	if this.isEventIndexEnabled() {
+		this.eventStore.SaveEventIndexByBlock(blockHeight, notifies)
+		this.eventStore.SaveEventIndexHeight(blockHeight)
+	}
 	err := this.eventStore.SaveCurrentBlock(blockHeight, blockHash)
 	if err != nil {
 		return fmt.Errorf("SaveCurrentBlock error %s", err)
@@ -750,6 +1012,223 @@ func (this *LedgerStoreImp) saveBlockToEventStore(block *types.Block) error {
 	return nil
 }
 
+func (this *LedgerStoreImp) isEventIndexEnabled() bool {
+	return config.DefConfig.Common.EnableEventLog && config.DefConfig.Common.EnableEventIndex
+}
+
+//buildEventIndex index the event notifies of blocks saved before event index was enabled
+func (this *LedgerStoreImp) buildEventIndex() error {
+	if !this.isEventIndexEnabled() {
+		return nil
+	}
+	blockHeight := this.GetCurrentBlockHeight()
+	startHeight := uint32(0)
+	indexHeight, err := this.eventStore.GetEventIndexHeight()
+	if err == nil {
+		startHeight = indexHeight + 1
+	} else if err != scom.ErrNotFound {
+		return fmt.Errorf("GetEventIndexHeight error %s", err)
+	}
+	if pruneHeight := this.getPruneHeight(); startHeight < pruneHeight {
+		startHeight = pruneHeight
+	}
+	if startHeight > blockHeight {
+		return nil
+	}
+	log.Infof("build event index from height %d to %d", startHeight, blockHeight)
+	for height := startHeight; height <= blockHeight; {
+		this.eventStore.NewBatch()
+		end := height + EVENT_INDEX_BATCH_SIZE
+		for ; height < end && height <= blockHeight; height++ {
+			txHashs, err := this.eventStore.getEventTxHashsByBlock(height)
+			if err != nil && err != scom.ErrNotFound {
+				return fmt.Errorf("getEventTxHashsByBlock height:%d error %s", height, err)
+			}
+			notifies := make([]*event.ExecuteNotify, len(txHashs))
+			for i, txHash := range txHashs {
+				notify, err := this.eventStore.GetEventNotifyByTx(txHash)
+				if err != nil && err != scom.ErrNotFound {
+					return fmt.Errorf("GetEventNotifyByTx height:%d error %s", height, err)
+				}
+				notifies[i] = notify
+			}
+			this.eventStore.SaveEventIndexByBlock(height, notifies)
+			this.eventStore.SaveEventIndexHeight(height)
+		}
+		err = this.eventStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("eventStore.CommitTo height:%d error %s", height-1, err)
+		}
+	}
+	return nil
+}
+
+func (this *LedgerStoreImp) isStateProofEnabled() bool {
+	return config.DefConfig.Common.EnableStateProof
+}
+
+//buildStateTrie build the state trie of current block if the state trie is not up to date or of other version.
+//The roots of other version are not served after rebuilt
+func (this *LedgerStoreImp) buildStateTrie() error {
+	if !this.isStateProofEnabled() {
+		return nil
+	}
+	blockHeight := this.GetCurrentBlockHeight()
+	version, _, err := this.stateStore.GetStateTrieVersion()
+	if err != nil && err != scom.ErrNotFound {
+		return fmt.Errorf("GetStateTrieVersion error %s", err)
+	}
+	if err == nil && version == STATE_TRIE_VERSION {
+		trieHeight, err := this.stateStore.GetStateTrieHeight()
+		if err == nil && trieHeight == blockHeight {
+			return nil
+		} else if err != nil && err != scom.ErrNotFound {
+			return fmt.Errorf("GetStateTrieHeight error %s", err)
+		}
+	}
+	log.Infof("build state trie version %d at height %d", STATE_TRIE_VERSION, blockHeight)
+	return this.stateStore.BuildStateTrie(blockHeight)
+}
+
+func (this *LedgerStoreImp) isPruneEnabled() bool {
+	return config.DefConfig.Common.PruneBlocks > 0
+}
+
+func (this *LedgerStoreImp) loadPruneHeight() error {
+	pruneHeight, err := this.blockStore.GetPruneHeight()
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	this.setPruneHeight(pruneHeight)
+	return nil
+}
+
+func (this *LedgerStoreImp) setPruneHeight(height uint32) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.pruneHeight = height
+}
+
+func (this *LedgerStoreImp) getPruneHeight() uint32 {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.pruneHeight
+}
+
+//getPruneTarget return the lowest height of block should be kept when current block height is blockHeight
+func (this *LedgerStoreImp) getPruneTarget(blockHeight uint32) uint32 {
+	keep := config.DefConfig.Common.PruneBlocks
+	if blockHeight < keep {
+		return 0
+	}
+	return blockHeight + 1 - keep
+}
+
+//pruneBlocks discard block bodies and event notifies of blocks lower than height.
+//Batch of block store and event store should have been started
+func (this *LedgerStoreImp) pruneBlocks(height uint32) error {
+	pruneIndex := this.isEventIndexEnabled()
+	for i := this.getPruneHeight(); i < height; i++ {
+		blockHash := this.GetBlockHash(i)
+		if blockHash == common.UINT256_EMPTY {
+			return fmt.Errorf("GetBlockHash height:%d hash nil", i)
+		}
+		err := this.blockStore.PruneBlock(blockHash)
+		if err != nil {
+			return fmt.Errorf("blockStore.PruneBlock height:%d error %s", i, err)
+		}
+		err = this.eventStore.PruneEventNotifyByBlock(i, pruneIndex)
+		if err != nil {
+			return fmt.Errorf("eventStore.PruneEventNotifyByBlock height:%d error %s", i, err)
+		}
+	}
+	this.blockStore.SavePruneHeight(height)
+	return nil
+}
+
+//pruneStore prune the blocks saved before pruning was enabled
+func (this *LedgerStoreImp) pruneStore() error {
+	if !this.isPruneEnabled() {
+		return nil
+	}
+	target := this.getPruneTarget(this.GetCurrentBlockHeight())
+	if this.getPruneHeight() >= target {
+		return this.pruneStateTrie()
+	}
+	log.Infof("prune blocks from height %d to %d", this.getPruneHeight(), target-1)
+	for height := this.getPruneHeight(); height < target; {
+		height += PRUNE_BATCH_SIZE
+		if height > target {
+			height = target
+		}
+		this.blockStore.NewBatch()
+		this.eventStore.NewBatch()
+		err := this.pruneBlocks(height)
+		if err != nil {
+			return err
+		}
+		err = this.blockStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("blockStore.CommitTo height:%d error %s", height-1, err)
+		}
+		err = this.eventStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("eventStore.CommitTo height:%d error %s", height-1, err)
+		}
+		this.setPruneHeight(height)
+	}
+	return this.pruneStateTrie()
+}
+
+//pruneStateTrie drop the state trie roots before prune height with their unreachable nodes. It runs every
+//STATE_TRIE_PRUNE_INTERVAL blocks in background, since marking the reachable nodes walks the whole state trie.
+//The state trie is never pruned if blocks are not pruned
+func (this *LedgerStoreImp) pruneStateTrie() error {
+	if !this.isStateProofEnabled() || !this.isPruneEnabled() {
+		return nil
+	}
+	version, start, err := this.stateStore.GetStateTrieVersion()
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return nil
+		}
+		return fmt.Errorf("GetStateTrieVersion error %s", err)
+	}
+	//the state trie of other version is rebuilt by buildStateTrie
+	target := this.getPruneHeight()
+	if version != STATE_TRIE_VERSION || target < start+STATE_TRIE_PRUNE_INTERVAL {
+		return nil
+	}
+	//the previous pruning is not finished
+	if !atomic.CompareAndSwapUint32(&this.stateTriePruning, 0, 1) {
+		return nil
+	}
+	log.Infof("prune state trie from height %d to %d", start, target-1)
+	this.stateTriePruneWait.Add(1)
+	go func() {
+		defer this.stateTriePruneWait.Done()
+		defer atomic.StoreUint32(&this.stateTriePruning, 0)
+		if err := this.stateStore.PruneStateTrie(target); err != nil {
+			log.Errorf("PruneStateTrie height:%d error %s", target, err)
+			return
+		}
+		log.Infof("state trie pruned to height %d", target)
+	}()
+	return nil
+}
+
+//isPrunedTx return whether the transaction body has been discarded by pruning
+func (this *LedgerStoreImp) isPrunedTx(txHash common.Uint256) bool {
+	if this.getPruneHeight() == 0 {
+		return false
+	}
+	_, _, err := this.blockStore.GetTransaction(txHash)
+	return err == scom.ErrPruned
+}
+
 func (this *LedgerStoreImp) tryGetSavingBlockLock() (hasLocked bool) {
 	select {
 	case this.savingBlockSemaphore <- true:
@@ -793,10 +1272,20 @@ func (this *LedgerStoreImp) submitBlock(block *types.Block, result store.Execute
 	if err != nil {
 		return fmt.Errorf("save to state store height:%d error:%s", blockHeight, err)
 	}
-	err = this.saveBlockToEventStore(block)
+	err = this.saveBlockToEventStore(block, result.Notify)
 	if err != nil {
 		return fmt.Errorf("save to event store height:%d error:%s", blockHeight, err)
 	}
+	pruneHeight := this.getPruneHeight()
+	if this.isPruneEnabled() {
+		if target := this.getPruneTarget(blockHeight); target > pruneHeight {
+			err = this.pruneBlocks(target)
+			if err != nil {
+				return fmt.Errorf("prune blocks height:%d error:%s", blockHeight, err)
+			}
+			pruneHeight = target
+		}
+	}
 	err = this.blockStore.CommitTo()
 	if err != nil {
 		return fmt.Errorf("blockStore.CommitTo height:%d error %s", blockHeight, err)
@@ -811,6 +1300,11 @@ func (this *LedgerStoreImp) submitBlock(block *types.Block, result store.Execute
 		return fmt.Errorf("stateStore.CommitTo height:%d error %s", blockHeight, err)
 	}
 	this.setCurrentBlock(blockHeight, blockHash)
+	this.setPruneHeight(pruneHeight)
+	//the block is committed, the state trie is pruned in background, and again at next interval or restart if failed
+	if err := this.pruneStateTrie(); err != nil {
+		log.Errorf("pruneStateTrie height:%d error %s", blockHeight, err)
+	}
 
 	if events.DefActorPublisher != nil {
 		events.DefActorPublisher.Publish(
@@ -846,7 +1340,8 @@ func (this *LedgerStoreImp) saveBlock(block *types.Block, stateMerkleRoot common
 	return this.submitBlock(block, result)
 }
 
-func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cache *storage.CacheDB, block *types.Block, tx *types.Transaction) (*event.ExecuteNotify, error) {
+func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cache *storage.CacheDB, block *types.Block, tx *types.Transaction,
+	tracer *trace.Tracer) (*event.ExecuteNotify, error) {
 	txHash := tx.Hash()
 	notify := &event.ExecuteNotify{TxHash: txHash, State: event.CONTRACT_STATE_FAIL}
 	switch tx.TxType {
@@ -858,8 +1353,8 @@ func (this *LedgerStoreImp) handleTransaction(overlay *overlaydb.OverlayDB, cach
 		if err != nil {
 			log.Debugf("HandleDeployTransaction tx %s error %s", txHash.ToHexString(), err)
 		}
-	case types.Invoke:
-		err := this.stateStore.HandleInvokeTransaction(this, overlay, cache, tx, block, notify)
+	case types.Invoke, types.InvokeWasm:
+		err := this.stateStore.HandleInvokeTransaction(this, overlay, cache, tx, block, notify, tracer)
 		if overlay.Error() != nil {
 			return nil, fmt.Errorf("HandleInvokeTransaction tx %s error %s", txHash.ToHexString(), overlay.Error())
 		}
@@ -958,7 +1453,15 @@ func (this *LedgerStoreImp) GetTransaction(txHash common.Uint256) (*types.Transa
 
 //GetBlockByHash return block by block hash. Wrap function of BlockStore.GetBlockByHash
 func (this *LedgerStoreImp) GetBlockByHash(blockHash common.Uint256) (*types.Block, error) {
-	return this.blockStore.GetBlock(blockHash)
+	block, err := this.blockStore.GetBlock(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	//blocks imported from snapshot only have header
+	if block.Header.Height < this.getPruneHeight() {
+		return nil, scom.ErrPruned
+	}
+	return block, nil
 }
 
 //GetBlockByHeight return block by height.
@@ -986,23 +1489,134 @@ func (this *LedgerStoreImp) GetContractState(contractHash common.Address) (*payl
 	return this.stateStore.GetContractState(contractHash)
 }
 
+//GetStorageProof return the storage item of key at block height and its proof. Wrap function of StateStore.GetStorageProof
+func (this *LedgerStoreImp) GetStorageProof(key *states.StorageKey, height uint32) (*store.StorageProof, error) {
+	if !this.isStateProofEnabled() {
+		return nil, fmt.Errorf("state proof is disabled")
+	}
+	return this.stateStore.GetStorageProof(key, height)
+}
+
 //GetStorageItem return the storage value of the key in smart contract. Wrap function of StateStore.GetStorageState
 func (this *LedgerStoreImp) GetStorageItem(key *states.StorageKey) (*states.StorageItem, error) {
 	return this.stateStore.GetStorageState(key)
 }
 
+//NewOverlayDB return a overlay db of current state, changes in overlay db are never saved to store. Wrap function of StateStore.NewOverlayDB
+func (this *LedgerStoreImp) NewOverlayDB() *overlaydb.OverlayDB {
+	return this.stateStore.NewOverlayDB()
+}
+
 //GetEventNotifyByTx return the events notify gen by executing of smart contract.  Wrap function of EventStore.GetEventNotifyByTx
 func (this *LedgerStoreImp) GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error) {
-	return this.eventStore.GetEventNotifyByTx(tx)
+	notify, err := this.eventStore.GetEventNotifyByTx(tx)
+	if err == scom.ErrNotFound && this.isPrunedTx(tx) {
+		return nil, scom.ErrPruned
+	}
+	return notify, err
 }
 
 //GetEventNotifyByBlock return the transaction hash which have event notice after execution of smart contract. Wrap function of EventStore.GetEventNotifyByBlock
 func (this *LedgerStoreImp) GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error) {
+	if height < this.getPruneHeight() {
+		return nil, scom.ErrPruned
+	}
 	return this.eventStore.GetEventNotifyByBlock(height)
 }
 
+//GetEventNotifyLogs return the event notifies match the filter in chain order. Use event index if possible, otherwise scan blocks,
+//scom.ErrRangeTooLarge if the blocks to scan exceed MAX_EVENT_SCAN_RANGE
+func (this *LedgerStoreImp) GetEventNotifyLogs(filter *event.EventFilter) ([]*event.NotifyEventLog, error) {
+	f := *filter
+	currentHeight := this.GetCurrentBlockHeight()
+	if f.ToHeight > currentHeight {
+		f.ToHeight = currentHeight
+	}
+	if f.FromHeight > f.ToHeight || f.Limit == 0 {
+		return []*event.NotifyEventLog{}, nil
+	}
+	if f.FromHeight < this.getPruneHeight() {
+		return nil, scom.ErrPruned
+	}
+	if this.isEventIndexEnabled() && len(f.Contracts) > 0 {
+		indexHeight, err := this.eventStore.GetEventIndexHeight()
+		if err == nil && indexHeight >= f.ToHeight {
+			return this.eventStore.GetEventLogsByIndex(&f)
+		}
+	}
+	return this.eventStore.GetEventLogsByScan(&f)
+}
+
 //PreExecuteContract return the result of smart contract execution without commit to store
 func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.PreExecResult, error) {
+	return this.preExecuteContract(tx, nil, nil)
+}
+
+//PreExecuteContractWithTrace pre-execute the transaction and return the trace of execution, the trace is returned even if execution failed
+func (this *LedgerStoreImp) PreExecuteContractWithTrace(tx *types.Transaction) (*sstate.PreExecResult, error) {
+	tracer := trace.NewTracer()
+	result, err := this.preExecuteContract(tx, tracer, nil)
+	result.Trace = tracer
+	return result, err
+}
+
+//TraceTransaction replay the block of committed transaction on the state of previous block, and return the trace of transaction.
+//The state of previous block is read from state trie, so state proof must be enabled (--enable-state-proof) since
+//that height.
+//Contracts iterating storage can not be replayed, and the gas table of current global params is used
+func (this *LedgerStoreImp) TraceTransaction(txHash common.Uint256) (*sstate.PreExecResult, error) {
+	if !this.isStateProofEnabled() {
+		return nil, fmt.Errorf("state proof is disabled")
+	}
+	_, height, err := this.GetTransaction(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("can not trace transaction of genesis block")
+	}
+	block, err := this.GetBlockByHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	root, err := this.stateStore.GetStateTrieRoot(height - 1)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateTrieRoot height:%d error %s", height-1, err)
+	}
+	overlay := overlaydb.NewOverlayDB(newStateTrieStore(this.stateStore, root))
+	cache := storage.NewCacheDB(overlay)
+	for _, tx := range block.Transactions {
+		cache.Reset()
+		if tx.Hash() != txHash {
+			_, err := this.handleTransaction(overlay, cache, block, tx, nil)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		tracer := trace.NewTracer()
+		notify, err := this.handleTransaction(overlay, cache, block, tx, tracer)
+		if err != nil {
+			return nil, err
+		}
+		return &sstate.PreExecResult{State: notify.State, Gas: notify.GasConsumed, Notify: notify.Notify, Trace: tracer}, nil
+	}
+	return nil, fmt.Errorf("transaction %s not in block %d", txHash.ToHexString(), height)
+}
+
+//EstimateGas pre-execute the transaction and return the gas breakdown and recommended gas limit of it.
+//Witnesses are not checked, so the transaction need not be signed
+func (this *LedgerStoreImp) EstimateGas(tx *types.Transaction) (*sstate.GasEstimate, error) {
+	estimate := &sstate.GasEstimate{MinGas: neovm.MIN_TRANSACTION_GAS}
+	_, err := this.preExecuteContract(tx, nil, estimate)
+	if err != nil {
+		return nil, err
+	}
+	return estimate, nil
+}
+
+func (this *LedgerStoreImp) preExecuteContract(tx *types.Transaction, tracer *trace.Tracer,
+	estimate *sstate.GasEstimate) (*sstate.PreExecResult, error) {
 	height := this.GetCurrentBlockHeight()
 	stf := &sstate.PreExecResult{State: event.CONTRACT_STATE_FAIL, Gas: neovm.MIN_TRANSACTION_GAS, Result: nil}
 
@@ -1020,28 +1634,44 @@ func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.P
 		return stf, err
 	}
 
-	if tx.TxType == types.Invoke {
+	if tx.TxType == types.Invoke || tx.TxType == types.InvokeWasm {
 		invoke := tx.Payload.(*payload.InvokeCode)
+		codeLenGas := calcGasByCodeLen(len(invoke.Code), preGas[neovm.UINT_INVOKE_CODE_LEN_NAME])
 
 		sc := smartcontract.SmartContract{
-			Config:  config,
-			Store:   this,
-			CacheDB: cache,
-			Gas:     math.MaxUint64 - calcGasByCodeLen(len(invoke.Code), preGas[neovm.UINT_INVOKE_CODE_LEN_NAME]),
-			PreExec: true,
+			Config:      config,
+			Store:       this,
+			CacheDB:     cache,
+			Gas:         math.MaxUint64 - codeLenGas,
+			PreExec:     true,
+			SkipWitness: estimate != nil,
+			Tracer:      tracer,
 		}
 
 		//start the smart contract executive function
-		engine, _ := sc.NewExecuteEngine(invoke.Code)
+		engine, _ := newInvokeEngine(&sc, tx.TxType, invoke.Code)
+		if tracer != nil {
+			cache.SetTracer(tracer)
+		}
 		result, err := engine.Invoke()
+		if tracer != nil {
+			tracer.Finish(sc.Gas, err)
+		}
 		if err != nil {
 			return stf, err
 		}
 		gasCost := math.MaxUint64 - sc.Gas
+		if estimate != nil {
+			estimate.CodeLenGas = codeLenGas
+			estimate.ExecGas = gasCost - codeLenGas
+		}
 		mixGas := neovm.MIN_TRANSACTION_GAS
 		if gasCost < mixGas {
 			gasCost = mixGas
 		}
+		if estimate != nil {
+			estimate.GasLimit = gasCost + (estimate.ExecGas*GAS_ESTIMATE_MARGIN+99)/100
+		}
 		cv, err := scommon.ConvertNeoVmTypeHexString(result)
 		if err != nil {
 			return stf, err
@@ -1049,7 +1679,22 @@ func (this *LedgerStoreImp) PreExecuteContract(tx *types.Transaction) (*sstate.P
 		return &sstate.PreExecResult{State: event.CONTRACT_STATE_SUCCESS, Gas: gasCost, Result: cv, Notify: sc.Notifications}, nil
 	} else if tx.TxType == types.Deploy {
 		deploy := tx.Payload.(*payload.DeployCode)
-		return &sstate.PreExecResult{State: event.CONTRACT_STATE_SUCCESS, Gas: preGas[neovm.CONTRACT_CREATE_NAME] + calcGasByCodeLen(len(deploy.Code), preGas[neovm.UINT_DEPLOY_CODE_LEN_NAME]), Result: nil}, nil
+		if deploy.VmType == payload.WASMVM_TYPE {
+			if err := exec.VerifyCode(deploy.Code); err != nil {
+				return stf, err
+			}
+		}
+		createGas := preGas[neovm.CONTRACT_CREATE_NAME]
+		codeLenGas := calcGasByCodeLen(len(deploy.Code), preGas[neovm.UINT_DEPLOY_CODE_LEN_NAME])
+		if estimate != nil {
+			estimate.CreateGas = createGas
+			estimate.CodeLenGas = codeLenGas
+			estimate.GasLimit = createGas + codeLenGas
+			if estimate.GasLimit < neovm.MIN_TRANSACTION_GAS {
+				estimate.GasLimit = neovm.MIN_TRANSACTION_GAS
+			}
+		}
+		return &sstate.PreExecResult{State: event.CONTRACT_STATE_SUCCESS, Gas: createGas + codeLenGas, Result: nil}, nil
 	} else {
 		return stf, errors.NewErr("transaction type error")
 	}
@@ -1100,6 +1745,7 @@ func (this *LedgerStoreImp) getPreGas(config *smartcontract.Config, cache *stora
 
 //Close ledger store.
 func (this *LedgerStoreImp) Close() error {
+	this.stateTriePruneWait.Wait()
 	err := this.blockStore.Close()
 	if err != nil {
 		return fmt.Errorf("blockStore close error %s", err)
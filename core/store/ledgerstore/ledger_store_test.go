@@ -25,6 +25,8 @@ import (
 	"github.com/OnyxPay/OnyxChain/common/config"
 	"github.com/OnyxPay/OnyxChain/common/log"
 	"github.com/OnyxPay/OnyxChain/core/genesis"
+	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/types"
 	"os"
 	"testing"
 )
@@ -137,3 +139,44 @@ func TestInitLedgerStoreWithGenesisBlock(t *testing.T) {
 		return
 	}
 }
+
+func TestEstimateGas(t *testing.T) {
+	mutable := &types.MutableTransaction{
+		TxType:  types.Invoke,
+		Payload: &payload.InvokeCode{Code: []byte{0x51}},
+	}
+	tx, err := mutable.IntoImmutable()
+	if err != nil {
+		t.Errorf("IntoImmutable error %s", err)
+		return
+	}
+	estimate, err := testLedgerStore.EstimateGas(tx)
+	if err != nil {
+		t.Errorf("EstimateGas invoke error %s", err)
+		return
+	}
+	margin := (estimate.ExecGas*GAS_ESTIMATE_MARGIN + 99) / 100
+	if estimate.GasLimit < estimate.MinGas || estimate.GasLimit < estimate.CodeLenGas+estimate.ExecGas+margin {
+		t.Errorf("TestEstimateGas failed invoke gas limit %d too small %+v", estimate.GasLimit, estimate)
+		return
+	}
+
+	mutable = &types.MutableTransaction{
+		TxType:  types.Deploy,
+		Payload: &payload.DeployCode{Code: []byte{0x51}, VmType: payload.NEOVM_TYPE},
+	}
+	tx, err = mutable.IntoImmutable()
+	if err != nil {
+		t.Errorf("IntoImmutable error %s", err)
+		return
+	}
+	estimate, err = testLedgerStore.EstimateGas(tx)
+	if err != nil {
+		t.Errorf("EstimateGas deploy error %s", err)
+		return
+	}
+	if estimate.CreateGas == 0 || estimate.GasLimit != estimate.CreateGas+estimate.CodeLenGas {
+		t.Errorf("TestEstimateGas failed deploy estimate %+v", estimate)
+		return
+	}
+}
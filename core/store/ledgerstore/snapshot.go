@@ -0,0 +1,457 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/common/serialization"
+	scom "github.com/OnyxPay/OnyxChain/core/store/common"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/merkle"
+)
+
+const (
+	SNAPSHOT_VERSION    = byte(1)      //Version of state snapshot
+	SNAPSHOT_BATCH_SIZE = uint32(5000) //Batch size of saving snapshot headers and states
+)
+
+//State prefixes dumped to snapshot. Merkle trees and current block are rebuilt by import
+var snapshotStatePrefixes = []scom.DataEntryPrefix{scom.ST_BOOKKEEPER, scom.ST_CONTRACT, scom.ST_STORAGE}
+
+func isSnapshotStateKey(key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for _, prefix := range snapshotStatePrefixes {
+		if key[0] == byte(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+//snapshotDigest return the digest binding the block, the state merkle root and the states of snapshot
+func snapshotDigest(blockHash, writeSetHash, stateRoot, stateSum common.Uint256) common.Uint256 {
+	hasher := sha256.New()
+	hasher.Write(blockHash[:])
+	hasher.Write(writeSetHash[:])
+	hasher.Write(stateRoot[:])
+	hasher.Write(stateSum[:])
+	var digest common.Uint256
+	hasher.Sum(digest[:0])
+	return digest
+}
+
+//hashSnapshotState write the length prefixed key and value of state to hasher, so that moving the boundary between
+//key and value changes the state hash
+func hashSnapshotState(hasher hash.Hash, key, value []byte) {
+	serialization.WriteVarBytes(hasher, key)
+	serialization.WriteVarBytes(hasher, value)
+}
+
+//ExportSnapshot write the state of current block to w, and return the height, hash and digest of snapshot. The
+//snapshot contains all block headers, the state merkle tree and the contract states, which is enough for a new node
+//to continue syncing from current block
+func (this *LedgerStoreImp) ExportSnapshot(w io.Writer) (uint32, common.Uint256, common.Uint256, error) {
+	empty := common.UINT256_EMPTY
+	blockHeight, blockHash := this.GetCurrentBlock()
+	stateHash, stateHeight, err := this.stateStore.GetCurrentBlock()
+	if err != nil {
+		return 0, empty, empty, fmt.Errorf("stateStore.GetCurrentBlock error %s", err)
+	}
+	if stateHeight != blockHeight || stateHash != blockHash {
+		return 0, empty, empty, fmt.Errorf("state height %d is inconsistent with block height %d", stateHeight, blockHeight)
+	}
+
+	err = serialization.WriteByte(w, SNAPSHOT_VERSION)
+	if err != nil {
+		return 0, empty, empty, err
+	}
+	err = serialization.WriteUint32(w, blockHeight)
+	if err != nil {
+		return 0, empty, empty, err
+	}
+	err = blockHash.Serialize(w)
+	if err != nil {
+		return 0, empty, empty, err
+	}
+
+	sink := common.NewZeroCopySink(nil)
+	for height := uint32(0); height <= blockHeight; height++ {
+		header, err := this.GetHeaderByHeight(height)
+		if err != nil {
+			return 0, empty, empty, fmt.Errorf("GetHeaderByHeight height:%d error %s", height, err)
+		}
+		if header == nil {
+			return 0, empty, empty, fmt.Errorf("GetHeaderByHeight height:%d header nil", height)
+		}
+		sink.Reset()
+		header.Serialization(sink)
+		err = serialization.WriteVarBytes(w, sink.Bytes())
+		if err != nil {
+			return 0, empty, empty, err
+		}
+	}
+
+	var writeSetHash, stateRoot common.Uint256
+	hasStateRoot := blockHeight >= this.stateHashCheckHeight
+	err = serialization.WriteBool(w, hasStateRoot)
+	if err != nil {
+		return 0, empty, empty, err
+	}
+	if hasStateRoot {
+		writeSetHash, stateRoot, err = this.stateStore.GetStateMerkleRootItem(blockHeight)
+		if err != nil {
+			return 0, empty, empty, fmt.Errorf("GetStateMerkleRootItem error %s", err)
+		}
+		treeSize, hashes, err := this.stateStore.GetStateMerkleTree()
+		if err != nil {
+			return 0, empty, empty, fmt.Errorf("GetStateMerkleTree error %s", err)
+		}
+		err = writeSetHash.Serialize(w)
+		if err != nil {
+			return 0, empty, empty, err
+		}
+		err = stateRoot.Serialize(w)
+		if err != nil {
+			return 0, empty, empty, err
+		}
+		err = serialization.WriteUint32(w, treeSize)
+		if err != nil {
+			return 0, empty, empty, err
+		}
+		err = serialization.WriteUint32(w, uint32(len(hashes)))
+		if err != nil {
+			return 0, empty, empty, err
+		}
+		for _, hash := range hashes {
+			err = hash.Serialize(w)
+			if err != nil {
+				return 0, empty, empty, err
+			}
+		}
+	}
+
+	hasher := sha256.New()
+	for _, prefix := range snapshotStatePrefixes {
+		iter := this.stateStore.store.NewIterator([]byte{byte(prefix)})
+		for has := iter.First(); has; has = iter.Next() {
+			key, value := iter.Key(), iter.Value()
+			hashSnapshotState(hasher, key, value)
+			if err = serialization.WriteVarBytes(w, key); err != nil {
+				break
+			}
+			if err = serialization.WriteVarBytes(w, value); err != nil {
+				break
+			}
+		}
+		iter.Release()
+		if err == nil {
+			err = iter.Error()
+		}
+		if err != nil {
+			return 0, empty, empty, fmt.Errorf("export state error %s", err)
+		}
+	}
+	//empty key marks the end of states
+	err = serialization.WriteVarBytes(w, nil)
+	if err != nil {
+		return 0, empty, empty, err
+	}
+	var stateSum common.Uint256
+	hasher.Sum(stateSum[:0])
+	err = stateSum.Serialize(w)
+	if err != nil {
+		return 0, empty, empty, err
+	}
+	return blockHeight, blockHash, snapshotDigest(blockHash, writeSetHash, stateRoot, stateSum), nil
+}
+
+//ImportSnapshot load the snapshot exported by ExportSnapshot into an empty ledger store. The block headers are
+//checked to link to genesis block, be signed by bookkeepers and match the block merkle tree. The state merkle
+//root is not signed by bookkeepers, so the states are trusted by the digest, which should be obtained from a trusted
+//source, and the state merkle root is checked again by the first block synced from peers. Blocks before the
+//snapshot height are regarded as pruned
+func (this *LedgerStoreImp) ImportSnapshot(genesisBlock *types.Block, digest common.Uint256, r io.Reader) (uint32,
+	common.Uint256, error) {
+	if digest == common.UINT256_EMPTY {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("trusted snapshot digest is required")
+	}
+	hasInit, err := this.hasAlreadyInitGenesisBlock()
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("hasAlreadyInit error %s", err)
+	}
+	if hasInit || this.stateStore.merkleTree.TreeSize() != 0 {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("ledger store is not empty")
+	}
+
+	version, err := serialization.ReadByte(r)
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("read snapshot version error %s", err)
+	}
+	if version != SNAPSHOT_VERSION {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	blockHeight, err := serialization.ReadUint32(r)
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("read snapshot height error %s", err)
+	}
+	var blockHash common.Uint256
+	err = blockHash.Deserialize(r)
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("read snapshot block hash error %s", err)
+	}
+	log.Infof("import snapshot of block height %d hash %s", blockHeight, blockHash.ToHexString())
+
+	err = this.importSnapshotHeaders(genesisBlock, blockHeight, blockHash, r)
+	if err != nil {
+		return 0, common.UINT256_EMPTY, err
+	}
+	writeSetHash, stateRoot, stateSum, err := this.importSnapshotStates(blockHeight, r)
+	if err != nil {
+		return 0, common.UINT256_EMPTY, err
+	}
+	if d := snapshotDigest(blockHash, writeSetHash, stateRoot, stateSum); d != digest {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("snapshot digest %s mismatch, expected %s", d.ToHexString(),
+			digest.ToHexString())
+	}
+
+	this.blockStore.NewBatch()
+	this.stateStore.NewBatch()
+	this.eventStore.NewBatch()
+	this.blockStore.SavePruneHeight(blockHeight + 1)
+	err = this.stateStore.SaveCurrentBlock(blockHeight, blockHash)
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("stateStore.SaveCurrentBlock error %s", err)
+	}
+	err = this.eventStore.SaveCurrentBlock(blockHeight, blockHash)
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("eventStore.SaveCurrentBlock error %s", err)
+	}
+	err = this.blockStore.CommitTo()
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("blockStore.CommitTo error %s", err)
+	}
+	err = this.eventStore.CommitTo()
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("eventStore.CommitTo error %s", err)
+	}
+	err = this.stateStore.CommitTo()
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("stateStore.CommitTo error %s", err)
+	}
+	//version is saved at last, an interrupted import will be cleared on next start
+	err = this.initGenesisBlock()
+	if err != nil {
+		return 0, common.UINT256_EMPTY, fmt.Errorf("init error %s", err)
+	}
+	this.setPruneHeight(blockHeight + 1)
+	return blockHeight, blockHash, nil
+}
+
+//importSnapshotHeaders verify and save the block headers, and rebuild the block merkle tree
+func (this *LedgerStoreImp) importSnapshotHeaders(genesisBlock *types.Block, blockHeight uint32,
+	blockHash common.Uint256, r io.Reader) error {
+	genesisHash := genesisBlock.Hash()
+	vbftPeerInfo := make(map[string]uint32)
+	if strings.ToLower(config.DefConfig.Genesis.ConsensusType) == "vbft" {
+		var err error
+		vbftPeerInfo, err = updateVbftPeerInfo(genesisBlock.Header, vbftPeerInfo)
+		if err != nil {
+			return fmt.Errorf("genesis block vbft info error %s", err)
+		}
+	}
+	var prevHash common.Uint256
+	var prevHeader *types.Header
+	for height := uint32(0); height <= blockHeight; {
+		this.blockStore.NewBatch()
+		this.stateStore.NewBatch()
+		end := height + SNAPSHOT_BATCH_SIZE
+		for ; height < end && height <= blockHeight; height++ {
+			data, err := serialization.ReadVarBytes(r)
+			if err != nil {
+				return fmt.Errorf("read header height:%d error %s", height, err)
+			}
+			header, err := types.HeaderFromRawBytes(data)
+			if err != nil {
+				return fmt.Errorf("header height:%d deserialize error %s", height, err)
+			}
+			hash := header.Hash()
+			if header.Height != height {
+				return fmt.Errorf("header height %d mismatch, expected %d", header.Height, height)
+			}
+			if height == 0 && hash != genesisHash {
+				return fmt.Errorf("genesis block hash %s mismatch, expected %s", hash.ToHexString(), genesisHash.ToHexString())
+			}
+			if height != 0 {
+				if header.PrevBlockHash != prevHash {
+					return fmt.Errorf("header height:%d prev block hash mismatch", height)
+				}
+				if err = this.checkHeaderLink(header, prevHeader); err != nil {
+					return fmt.Errorf("header height:%d %s", height, err)
+				}
+				vbftPeerInfo, err = verifyHeaderSig(header, prevHeader, vbftPeerInfo)
+				if err != nil {
+					return fmt.Errorf("verify header height:%d error %s", height, err)
+				}
+			}
+			blockRoot := this.stateStore.GetBlockRootWithNewTxRoots([]common.Uint256{header.TransactionsRoot})
+			if height != 0 && blockRoot != header.BlockRoot {
+				return fmt.Errorf("wrong block root at height:%d, expected:%s, got:%s",
+					height, blockRoot.ToHexString(), header.BlockRoot.ToHexString())
+			}
+			err = this.stateStore.AddBlockMerkleTreeRoot(header.TransactionsRoot)
+			if err != nil {
+				return fmt.Errorf("AddBlockMerkleTreeRoot height:%d error %s", height, err)
+			}
+			err = this.blockStore.SaveHeader(&types.Block{Header: header}, 0)
+			if err != nil {
+				return fmt.Errorf("SaveHeader height:%d error %s", height, err)
+			}
+			this.blockStore.SaveBlockHash(height, hash)
+			this.setHeaderIndex(height, hash)
+			this.setCurrentBlock(height, hash)
+			err = this.saveHeaderIndexList()
+			if err != nil {
+				return fmt.Errorf("saveHeaderIndexList error %s", err)
+			}
+			prevHash = hash
+			prevHeader = header
+		}
+		err := this.blockStore.SaveCurrentBlock(height-1, prevHash)
+		if err != nil {
+			return fmt.Errorf("SaveCurrentBlock error %s", err)
+		}
+		err = this.blockStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("blockStore.CommitTo height:%d error %s", height-1, err)
+		}
+		err = this.stateStore.CommitTo()
+		if err != nil {
+			return fmt.Errorf("stateStore.CommitTo height:%d error %s", height-1, err)
+		}
+	}
+	if prevHash != blockHash {
+		return fmt.Errorf("block hash %s mismatch, expected %s", prevHash.ToHexString(), blockHash.ToHexString())
+	}
+	return nil
+}
+
+//importSnapshotStates save the state merkle tree and contract states, return the write set hash and state merkle root
+//of snapshot block and the hash of states
+func (this *LedgerStoreImp) importSnapshotStates(blockHeight uint32, r io.Reader) (common.Uint256, common.Uint256,
+	common.Uint256, error) {
+	var writeSetHash, stateRoot, stateSum common.Uint256
+	empty := common.UINT256_EMPTY
+	hasStateRoot, err := serialization.ReadBool(r)
+	if err != nil {
+		return empty, empty, empty, fmt.Errorf("read state root flag error %s", err)
+	}
+	if hasStateRoot != (blockHeight >= this.stateHashCheckHeight) {
+		return empty, empty, empty, fmt.Errorf("state root is inconsistent with state hash check height %d", this.stateHashCheckHeight)
+	}
+	this.stateStore.NewBatch()
+	if hasStateRoot {
+		err = writeSetHash.Deserialize(r)
+		if err != nil {
+			return empty, empty, empty, fmt.Errorf("read write set hash error %s", err)
+		}
+		err = stateRoot.Deserialize(r)
+		if err != nil {
+			return empty, empty, empty, fmt.Errorf("read state merkle root error %s", err)
+		}
+		treeSize, err := serialization.ReadUint32(r)
+		if err != nil {
+			return empty, empty, empty, fmt.Errorf("read state merkle tree size error %s", err)
+		}
+		if treeSize != blockHeight-this.stateHashCheckHeight+1 {
+			return empty, empty, empty, fmt.Errorf("state merkle tree size %d is inconsistent with block height %d", treeSize, blockHeight)
+		}
+		count, err := serialization.ReadUint32(r)
+		if err != nil {
+			return empty, empty, empty, fmt.Errorf("read state merkle tree error %s", err)
+		}
+		if count > 32 {
+			return empty, empty, empty, fmt.Errorf("invalid state merkle tree hash count %d", count)
+		}
+		hashes := make([]common.Uint256, count)
+		for i := range hashes {
+			err = hashes[i].Deserialize(r)
+			if err != nil {
+				return empty, empty, empty, fmt.Errorf("read state merkle tree error %s", err)
+			}
+		}
+		//state merkle root is bound by the snapshot digest, and checked again by the next block synced from peers
+		tree := merkle.NewTree(treeSize, hashes, nil)
+		if root := tree.Root(); root != stateRoot {
+			return empty, empty, empty, fmt.Errorf("state merkle root %s mismatch, expected %s", root.ToHexString(), stateRoot.ToHexString())
+		}
+		this.stateStore.SaveStateMerkleTree(blockHeight, writeSetHash, tree)
+	}
+
+	hasher := sha256.New()
+	count := uint32(0)
+	for {
+		key, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return empty, empty, empty, fmt.Errorf("read state key error %s", err)
+		}
+		if len(key) == 0 {
+			break
+		}
+		if !isSnapshotStateKey(key) {
+			return empty, empty, empty, fmt.Errorf("invalid state key %x", key)
+		}
+		value, err := serialization.ReadVarBytes(r)
+		if err != nil {
+			return empty, empty, empty, fmt.Errorf("read state value error %s", err)
+		}
+		hashSnapshotState(hasher, key, value)
+		this.stateStore.BatchPutRawKeyVal(key, value)
+		count++
+		if count%SNAPSHOT_BATCH_SIZE == 0 {
+			err = this.stateStore.CommitTo()
+			if err != nil {
+				return empty, empty, empty, fmt.Errorf("stateStore.CommitTo error %s", err)
+			}
+			this.stateStore.NewBatch()
+		}
+	}
+	var expected common.Uint256
+	hasher.Sum(stateSum[:0])
+	err = expected.Deserialize(r)
+	if err != nil {
+		return empty, empty, empty, fmt.Errorf("read state hash error %s", err)
+	}
+	if stateSum != expected {
+		return empty, empty, empty, fmt.Errorf("state hash %s mismatch, expected %s", stateSum.ToHexString(), expected.ToHexString())
+	}
+	if err = this.stateStore.CommitTo(); err != nil {
+		return empty, empty, empty, err
+	}
+	return writeSetHash, stateRoot, stateSum, nil
+}
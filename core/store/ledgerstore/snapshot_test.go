@@ -0,0 +1,123 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
+	"github.com/OnyxPay/OnyxChain/account"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/common/serialization"
+	"github.com/OnyxPay/OnyxChain/core/genesis"
+	scom "github.com/OnyxPay/OnyxChain/core/store/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	acc := account.NewAccount("")
+	bookkeepers := []keypair.PublicKey{acc.PublicKey}
+	block, err := genesis.BuildGenesisBlock(bookkeepers, config.DefConfig.Genesis)
+	assert.Nil(t, err)
+
+	src, err := NewLedgerStore("test/snapshot_src", 0)
+	assert.Nil(t, err)
+	defer src.Close()
+	err = src.InitLedgerStoreWithGenesisBlock(block, bookkeepers)
+	assert.Nil(t, err)
+
+	buf := bytes.NewBuffer(nil)
+	height, blockHash, digest, err := src.ExportSnapshot(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, src.GetCurrentBlockHeight(), height)
+	assert.Equal(t, src.GetCurrentBlockHash(), blockHash)
+	data := buf.Bytes()
+
+	// a broken state is rejected
+	broken := make([]byte, len(data))
+	copy(broken, data)
+	broken[len(broken)-1] ^= 0xff
+	dst, err := NewLedgerStore("test/snapshot_broken", 0)
+	assert.Nil(t, err)
+	_, _, err = dst.ImportSnapshot(block, digest, bytes.NewReader(broken))
+	assert.NotNil(t, err)
+	dst.Close()
+
+	// a state with the boundary of key and value shifted is rejected
+	iter := src.stateStore.store.NewIterator([]byte{byte(scom.ST_STORAGE)})
+	var key, value []byte
+	for has := iter.First(); has; has = iter.Next() {
+		if len(iter.Value()) >= 2 {
+			key, value = append([]byte{}, iter.Key()...), append([]byte{}, iter.Value()...)
+			break
+		}
+	}
+	iter.Release()
+	assert.True(t, len(value) >= 2)
+	state, shifted := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	serialization.WriteVarBytes(state, key)
+	serialization.WriteVarBytes(state, value)
+	serialization.WriteVarBytes(shifted, append(key, value[0]))
+	serialization.WriteVarBytes(shifted, value[1:])
+	assert.True(t, bytes.Contains(data, state.Bytes()))
+	dst, err = NewLedgerStore("test/snapshot_shifted", 0)
+	assert.Nil(t, err)
+	_, _, err = dst.ImportSnapshot(block, digest, bytes.NewReader(bytes.Replace(data, state.Bytes(), shifted.Bytes(), 1)))
+	assert.NotNil(t, err)
+	dst.Close()
+
+	// an untrusted snapshot is rejected
+	dst, err = NewLedgerStore("test/snapshot_untrusted", 0)
+	assert.Nil(t, err)
+	_, _, err = dst.ImportSnapshot(block, common.UINT256_EMPTY, bytes.NewReader(data))
+	assert.NotNil(t, err)
+	_, _, err = dst.ImportSnapshot(block, common.Uint256{1}, bytes.NewReader(data))
+	assert.NotNil(t, err)
+	dst.Close()
+
+	dst, err = NewLedgerStore("test/snapshot_dst", 0)
+	assert.Nil(t, err)
+	defer dst.Close()
+	height, blockHash, err = dst.ImportSnapshot(block, digest, bytes.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, src.GetCurrentBlockHeight(), height)
+	assert.Equal(t, src.GetCurrentBlockHash(), blockHash)
+	// import again to a initialized store is rejected
+	_, _, err = dst.ImportSnapshot(block, digest, bytes.NewReader(data))
+	assert.NotNil(t, err)
+
+	err = dst.InitLedgerStoreWithGenesisBlock(block, bookkeepers)
+	assert.Nil(t, err)
+	assert.Equal(t, src.GetCurrentBlockHash(), dst.GetCurrentBlockHash())
+	header, err := dst.GetHeaderByHeight(0)
+	assert.Nil(t, err)
+	assert.Equal(t, block.Hash(), header.Hash())
+	_, err = dst.GetBlockByHeight(0)
+	assert.Equal(t, scom.ErrPruned, err)
+
+	srcRoot, err := src.GetStateMerkleRoot(height)
+	assert.Nil(t, err)
+	dstRoot, err := dst.GetStateMerkleRoot(height)
+	assert.Nil(t, err)
+	assert.Equal(t, srcRoot, dstRoot)
+	assert.Equal(t, src.GetBlockRootWithNewTxRoots(height+1, []common.Uint256{common.UINT256_EMPTY}),
+		dst.GetBlockRootWithNewTxRoots(height+1, []common.Uint256{common.UINT256_EMPTY}))
+}
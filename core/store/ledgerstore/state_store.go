@@ -20,16 +20,19 @@ package ledgerstore
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/log"
 	"github.com/OnyxPay/OnyxChain/common/serialization"
 	"github.com/OnyxPay/OnyxChain/core/payload"
 	"github.com/OnyxPay/OnyxChain/core/states"
+	"github.com/OnyxPay/OnyxChain/core/store"
 	scom "github.com/OnyxPay/OnyxChain/core/store/common"
 	"github.com/OnyxPay/OnyxChain/core/store/leveldbstore"
 	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
@@ -51,6 +54,8 @@ type StateStore struct {
 	deltaMerkleTree      *merkle.CompactMerkleTree //Merkle tree of delta state root
 	merkleHashStore      merkle.HashStore
 	stateHashCheckHeight uint32
+	pruneLock            sync.Mutex
+	pruneWritten         map[common.Uint256]bool //State trie nodes and values written while pruning, nil if not pruning
 }
 
 //NewStateStore return state store instance
@@ -210,6 +215,39 @@ func (self *StateStore) AddStateMerkleTreeRoot(blockHeight uint32, writeSetHash
 	return nil
 }
 
+//SaveStateMerkleTree persist the state merkle tree and the state merkle root of block height. Used by snapshot import
+func (self *StateStore) SaveStateMerkleTree(blockHeight uint32, writeSetHash common.Uint256, tree *merkle.CompactMerkleTree) {
+	hashes := tree.Hashes()
+	value := common.NewZeroCopySink(make([]byte, 0, 4+len(hashes)*common.UINT256_SIZE))
+	value.WriteUint32(tree.TreeSize())
+	for _, hash := range hashes {
+		value.WriteHash(hash)
+	}
+	self.store.BatchPut(self.genStateMerkleTreeKey(), value.Bytes())
+
+	value.Reset()
+	value.WriteHash(writeSetHash)
+	value.WriteHash(tree.Root())
+	self.store.BatchPut(self.genStateMerkleRootKey(blockHeight), value.Bytes())
+	self.deltaMerkleTree = tree
+}
+
+//GetStateMerkleRootItem return the write set hash and state merkle root of block height
+func (self *StateStore) GetStateMerkleRootItem(height uint32) (writeSetHash common.Uint256, root common.Uint256, err error) {
+	var value []byte
+	value, err = self.store.Get(self.genStateMerkleRootKey(height))
+	if err != nil {
+		return
+	}
+	source := common.NewZeroCopySource(value)
+	writeSetHash, _ = source.NextHash()
+	root, eof := source.NextHash()
+	if eof {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
 //AddBlockMerkleTreeRoot add a new tree root
 func (self *StateStore) AddBlockMerkleTreeRoot(txRoot common.Uint256) error {
 	key := self.genBlockMerkleTreeKey()
@@ -372,6 +410,330 @@ func (self *StateStore) getStorageKey(key *states.StorageKey) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+//GetNode return the state trie node of hash
+func (self *StateStore) GetNode(hash common.Uint256) ([]byte, error) {
+	return self.store.Get(self.genStateTrieHashKey(scom.DATA_STATE_TRIE_NODE, hash))
+}
+
+//PutNode put the state trie node to batch
+func (self *StateStore) PutNode(hash common.Uint256, node []byte) {
+	self.markPruneWritten(hash)
+	self.store.BatchPut(self.genStateTrieHashKey(scom.DATA_STATE_TRIE_NODE, hash), node)
+}
+
+//UpdateStateTrie update the state trie with the contracts and storage items in write set, and save the state trie root of block height.
+//The state trie is skipped with warning if it is not built to the previous block, it will be rebuilt by BuildStateTrie
+func (self *StateStore) UpdateStateTrie(blockHeight uint32, writeSet *overlaydb.MemDB) error {
+	root := merkle.EMPTY_HASH
+	trieHeight, err := self.GetStateTrieHeight()
+	if err != nil {
+		if err != scom.ErrNotFound {
+			return err
+		}
+		if blockHeight != 0 {
+			log.Warnf("state trie not found at block %d, skip updating, it is built on restart", blockHeight)
+			return nil
+		}
+		self.saveStateTrieVersion(blockHeight)
+	} else {
+		if trieHeight+1 != blockHeight {
+			log.Warnf("state trie height %d is behind block %d, skip updating, it is rebuilt on restart", trieHeight, blockHeight)
+			return nil
+		}
+		root, err = self.GetStateTrieRoot(trieHeight)
+		if err != nil {
+			return fmt.Errorf("GetStateTrieRoot height:%d error %s", trieHeight, err)
+		}
+	}
+	updates := make([]merkle.SMTUpdate, 0)
+	writeSet.ForEach(func(key, val []byte) {
+		if len(key) == 0 || (key[0] != byte(scom.ST_CONTRACT) && key[0] != byte(scom.ST_STORAGE)) {
+			return
+		}
+		updates = append(updates, self.newStateTrieUpdate(key, val))
+	})
+	root, err = merkle.NewSparseMerkleTree(self).Update(root, updates)
+	if err != nil {
+		return err
+	}
+	self.saveStateTrieRoot(blockHeight, root)
+	return nil
+}
+
+//BuildStateTrie build the state trie of block height from all the contracts and storage items. If the state trie
+//is of other version, the state trie starts from block height
+func (self *StateStore) BuildStateTrie(blockHeight uint32) error {
+	tree := merkle.NewSparseMerkleTree(self)
+	root := merkle.EMPTY_HASH
+	updates := make([]merkle.SMTUpdate, 0, STATE_TRIE_BATCH_SIZE)
+	version, _, err := self.GetStateTrieVersion()
+	if err != nil && err != scom.ErrNotFound {
+		return err
+	}
+	newVersion := err != nil || version != STATE_TRIE_VERSION
+	self.store.NewBatch()
+	for _, prefix := range []scom.DataEntryPrefix{scom.ST_CONTRACT, scom.ST_STORAGE} {
+		iter := self.store.NewIterator([]byte{byte(prefix)})
+		for iter.Next() {
+			updates = append(updates, self.newStateTrieUpdate(iter.Key(), iter.Value()))
+			if len(updates) < STATE_TRIE_BATCH_SIZE {
+				continue
+			}
+			root, err = tree.Update(root, updates)
+			if err == nil {
+				err = self.store.BatchCommit()
+			}
+			if err != nil {
+				iter.Release()
+				return err
+			}
+			self.store.NewBatch()
+			updates = updates[:0]
+		}
+		err = iter.Error()
+		iter.Release()
+		if err != nil {
+			return err
+		}
+	}
+	root, err = tree.Update(root, updates)
+	if err != nil {
+		return err
+	}
+	self.saveStateTrieRoot(blockHeight, root)
+	if newVersion {
+		self.saveStateTrieVersion(blockHeight)
+	}
+	return self.store.BatchCommit()
+}
+
+//PruneStateTrie delete the state trie roots before start height, and the nodes and values not reachable from the
+//roots kept. The reachable node hashes are marked in memory, so it costs memory of the size of state trie nodes.
+//It writes the store directly instead of batch, so it is safe to run in background while blocks are saved, the
+//nodes and values written by blocks while pruning are never deleted
+func (self *StateStore) PruneStateTrie(startHeight uint32) error {
+	self.pruneLock.Lock()
+	if self.pruneWritten != nil {
+		self.pruneLock.Unlock()
+		return fmt.Errorf("state trie is being pruned")
+	}
+	self.pruneWritten = make(map[common.Uint256]bool)
+	self.pruneLock.Unlock()
+	defer func() {
+		self.pruneLock.Lock()
+		self.pruneWritten = nil
+		self.pruneLock.Unlock()
+	}()
+
+	_, start, err := self.GetStateTrieVersion()
+	if err != nil {
+		return err
+	}
+	trieHeight, err := self.GetStateTrieHeight()
+	if err != nil {
+		return err
+	}
+	if startHeight > trieHeight {
+		startHeight = trieHeight
+	}
+	if startHeight <= start {
+		return nil
+	}
+	//move the start height first, so the pruned heights are not served while sweeping
+	err = self.store.Put([]byte{byte(scom.SYS_STATE_TRIE_VER)}, genStateTrieVersion(startHeight))
+	if err != nil {
+		return err
+	}
+	for height := start; height < startHeight; height++ {
+		err = self.store.Delete(self.genStateTrieRootKey(height))
+		if err != nil {
+			return err
+		}
+	}
+
+	tree := merkle.NewSparseMerkleTree(self)
+	nodes := make(map[common.Uint256]bool)
+	values := make(map[common.Uint256]bool)
+	for height := startHeight; height <= trieHeight; height++ {
+		root, err := self.GetStateTrieRoot(height)
+		if err != nil {
+			return fmt.Errorf("GetStateTrieRoot height:%d error %s", height, err)
+		}
+		err = tree.Walk(root, func(hash common.Uint256, typ byte, left, right common.Uint256) bool {
+			if nodes[hash] {
+				return false
+			}
+			nodes[hash] = true
+			if typ == merkle.SMT_LEAF_NODE {
+				values[right] = true
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("walk state trie height:%d error %s", height, err)
+		}
+	}
+	err = self.sweepStateTrie(scom.DATA_STATE_TRIE_NODE, nodes)
+	if err != nil {
+		return err
+	}
+	return self.sweepStateTrie(scom.DATA_STATE_TRIE_VALUE, values)
+}
+
+func (self *StateStore) sweepStateTrie(prefix scom.DataEntryPrefix, marked map[common.Uint256]bool) error {
+	iter := self.store.NewIterator([]byte{byte(prefix)})
+	defer iter.Release()
+	for iter.Next() {
+		hash, err := common.Uint256ParseFromBytes(iter.Key()[1:])
+		if err != nil || marked[hash] {
+			continue
+		}
+		//the check and delete are under lock, a node written by block after deleting is saved again by the block
+		self.pruneLock.Lock()
+		if !self.pruneWritten[hash] {
+			err = self.store.Delete(iter.Key())
+		}
+		self.pruneLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+func (self *StateStore) markPruneWritten(hash common.Uint256) {
+	self.pruneLock.Lock()
+	if self.pruneWritten != nil {
+		self.pruneWritten[hash] = true
+	}
+	self.pruneLock.Unlock()
+}
+
+//GetStorageProof return the storage item of key at block height, and its proof against the state trie root of block height
+func (self *StateStore) GetStorageProof(key *states.StorageKey, height uint32) (*store.StorageProof, error) {
+	root, err := self.GetStateTrieRoot(height)
+	if err != nil {
+		return nil, err
+	}
+	storeKey, err := self.getStorageKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyHash := common.Uint256(sha256.Sum256(storeKey))
+	proof, err := merkle.NewSparseMerkleTree(self).GetProof(root, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	result := &store.StorageProof{
+		Height:    height,
+		StateRoot: root,
+		Key:       storeKey,
+		Proof:     proof,
+	}
+	if proof.LeafKey == keyHash {
+		result.Value, err = self.store.Get(self.genStateTrieHashKey(scom.DATA_STATE_TRIE_VALUE, proof.LeafValue))
+		if err != nil {
+			return nil, fmt.Errorf("get value of state trie leaf error %s", err)
+		}
+	}
+	return result, nil
+}
+
+//GetStateTrieValue return the value of state key in state trie of root, scom.ErrNotFound if key not exist
+func (self *StateStore) GetStateTrieValue(root common.Uint256, key []byte) ([]byte, error) {
+	valueHash, err := merkle.NewSparseMerkleTree(self).Get(root, sha256.Sum256(key))
+	if err != nil {
+		return nil, err
+	}
+	if valueHash == merkle.EMPTY_HASH {
+		return nil, scom.ErrNotFound
+	}
+	return self.store.Get(self.genStateTrieHashKey(scom.DATA_STATE_TRIE_VALUE, valueHash))
+}
+
+//GetStateTrieRoot return the state trie root of block height, scom.ErrNotFound if the height is before the state
+//trie of current version starts
+func (self *StateStore) GetStateTrieRoot(height uint32) (common.Uint256, error) {
+	version, start, err := self.GetStateTrieVersion()
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	if version != STATE_TRIE_VERSION || height < start {
+		return common.Uint256{}, scom.ErrNotFound
+	}
+	value, err := self.store.Get(self.genStateTrieRootKey(height))
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	return common.Uint256ParseFromBytes(value)
+}
+
+//GetStateTrieHeight return the height of last block saved in state trie
+func (self *StateStore) GetStateTrieHeight() (uint32, error) {
+	value, err := self.store.Get([]byte{byte(scom.SYS_STATE_TRIE)})
+	if err != nil {
+		return 0, err
+	}
+	if len(value) != 4 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return binary.LittleEndian.Uint32(value), nil
+}
+
+//GetStateTrieVersion return the version of state trie and the height it starts from
+func (self *StateStore) GetStateTrieVersion() (byte, uint32, error) {
+	value, err := self.store.Get([]byte{byte(scom.SYS_STATE_TRIE_VER)})
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(value) != 5 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	return value[0], binary.LittleEndian.Uint32(value[1:]), nil
+}
+
+func (self *StateStore) saveStateTrieVersion(startHeight uint32) {
+	self.store.BatchPut([]byte{byte(scom.SYS_STATE_TRIE_VER)}, genStateTrieVersion(startHeight))
+}
+
+func genStateTrieVersion(startHeight uint32) []byte {
+	value := make([]byte, 5)
+	value[0] = STATE_TRIE_VERSION
+	binary.LittleEndian.PutUint32(value[1:], startHeight)
+	return value
+}
+
+func (self *StateStore) saveStateTrieRoot(height uint32, root common.Uint256) {
+	self.store.BatchPut(self.genStateTrieRootKey(height), root.ToArray())
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, height)
+	self.store.BatchPut([]byte{byte(scom.SYS_STATE_TRIE)}, value)
+}
+
+func (self *StateStore) newStateTrieUpdate(key, val []byte) merkle.SMTUpdate {
+	update := merkle.SMTUpdate{Key: sha256.Sum256(key)}
+	if len(val) != 0 {
+		update.Value = sha256.Sum256(val)
+		self.markPruneWritten(update.Value)
+		self.store.BatchPut(self.genStateTrieHashKey(scom.DATA_STATE_TRIE_VALUE, update.Value), val)
+	}
+	return update
+}
+
+func (self *StateStore) genStateTrieHashKey(prefix scom.DataEntryPrefix, hash common.Uint256) []byte {
+	key := make([]byte, 1+common.UINT256_SIZE)
+	key[0] = byte(prefix)
+	copy(key[1:], hash[:])
+	return key
+}
+
+func (self *StateStore) genStateTrieRootKey(height uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(scom.DATA_STATE_TRIE_ROOT)
+	binary.LittleEndian.PutUint32(key[1:], height)
+	return key
+}
+
 func (self *StateStore) GetStateMerkleRootWithNewHash(writeSetHash common.Uint256) common.Uint256 {
 	return self.deltaMerkleTree.GetRootWithNewLeaf(writeSetHash)
 }
@@ -19,12 +19,14 @@
 package ledgerstore
 
 import (
+	"crypto/sha256"
 	"math/rand"
 	"testing"
 
-
 	"github.com/OnyxPay/OnyxChain/common"
-
+	"github.com/OnyxPay/OnyxChain/core/states"
+	scom "github.com/OnyxPay/OnyxChain/core/store/common"
+	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
 	"github.com/OnyxPay/OnyxChain/merkle"
 	"github.com/stretchr/testify/assert"
 )
@@ -73,3 +75,172 @@ func TestStateMerkleRoot(t *testing.T) {
 	}
 
 }
+
+func TestStateTrie(t *testing.T) {
+	db := NewMemStateStore(0)
+	keys := make([]*states.StorageKey, 0, 10)
+	for i := 0; i < 10; i++ {
+		keys = append(keys, &states.StorageKey{ContractAddress: common.ADDRESS_EMPTY, Key: []byte{byte(i)}})
+	}
+	writeSet := func(from, to int, value byte) *overlaydb.MemDB {
+		memdb := overlaydb.NewMemDB(0, 0)
+		for _, key := range keys[from:to] {
+			storeKey, _ := db.getStorageKey(key)
+			if value == 0 {
+				memdb.Delete(storeKey)
+			} else {
+				memdb.Put(storeKey, (&states.StorageItem{Value: []byte{value}}).ToArray())
+			}
+		}
+		return memdb
+	}
+	update := func(height uint32, ws *overlaydb.MemDB) {
+		db.NewBatch()
+		ws.ForEach(func(key, val []byte) {
+			if len(val) == 0 {
+				db.BatchDeleteRawKey(key)
+			} else {
+				db.BatchPutRawKeyVal(key, val)
+			}
+		})
+		assert.Nil(t, db.UpdateStateTrie(height, ws))
+		assert.Nil(t, db.CommitTo())
+	}
+	update(0, writeSet(0, 10, 1))
+	update(1, writeSet(0, 5, 2))
+	update(2, writeSet(5, 10, 0))
+
+	checkProof := func(height uint32, key *states.StorageKey, value byte) {
+		proof, err := db.GetStorageProof(key, height)
+		assert.Nil(t, err)
+		valueHash := merkle.EMPTY_HASH
+		if value == 0 {
+			assert.Nil(t, proof.Value)
+		} else {
+			assert.Equal(t, (&states.StorageItem{Value: []byte{value}}).ToArray(), proof.Value)
+			valueHash = sha256.Sum256(proof.Value)
+		}
+		assert.Nil(t, merkle.VerifySMTProof(proof.StateRoot, sha256.Sum256(proof.Key), valueHash, proof.Proof))
+	}
+	checkProof(0, keys[0], 1)
+	checkProof(0, keys[9], 1)
+	checkProof(1, keys[0], 2)
+	checkProof(1, keys[9], 1)
+	checkProof(2, keys[0], 2)
+	checkProof(2, keys[9], 0)
+
+	root, err := db.GetStateTrieRoot(2)
+	assert.Nil(t, err)
+	// the state trie built from all storage items has the same root
+	assert.Nil(t, db.BuildStateTrie(3))
+	root2, err := db.GetStateTrieRoot(3)
+	assert.Nil(t, err)
+	assert.Equal(t, root, root2)
+	height, err := db.GetStateTrieHeight()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), height)
+
+	_, err = db.GetStorageProof(keys[0], 4)
+	assert.NotNil(t, err)
+
+	// read history state at height 0 through state trie
+	root, err = db.GetStateTrieRoot(0)
+	assert.Nil(t, err)
+	history := newStateTrieStore(db, root)
+	storeKey, _ := db.getStorageKey(keys[9])
+	value, err := history.Get(storeKey)
+	assert.Nil(t, err)
+	assert.Equal(t, (&states.StorageItem{Value: []byte{1}}).ToArray(), value)
+	_, err = db.store.Get(storeKey)
+	assert.Equal(t, scom.ErrNotFound, err)
+	assert.NotNil(t, history.Put(storeKey, value))
+	// other states are not in state trie
+	bookkeeperKey, _ := db.getBookkeeperKey()
+	_, err = history.Get(bookkeeperKey)
+	assert.NotNil(t, err)
+
+	// the state trie of other version is rebuilt, and the roots before are not served
+	db.NewBatch()
+	db.store.BatchPut([]byte{byte(scom.SYS_STATE_TRIE_VER)}, []byte{0, 0, 0, 0, 0})
+	assert.Nil(t, db.CommitTo())
+	_, err = db.GetStateTrieRoot(3)
+	assert.Equal(t, scom.ErrNotFound, err)
+	assert.Nil(t, db.BuildStateTrie(3))
+	version, start, err := db.GetStateTrieVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, STATE_TRIE_VERSION, version)
+	assert.Equal(t, uint32(3), start)
+	root3, err := db.GetStateTrieRoot(3)
+	assert.Nil(t, err)
+	assert.Equal(t, root2, root3)
+	_, err = db.GetStateTrieRoot(2)
+	assert.Equal(t, scom.ErrNotFound, err)
+}
+
+func TestStateTriePrune(t *testing.T) {
+	db := NewMemStateStore(0)
+	keys := make([]*states.StorageKey, 0, 10)
+	for i := 0; i < 10; i++ {
+		keys = append(keys, &states.StorageKey{ContractAddress: common.ADDRESS_EMPTY, Key: []byte{byte(i)}})
+	}
+	for height := uint32(0); height < 4; height++ {
+		memdb := overlaydb.NewMemDB(0, 0)
+		for _, key := range keys {
+			storeKey, _ := db.getStorageKey(key)
+			memdb.Put(storeKey, (&states.StorageItem{Value: []byte{byte(height + 1)}}).ToArray())
+		}
+		db.NewBatch()
+		assert.Nil(t, db.UpdateStateTrie(height, memdb))
+		assert.Nil(t, db.CommitTo())
+	}
+	count := func(prefix scom.DataEntryPrefix) int {
+		n := 0
+		iter := db.store.NewIterator([]byte{byte(prefix)})
+		for iter.Next() {
+			n++
+		}
+		iter.Release()
+		return n
+	}
+	nodes, values := count(scom.DATA_STATE_TRIE_NODE), count(scom.DATA_STATE_TRIE_VALUE)
+	assert.Equal(t, 4, values)
+
+	assert.Nil(t, db.PruneStateTrie(2))
+	_, start, err := db.GetStateTrieVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), start)
+	for height := uint32(0); height < 2; height++ {
+		_, err = db.GetStateTrieRoot(height)
+		assert.Equal(t, scom.ErrNotFound, err)
+	}
+	assert.True(t, count(scom.DATA_STATE_TRIE_NODE) < nodes)
+	// the values only set at height 0 and 1 are swept
+	assert.Equal(t, 2, count(scom.DATA_STATE_TRIE_VALUE))
+	for height := uint32(2); height < 4; height++ {
+		for _, key := range keys {
+			proof, err := db.GetStorageProof(key, height)
+			assert.Nil(t, err)
+			assert.Equal(t, (&states.StorageItem{Value: []byte{byte(height + 1)}}).ToArray(), proof.Value)
+			assert.Nil(t, merkle.VerifySMTProof(proof.StateRoot, sha256.Sum256(proof.Key), sha256.Sum256(proof.Value), proof.Proof))
+		}
+	}
+	// prune before start height does nothing
+	assert.Nil(t, db.PruneStateTrie(1))
+	_, err = db.GetStateTrieRoot(2)
+	assert.Nil(t, err)
+
+	// the node written by block while pruning is not swept
+	iter := db.store.NewIterator([]byte{byte(scom.DATA_STATE_TRIE_NODE)})
+	assert.True(t, iter.First())
+	hash, err := common.Uint256ParseFromBytes(iter.Key()[1:])
+	assert.Nil(t, err)
+	node := append([]byte{}, iter.Value()...)
+	iter.Release()
+	db.pruneWritten = make(map[common.Uint256]bool)
+	db.NewBatch()
+	db.PutNode(hash, node)
+	assert.Nil(t, db.sweepStateTrie(scom.DATA_STATE_TRIE_NODE, nil))
+	assert.Nil(t, db.CommitTo())
+	db.pruneWritten = nil
+	assert.Equal(t, 1, count(scom.DATA_STATE_TRIE_NODE))
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ledgerstore
+
+import (
+	"errors"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	scom "github.com/OnyxPay/OnyxChain/core/store/common"
+)
+
+var errStateTrieReadOnly = errors.New("state trie store is read only")
+var errStateTrieIterator = errors.New("state trie store does not support iteration")
+var errStateTrieKey = errors.New("state trie store only contains contracts and storage items")
+
+//stateTrieStore is a read only view of contracts and storage items at the state trie root of history block.
+//Keys are hashed in state trie, so prefix iteration is not supported
+type stateTrieStore struct {
+	stateStore *StateStore
+	root       common.Uint256
+}
+
+func newStateTrieStore(stateStore *StateStore, root common.Uint256) *stateTrieStore {
+	return &stateTrieStore{
+		stateStore: stateStore,
+		root:       root,
+	}
+}
+
+func (self *stateTrieStore) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 || (key[0] != byte(scom.ST_CONTRACT) && key[0] != byte(scom.ST_STORAGE)) {
+		return nil, errStateTrieKey
+	}
+	return self.stateStore.GetStateTrieValue(self.root, key)
+}
+
+func (self *stateTrieStore) Has(key []byte) (bool, error) {
+	_, err := self.Get(key)
+	if err == scom.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (self *stateTrieStore) Put(key []byte, value []byte) error {
+	return errStateTrieReadOnly
+}
+
+func (self *stateTrieStore) Delete(key []byte) error {
+	return errStateTrieReadOnly
+}
+
+func (self *stateTrieStore) NewBatch() {
+}
+
+func (self *stateTrieStore) BatchPut(key []byte, value []byte) {
+}
+
+func (self *stateTrieStore) BatchDelete(key []byte) {
+}
+
+func (self *stateTrieStore) BatchCommit() error {
+	return errStateTrieReadOnly
+}
+
+func (self *stateTrieStore) Close() error {
+	return nil
+}
+
+func (self *stateTrieStore) NewIterator(prefix []byte) scom.StoreIterator {
+	return &errorIterator{err: errStateTrieIterator}
+}
+
+//errorIterator is an empty iterator with error
+type errorIterator struct {
+	err error
+}
+
+func (self *errorIterator) Next() bool {
+	return false
+}
+
+func (self *errorIterator) First() bool {
+	return false
+}
+
+func (self *errorIterator) Key() []byte {
+	return nil
+}
+
+func (self *errorIterator) Value() []byte {
+	return nil
+}
+
+func (self *errorIterator) Release() {
+}
+
+func (self *errorIterator) Error() error {
+	return self.err
+}
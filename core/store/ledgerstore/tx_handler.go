@@ -35,13 +35,17 @@ import (
 	"github.com/OnyxPay/OnyxChain/core/types"
 	"github.com/OnyxPay/OnyxChain/errors"
 	"github.com/OnyxPay/OnyxChain/smartcontract"
+	"github.com/OnyxPay/OnyxChain/smartcontract/context"
 	"github.com/OnyxPay/OnyxChain/smartcontract/event"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/global_params"
 	ninit "github.com/OnyxPay/OnyxChain/smartcontract/service/native/init"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/scheduler"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
 	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	"github.com/OnyxPay/OnyxChain/smartcontract/trace"
+	"github.com/OnyxPay/OnyxChain/vm/wasmvm/exec"
 )
 
 //HandleDeployTransaction deal with smart contract deploy transaction
@@ -97,6 +101,14 @@ func (self *StateStore) HandleDeployTransaction(store store.LedgerStore, overlay
 		cache.Commit()
 	}
 
+	if deploy.VmType == payload.WASMVM_TYPE {
+		if err := exec.VerifyCode(deploy.Code); err != nil {
+			notify.Notify = append(notify.Notify, notifies...)
+			notify.GasConsumed = gasConsumed
+			return fmt.Errorf("deploy wasm contract error: %s", err)
+		}
+	}
+
 	address := deploy.Address()
 	log.Infof("deploy contract address:%s", address.ToHexString())
 	// store contract message
@@ -115,9 +127,9 @@ func (self *StateStore) HandleDeployTransaction(store store.LedgerStore, overlay
 	return nil
 }
 
-//HandleInvokeTransaction deal with smart contract invoke transaction
+//HandleInvokeTransaction deal with smart contract invoke transaction, the contract execution is recorded to tracer if not nil
 func (self *StateStore) HandleInvokeTransaction(store store.LedgerStore, overlay *overlaydb.OverlayDB, cache *storage.CacheDB,
-	tx *types.Transaction, block *types.Block, notify *event.ExecuteNotify) error {
+	tx *types.Transaction, block *types.Block, notify *event.ExecuteNotify, tracer *trace.Tracer) error {
 	invoke := tx.Payload.(*payload.InvokeCode)
 	code := invoke.Code
 	sysTransFlag := bytes.Compare(code, ninit.COMMIT_DPOS_BYTES) == 0 || block.Header.Height == 0
@@ -193,12 +205,20 @@ func (self *StateStore) HandleInvokeTransaction(store store.LedgerStore, overlay
 		CacheDB: cache,
 		Store:   store,
 		Gas:     availableGasLimit - codeLenGasLimit,
+		Tracer:  tracer,
 	}
 
 	//start the smart contract executive function
-	engine, _ := sc.NewExecuteEngine(invoke.Code)
+	engine, _ := newInvokeEngine(&sc, tx.TxType, invoke.Code)
 
+	if tracer != nil {
+		cache.SetTracer(tracer)
+	}
 	_, err = engine.Invoke()
+	if tracer != nil {
+		tracer.Finish(sc.Gas, err)
+		cache.SetTracer(nil)
+	}
 
 	costGasLimit = availableGasLimit - sc.Gas
 	if costGasLimit < neovm.MIN_TRANSACTION_GAS {
@@ -242,6 +262,130 @@ func (self *StateStore) HandleInvokeTransaction(store store.LedgerStore, overlay
 	return nil
 }
 
+//HandleScheduledEntries execute the entries of scheduler contract due in block, the notify of each entry is saved under its entry hash
+func (self *StateStore) HandleScheduledEntries(store store.LedgerStore, overlay *overlaydb.OverlayDB, cache *storage.CacheDB,
+	block *types.Block) ([]*event.ExecuteNotify, error) {
+	config := &smartcontract.Config{
+		Time:      block.Header.Timestamp,
+		Height:    block.Header.Height,
+		Tx:        &types.Transaction{},
+		BlockHash: block.Hash(),
+	}
+	cache.Reset()
+	sc := smartcontract.SmartContract{
+		Config:  config,
+		CacheDB: cache,
+		Store:   store,
+		Gas:     math.MaxUint64,
+	}
+	service, _ := sc.NewNativeService()
+	entries, err := scheduler.PopDueEntries(service, block.Header.Height, block.Header.Timestamp,
+		scheduler.MAX_EXECUTE_PER_BLOCK, scheduler.MAX_GAS_PER_BLOCK)
+	if err != nil {
+		return nil, err
+	}
+	minGasPrice, err := scheduler.GetMinGasPrice(service)
+	if err != nil {
+		return nil, err
+	}
+	cache.Commit()
+
+	notifies := make([]*event.ExecuteNotify, 0, len(entries))
+	for _, entry := range entries {
+		cache.Reset()
+		notify, err := self.handleScheduledEntry(store, cache, block, entry, minGasPrice)
+		if overlay.Error() != nil {
+			return nil, fmt.Errorf("handleScheduledEntry entry %d error %s", entry.Id, overlay.Error())
+		}
+		if err != nil {
+			log.Debugf("handleScheduledEntry entry %d error %s", entry.Id, err)
+		}
+		if notify != nil {
+			notifies = append(notifies, notify)
+		}
+	}
+	return notifies, nil
+}
+
+//handleScheduledEntry execute the entry with the witness of owner and settle its fee, the execution is discarded if failed.
+//An entry under the min gas price of chain or over the max gas limit is not executed and its fee is refunded
+func (self *StateStore) handleScheduledEntry(store store.LedgerStore, cache *storage.CacheDB, block *types.Block,
+	entry *scheduler.Entry, minGasPrice uint64) (*event.ExecuteNotify, error) {
+	tx, err := entry.Transaction()
+	if err != nil {
+		return nil, err
+	}
+	config := &smartcontract.Config{
+		Time:      block.Header.Timestamp,
+		Height:    block.Header.Height,
+		Tx:        tx,
+		BlockHash: block.Hash(),
+	}
+	notify := &event.ExecuteNotify{TxHash: scheduler.EntryHash(entry.Id), State: event.CONTRACT_STATE_FAIL}
+
+	uintCodeGasPrice, ok := neovm.GAS_TABLE.Load(neovm.UINT_INVOKE_CODE_LEN_NAME)
+	if !ok {
+		return nil, errors.NewErr("[handleScheduledEntry] get UINT_INVOKE_CODE_LEN_NAME gas failed")
+	}
+	codeLenGasLimit := calcGasByCodeLen(len(entry.Code), uintCodeGasPrice.(uint64))
+	costGasLimit := entry.GasLimit
+	if entry.GasPrice < minGasPrice || entry.GasLimit > scheduler.MAX_ENTRY_GAS_LIMIT {
+		err = fmt.Errorf("gasPrice %d or gasLimit %d out of range", entry.GasPrice, entry.GasLimit)
+		costGasLimit = 0
+	} else if entry.GasLimit < codeLenGasLimit {
+		err = fmt.Errorf("gasLimit insufficient: need:%d actual:%d", codeLenGasLimit, entry.GasLimit)
+	} else {
+		sc := smartcontract.SmartContract{
+			Config:    config,
+			CacheDB:   cache,
+			Store:     store,
+			Gas:       entry.GasLimit - codeLenGasLimit,
+			Witnesses: []common.Address{entry.Owner},
+		}
+		engine, _ := sc.NewExecuteEngine(entry.Code)
+		_, err = engine.Invoke()
+		costGasLimit = entry.GasLimit - sc.Gas
+		if costGasLimit < neovm.MIN_TRANSACTION_GAS {
+			costGasLimit = neovm.MIN_TRANSACTION_GAS
+		}
+		if err == nil {
+			notify.Notify = append(notify.Notify, sc.Notifications...)
+		}
+	}
+	if err != nil {
+		cache.Reset()
+	}
+
+	costGas := costGasLimit * entry.GasPrice
+	settle := smartcontract.SmartContract{
+		Config:    config,
+		CacheDB:   cache,
+		Store:     store,
+		Gas:       math.MaxUint64,
+		Witnesses: []common.Address{utils.SchedulerContractAddress},
+	}
+	service, _ := settle.NewNativeService()
+	if e := scheduler.Settle(service, entry, costGas, err == nil); e != nil {
+		return nil, fmt.Errorf("settle entry %d error %s", entry.Id, e)
+	}
+	notify.Notify = append(notify.Notify, settle.Notifications...)
+	notify.Notify = append(notify.Notify, service.Notifications...)
+	notify.GasConsumed = costGas
+	if err == nil {
+		notify.State = event.CONTRACT_STATE_SUCCESS
+	}
+	cache.Commit()
+	return notify, err
+}
+
+// newInvokeEngine launch the vm matching the invoke transaction type
+func newInvokeEngine(sc *smartcontract.SmartContract, txType types.TransactionType, code []byte) (context.Engine, error) {
+	if txType == types.InvokeWasm {
+		return sc.NewWasmExecuteEngine(code)
+	}
+	return sc.NewExecuteEngine(code)
+}
+
 func SaveNotify(eventStore scommon.EventStore, txHash common.Uint256, notify *event.ExecuteNotify) error {
 	if !config.DefConfig.Common.EnableEventLog {
 		return nil
@@ -157,3 +157,11 @@ func (self *LevelDBStore) NewIterator(prefix []byte) common.StoreIterator {
 
 	return iter
 }
+
+//NewRangeIterator return a iterator of leveldb with the key range [start, limit). nil limit means no upper bound
+func (self *LevelDBStore) NewRangeIterator(start, limit []byte) common.StoreIterator {
+
+	iter := self.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
+
+	return iter
+}
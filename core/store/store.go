@@ -25,6 +25,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/core/states"
 	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
 	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/merkle"
 	"github.com/OnyxPay/OnyxChain/smartcontract/event"
 	cstates "github.com/OnyxPay/OnyxChain/smartcontract/states"
 )
@@ -36,12 +37,23 @@ type ExecuteResult struct {
 	Notify     []*event.ExecuteNotify
 }
 
+//StorageProof is the storage item at block height and its proof in the state trie.
+//The state trie root is kept by node locally, it is not committed in block header nor signed by bookkeepers
+type StorageProof struct {
+	Height    uint32
+	StateRoot common.Uint256
+	Key       []byte //Key of storage item in state store
+	Value     []byte //Serialized storage item, nil if key not exist
+	Proof     *merkle.SMTProof
+}
+
 // LedgerStore provides func with store package.
 type LedgerStore interface {
 	InitLedgerStoreWithGenesisBlock(genesisblock *types.Block, defaultBookkeeper []keypair.PublicKey) error
 	Close() error
 	AddHeaders(headers []*types.Header) error
 	AddBlock(block *types.Block, stateMerkleRoot common.Uint256) error
+	AddCheckpointBlocks(blocks []*types.Block) error      // called by block sync below the last checkpoint
 	ExecuteBlock(b *types.Block) (ExecuteResult, error)   // called by consensus
 	SubmitBlock(b *types.Block, exec ExecuteResult) error // called by consensus
 	GetStateMerkleRoot(height uint32) (result common.Uint256, err error)
@@ -49,6 +61,8 @@ type LedgerStore interface {
 	GetCurrentBlockHeight() uint32
 	GetCurrentHeaderHeight() uint32
 	GetCurrentHeaderHash() common.Uint256
+	GetCheckpoints() map[uint32]common.Uint256
+	GetLastCheckpoint() uint32
 	GetBlockHash(height uint32) common.Uint256
 	GetHeaderByHash(blockHash common.Uint256) (*types.Header, error)
 	GetHeaderByHeight(height uint32) (*types.Header, error)
@@ -62,7 +76,12 @@ type LedgerStore interface {
 	GetContractState(contractHash common.Address) (*payload.DeployCode, error)
 	GetBookkeeperState() (*states.BookkeeperState, error)
 	GetStorageItem(key *states.StorageKey) (*states.StorageItem, error)
+	GetStorageProof(key *states.StorageKey, height uint32) (*StorageProof, error)
 	PreExecuteContract(tx *types.Transaction) (*cstates.PreExecResult, error)
+	PreExecuteContractWithTrace(tx *types.Transaction) (*cstates.PreExecResult, error)
+	TraceTransaction(txHash common.Uint256) (*cstates.PreExecResult, error)
+	EstimateGas(tx *types.Transaction) (*cstates.GasEstimate, error)
 	GetEventNotifyByTx(tx common.Uint256) (*event.ExecuteNotify, error)
 	GetEventNotifyByBlock(height uint32) ([]*event.ExecuteNotify, error)
+	GetEventNotifyLogs(filter *event.EventFilter) ([]*event.NotifyEventLog, error)
 }
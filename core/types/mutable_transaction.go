@@ -52,6 +52,16 @@ func (self *MutableTransaction) IntoImmutable() (*Transaction, error) {
 	return TransactionFromRawBytes(sink.Bytes())
 }
 
+//UnsignedSize return the size of serialized transaction without signatures
+func (self *MutableTransaction) UnsignedSize() (int, error) {
+	sink := common.NewZeroCopySink(nil)
+	err := self.serializeUnsigned(sink)
+	if err != nil {
+		return 0, err
+	}
+	return int(sink.Size()), nil
+}
+
 func (self *MutableTransaction) Hash() common.Uint256 {
 	tx, err := self.IntoImmutable()
 	if err != nil {
@@ -156,7 +166,7 @@ func (tx *MutableTransaction) DeserializeUnsigned(r io.Reader) error {
 	}
 
 	switch tx.TxType {
-	case Invoke:
+	case Invoke, InvokeWasm:
 		tx.Payload = new(payload.InvokeCode)
 	case Deploy:
 		tx.Payload = new(payload.DeployCode)
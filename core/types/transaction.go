@@ -158,7 +158,7 @@ func (tx *Transaction) deserializationUnsigned(source *common.ZeroCopySource) er
 	copy(tx.Payer[:], buf)
 
 	switch tx.TxType {
-	case Invoke:
+	case Invoke, InvokeWasm:
 		pl := new(payload.InvokeCode)
 		err := pl.Deserialization(source)
 		if err != nil {
@@ -379,6 +379,7 @@ const (
 	Bookkeeper TransactionType = 0x02
 	Deploy     TransactionType = 0xd0
 	Invoke     TransactionType = 0xd1
+	InvokeWasm TransactionType = 0xd2
 )
 
 // Payload define the func for loading the payload data
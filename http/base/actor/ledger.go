@@ -22,6 +22,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/core/ledger"
 	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/store"
 	"github.com/OnyxPay/OnyxChain/core/types"
 	"github.com/OnyxPay/OnyxChain/smartcontract/event"
 	cstate "github.com/OnyxPay/OnyxChain/smartcontract/states"
@@ -72,6 +73,11 @@ func GetStorageItem(address common.Address, key []byte) ([]byte, error) {
 	return ledger.DefLedger.GetStorageItem(address, key)
 }
 
+//GetStorageProof from ledger
+func GetStorageProof(address common.Address, key []byte, height uint32) (*store.StorageProof, error) {
+	return ledger.DefLedger.GetStorageProof(address, key, height)
+}
+
 //GetContractStateFromStore from ledger
 func GetContractStateFromStore(hash common.Address) (*payload.DeployCode, error) {
 	hash = updateNativeSCAddr(hash)
@@ -89,6 +95,21 @@ func PreExecuteContract(tx *types.Transaction) (*cstate.PreExecResult, error) {
 	return ledger.DefLedger.PreExecuteContract(tx)
 }
 
+//PreExecuteContractWithTrace from ledger
+func PreExecuteContractWithTrace(tx *types.Transaction) (*cstate.PreExecResult, error) {
+	return ledger.DefLedger.PreExecuteContractWithTrace(tx)
+}
+
+//TraceTransaction from ledger
+func TraceTransaction(txHash common.Uint256) (*cstate.PreExecResult, error) {
+	return ledger.DefLedger.TraceTransaction(txHash)
+}
+
+//EstimateGas from ledger
+func EstimateGas(tx *types.Transaction) (*cstate.GasEstimate, error) {
+	return ledger.DefLedger.EstimateGas(tx)
+}
+
 //GetEventNotifyByTxHash from ledger
 func GetEventNotifyByTxHash(txHash common.Uint256) (*event.ExecuteNotify, error) {
 	return ledger.DefLedger.GetEventNotifyByTx(txHash)
@@ -99,6 +120,11 @@ func GetEventNotifyByHeight(height uint32) ([]*event.ExecuteNotify, error) {
 	return ledger.DefLedger.GetEventNotifyByBlock(height)
 }
 
+//GetEventNotifyLogs from ledger
+func GetEventNotifyLogs(filter *event.EventFilter) ([]*event.NotifyEventLog, error) {
+	return ledger.DefLedger.GetEventNotifyLogs(filter)
+}
+
 //GetMerkleProof from ledger
 func GetMerkleProof(proofHeight uint32, rootHeight uint32) ([]common.Uint256, error) {
 	return ledger.DefLedger.GetMerkleProof(proofHeight, rootHeight)
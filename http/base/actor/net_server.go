@@ -204,3 +204,57 @@ func GetNodeType() (uint64, error) {
 	}
 	return r.NodeType, nil
 }
+
+//GetBanList from netSever actor
+func GetBanList() ([]common.BanInfo, error) {
+	if netServerPid == nil {
+		return []common.BanInfo{}, nil
+	}
+	future := netServerPid.RequestFuture(&ac.GetBanListReq{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, err
+	}
+	r, ok := result.(*ac.GetBanListRsp)
+	if !ok {
+		return nil, errors.New("fail")
+	}
+	return r.List, nil
+}
+
+//BanPeer from netSever actor
+func BanPeer(ip string, id uint64, duration uint64) error {
+	if netServerPid == nil {
+		return errors.New("net server not started")
+	}
+	future := netServerPid.RequestFuture(&ac.BanPeerReq{IP: ip, ID: id, Duration: duration}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return err
+	}
+	r, ok := result.(*ac.BanPeerRsp)
+	if !ok {
+		return errors.New("fail")
+	}
+	return r.Error
+}
+
+//UnbanPeer from netSever actor
+func UnbanPeer(key string) (bool, error) {
+	if netServerPid == nil {
+		return false, errors.New("net server not started")
+	}
+	future := netServerPid.RequestFuture(&ac.UnbanPeerReq{Key: key}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return false, err
+	}
+	r, ok := result.(*ac.UnbanPeerRsp)
+	if !ok {
+		return false, errors.New("fail")
+	}
+	return r.Found, r.Error
+}
@@ -114,6 +114,21 @@ func GetTxFromPool(hash common.Uint256) (tcomn.TXEntry, error) {
 	return txnEntry, nil
 }
 
+//GetTxListFromPool return the verified and pending transactions paid by payer from txpool actor
+func GetTxListFromPool(payer common.Address) ([]*tcomn.TXEntry, []*tcomn.TXEntry, error) {
+	future := txnPid.RequestFuture(&tcomn.GetTxnListReq{Payer: payer}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return nil, nil, err
+	}
+	rsp, ok := result.(*tcomn.GetTxnListRsp)
+	if !ok {
+		return nil, nil, errors.New("fail")
+	}
+	return rsp.Verified, rsp.Pending, nil
+}
+
 //GetTxnCount from txpool actor
 func GetTxnCount() ([]uint32, error) {
 	future := txnPid.RequestFuture(&tcomn.GetTxnCountReq{}, REQ_TIMEOUT*time.Second)
@@ -128,3 +143,18 @@ func GetTxnCount() ([]uint32, error) {
 	}
 	return txnCnt.Count, nil
 }
+
+//GetGasPriceFromPool return the minimum gas price accepted by txpool
+func GetGasPriceFromPool() (uint64, error) {
+	future := txnPid.RequestFuture(&tcomn.GetGasPriceReq{}, REQ_TIMEOUT*time.Second)
+	result, err := future.Result()
+	if err != nil {
+		log.Errorf(ERR_ACTOR_COMM, err)
+		return 0, err
+	}
+	rsp, ok := result.(*tcomn.GetGasPriceRsp)
+	if !ok {
+		return 0, errors.New("fail")
+	}
+	return rsp.GasPrice, nil
+}
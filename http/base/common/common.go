@@ -30,26 +30,49 @@ import (
 	"github.com/OnyxPay/OnyxChain/common/serialization"
 	"github.com/OnyxPay/OnyxChain/core/ledger"
 	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/states"
+	"github.com/OnyxPay/OnyxChain/core/store"
 	"github.com/OnyxPay/OnyxChain/core/types"
 	cutils "github.com/OnyxPay/OnyxChain/core/utils"
 	onxErrors "github.com/OnyxPay/OnyxChain/errors"
 	bactor "github.com/OnyxPay/OnyxChain/http/base/actor"
 	"github.com/OnyxPay/OnyxChain/smartcontract/event"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/asset"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
 	cstate "github.com/OnyxPay/OnyxChain/smartcontract/states"
+	"github.com/OnyxPay/OnyxChain/smartcontract/trace"
+	tcomn "github.com/OnyxPay/OnyxChain/txnpool/common"
 	"github.com/OnyxPay/OnyxChain/vm/neovm"
+	"math/rand"
+	"sort"
 	"strings"
 	"time"
 )
 
 const MAX_SEARCH_HEIGHT uint32 = 100
 
+const (
+	DEFAULT_EVENT_LOG_LIMIT uint32 = 100  //default number of event logs of one query
+	MAX_EVENT_LOG_LIMIT     uint32 = 1000 //max number of event logs of one query
+)
+
 type BalanceOfRsp struct {
 	Onx string `json:"onyx"`
 	Oxg string `json:"oxg"`
 }
 
+type AssetInfo struct {
+	Id          uint64 `json:"id"`
+	Address     string `json:"address"`
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	Decimals    uint64 `json:"decimals"`
+	TotalSupply string `json:"totalSupply"`
+	Admin       string `json:"admin"`
+	Creator     string `json:"creator"`
+}
+
 type MerkleProof struct {
 	Type             string
 	TransactionsRoot string
@@ -59,6 +82,21 @@ type MerkleProof struct {
 	TargetHashes     []string
 }
 
+//StorageProof proof the storage item in state trie of block height.
+//Leaf key is sha256 of Key, leaf value is sha256 of RawValue. StateRoot is not part of block header, it is
+//computed by the serving node. The proof only binds the value to the StateRoot reported by the node, it does not
+//prove the value against consensus unless the StateRoot is cross checked with other nodes
+type StorageProof struct {
+	Height    uint32
+	StateRoot string
+	Key       string
+	Value     string
+	RawValue  string
+	Siblings  []string
+	LeafKey   string
+	LeafValue string
+}
+
 type LogEventArgs struct {
 	TxHash          string
 	ContractAddress string
@@ -77,6 +115,17 @@ type PreExecuteResult struct {
 	Gas    uint64
 	Result interface{}
 	Notify []NotifyEventInfo
+	Trace  *trace.Tracer `json:",omitempty"`
+}
+
+type GasEstimate struct {
+	GasLimit   uint64
+	GasPrice   uint64
+	Fee        uint64
+	CodeLenGas uint64
+	CreateGas  uint64
+	ExecGas    uint64
+	MinGas     uint64
 }
 
 type NotifyEventInfo struct {
@@ -84,6 +133,19 @@ type NotifyEventInfo struct {
 	States          interface{}
 }
 
+type EventLog struct {
+	Height          uint32
+	TxHash          string
+	EventIndex      uint32
+	ContractAddress string
+	States          interface{}
+}
+
+type EventLogsResult struct {
+	Events  []*EventLog
+	HasMore bool
+}
+
 type TxAttributeInfo struct {
 	Usage types.TransactionAttributeUsage
 	Data  string
@@ -169,6 +231,17 @@ type TXNEntryInfo struct {
 	State []TXNAttrInfo // the result from each validator
 }
 
+type MemPoolTxInfo struct {
+	TxHash   string
+	Payer    string
+	Nonce    uint32
+	GasPrice uint64
+	GasLimit uint64
+	Pending  bool          // whether the transaction is still under verification
+	State    []TXNAttrInfo // the result from each validator
+	Tx       *Transactions `json:",omitempty"`
+}
+
 func GetLogEvent(obj *event.LogEventArgs) (map[string]bool, LogEventArgs) {
 	hash := obj.TxHash
 	addr := obj.ContractAddress.ToHexString()
@@ -187,12 +260,159 @@ func GetExecuteNotify(obj *event.ExecuteNotify) (map[string]bool, ExecuteNotify)
 	return contractAddrs, ExecuteNotify{txhash, obj.State, obj.GasConsumed, evts}
 }
 
+//GetEventLogs query event logs of the filter. The limit of filter is normalized to [1, MAX_EVENT_LOG_LIMIT]
+func GetEventLogs(filter *event.EventFilter) (*EventLogsResult, error) {
+	if filter.Limit == 0 {
+		filter.Limit = DEFAULT_EVENT_LOG_LIMIT
+	}
+	if filter.Limit > MAX_EVENT_LOG_LIMIT {
+		filter.Limit = MAX_EVENT_LOG_LIMIT
+	}
+	limit := filter.Limit
+	//query one more event log to know whether there are more
+	filter.Limit++
+	logs, err := bactor.GetEventNotifyLogs(filter)
+	filter.Limit = limit
+	if err != nil {
+		return nil, err
+	}
+	result := &EventLogsResult{Events: make([]*EventLog, 0, len(logs))}
+	if uint32(len(logs)) > limit {
+		logs = logs[:limit]
+		result.HasMore = true
+	}
+	for _, l := range logs {
+		result.Events = append(result.Events, &EventLog{
+			Height:          l.Height,
+			TxHash:          l.TxHash.ToHexString(),
+			EventIndex:      l.NotifyIndex,
+			ContractAddress: l.Notify.ContractAddress.ToHexString(),
+			States:          l.Notify.States,
+		})
+	}
+	return result, nil
+}
+
+//GetMemPoolTxInfo convert transaction entry of txpool to MemPoolTxInfo, with transaction body if verbose
+func GetMemPoolTxInfo(entry *tcomn.TXEntry, pending bool, verbose bool) *MemPoolTxInfo {
+	txHash := entry.Tx.Hash()
+	info := &MemPoolTxInfo{
+		TxHash:   txHash.ToHexString(),
+		Payer:    entry.Tx.Payer.ToBase58(),
+		Nonce:    entry.Tx.Nonce,
+		GasPrice: entry.Tx.GasPrice,
+		GasLimit: entry.Tx.GasLimit,
+		Pending:  pending,
+		State:    []TXNAttrInfo{},
+	}
+	for _, t := range entry.Attrs {
+		info.State = append(info.State, TXNAttrInfo{t.Height, int(t.Type), int(t.ErrCode)})
+	}
+	if verbose {
+		info.Tx = TransArryByteToHexString(entry.Tx)
+	}
+	return info
+}
+
+//GetMemPoolTxInfos return the transactions in txpool paid by payer, empty payer means any payer.
+//Verified transactions come first, then transactions are ordered by gas price desc
+func GetMemPoolTxInfos(payer common.Address, verbose bool) ([]*MemPoolTxInfo, error) {
+	verified, pending, err := bactor.GetTxListFromPool(payer)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*MemPoolTxInfo, 0, len(verified)+len(pending))
+	for _, entry := range verified {
+		infos = append(infos, GetMemPoolTxInfo(entry, false, verbose))
+	}
+	for _, entry := range pending {
+		infos = append(infos, GetMemPoolTxInfo(entry, true, verbose))
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Pending != infos[j].Pending {
+			return !infos[i].Pending
+		}
+		if infos[i].GasPrice != infos[j].GasPrice {
+			return infos[i].GasPrice > infos[j].GasPrice
+		}
+		return infos[i].TxHash < infos[j].TxHash
+	})
+	return infos, nil
+}
+
+//GetMemPoolTx return the verified or pending transaction in txpool
+func GetMemPoolTx(hash common.Uint256) (*MemPoolTxInfo, error) {
+	verified, pending, err := bactor.GetTxListFromPool(common.ADDRESS_EMPTY)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range verified {
+		if entry.Tx.Hash() == hash {
+			return GetMemPoolTxInfo(entry, false, true), nil
+		}
+	}
+	for _, entry := range pending {
+		if entry.Tx.Hash() == hash {
+			return GetMemPoolTxInfo(entry, true, true), nil
+		}
+	}
+	return nil, nil
+}
+
 func ConvertPreExecuteResult(obj *cstate.PreExecResult) PreExecuteResult {
 	evts := []NotifyEventInfo{}
 	for _, v := range obj.Notify {
 		evts = append(evts, NotifyEventInfo{v.ContractAddress.ToHexString(), v.States})
 	}
-	return PreExecuteResult{obj.State, obj.Gas, obj.Result, evts}
+	return PreExecuteResult{obj.State, obj.Gas, obj.Result, evts, obj.Trace}
+}
+
+//EstimateGas return the recommended gas limit of transaction, the minimum gas price accepted by txpool and the fee to pay
+func EstimateGas(tx *types.Transaction) (*GasEstimate, error) {
+	estimate, err := bactor.EstimateGas(tx)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := bactor.GetGasPriceFromPool()
+	if err != nil {
+		return nil, err
+	}
+	fee, overflow := common.SafeMul(estimate.GasLimit, gasPrice)
+	if overflow {
+		return nil, fmt.Errorf("fee overflow, gas limit:%d gas price:%d", estimate.GasLimit, gasPrice)
+	}
+	return &GasEstimate{
+		GasLimit:   estimate.GasLimit,
+		GasPrice:   gasPrice,
+		Fee:        fee,
+		CodeLenGas: estimate.CodeLenGas,
+		CreateGas:  estimate.CreateGas,
+		ExecGas:    estimate.ExecGas,
+		MinGas:     estimate.MinGas,
+	}, nil
+}
+
+func TransStorageProof(proof *store.StorageProof) (*StorageProof, error) {
+	rsp := &StorageProof{
+		Height:    proof.Height,
+		StateRoot: proof.StateRoot.ToHexString(),
+		Key:       common.ToHexString(proof.Key),
+		RawValue:  common.ToHexString(proof.Value),
+		Siblings:  make([]string, 0, len(proof.Proof.Siblings)),
+		LeafKey:   proof.Proof.LeafKey.ToHexString(),
+		LeafValue: proof.Proof.LeafValue.ToHexString(),
+	}
+	if len(proof.Value) > 0 {
+		item := &states.StorageItem{}
+		if err := item.Deserialize(bytes.NewBuffer(proof.Value)); err != nil {
+			return nil, err
+		}
+		rsp.Value = common.ToHexString(item.Value)
+	}
+	for _, sibling := range proof.Proof.Siblings {
+		rsp.Siblings = append(rsp.Siblings, sibling.ToHexString())
+	}
+	return rsp, nil
 }
 
 func TransArryByteToHexString(ptx *types.Transaction) *Transactions {
@@ -325,6 +545,69 @@ func GetAllowance(asset string, from, to common.Address) (string, error) {
 	return fmt.Sprintf("%v", allowance), nil
 }
 
+//GetAssetBalance return the balance of address of asset created by the asset factory contract
+func GetAssetBalance(assetId uint64, address common.Address) (string, error) {
+	type balanceStruct struct {
+		AssetId uint64
+		Address common.Address
+	}
+	data, err := preExecuteNative(utils.AssetContractAddress, asset.BALANCEOF_NAME,
+		[]interface{}{&balanceStruct{AssetId: assetId, Address: address}})
+	if err != nil {
+		return "", fmt.Errorf("get asset balance error:%s", err)
+	}
+	return common.BigIntFromNeoBytes(data).String(), nil
+}
+
+//GetAssetInfo return the info of asset created by the asset factory contract, nil if not exist
+func GetAssetInfo(assetId uint64) (*AssetInfo, error) {
+	data, err := preExecuteNative(utils.AssetContractAddress, asset.GET_ASSET_NAME, []interface{}{assetId})
+	if err != nil {
+		return nil, fmt.Errorf("get asset error:%s", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	info := new(asset.AssetInfo)
+	if err := info.Deserialization(common.NewZeroCopySource(data)); err != nil {
+		return nil, fmt.Errorf("deserialize asset error:%s", err)
+	}
+	supply, err := preExecuteNative(utils.AssetContractAddress, asset.TOTALSUPPLY_NAME, []interface{}{assetId})
+	if err != nil {
+		return nil, fmt.Errorf("get asset total supply error:%s", err)
+	}
+	assetAddr := asset.GenAssetAddress(info.Id)
+	return &AssetInfo{
+		Id:          info.Id,
+		Address:     assetAddr.ToHexString(),
+		Name:        info.Name,
+		Symbol:      info.Symbol,
+		Decimals:    info.Decimals,
+		TotalSupply: common.BigIntFromNeoBytes(supply).String(),
+		Admin:       info.Admin.ToBase58(),
+		Creator:     string(info.Creator),
+	}, nil
+}
+
+func preExecuteNative(contractAddr common.Address, method string, params []interface{}) ([]byte, error) {
+	mutable, err := NewNativeInvokeTransaction(0, 0, contractAddr, 0, method, params)
+	if err != nil {
+		return nil, fmt.Errorf("NewNativeInvokeTransaction error:%s", err)
+	}
+	tx, err := mutable.IntoImmutable()
+	if err != nil {
+		return nil, err
+	}
+	result, err := bactor.PreExecuteContract(tx)
+	if err != nil {
+		return nil, fmt.Errorf("PrepareInvokeContract error:%s", err)
+	}
+	if result.State == 0 {
+		return nil, fmt.Errorf("prepare invoke failed")
+	}
+	return hex.DecodeString(result.Result.(string))
+}
+
 func GetContractBalance(cVersion byte, contractAddr, accAddr common.Address) (uint64, error) {
 	mutable, err := NewNativeInvokeTransaction(0, 0, contractAddr, cVersion, "balanceOf", []interface{}{accAddr[:]})
 	if err != nil {
@@ -457,13 +740,24 @@ func NewSmartContractTransaction(gasPrice, gasLimit uint64, invokeCode []byte) (
 		GasPrice: gasPrice,
 		GasLimit: gasLimit,
 		TxType:   types.Invoke,
-		Nonce:    uint32(time.Now().Unix()),
+		Nonce:    rand.Uint32(),
 		Payload:  invokePayload,
 		Sigs:     nil,
 	}
 	return tx, nil
 }
 
+//NewWasmVMInvokeTransaction return wasm vm smart contract invoke transaction,
+//invokeCode is a serialized ContractInvokeParam
+func NewWasmVMInvokeTransaction(gasPrice, gasLimit uint64, invokeCode []byte) (*types.MutableTransaction, error) {
+	tx, err := NewSmartContractTransaction(gasPrice, gasLimit, invokeCode)
+	if err != nil {
+		return nil, err
+	}
+	tx.TxType = types.InvokeWasm
+	return tx, nil
+}
+
 //BuildNeoVMInvokeCode build NeoVM Invoke code for params
 func BuildNeoVMInvokeCode(smartContractAddress common.Address, params []interface{}) ([]byte, error) {
 	builder := neovm.NewParamsBuilder(new(bytes.Buffer))
@@ -39,6 +39,8 @@ const (
 	UNKNOWN_ASSET       int64 = 44002
 	UNKNOWN_BLOCK       int64 = 44003
 	UNKNOWN_CONTRACT    int64 = 44004
+	PRUNED_DATA         int64 = 44005
+	UNKNOWN_STATE_PROOF int64 = 44006
 
 	INTERNAL_ERROR  int64 = 45001
 	SMARTCODE_ERROR int64 = 47001
@@ -63,6 +65,8 @@ var ErrMap = map[int64]string{
 	UNKNOWN_ASSET:       "UNKNOWN ASSET",
 	UNKNOWN_BLOCK:       "UNKNOWN BLOCK",
 	UNKNOWN_CONTRACT:    "UNKNOWN CONTRACT",
+	PRUNED_DATA:         "PRUNED DATA",
+	UNKNOWN_STATE_PROOF: "UNKNOWN STATE PROOF",
 
 	INTERNAL_ERROR:                           "INTERNAL ERROR",
 	SMARTCODE_ERROR:                          "SMARTCODE EXEC ERROR",
@@ -29,8 +29,10 @@ import (
 	bactor "github.com/OnyxPay/OnyxChain/http/base/actor"
 	bcomn "github.com/OnyxPay/OnyxChain/http/base/common"
 	berr "github.com/OnyxPay/OnyxChain/http/base/error"
+	"github.com/OnyxPay/OnyxChain/smartcontract/event"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
 	"strconv"
+	"strings"
 )
 
 const TLS_PORT int = 443
@@ -95,6 +97,9 @@ func GetBlockHash(cmd map[string]interface{}) map[string]interface{} {
 func getBlock(hash common.Uint256, getTxBytes bool) (interface{}, int64) {
 	block, err := bactor.GetBlockFromStore(hash)
 	if err != nil {
+		if err == scom.ErrPruned {
+			return nil, berr.PRUNED_DATA
+		}
 		return nil, berr.UNKNOWN_BLOCK
 	}
 	if block == nil {
@@ -141,6 +146,10 @@ func GetBlockHeightByTxHash(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
+	if err == scom.ErrPruned {
+		resp["Result"] = height
+		return resp
+	}
 	if err != nil {
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
@@ -170,6 +179,9 @@ func GetBlockTxsByHeight(cmd map[string]interface{}) map[string]interface{} {
 	}
 	block, err := bactor.GetBlockFromStore(hash)
 	if err != nil {
+		if err == scom.ErrPruned {
+			return ResponsePack(berr.PRUNED_DATA)
+		}
 		return ResponsePack(berr.UNKNOWN_BLOCK)
 	}
 	resp["Result"] = bcomn.GetBlockTransactions(block)
@@ -197,6 +209,9 @@ func GetBlockByHeight(cmd map[string]interface{}) map[string]interface{} {
 	}
 	index := uint32(height)
 	block, err := bactor.GetBlockByHeight(index)
+	if err == scom.ErrPruned {
+		return ResponsePack(berr.PRUNED_DATA)
+	}
 	if err != nil || block == nil {
 		return ResponsePack(berr.UNKNOWN_BLOCK)
 	}
@@ -221,6 +236,9 @@ func GetTransactionByHash(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
+	if err == scom.ErrPruned {
+		return ResponsePack(berr.PRUNED_DATA)
+	}
 	if tx == nil {
 		return ResponsePack(berr.UNKNOWN_TRANSACTION)
 	}
@@ -262,7 +280,7 @@ func SendRawTransaction(cmd map[string]interface{}) map[string]interface{} {
 	var hash common.Uint256
 	hash = txn.Hash()
 	log.Debugf("SendRawTransaction recv %s", hash.ToHexString())
-	if txn.TxType == types.Invoke || txn.TxType == types.Deploy {
+	if txn.TxType == types.Invoke || txn.TxType == types.InvokeWasm || txn.TxType == types.Deploy {
 		if preExec, ok := cmd["PreExec"].(string); ok && preExec == "1" {
 			rst, err := bactor.PreExecuteContract(txn)
 			if err != nil {
@@ -274,6 +292,15 @@ func SendRawTransaction(cmd map[string]interface{}) map[string]interface{} {
 			resp["Result"] = bcomn.ConvertPreExecuteResult(rst)
 			return resp
 		}
+		if preExec, ok := cmd["PreExec"].(string); ok && preExec == "2" {
+			rst, err := bactor.PreExecuteContractWithTrace(txn)
+			if err != nil {
+				log.Infof("PreExec: ", err)
+				resp = ResponsePack(berr.SMARTCODE_ERROR)
+			}
+			resp["Result"] = bcomn.ConvertPreExecuteResult(rst)
+			return resp
+		}
 	}
 	log.Debugf("SendRawTransaction send to txpool %s", hash.ToHexString())
 	if errCode, desc := bcomn.SendTxToPool(txn); errCode != onxErrors.ErrNoError {
@@ -287,6 +314,32 @@ func SendRawTransaction(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+//estimate the gas limit, gas price and fee of unsigned transaction
+func EstimateGas(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+
+	str, ok := cmd["Data"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	bys, err := common.HexToBytes(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	txn, err := types.TransactionFromRawBytes(bys)
+	if err != nil {
+		return ResponsePack(berr.INVALID_TRANSACTION)
+	}
+	estimate, err := bcomn.EstimateGas(txn)
+	if err != nil {
+		resp = ResponsePack(berr.SMARTCODE_ERROR)
+		resp["Result"] = err.Error()
+		return resp
+	}
+	resp["Result"] = estimate
+	return resp
+}
+
 //get smartcontract event by height
 func GetSmartCodeEventTxsByHeight(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -308,6 +361,9 @@ func GetSmartCodeEventTxsByHeight(cmd map[string]interface{}) map[string]interfa
 		if scom.ErrNotFound == err {
 			return ResponsePack(berr.SUCCESS)
 		}
+		if scom.ErrPruned == err {
+			return ResponsePack(berr.PRUNED_DATA)
+		}
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
 	eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
@@ -340,6 +396,9 @@ func GetSmartCodeEventByTxHash(cmd map[string]interface{}) map[string]interface{
 		if scom.ErrNotFound == err {
 			return ResponsePack(berr.SUCCESS)
 		}
+		if scom.ErrPruned == err {
+			return ResponsePack(berr.PRUNED_DATA)
+		}
 		return ResponsePack(berr.INTERNAL_ERROR)
 	}
 	if eventInfo == nil {
@@ -350,6 +409,60 @@ func GetSmartCodeEventByTxHash(cmd map[string]interface{}) map[string]interface{
 	return resp
 }
 
+//get smartcontract events of contracts and topics in block height range.
+//The range is limited to 10000 blocks if the query can not be served by event index
+func GetSmartCodeEvents(cmd map[string]interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return ResponsePack(berr.INVALID_METHOD)
+	}
+
+	resp := ResponsePack(berr.SUCCESS)
+
+	filter := &event.EventFilter{}
+	values := []*uint32{&filter.FromHeight, &filter.ToHeight, &filter.Offset, &filter.Limit}
+	for i, name := range []string{"From", "To", "Offset", "Limit"} {
+		param, _ := cmd[name].(string)
+		if len(param) == 0 {
+			if i < 2 {
+				return ResponsePack(berr.INVALID_PARAMS)
+			}
+			continue
+		}
+		v, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		*values[i] = uint32(v)
+	}
+	if filter.FromHeight > filter.ToHeight {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	if param, _ := cmd["Contracts"].(string); len(param) > 0 {
+		for _, str := range strings.Split(param, ",") {
+			address, err := bcomn.GetAddress(strings.TrimSpace(str))
+			if err != nil {
+				return ResponsePack(berr.INVALID_PARAMS)
+			}
+			filter.Contracts = append(filter.Contracts, address)
+		}
+	}
+	if param, _ := cmd["Topics"].(string); len(param) > 0 {
+		filter.Topics = strings.Split(param, ",")
+	}
+	result, err := bcomn.GetEventLogs(filter)
+	if err != nil {
+		if err == scom.ErrPruned {
+			return ResponsePack(berr.PRUNED_DATA)
+		}
+		if err == scom.ErrRangeTooLarge {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	resp["Result"] = result
+	return resp
+}
+
 //get contract state
 func GetContractState(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -405,6 +518,49 @@ func GetStorage(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+//get storage from contract with its proof in state trie of block height.
+//The state root is computed by this node and not signed by bookkeepers, the proof is only as trustworthy as the node,
+//cross check the state root with other nodes for a read not trusting this node
+func GetStorageProof(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Hash"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	str, ok = cmd["Key"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	key, err := common.HexToBytes(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	height := bactor.GetCurrentBlockHeight()
+	if str, ok := cmd["Height"].(string); ok && str != "" {
+		h, err := strconv.ParseUint(str, 10, 32)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		height = uint32(h)
+	}
+	proof, err := bactor.GetStorageProof(address, key, height)
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return ResponsePack(berr.UNKNOWN_STATE_PROOF)
+		}
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	resp["Result"], err = bcomn.TransStorageProof(proof)
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	return resp
+}
+
 //get balance of address
 func GetBalance(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -436,10 +592,10 @@ func GetMerkleProof(cmd map[string]interface{}) map[string]interface{} {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	height, tx, err := bactor.GetTxnWithHeightByTxHash(hash)
-	if err != nil {
+	if err != nil && err != scom.ErrPruned {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
-	if tx == nil {
+	if tx == nil && err == nil {
 		return ResponsePack(berr.INVALID_PARAMS)
 	}
 	header, err := bactor.GetHeaderByHeight(height)
@@ -507,6 +663,55 @@ func GetAllowance(cmd map[string]interface{}) map[string]interface{} {
 	return resp
 }
 
+//get balance of asset created by asset factory contract
+func GetAssetBalance(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	idStr, ok := cmd["Id"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	addrStr, ok := cmd["Addr"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	address, err := bcomn.GetAddress(addrStr)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	rsp, err := bcomn.GetAssetBalance(id, address)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	resp["Result"] = rsp
+	return resp
+}
+
+//get info of asset created by asset factory contract
+func GetAsset(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	idStr, ok := cmd["Id"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	rsp, err := bcomn.GetAssetInfo(id)
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	if rsp == nil {
+		return ResponsePack(berr.UNKNOWN_ASSET)
+	}
+	resp["Result"] = rsp
+	return resp
+}
+
 //get unbound oxg
 func GetUnboundOxg(cmd map[string]interface{}) map[string]interface{} {
 	resp := ResponsePack(berr.SUCCESS)
@@ -580,3 +785,56 @@ func GetMemPoolTxState(cmd map[string]interface{}) map[string]interface{} {
 	resp["Result"] = bcomn.TXNEntryInfo{attrs}
 	return resp
 }
+
+//get verified and pending transactions in memory pool
+func GetRawMemPool(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	payer := common.ADDRESS_EMPTY
+	if str, ok := cmd["Payer"].(string); ok && len(str) > 0 {
+		address, err := common.AddressFromBase58(str)
+		if err != nil {
+			return ResponsePack(berr.INVALID_PARAMS)
+		}
+		payer = address
+	}
+	verbose := false
+	if str, ok := cmd["Verbose"].(string); ok && str == "1" {
+		verbose = true
+	}
+	infos, err := bcomn.GetMemPoolTxInfos(payer, verbose)
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	if verbose {
+		resp["Result"] = infos
+		return resp
+	}
+	hashes := make([]string, 0, len(infos))
+	for _, info := range infos {
+		hashes = append(hashes, info.TxHash)
+	}
+	resp["Result"] = hashes
+	return resp
+}
+
+//get verified or pending transaction in memory pool
+func GetMemPoolTx(cmd map[string]interface{}) map[string]interface{} {
+	resp := ResponsePack(berr.SUCCESS)
+	str, ok := cmd["Hash"].(string)
+	if !ok {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	hash, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return ResponsePack(berr.INVALID_PARAMS)
+	}
+	info, err := bcomn.GetMemPoolTx(hash)
+	if err != nil {
+		return ResponsePack(berr.INTERNAL_ERROR)
+	}
+	if info == nil {
+		return ResponsePack(berr.UNKNOWN_TRANSACTION)
+	}
+	resp["Result"] = info
+	return resp
+}
@@ -21,6 +21,8 @@ package rpc
 import (
 	"bytes"
 	"encoding/hex"
+	"math"
+
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/config"
 	"github.com/OnyxPay/OnyxChain/common/log"
@@ -31,6 +33,7 @@ import (
 	bactor "github.com/OnyxPay/OnyxChain/http/base/actor"
 	bcomn "github.com/OnyxPay/OnyxChain/http/base/common"
 	berr "github.com/OnyxPay/OnyxChain/http/base/error"
+	"github.com/OnyxPay/OnyxChain/smartcontract/event"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
 )
 
@@ -70,6 +73,9 @@ func GetBlock(params []interface{}) map[string]interface{} {
 	}
 	block, err := bactor.GetBlockFromStore(hash)
 	if err != nil {
+		if err == scom.ErrPruned {
+			return responsePack(berr.PRUNED_DATA, "block pruned")
+		}
 		return responsePack(berr.UNKNOWN_BLOCK, "unknown block")
 	}
 	if len(params) >= 2 {
@@ -122,16 +128,67 @@ func GetConnectionCount(params []interface{}) map[string]interface{} {
 	return responseSuccess(count)
 }
 
+//get verified and pending transactions in memory pool
+//params: [verbose], [payer]. Return transaction hashes, or transactions with verified state if verbose is 1
 func GetRawMemPool(params []interface{}) map[string]interface{} {
-	txs := []*bcomn.Transactions{}
-	txpool := bactor.GetTxsFromPool(false)
-	for _, t := range txpool {
-		txs = append(txs, bcomn.TransArryByteToHexString(t))
+	verbose := false
+	if len(params) >= 1 {
+		switch params[0].(type) {
+		case float64:
+			verbose = params[0].(float64) == 1
+		default:
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+	}
+	payer := common.ADDRESS_EMPTY
+	if len(params) >= 2 {
+		str, ok := params[1].(string)
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		address, err := common.AddressFromBase58(str)
+		if err != nil {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		payer = address
+	}
+	infos, err := bcomn.GetMemPoolTxInfos(payer, verbose)
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, nil)
+	}
+	if verbose {
+		return responseSuccess(infos)
 	}
-	if len(txs) == 0 {
+	hashes := make([]string, 0, len(infos))
+	for _, info := range infos {
+		hashes = append(hashes, info.TxHash)
+	}
+	return responseSuccess(hashes)
+}
+
+//get verified or pending transaction in memory pool with its verified state
+func GetMemPoolTx(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
 		return responsePack(berr.INVALID_PARAMS, nil)
 	}
-	return responseSuccess(txs)
+	switch params[0].(type) {
+	case string:
+		str := params[0].(string)
+		hash, err := common.Uint256FromHexString(str)
+		if err != nil {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		info, err := bcomn.GetMemPoolTx(hash)
+		if err != nil {
+			return responsePack(berr.INTERNAL_ERROR, "")
+		}
+		if info == nil {
+			return responsePack(berr.UNKNOWN_TRANSACTION, "unknown transaction")
+		}
+		return responseSuccess(info)
+	default:
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
 }
 
 //get memory pool transaction count
@@ -188,6 +245,9 @@ func GetRawTransaction(params []interface{}) map[string]interface{} {
 		}
 		h, t, err := bactor.GetTxnWithHeightByTxHash(hash)
 		if err != nil {
+			if err == scom.ErrPruned {
+				return responsePack(berr.PRUNED_DATA, "transaction pruned")
+			}
 			return responsePack(berr.UNKNOWN_TRANSACTION, "unknown transaction")
 		}
 		height = h
@@ -256,9 +316,110 @@ func GetStorage(params []interface{}) map[string]interface{} {
 	return responseSuccess(common.ToHexString(value))
 }
 
+//get storage value of contract with its proof in state trie of block height, current block if height is omitted.
+//The state root is computed by this node and not signed by bookkeepers, the proof is only as trustworthy as the node,
+//cross check the state root with other nodes for a read not trusting this node
+//   {"jsonrpc": "2.0", "method": "getstorageproof", "params": ["code hash", "key", height], "id": 0}
+func GetStorageProof(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	str, ok = params[1].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	key, err := hex.DecodeString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	height := bactor.GetCurrentBlockHeight()
+	if len(params) >= 3 {
+		h, ok := params[2].(float64)
+		if !ok || h < 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		height = uint32(h)
+	}
+	proof, err := bactor.GetStorageProof(address, key, height)
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return responsePack(berr.UNKNOWN_STATE_PROOF, "state proof unavailable at height")
+		}
+		return responsePack(berr.INTERNAL_ERROR, err.Error())
+	}
+	rsp, err := bcomn.TransStorageProof(proof)
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(rsp)
+}
+
+//replay the block of committed transaction and return its execution trace. State proof must be enabled
+//(--enable-state-proof) before the block of transaction, the contracts iterating storage can not be traced
+//   {"jsonrpc": "2.0", "method": "tracetransaction", "params": ["transaction hash"], "id": 0}
+func TraceTransaction(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	hash, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	result, err := bactor.TraceTransaction(hash)
+	if err != nil {
+		if err == scom.ErrNotFound {
+			return responsePack(berr.UNKNOWN_TRANSACTION, "")
+		}
+		if err == scom.ErrPruned {
+			return responsePack(berr.PRUNED_DATA, "block pruned")
+		}
+		return responsePack(berr.SMARTCODE_ERROR, err.Error())
+	}
+	return responseSuccess(bcomn.ConvertPreExecuteResult(result))
+}
+
+//estimate the gas limit, gas price and fee of transaction, the transaction need not be signed
+// A JSON example for estimategas method as following:
+//   {"jsonrpc": "2.0", "method": "estimategas", "params": ["raw transactioin in hex"], "id": 0}
+func EstimateGas(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	str, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	raw, err := common.HexToBytes(str)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	txn, err := types.TransactionFromRawBytes(raw)
+	if err != nil {
+		return responsePack(berr.INVALID_TRANSACTION, "")
+	}
+	estimate, err := bcomn.EstimateGas(txn)
+	if err != nil {
+		return responsePack(berr.SMARTCODE_ERROR, err.Error())
+	}
+	return responseSuccess(estimate)
+}
+
 //send raw transaction
 // A JSON example for sendrawtransaction method as following:
 //   {"jsonrpc": "2.0", "method": "sendrawtransaction", "params": ["raw transactioin in hex"], "id": 0}
+//second param 1 pre-execute the transaction, 2 pre-execute with execution trace
 func SendRawTransaction(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
 		return responsePack(berr.INVALID_PARAMS, nil)
@@ -277,7 +438,7 @@ func SendRawTransaction(params []interface{}) map[string]interface{} {
 		}
 		hash = txn.Hash()
 		log.Debugf("SendRawTransaction recv %s", hash.ToHexString())
-		if txn.TxType == types.Invoke || txn.TxType == types.Deploy {
+		if txn.TxType == types.Invoke || txn.TxType == types.InvokeWasm || txn.TxType == types.Deploy {
 			if len(params) > 1 {
 				preExec, ok := params[1].(float64)
 				if ok && preExec == 1 {
@@ -288,6 +449,14 @@ func SendRawTransaction(params []interface{}) map[string]interface{} {
 					}
 					return responseSuccess(bcomn.ConvertPreExecuteResult(result))
 				}
+				if ok && preExec == 2 {
+					result, err := bactor.PreExecuteContractWithTrace(txn)
+					if err != nil {
+						log.Infof("PreExec: ", err)
+						return responsePack(berr.SMARTCODE_ERROR, bcomn.ConvertPreExecuteResult(result))
+					}
+					return responseSuccess(bcomn.ConvertPreExecuteResult(result))
+				}
 			}
 		}
 
@@ -368,6 +537,9 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 			if err == scom.ErrNotFound {
 				return responseSuccess(nil)
 			}
+			if err == scom.ErrPruned {
+				return responsePack(berr.PRUNED_DATA, "event pruned")
+			}
 			return responsePack(berr.INTERNAL_ERROR, "")
 		}
 		eInfos := make([]*bcomn.ExecuteNotify, 0, len(eventInfos))
@@ -388,6 +560,9 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 			if scom.ErrNotFound == err {
 				return responseSuccess(nil)
 			}
+			if scom.ErrPruned == err {
+				return responsePack(berr.PRUNED_DATA, "event pruned")
+			}
 			return responsePack(berr.INTERNAL_ERROR, "")
 		}
 		_, notify := bcomn.GetExecuteNotify(eventInfo)
@@ -398,6 +573,75 @@ func GetSmartCodeEvent(params []interface{}) map[string]interface{} {
 	return responsePack(berr.INVALID_PARAMS, "")
 }
 
+//get smartconstract events of contracts and topics in block height range
+//params: fromHeight, toHeight, [contracts], [topics], [offset], [limit]
+//The range is limited to 10000 blocks if the query can not be served by event index
+func GetSmartCodeEvents(params []interface{}) map[string]interface{} {
+	if !config.DefConfig.Common.EnableEventLog {
+		return responsePack(berr.INVALID_METHOD, "")
+	}
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, nil)
+	}
+	filter := &event.EventFilter{}
+	values := make([]uint32, 4)
+	for i, pos := range []int{0, 1, 4, 5} {
+		if pos >= len(params) {
+			break
+		}
+		v, ok := params[pos].(float64)
+		if !ok || v < 0 || v > math.MaxUint32 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		values[i] = uint32(v)
+	}
+	filter.FromHeight, filter.ToHeight, filter.Offset, filter.Limit = values[0], values[1], values[2], values[3]
+	if filter.FromHeight > filter.ToHeight {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	if len(params) > 2 && params[2] != nil {
+		contracts, ok := params[2].([]interface{})
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		for _, c := range contracts {
+			str, ok := c.(string)
+			if !ok {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			address, err := bcomn.GetAddress(str)
+			if err != nil {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			filter.Contracts = append(filter.Contracts, address)
+		}
+	}
+	if len(params) > 3 && params[3] != nil {
+		topics, ok := params[3].([]interface{})
+		if !ok {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		for _, t := range topics {
+			str, ok := t.(string)
+			if !ok {
+				return responsePack(berr.INVALID_PARAMS, "")
+			}
+			filter.Topics = append(filter.Topics, str)
+		}
+	}
+	result, err := bcomn.GetEventLogs(filter)
+	if err != nil {
+		if err == scom.ErrPruned {
+			return responsePack(berr.PRUNED_DATA, "event pruned")
+		}
+		if err == scom.ErrRangeTooLarge {
+			return responsePack(berr.INVALID_PARAMS, "height range too large")
+		}
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	return responseSuccess(result)
+}
+
 //get block height by transaction hash
 func GetBlockHeightByTxHash(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -413,7 +657,7 @@ func GetBlockHeightByTxHash(params []interface{}) map[string]interface{} {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		height, _, err := bactor.GetTxnWithHeightByTxHash(hash)
-		if err != nil {
+		if err != nil && err != scom.ErrPruned {
 			return responsePack(berr.INVALID_PARAMS, "")
 		}
 		return responseSuccess(height)
@@ -475,6 +719,49 @@ func GetAllowance(params []interface{}) map[string]interface{} {
 	return responseSuccess(rsp)
 }
 
+//get balance of asset created by asset factory contract
+func GetAssetBalance(params []interface{}) map[string]interface{} {
+	if len(params) < 2 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	id, ok := params[0].(float64)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	addrStr, ok := params[1].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	address, err := bcomn.GetAddress(addrStr)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	rsp, err := bcomn.GetAssetBalance(uint64(id), address)
+	if err != nil {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	return responseSuccess(rsp)
+}
+
+//get info of asset created by asset factory contract
+func GetAsset(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	id, ok := params[0].(float64)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	rsp, err := bcomn.GetAssetInfo(uint64(id))
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, "")
+	}
+	if rsp == nil {
+		return responsePack(berr.UNKNOWN_ASSET, "")
+	}
+	return responseSuccess(rsp)
+}
+
 //get merkle proof by transaction hash
 func GetMerkleProof(params []interface{}) map[string]interface{} {
 	if len(params) < 1 {
@@ -489,7 +776,7 @@ func GetMerkleProof(params []interface{}) map[string]interface{} {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	height, _, err := bactor.GetTxnWithHeightByTxHash(hash)
-	if err != nil {
+	if err != nil && err != scom.ErrPruned {
 		return responsePack(berr.INVALID_PARAMS, "")
 	}
 	header, err := bactor.GetHeaderByHeight(height)
@@ -528,6 +815,9 @@ func GetBlockTxsByHeight(params []interface{}) map[string]interface{} {
 		}
 		block, err := bactor.GetBlockFromStore(hash)
 		if err != nil {
+			if err == scom.ErrPruned {
+				return responsePack(berr.PRUNED_DATA, "block pruned")
+			}
 			return responsePack(berr.UNKNOWN_BLOCK, "")
 		}
 		return responseSuccess(bcomn.GetBlockTransactions(block))
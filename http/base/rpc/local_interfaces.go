@@ -19,13 +19,16 @@
 package rpc
 
 import (
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/OnyxPay/OnyxChain/common/log"
 	bactor "github.com/OnyxPay/OnyxChain/http/base/actor"
 	"github.com/OnyxPay/OnyxChain/http/base/common"
 	berr "github.com/OnyxPay/OnyxChain/http/base/error"
+	p2pcom "github.com/OnyxPay/OnyxChain/p2pserver/common"
 )
 
 const (
@@ -122,3 +125,64 @@ func SetDebugInfo(params []interface{}) map[string]interface{} {
 	}
 	return responsePack(berr.SUCCESS, true)
 }
+
+func GetBannedPeers(params []interface{}) map[string]interface{} {
+	list, err := bactor.GetBanList()
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, false)
+	}
+	return responseSuccess(list)
+}
+
+//BanPeer ban a peer ip or peer id, the optional second param is the ban time in second
+func BanPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	key, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	var ip string
+	var id uint64
+	if net.ParseIP(key) != nil {
+		ip = key
+	} else {
+		var err error
+		id, err = strconv.ParseUint(key, 10, 64)
+		if err != nil || id == 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+	}
+	duration := uint64(p2pcom.PEER_BAN_DURATION)
+	if len(params) > 1 {
+		secs, ok := params[1].(float64)
+		if !ok || secs <= 0 {
+			return responsePack(berr.INVALID_PARAMS, "")
+		}
+		duration = uint64(secs)
+	}
+	if err := bactor.BanPeer(ip, id, duration); err != nil {
+		return responsePack(berr.INTERNAL_ERROR, false)
+	}
+	return responsePack(berr.SUCCESS, true)
+}
+
+//UnbanPeer lift the ban of a peer ip or peer id
+func UnbanPeer(params []interface{}) map[string]interface{} {
+	if len(params) < 1 {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	key, ok := params[0].(string)
+	if !ok {
+		return responsePack(berr.INVALID_PARAMS, "")
+	}
+	found, err := bactor.UnbanPeer(key)
+	if err != nil {
+		return responsePack(berr.INTERNAL_ERROR, false)
+	}
+	if !found {
+		return responsePack(berr.INVALID_PARAMS, false)
+	}
+	return responsePack(berr.SUCCESS, true)
+}
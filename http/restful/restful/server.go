@@ -61,22 +61,29 @@ const (
 	GET_BLK_HASH          = "/api/v1/block/hash/:height"
 	GET_TX                = "/api/v1/transaction/:hash"
 	GET_STORAGE           = "/api/v1/storage/:hash/:key"
+	GET_STORAGE_PROOF     = "/api/v1/storageproof/:hash/:key"
 	GET_BALANCE           = "/api/v1/balance/:addr"
 	GET_CONTRACT_STATE    = "/api/v1/contract/:hash"
 	GET_SMTCOCE_EVT_TXS   = "/api/v1/smartcode/event/transactions/:height"
 	GET_SMTCOCE_EVTS      = "/api/v1/smartcode/event/txhash/:hash"
+	GET_SMTCOCE_EVT_LOGS  = "/api/v1/smartcode/events"
 	GET_BLK_HGT_BY_TXHASH = "/api/v1/block/height/txhash/:hash"
 	GET_MERKLE_PROOF      = "/api/v1/merkleproof/:hash"
 	GET_GAS_PRICE         = "/api/v1/gasprice"
 	GET_ALLOWANCE         = "/api/v1/allowance/:asset/:from/:to"
+	GET_ASSET_BALANCE     = "/api/v1/asset/balance/:id/:addr"
+	GET_ASSET             = "/api/v1/asset/:id"
 	GET_UNBOUNDOXG        = "/api/v1/unboundoxg/:addr"
 	GET_GRANTOXG          = "/api/v1/grantong/:addr"
 	GET_MEMPOOL_TXCOUNT   = "/api/v1/mempool/txcount"
 	GET_MEMPOOL_TXSTATE   = "/api/v1/mempool/txstate/:hash"
+	GET_MEMPOOL_TXS       = "/api/v1/mempool/txs"
+	GET_MEMPOOL_TX        = "/api/v1/mempool/tx/:hash"
 	GET_VERSION           = "/api/v1/version"
 	GET_NETWORKID         = "/api/v1/networkid"
 
-	POST_RAW_TX = "/api/v1/transaction"
+	POST_RAW_TX       = "/api/v1/transaction"
+	POST_ESTIMATE_GAS = "/api/v1/estimategas"
 )
 
 //init restful server
@@ -144,22 +151,29 @@ func (this *restServer) registryMethod() {
 		GET_CONTRACT_STATE:    {name: "getcontract", handler: rest.GetContractState},
 		GET_SMTCOCE_EVT_TXS:   {name: "getsmartcodeeventbyheight", handler: rest.GetSmartCodeEventTxsByHeight},
 		GET_SMTCOCE_EVTS:      {name: "getsmartcodeeventbyhash", handler: rest.GetSmartCodeEventByTxHash},
+		GET_SMTCOCE_EVT_LOGS:  {name: "getsmartcodeevents", handler: rest.GetSmartCodeEvents},
 		GET_BLK_HGT_BY_TXHASH: {name: "getblockheightbytxhash", handler: rest.GetBlockHeightByTxHash},
 		GET_STORAGE:           {name: "getstorage", handler: rest.GetStorage},
+		GET_STORAGE_PROOF:     {name: "getstorageproof", handler: rest.GetStorageProof},
 		GET_BALANCE:           {name: "getbalance", handler: rest.GetBalance},
 		GET_ALLOWANCE:         {name: "getallowance", handler: rest.GetAllowance},
 		GET_MERKLE_PROOF:      {name: "getmerkleproof", handler: rest.GetMerkleProof},
 		GET_GAS_PRICE:         {name: "getgasprice", handler: rest.GetGasPrice},
+		GET_ASSET_BALANCE:     {name: "getassetbalance", handler: rest.GetAssetBalance},
+		GET_ASSET:             {name: "getasset", handler: rest.GetAsset},
 		GET_UNBOUNDOXG:        {name: "getunboundoxg", handler: rest.GetUnboundOxg},
 		GET_GRANTOXG:          {name: "getgrantoxg", handler: rest.GetGrantOxg},
 		GET_MEMPOOL_TXCOUNT:   {name: "getmempooltxcount", handler: rest.GetMemPoolTxCount},
 		GET_MEMPOOL_TXSTATE:   {name: "getmempooltxstate", handler: rest.GetMemPoolTxState},
+		GET_MEMPOOL_TXS:       {name: "getrawmempool", handler: rest.GetRawMemPool},
+		GET_MEMPOOL_TX:        {name: "getmempooltx", handler: rest.GetMemPoolTx},
 		GET_VERSION:           {name: "getversion", handler: rest.GetNodeVersion},
 		GET_NETWORKID:         {name: "getnetworkid", handler: rest.GetNetworkId},
 	}
 
 	postMethodMap := map[string]Action{
-		POST_RAW_TX: {name: "sendrawtransaction", handler: rest.SendRawTransaction},
+		POST_RAW_TX:       {name: "sendrawtransaction", handler: rest.SendRawTransaction},
+		POST_ESTIMATE_GAS: {name: "estimategas", handler: rest.EstimateGas},
 	}
 	this.postMap = postMethodMap
 	this.getMap = getMethodMap
@@ -184,6 +198,8 @@ func (this *restServer) getPath(url string) string {
 		return GET_SMTCOCE_EVTS
 	} else if strings.Contains(url, strings.TrimRight(GET_BLK_HGT_BY_TXHASH, ":hash")) {
 		return GET_BLK_HGT_BY_TXHASH
+	} else if strings.Contains(url, strings.TrimRight(GET_STORAGE_PROOF, ":hash/:key")) {
+		return GET_STORAGE_PROOF
 	} else if strings.Contains(url, strings.TrimRight(GET_STORAGE, ":hash/:key")) {
 		return GET_STORAGE
 	} else if strings.Contains(url, strings.TrimRight(GET_BALANCE, ":addr")) {
@@ -192,12 +208,18 @@ func (this *restServer) getPath(url string) string {
 		return GET_MERKLE_PROOF
 	} else if strings.Contains(url, strings.TrimRight(GET_ALLOWANCE, ":asset/:from/:to")) {
 		return GET_ALLOWANCE
+	} else if strings.Contains(url, strings.TrimRight(GET_ASSET_BALANCE, ":id/:addr")) {
+		return GET_ASSET_BALANCE
+	} else if strings.Contains(url, strings.TrimRight(GET_ASSET, ":id")) {
+		return GET_ASSET
 	} else if strings.Contains(url, strings.TrimRight(GET_UNBOUNDOXG, ":addr")) {
 		return GET_UNBOUNDOXG
 	} else if strings.Contains(url, strings.TrimRight(GET_GRANTOXG, ":addr")) {
 		return GET_GRANTOXG
 	} else if strings.Contains(url, strings.TrimRight(GET_MEMPOOL_TXSTATE, ":hash")) {
 		return GET_MEMPOOL_TXSTATE
+	} else if strings.Contains(url, strings.TrimRight(GET_MEMPOOL_TX, ":hash")) {
+		return GET_MEMPOOL_TX
 	}
 	return url
 }
@@ -223,10 +245,17 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 		req["PreExec"] = r.FormValue("preExec")
 	case GET_STORAGE:
 		req["Hash"], req["Key"] = getParam(r, "hash"), getParam(r, "key")
+	case GET_STORAGE_PROOF:
+		req["Hash"], req["Key"] = getParam(r, "hash"), getParam(r, "key")
+		req["Height"] = r.FormValue("height")
 	case GET_SMTCOCE_EVT_TXS:
 		req["Height"] = getParam(r, "height")
 	case GET_SMTCOCE_EVTS:
 		req["Hash"] = getParam(r, "hash")
+	case GET_SMTCOCE_EVT_LOGS:
+		req["From"], req["To"] = r.FormValue("from"), r.FormValue("to")
+		req["Contracts"], req["Topics"] = r.FormValue("contracts"), r.FormValue("topics")
+		req["Offset"], req["Limit"] = r.FormValue("offset"), r.FormValue("limit")
 	case GET_BLK_HGT_BY_TXHASH:
 		req["Hash"] = getParam(r, "hash")
 	case GET_BALANCE:
@@ -236,12 +265,20 @@ func (this *restServer) getParams(r *http.Request, url string, req map[string]in
 	case GET_ALLOWANCE:
 		req["Asset"] = getParam(r, "asset")
 		req["From"], req["To"] = getParam(r, "from"), getParam(r, "to")
+	case GET_ASSET_BALANCE:
+		req["Id"], req["Addr"] = getParam(r, "id"), getParam(r, "addr")
+	case GET_ASSET:
+		req["Id"] = getParam(r, "id")
 	case GET_UNBOUNDOXG:
 		req["Addr"] = getParam(r, "addr")
 	case GET_GRANTOXG:
 		req["Addr"] = getParam(r, "addr")
 	case GET_MEMPOOL_TXSTATE:
 		req["Hash"] = getParam(r, "hash")
+	case GET_MEMPOOL_TXS:
+		req["Payer"], req["Verbose"] = r.FormValue("payer"), r.FormValue("verbose")
+	case GET_MEMPOOL_TX:
+		req["Hash"] = getParam(r, "hash")
 	default:
 	}
 	return req
@@ -191,10 +191,14 @@ func (self *WsServer) registryMethod() {
 		"getblockheight":            {handler: rest.GetBlockHeight},
 		"gettransaction":            {handler: rest.GetTransactionByHash},
 		"sendrawtransaction":        {handler: rest.SendRawTransaction, pushFlag: true},
+		"estimategas":               {handler: rest.EstimateGas},
 		"heartbeat":                 {handler: heartbeat},
 		"subscribe":                 {handler: subscribe},
 		"getstorage":                {handler: rest.GetStorage},
+		"getstorageproof":           {handler: rest.GetStorageProof},
 		"getallowance":              {handler: rest.GetAllowance},
+		"getassetbalance":           {handler: rest.GetAssetBalance},
+		"getasset":                  {handler: rest.GetAsset},
 		"getmerkleproof":            {handler: rest.GetMerkleProof},
 		"getblocktxsbyheight":       {handler: rest.GetBlockTxsByHeight},
 		"getgasprice":               {handler: rest.GetGasPrice},
@@ -202,6 +206,8 @@ func (self *WsServer) registryMethod() {
 		"getgrantoxg":               {handler: rest.GetGrantOxg},
 		"getmempooltxcount":         {handler: rest.GetMemPoolTxCount},
 		"getmempooltxstate":         {handler: rest.GetMemPoolTxState},
+		"getrawmempool":             {handler: rest.GetRawMemPool},
+		"getmempooltx":              {handler: rest.GetMemPoolTx},
 		"getversion":                {handler: rest.GetNodeVersion},
 		"getnetworkid":              {handler: rest.GetNetworkId},
 
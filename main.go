@@ -21,8 +21,10 @@ package main
 import (
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
@@ -74,6 +76,7 @@ func setupAPP() *cli.App {
 		cmd.ContractCommand,
 		cmd.ImportCommand,
 		cmd.ExportCommand,
+		cmd.SnapshotCommand,
 		cmd.TxCommond,
 		cmd.SigTxCommand,
 		cmd.MultiSigAddrCommand,
@@ -86,6 +89,10 @@ func setupAPP() *cli.App {
 		utils.ConfigFlag,
 		utils.LogLevelFlag,
 		utils.DisableEventLogFlag,
+		utils.EnableEventIndexFlag,
+		utils.EnableStateProofFlag,
+		utils.PruneBlocksFlag,
+		utils.CheckpointsFileFlag,
 		utils.DataDirFlag,
 		utils.CertFileFlag,
 		utils.KeyFileFlag,
@@ -93,18 +100,25 @@ func setupAPP() *cli.App {
 		utils.WalletFileFlag,
 		utils.AccountAddressFlag,
 		utils.AccountPassFlag,
+		utils.RemoteSignerFlag,
+		utils.RemoteSignerTokenFlag,
 		//consensus setting
 		utils.EnableConsensusFlag,
 		utils.MaxTxInBlockFlag,
 		//txpool setting
 		utils.GasPriceFlag,
 		utils.GasLimitFlag,
+		utils.MaxTxPerPayerFlag,
 		utils.TxpoolPreExecDisableFlag,
 		utils.DisableSyncVerifyTxFlag,
 		utils.DisableBroadcastNetTxFlag,
+		utils.DisableTxPoolJournalFlag,
 		//p2p setting
 		utils.ReservedPeersOnlyFlag,
 		utils.ReservedPeersFileFlag,
+		utils.NodeKeyFileFlag,
+		utils.DHTPortFlag,
+		utils.DiscoveryOnlyFlag,
 		utils.NetworkIdFlag,
 		utils.NodePortFlag,
 		utils.ConsensusPortFlag,
@@ -155,6 +169,16 @@ func startOnyxChain(ctx *cli.Context) {
 		log.Errorf("initConfig error:%s", err)
 		return
 	}
+	if cfg.P2PNode.DiscoveryOnly {
+		discovery, err := p2pserver.StartDiscovery()
+		if err != nil {
+			log.Errorf("StartDiscovery error:%s", err)
+			return
+		}
+		defer discovery.Stop()
+		waitToExit()
+		return
+	}
 	acc, err := initAccount(ctx)
 	if err != nil {
 		log.Errorf("initWallet error:%s", err)
@@ -172,6 +196,7 @@ func startOnyxChain(ctx *cli.Context) {
 		log.Errorf("initTxPool error:%s", err)
 		return
 	}
+	defer txpool.CloseJournal()
 	p2pSvr, p2pPid, err := initP2PNode(ctx, txpool)
 	if err != nil {
 		log.Errorf("initP2PNode error:%s", err)
@@ -221,6 +246,36 @@ func initAccount(ctx *cli.Context) (*account.Account, error) {
 	if !config.DefConfig.Consensus.EnableConsensus {
 		return nil, nil
 	}
+	var acc *account.Account
+	var err error
+	if remoteSigner := ctx.GlobalString(utils.GetFlagName(utils.RemoteSignerFlag)); remoteSigner != "" {
+		tokenFile := ctx.GlobalString(utils.GetFlagName(utils.RemoteSignerTokenFlag))
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("read remote signer token error:%s", err)
+		}
+		acc, err = account.NewRemoteAccount(remoteSigner, strings.TrimSpace(string(token)))
+		if err != nil {
+			return nil, fmt.Errorf("connect remote signer error:%s", err)
+		}
+	} else {
+		acc, err = initWalletAccount(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Infof("Using account:%s", acc.Address.ToBase58())
+
+	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
+		curPk := hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
+		config.DefConfig.Genesis.SOLO.Bookkeepers = []string{curPk}
+	}
+
+	log.Infof("Account init success")
+	return acc, nil
+}
+
+func initWalletAccount(ctx *cli.Context) (*account.Account, error) {
 	walletFile := ctx.GlobalString(utils.GetFlagName(utils.WalletFileFlag))
 	if walletFile == "" {
 		return nil, fmt.Errorf("Please config wallet file using --wallet flag")
@@ -233,14 +288,6 @@ func initAccount(ctx *cli.Context) (*account.Account, error) {
 	if err != nil {
 		return nil, fmt.Errorf("get account error:%s", err)
 	}
-	log.Infof("Using account:%s", acc.Address.ToBase58())
-
-	if config.DefConfig.Genesis.ConsensusType == config.CONSENSUS_TYPE_SOLO {
-		curPk := hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey))
-		config.DefConfig.Genesis.SOLO.Bookkeepers = []string{curPk}
-	}
-
-	log.Infof("Account init success")
 	return acc, nil
 }
 
@@ -289,6 +336,14 @@ func initTxPool(ctx *cli.Context) (*proc.TXPoolServer, error) {
 	hserver.SetTxnPoolPid(txPoolServer.GetPID(tc.TxPoolActor))
 	hserver.SetTxPid(txPoolServer.GetPID(tc.TxActor))
 
+	if !ctx.GlobalBool(utils.GetFlagName(utils.DisableTxPoolJournalFlag)) {
+		dbDir := utils.GetStoreDirPath(config.DefConfig.Common.DataDir, config.DefConfig.P2PNode.NetworkName)
+		err = txPoolServer.OpenJournal(filepath.Join(dbDir, tc.JOURNAL_FILE))
+		if err != nil {
+			return nil, fmt.Errorf("Init txpool journal error:%s", err)
+		}
+	}
+
 	log.Infof("TxPool init success")
 	return txPoolServer, nil
 }
@@ -0,0 +1,359 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/OnyxPay/OnyxChain/common"
+)
+
+const (
+	SMT_LEAF_NODE     byte = 0x00
+	SMT_INTERNAL_NODE byte = 0x01
+	SMT_NODE_SIZE          = 1 + 2*common.UINT256_SIZE
+	SMT_MAX_DEPTH          = 8 * common.UINT256_SIZE
+)
+
+//SMTNodeStore persist the nodes of sparse merkle tree by node hash
+type SMTNodeStore interface {
+	GetNode(hash common.Uint256) ([]byte, error)
+	PutNode(hash common.Uint256, node []byte)
+}
+
+//SMTUpdate set the value hash of key. EMPTY_HASH value delete the key
+type SMTUpdate struct {
+	Key   common.Uint256
+	Value common.Uint256
+}
+
+//SMTProof proof the value of a key in sparse merkle tree.
+//Siblings are ordered from root to leaf. LeafKey and LeafValue is the leaf reached by the path of key,
+//if LeafKey is EMPTY_HASH the path ends in an empty subtree
+type SMTProof struct {
+	Siblings  []common.Uint256
+	LeafKey   common.Uint256
+	LeafValue common.Uint256
+}
+
+//SparseMerkleTree is a binary merkle tree indexed by the bits of 256 bits key. Leaf is put at the
+//shallowest depth where no other key shares its path, so the root is determined only by the set of key values
+type SparseMerkleTree struct {
+	store SMTNodeStore
+	dirty map[common.Uint256][]byte
+}
+
+//NewSparseMerkleTree return sparse merkle tree which nodes saved in store
+func NewSparseMerkleTree(store SMTNodeStore) *SparseMerkleTree {
+	return &SparseMerkleTree{
+		store: store,
+		dirty: make(map[common.Uint256][]byte),
+	}
+}
+
+//Update apply updates to tree of root, return new root. New nodes are put to store
+func (self *SparseMerkleTree) Update(root common.Uint256, updates []SMTUpdate) (common.Uint256, error) {
+	ups := make([]SMTUpdate, len(updates))
+	copy(ups, updates)
+	sort.SliceStable(ups, func(i, j int) bool {
+		return keyLess(ups[i].Key, ups[j].Key)
+	})
+	// keep last update of the same key
+	n := 0
+	for i := range ups {
+		if n > 0 && ups[n-1].Key == ups[i].Key {
+			ups[n-1] = ups[i]
+		} else {
+			ups[n] = ups[i]
+			n++
+		}
+	}
+	newRoot, err := self.update(root, 0, ups[:n])
+	if err != nil {
+		self.dirty = make(map[common.Uint256][]byte)
+		return common.Uint256{}, err
+	}
+	for hash, node := range self.dirty {
+		self.store.PutNode(hash, node)
+	}
+	self.dirty = make(map[common.Uint256][]byte)
+	return newRoot, nil
+}
+
+//Get return value hash of key. EMPTY_HASH if key not in tree
+func (self *SparseMerkleTree) Get(root common.Uint256, key common.Uint256) (common.Uint256, error) {
+	proof, err := self.GetProof(root, key)
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	if proof.LeafKey != key {
+		return EMPTY_HASH, nil
+	}
+	return proof.LeafValue, nil
+}
+
+//GetProof return the proof of key in tree of root
+func (self *SparseMerkleTree) GetProof(root common.Uint256, key common.Uint256) (*SMTProof, error) {
+	proof := &SMTProof{}
+	node := root
+	for depth := 0; node != EMPTY_HASH; depth++ {
+		if depth > SMT_MAX_DEPTH {
+			return nil, errors.New("sparse merkle tree too deep")
+		}
+		typ, left, right, err := self.getNode(node)
+		if err != nil {
+			return nil, err
+		}
+		if typ == SMT_LEAF_NODE {
+			proof.LeafKey = left
+			proof.LeafValue = right
+			break
+		}
+		if keyBit(key, depth) == 0 {
+			proof.Siblings = append(proof.Siblings, right)
+			node = left
+		} else {
+			proof.Siblings = append(proof.Siblings, left)
+			node = right
+		}
+	}
+	return proof, nil
+}
+
+//Walk visit the nodes of tree of root in depth first order, left and right of leaf node are its key and value hash.
+//The children of node are skipped if visit return false
+func (self *SparseMerkleTree) Walk(root common.Uint256, visit func(hash common.Uint256, typ byte, left, right common.Uint256) bool) error {
+	return self.walk(root, 0, visit)
+}
+
+func (self *SparseMerkleTree) walk(node common.Uint256, depth int, visit func(hash common.Uint256, typ byte, left, right common.Uint256) bool) error {
+	if node == EMPTY_HASH {
+		return nil
+	}
+	if depth > SMT_MAX_DEPTH {
+		return errors.New("sparse merkle tree too deep")
+	}
+	typ, left, right, err := self.getNode(node)
+	if err != nil {
+		return err
+	}
+	if !visit(node, typ, left, right) || typ == SMT_LEAF_NODE {
+		return nil
+	}
+	err = self.walk(left, depth+1, visit)
+	if err != nil {
+		return err
+	}
+	return self.walk(right, depth+1, visit)
+}
+
+//VerifySMTProof verify the value hash of key in tree of root. EMPTY_HASH value verify key not in tree
+func VerifySMTProof(root common.Uint256, key common.Uint256, value common.Uint256, proof *SMTProof) error {
+	depth := len(proof.Siblings)
+	if depth > SMT_MAX_DEPTH {
+		return errors.New("proof too long")
+	}
+	node := EMPTY_HASH
+	if proof.LeafKey == key {
+		if value == EMPTY_HASH || proof.LeafValue != value {
+			return errors.New("leaf value mismatch")
+		}
+		node = hashSMTLeaf(proof.LeafKey, proof.LeafValue)
+	} else {
+		if value != EMPTY_HASH {
+			return errors.New("key not in tree")
+		}
+		if proof.LeafKey != EMPTY_HASH {
+			for i := 0; i < depth; i++ {
+				if keyBit(key, i) != keyBit(proof.LeafKey, i) {
+					return errors.New("leaf not in path of key")
+				}
+			}
+			node = hashSMTLeaf(proof.LeafKey, proof.LeafValue)
+		}
+	}
+	for i := depth - 1; i >= 0; i-- {
+		if keyBit(key, i) == 0 {
+			node = hashSMTInternal(node, proof.Siblings[i])
+		} else {
+			node = hashSMTInternal(proof.Siblings[i], node)
+		}
+	}
+	if node != root {
+		return fmt.Errorf("root mismatch, expect %s", root.ToHexString())
+	}
+	return nil
+}
+
+func (self *SparseMerkleTree) update(node common.Uint256, depth int, ups []SMTUpdate) (common.Uint256, error) {
+	if len(ups) == 0 {
+		return node, nil
+	}
+	if depth > SMT_MAX_DEPTH {
+		return common.Uint256{}, errors.New("sparse merkle tree too deep")
+	}
+	if node == EMPTY_HASH {
+		return self.build(depth, ups), nil
+	}
+	typ, left, right, err := self.getNode(node)
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	if typ == SMT_LEAF_NODE {
+		// rebuild the subtree from the old leaf and the updates
+		idx := sort.Search(len(ups), func(i int) bool {
+			return !keyLess(ups[i].Key, left)
+		})
+		if idx < len(ups) && ups[idx].Key == left {
+			return self.build(depth, ups), nil
+		}
+		leaves := make([]SMTUpdate, 0, len(ups)+1)
+		leaves = append(leaves, ups[:idx]...)
+		leaves = append(leaves, SMTUpdate{Key: left, Value: right})
+		leaves = append(leaves, ups[idx:]...)
+		return self.build(depth, leaves), nil
+	}
+	mid := sort.Search(len(ups), func(i int) bool {
+		return keyBit(ups[i].Key, depth) == 1
+	})
+	left, err = self.update(left, depth+1, ups[:mid])
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	right, err = self.update(right, depth+1, ups[mid:])
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	// lift the only leaf of subtree up
+	if left == EMPTY_HASH || right == EMPTY_HASH {
+		child := left
+		if child == EMPTY_HASH {
+			child = right
+		}
+		if child == EMPTY_HASH {
+			return EMPTY_HASH, nil
+		}
+		typ, _, _, err := self.getNode(child)
+		if err != nil {
+			return common.Uint256{}, err
+		}
+		if typ == SMT_LEAF_NODE {
+			return child, nil
+		}
+	}
+	return self.putInternal(left, right), nil
+}
+
+//build create subtree of sorted leaves, the leaves with EMPTY_HASH value are skipped
+func (self *SparseMerkleTree) build(depth int, leaves []SMTUpdate) common.Uint256 {
+	n := 0
+	for _, leaf := range leaves {
+		if leaf.Value != EMPTY_HASH {
+			n++
+		}
+	}
+	if n < len(leaves) {
+		filtered := make([]SMTUpdate, 0, n)
+		for _, leaf := range leaves {
+			if leaf.Value != EMPTY_HASH {
+				filtered = append(filtered, leaf)
+			}
+		}
+		leaves = filtered
+	}
+	return self.buildSorted(depth, leaves)
+}
+
+func (self *SparseMerkleTree) buildSorted(depth int, leaves []SMTUpdate) common.Uint256 {
+	switch len(leaves) {
+	case 0:
+		return EMPTY_HASH
+	case 1:
+		return self.putLeaf(leaves[0].Key, leaves[0].Value)
+	}
+	mid := sort.Search(len(leaves), func(i int) bool {
+		return keyBit(leaves[i].Key, depth) == 1
+	})
+	left := self.buildSorted(depth+1, leaves[:mid])
+	right := self.buildSorted(depth+1, leaves[mid:])
+	return self.putInternal(left, right)
+}
+
+func (self *SparseMerkleTree) getNode(hash common.Uint256) (byte, common.Uint256, common.Uint256, error) {
+	node, ok := self.dirty[hash]
+	if !ok {
+		var err error
+		node, err = self.store.GetNode(hash)
+		if err != nil {
+			return 0, common.Uint256{}, common.Uint256{}, fmt.Errorf("get node %s error %s", hash.ToHexString(), err)
+		}
+	}
+	if len(node) != SMT_NODE_SIZE || node[0] > SMT_INTERNAL_NODE {
+		return 0, common.Uint256{}, common.Uint256{}, fmt.Errorf("invalid node %s", hash.ToHexString())
+	}
+	var left, right common.Uint256
+	copy(left[:], node[1:1+common.UINT256_SIZE])
+	copy(right[:], node[1+common.UINT256_SIZE:])
+	return node[0], left, right, nil
+}
+
+func (self *SparseMerkleTree) putLeaf(key, value common.Uint256) common.Uint256 {
+	node := encodeSMTNode(SMT_LEAF_NODE, key, value)
+	hash := common.Uint256(sha256.Sum256(node))
+	self.dirty[hash] = node
+	return hash
+}
+
+func (self *SparseMerkleTree) putInternal(left, right common.Uint256) common.Uint256 {
+	node := encodeSMTNode(SMT_INTERNAL_NODE, left, right)
+	hash := common.Uint256(sha256.Sum256(node))
+	self.dirty[hash] = node
+	return hash
+}
+
+func encodeSMTNode(typ byte, left, right common.Uint256) []byte {
+	node := make([]byte, 0, SMT_NODE_SIZE)
+	node = append(node, typ)
+	node = append(node, left[:]...)
+	return append(node, right[:]...)
+}
+
+func hashSMTLeaf(key, value common.Uint256) common.Uint256 {
+	return sha256.Sum256(encodeSMTNode(SMT_LEAF_NODE, key, value))
+}
+
+func hashSMTInternal(left, right common.Uint256) common.Uint256 {
+	return sha256.Sum256(encodeSMTNode(SMT_INTERNAL_NODE, left, right))
+}
+
+func keyBit(key common.Uint256, i int) byte {
+	return (key[i/8] >> uint(7-i%8)) & 1
+}
+
+func keyLess(a, b common.Uint256) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type memNodeStore map[common.Uint256][]byte
+
+func (self memNodeStore) GetNode(hash common.Uint256) ([]byte, error) {
+	node, ok := self[hash]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return node, nil
+}
+
+func (self memNodeStore) PutNode(hash common.Uint256, node []byte) {
+	self[hash] = node
+}
+
+func smtTestUpdates(n int) []SMTUpdate {
+	ups := make([]SMTUpdate, n)
+	for i := range ups {
+		ups[i].Key = sha256.Sum256([]byte{byte(i), byte(i >> 8)})
+		ups[i].Value = sha256.Sum256([]byte{byte(i), 1})
+	}
+	return ups
+}
+
+func TestSparseMerkleTreeUpdate(t *testing.T) {
+	ups := smtTestUpdates(100)
+	tree := NewSparseMerkleTree(make(memNodeStore))
+	root, err := tree.Update(EMPTY_HASH, ups)
+	assert.Nil(t, err)
+	assert.NotEqual(t, EMPTY_HASH, root)
+
+	// root only depend on the set of key values
+	root2 := EMPTY_HASH
+	for i := len(ups) - 1; i >= 0; i-- {
+		root2, err = tree.Update(root2, ups[i:i+1])
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, root, root2)
+
+	value, err := tree.Get(root, ups[10].Key)
+	assert.Nil(t, err)
+	assert.Equal(t, ups[10].Value, value)
+
+	dels := make([]SMTUpdate, 50)
+	for i := range dels {
+		dels[i].Key = ups[i].Key
+	}
+	root3, err := tree.Update(root, dels)
+	assert.Nil(t, err)
+	expect, err := tree.Update(EMPTY_HASH, ups[50:])
+	assert.Nil(t, err)
+	assert.Equal(t, expect, root3)
+
+	for i := range ups {
+		ups[i].Value = EMPTY_HASH
+	}
+	root4, err := tree.Update(root, ups)
+	assert.Nil(t, err)
+	assert.Equal(t, EMPTY_HASH, root4)
+}
+
+func TestSparseMerkleTreeProof(t *testing.T) {
+	ups := smtTestUpdates(100)
+	tree := NewSparseMerkleTree(make(memNodeStore))
+	root, err := tree.Update(EMPTY_HASH, ups)
+	assert.Nil(t, err)
+
+	for _, up := range ups {
+		proof, err := tree.GetProof(root, up.Key)
+		assert.Nil(t, err)
+		assert.Nil(t, VerifySMTProof(root, up.Key, up.Value, proof))
+		assert.NotNil(t, VerifySMTProof(root, up.Key, EMPTY_HASH, proof))
+		assert.NotNil(t, VerifySMTProof(root, up.Key, ups[0].Key, proof))
+	}
+
+	for i := 100; i < 200; i++ {
+		key := common.Uint256(sha256.Sum256([]byte{byte(i), byte(i >> 8)}))
+		proof, err := tree.GetProof(root, key)
+		assert.Nil(t, err)
+		assert.Nil(t, VerifySMTProof(root, key, EMPTY_HASH, proof))
+		assert.NotNil(t, VerifySMTProof(root, key, ups[0].Value, proof))
+	}
+
+	proof, err := tree.GetProof(root, ups[0].Key)
+	assert.Nil(t, err)
+	// leaf of other key can not proof the key not in tree
+	assert.NotNil(t, VerifySMTProof(root, ups[1].Key, EMPTY_HASH, proof))
+
+	proof, err = tree.GetProof(EMPTY_HASH, ups[0].Key)
+	assert.Nil(t, err)
+	assert.Nil(t, VerifySMTProof(EMPTY_HASH, ups[0].Key, EMPTY_HASH, proof))
+}
@@ -66,3 +66,26 @@ func GetTransaction(hash common.Uint256) (*types.Transaction, error) {
 	}
 	return result.(tc.GetTxnRsp).Txn, nil
 }
+
+//get all txns in txnpool, verified and pending
+func GetTxnList() ([]*types.Transaction, error) {
+	if txnPoolPid == nil {
+		log.Warn("[p2p]net_server tx pool pid is nil")
+		return nil, errors.NewErr("[p2p]net_server tx pool pid is nil")
+	}
+	future := txnPoolPid.RequestFuture(&tc.GetTxnListReq{}, txnPoolReqTimeout)
+	result, err := future.Result()
+	if err != nil {
+		log.Warnf("[p2p]net_server GetTxnList error: %v\n", err)
+		return nil, err
+	}
+	rsp := result.(*tc.GetTxnListRsp)
+	txs := make([]*types.Transaction, 0, len(rsp.Verified)+len(rsp.Pending))
+	for _, entry := range rsp.Verified {
+		txs = append(txs, entry.Tx)
+	}
+	for _, entry := range rsp.Pending {
+		txs = append(txs, entry.Tx)
+	}
+	return txs, nil
+}
@@ -20,6 +20,7 @@ package server
 
 import (
 	"reflect"
+	"time"
 
 	"github.com/OnyxPay/OnyxChain-eventbus/actor"
 	"github.com/OnyxPay/OnyxChain/common/log"
@@ -86,6 +87,12 @@ func (this *P2PActor) Receive(ctx actor.Context) {
 		this.handleGetNodeTypeReq(ctx, msg)
 	case *TransmitConsensusMsgReq:
 		this.handleTransmitConsensusMsgReq(ctx, msg)
+	case *GetBanListReq:
+		this.handleGetBanListReq(ctx, msg)
+	case *BanPeerReq:
+		this.handleBanPeerReq(ctx, msg)
+	case *UnbanPeerReq:
+		this.handleUnbanPeerReq(ctx, msg)
 	case *common.AppendPeerID:
 		this.server.OnAddNode(msg.ID)
 	case *common.RemovePeerID:
@@ -243,3 +250,38 @@ func (this *P2PActor) handleTransmitConsensusMsgReq(ctx actor.Context, req *Tran
 		log.Warnf("[p2p]can`t transmit consensus msg:no valid neighbor peer: %d\n", req.Target)
 	}
 }
+
+//banned peers handler
+func (this *P2PActor) handleGetBanListReq(ctx actor.Context, req *GetBanListReq) {
+	list := this.server.GetNetWork().GetBanList()
+	if ctx.Sender() != nil {
+		resp := &GetBanListRsp{
+			List: list,
+		}
+		ctx.Sender().Request(resp, ctx.Self())
+	}
+}
+
+//ban peer handler
+func (this *P2PActor) handleBanPeerReq(ctx actor.Context, req *BanPeerReq) {
+	duration := time.Duration(req.Duration) * time.Second
+	err := this.server.GetNetWork().BanPeer(req.IP, req.ID, duration, "banned by rpc")
+	if ctx.Sender() != nil {
+		resp := &BanPeerRsp{
+			Error: err,
+		}
+		ctx.Sender().Request(resp, ctx.Self())
+	}
+}
+
+//unban peer handler
+func (this *P2PActor) handleUnbanPeerReq(ctx actor.Context, req *UnbanPeerReq) {
+	found, err := this.server.GetNetWork().UnbanPeer(req.Key)
+	if ctx.Sender() != nil {
+		resp := &UnbanPeerRsp{
+			Found: found,
+			Error: err,
+		}
+		ctx.Sender().Request(resp, ctx.Self())
+	}
+}
@@ -132,3 +132,35 @@ type TransmitConsensusMsgReq struct {
 	Target uint64
 	Msg    ptypes.Message
 }
+
+//get banned peers request
+type GetBanListReq struct {
+}
+
+//response of banned peers
+type GetBanListRsp struct {
+	List []types.BanInfo
+}
+
+//ban peer request, ip or peer id
+type BanPeerReq struct {
+	IP       string
+	ID       uint64
+	Duration uint64 //ban time in second
+}
+
+//response of ban peer request
+type BanPeerRsp struct {
+	Error error
+}
+
+//unban peer request, the key is ip or peer id
+type UnbanPeerReq struct {
+	Key string
+}
+
+//response of unban peer request
+type UnbanPeerRsp struct {
+	Found bool
+	Error error
+}
@@ -19,6 +19,7 @@
 package p2pserver
 
 import (
+	"fmt"
 	"math"
 	"sort"
 	"sync"
@@ -36,6 +37,7 @@ import (
 const (
 	SYNC_MAX_HEADER_FORWARD_SIZE = 5000       //keep CurrentHeaderHeight - CurrentBlockHeight <= SYNC_MAX_HEADER_FORWARD_SIZE
 	SYNC_MAX_FLIGHT_HEADER_SIZE  = 1          //Number of headers on flight
+	SYNC_MAX_FLIGHT_RANGE_SIZE   = 4          //Number of header ranges between checkpoints on flight, each from one node at a time
 	SYNC_MAX_FLIGHT_BLOCK_SIZE   = 50         //Number of blocks on flight
 	SYNC_MAX_BLOCK_CACHE_SIZE    = 500        //Cache size of block wait to commit to ledger
 	SYNC_HEADER_REQUEST_TIMEOUT  = 2          //s, Request header timeout time. If header haven't receive after SYNC_HEADER_REQUEST_TIMEOUT second, retry
@@ -213,6 +215,90 @@ func (this *SyncFlightInfo) GetStartTime() time.Time {
 	return this.startTime
 }
 
+//SyncHeaderRange record the headers between two checkpoints. The headers are downloaded backward
+//from the checkpoint at the end, so that every header received is linked to the checkpoint
+type SyncHeaderRange struct {
+	startHeight uint32            //Height of the header before the range, the lower checkpoint or the current header
+	startHash   common.Uint256    //Hash of the header before the range
+	endHeight   uint32            //Height of the checkpoint at the end of the range
+	nextHeight  uint32            //Height of the highest header of the next request
+	nextHash    common.Uint256    //Hash of the highest header of the next request
+	chunks      [][]*types.Header //Received headers, the higher chunk first
+	flight      *SyncFlightInfo   //Flight of the next request
+	lock        sync.RWMutex
+}
+
+//NewSyncHeaderRange return a new SyncHeaderRange instance
+func NewSyncHeaderRange(startHeight uint32, startHash common.Uint256, endHeight uint32, endHash common.Uint256) *SyncHeaderRange {
+	return &SyncHeaderRange{
+		startHeight: startHeight,
+		startHash:   startHash,
+		endHeight:   endHeight,
+		nextHeight:  endHeight,
+		nextHash:    endHash,
+		flight:      NewSyncFlightInfo(endHeight, 0),
+	}
+}
+
+//GetNext return the height and hash of the highest header of the next request
+func (this *SyncHeaderRange) GetNext() (uint32, common.Uint256) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.nextHeight, this.nextHash
+}
+
+//IsCompleted return whether all the headers of the range have been received
+func (this *SyncHeaderRange) IsCompleted() bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.nextHeight == this.startHeight
+}
+
+//AddHeaders check the sorted headers are the next chunk of the range and save them
+func (this *SyncHeaderRange) AddHeaders(headers []*types.Header) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	count := this.nextHeight - this.startHeight
+	if count > p2pComm.MAX_BLK_HDR_CNT {
+		count = p2pComm.MAX_BLK_HDR_CNT
+	}
+	if count == 0 || uint32(len(headers)) != count {
+		return fmt.Errorf("expect %d headers, got %d", count, len(headers))
+	}
+	last := headers[len(headers)-1]
+	if last.Height != this.nextHeight || last.Hash() != this.nextHash {
+		return fmt.Errorf("header %d is not linked to the range", last.Height)
+	}
+	for i := len(headers) - 1; i > 0; i-- {
+		if headers[i].Height != headers[i-1].Height+1 || headers[i].PrevBlockHash != headers[i-1].Hash() {
+			return fmt.Errorf("header %d is not linked to the next", headers[i-1].Height)
+		}
+	}
+	first := headers[0]
+	if first.Height == this.startHeight+1 && first.PrevBlockHash != this.startHash {
+		return fmt.Errorf("header %d is not linked to the start of the range", first.Height)
+	}
+	this.chunks = append(this.chunks, headers)
+	this.nextHeight = first.Height - 1
+	this.nextHash = first.PrevBlockHash
+	return nil
+}
+
+//GetHeaders return the received headers higher than height in ascending order
+func (this *SyncHeaderRange) GetHeaders(height uint32) []*types.Header {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	headers := make([]*types.Header, 0, this.endHeight-this.nextHeight)
+	for i := len(this.chunks) - 1; i >= 0; i-- {
+		for _, header := range this.chunks[i] {
+			if header.Height > height {
+				headers = append(headers, header)
+			}
+		}
+	}
+	return headers
+}
+
 //BlockInfo is used for saving block information in cache
 type BlockInfo struct {
 	nodeID     uint64
@@ -224,6 +310,7 @@ type BlockInfo struct {
 type BlockSyncMgr struct {
 	flightBlocks   map[common.Uint256][]*SyncFlightInfo //Map BlockHash => []SyncFlightInfo, using for manager all of those block flights
 	flightHeaders  map[uint32]*SyncFlightInfo           //Map HeaderHeight => SyncFlightInfo, using for manager all of those header flights
+	headerRanges   map[uint32]*SyncHeaderRange          //Map CheckpointHeight => SyncHeaderRange, using for manager the header ranges below checkpoints
 	blocksCache    map[uint32]*BlockInfo                //Map BlockHash => BlockInfo, using for cache the blocks receive from net, and waiting for commit to ledger
 	server         *P2PServer                           //Pointer to the local node
 	syncBlockLock  bool                                 //Help to avoid send block sync request duplicate
@@ -240,6 +327,7 @@ func NewBlockSyncMgr(server *P2PServer) *BlockSyncMgr {
 	return &BlockSyncMgr{
 		flightBlocks:  make(map[common.Uint256][]*SyncFlightInfo, 0),
 		flightHeaders: make(map[uint32]*SyncFlightInfo, 0),
+		headerRanges:  make(map[uint32]*SyncHeaderRange, 0),
 		blocksCache:   make(map[uint32]*BlockInfo, 0),
 		server:        server,
 		ledger:        server.ledger,
@@ -281,6 +369,12 @@ func (this *BlockSyncMgr) checkTimeout() {
 			}
 		}
 	}
+	rangeTimeouts := make([]*SyncHeaderRange, 0)
+	for _, rng := range this.headerRanges {
+		if !rng.IsCompleted() && int(now.Sub(rng.flight.GetStartTime()).Seconds()) >= SYNC_HEADER_REQUEST_TIMEOUT {
+			rangeTimeouts = append(rangeTimeouts, rng)
+		}
+	}
 	this.lock.RUnlock()
 
 	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
@@ -310,6 +404,16 @@ func (this *BlockSyncMgr) checkTimeout() {
 			this.appendReqTime(reqNode.GetID())
 		}
 	}
+	for _, rng := range rangeTimeouts {
+		this.addTimeoutCnt(rng.flight.GetNodeId())
+		if rng.endHeight <= curHeaderHeight {
+			this.delHeaderRange(rng.endHeight)
+			continue
+		}
+		rng.flight.MarkFailedNode()
+		log.Tracef("[p2p]checkTimeout sync header range from id:%d :%d timeout after:%d s Times:%d", rng.flight.GetNodeId(), rng.endHeight, SYNC_HEADER_REQUEST_TIMEOUT, rng.flight.GetTotalFailedTimes())
+		this.requestHeaderRange(rng)
+	}
 	for blockHash, flightInfos := range blockTimeoutFlights {
 		for _, flightInfo := range flightInfos {
 			this.addTimeoutCnt(flightInfo.GetNodeId())
@@ -352,6 +456,10 @@ func (this *BlockSyncMgr) syncHeader() {
 	}
 	defer this.releaseSyncHeaderLock()
 
+	if this.ledger.GetCurrentHeaderHeight() < this.ledger.GetLastCheckpoint() {
+		this.syncCheckpointHeaders()
+		return
+	}
 	if this.getFlightHeaderCount() >= SYNC_MAX_FLIGHT_HEADER_SIZE {
 		return
 	}
@@ -381,6 +489,117 @@ func (this *BlockSyncMgr) syncHeader() {
 	log.Infof("Header sync request height:%d", NextHeaderId)
 }
 
+//syncCheckpointHeaders download the header ranges between checkpoints from several nodes in parallel,
+//and add the completed ranges to ledger in order. At most SYNC_MAX_FLIGHT_RANGE_SIZE ranges are on flight,
+//and the headers of one range are requested batch by batch with one request on flight, so a range is downloaded
+//from one node at a time. The speedup is bounded by the number of ranges, and there is none without checkpoints
+func (this *BlockSyncMgr) syncCheckpointHeaders() {
+	this.commitHeaderRanges()
+
+	curBlockHeight := this.ledger.GetCurrentBlockHeight()
+	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
+	//Waiting for block catch up header
+	if curHeaderHeight-curBlockHeight >= SYNC_MAX_HEADER_FORWARD_SIZE {
+		return
+	}
+	checkpoints := this.ledger.GetCheckpoints()
+	heights := make([]uint32, 0, len(checkpoints))
+	for height := range checkpoints {
+		if height > curHeaderHeight {
+			heights = append(heights, height)
+		}
+	}
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+
+	startHeight, startHash := curHeaderHeight, this.ledger.GetCurrentHeaderHash()
+	for _, height := range heights {
+		if this.getHeaderRange(height) == nil {
+			if this.getHeaderRangeCount() >= SYNC_MAX_FLIGHT_RANGE_SIZE {
+				return
+			}
+			rng := NewSyncHeaderRange(startHeight, startHash, height, checkpoints[height])
+			if !this.requestHeaderRange(rng) {
+				return
+			}
+			this.addHeaderRange(rng)
+		}
+		startHeight, startHash = height, checkpoints[height]
+	}
+}
+
+//requestHeaderRange send the next headers request of the range, to the node not busy with other ranges if possible
+func (this *BlockSyncMgr) requestHeaderRange(rng *SyncHeaderRange) bool {
+	busyNodes := make(map[uint64]bool)
+	this.lock.RLock()
+	for _, r := range this.headerRanges {
+		if r != rng && !r.IsCompleted() {
+			busyNodes[r.flight.GetNodeId()] = true
+		}
+	}
+	this.lock.RUnlock()
+
+	var reqNode *peer.Peer
+	minScore := math.MaxInt64
+	for _, w := range this.getAllNodeWeights() {
+		n := this.server.getNode(w.id)
+		if n == nil || n.GetSyncState() != p2pComm.ESTABLISH || uint32(n.GetHeight()) < rng.endHeight {
+			continue
+		}
+		score := rng.flight.GetFailedTimes(w.id) * 2
+		if busyNodes[w.id] {
+			score++
+		}
+		if score < minScore {
+			minScore = score
+			reqNode = n
+		}
+	}
+	if reqNode == nil {
+		return false
+	}
+	rng.flight.SetNodeId(reqNode.GetID())
+	rng.flight.ResetStartTime()
+
+	nextHeight, nextHash := rng.GetNext()
+	msg := msgpack.NewHeadersRangeReq(nextHash, rng.startHash)
+	err := this.server.Send(reqNode, msg, false)
+	if err != nil {
+		log.Warnf("[p2p]requestHeaderRange failed to send a new headersReq:%s", err)
+	} else {
+		this.appendReqTime(reqNode.GetID())
+	}
+	log.Infof("Header sync request range:%d - %d", rng.startHeight+1, nextHeight)
+	return true
+}
+
+//commitHeaderRanges add the completed header ranges to ledger in order
+func (this *BlockSyncMgr) commitHeaderRanges() {
+	for {
+		curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
+		var next *SyncHeaderRange
+		this.lock.Lock()
+		for height, rng := range this.headerRanges {
+			if height <= curHeaderHeight {
+				delete(this.headerRanges, height)
+			} else if rng.startHeight <= curHeaderHeight {
+				next = rng
+			}
+		}
+		this.lock.Unlock()
+		if next == nil || !next.IsCompleted() {
+			return
+		}
+		err := this.ledger.AddHeaders(next.GetHeaders(curHeaderHeight))
+		if err != nil {
+			this.delHeaderRange(next.endHeight)
+			log.Warnf("[p2p]commitHeaderRanges AddHeaders error:%s", err)
+			return
+		}
+	}
+}
+
 func (this *BlockSyncMgr) syncBlock() {
 	if this.tryGetSyncBlockLock() {
 		return
@@ -458,6 +677,9 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID uint64, headers []*types.Header
 		return
 	}
 	log.Infof("Header receive height:%d - %d", headers[0].Height, headers[len(headers)-1].Height)
+	if this.onRangeHeaderReceive(fromID, headers) {
+		return
+	}
 	height := headers[0].Height
 	curHeaderHeight := this.ledger.GetCurrentHeaderHeight()
 
@@ -471,17 +693,57 @@ func (this *BlockSyncMgr) OnHeaderReceive(fromID uint64, headers []*types.Header
 	err := this.ledger.AddHeaders(headers)
 	this.delFlightHeader(height)
 	if err != nil {
-		this.addErrorRespCnt(fromID)
-		n := this.getNodeWeight(fromID)
-		if n != nil && n.GetErrorRespCnt() >= SYNC_MAX_ERROR_RESP_TIMES {
-			this.delNode(fromID)
-		}
+		this.onInvalidHeaders(fromID)
 		log.Warnf("[p2p]OnHeaderReceive AddHeaders error:%s", err)
 		return
 	}
 	this.syncHeader()
 }
 
+//onRangeHeaderReceive handle the headers of a range below checkpoint. Return false if no range requests them
+func (this *BlockSyncMgr) onRangeHeaderReceive(fromID uint64, headers []*types.Header) bool {
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Height < headers[j].Height
+	})
+	var rng *SyncHeaderRange
+	lastHeight := headers[len(headers)-1].Height
+	this.lock.RLock()
+	for _, r := range this.headerRanges {
+		if nextHeight, _ := r.GetNext(); nextHeight == lastHeight && !r.IsCompleted() {
+			rng = r
+			break
+		}
+	}
+	this.lock.RUnlock()
+	if rng == nil {
+		return false
+	}
+	err := rng.AddHeaders(headers)
+	if err != nil {
+		this.onInvalidHeaders(fromID)
+		log.Warnf("[p2p]onRangeHeaderReceive range:%d AddHeaders error:%s", rng.endHeight, err)
+		rng.flight.MarkFailedNode()
+		this.requestHeaderRange(rng)
+		return true
+	}
+	if rng.IsCompleted() {
+		this.syncHeader()
+		return true
+	}
+	this.requestHeaderRange(rng)
+	return true
+}
+
+//onInvalidHeaders record the error response of node, and penalize it
+func (this *BlockSyncMgr) onInvalidHeaders(fromID uint64) {
+	this.addErrorRespCnt(fromID)
+	n := this.getNodeWeight(fromID)
+	if n != nil && n.GetErrorRespCnt() >= SYNC_MAX_ERROR_RESP_TIMES {
+		this.delNode(fromID)
+	}
+	this.server.network.PenalizePeer(fromID, "", p2pComm.PENALTY_INVALID_HEADER, "invalid headers")
+}
+
 // OnBlockReceive receive block from net
 func (this *BlockSyncMgr) OnBlockReceive(fromID uint64, blockSize uint32, block *types.Block,
 	merkleRoot common.Uint256) {
@@ -630,6 +892,12 @@ func (this *BlockSyncMgr) saveBlock() {
 		}
 	}
 	this.lock.Unlock()
+	if nextBlockHeight <= this.ledger.GetLastCheckpoint() {
+		if !this.saveCheckpointBlocks(nextBlockHeight) {
+			return
+		}
+		nextBlockHeight = this.ledger.GetCurrentBlockHeight() + 1
+	}
 	for {
 		fromID, nextBlock, merkleRoot := this.getBlockCache(nextBlockHeight)
 		if nextBlock == nil {
@@ -638,25 +906,8 @@ func (this *BlockSyncMgr) saveBlock() {
 		err := this.ledger.AddBlock(nextBlock, merkleRoot)
 		this.delBlockCache(nextBlockHeight)
 		if err != nil {
-			this.addErrorRespCnt(fromID)
-			n := this.getNodeWeight(fromID)
-			if n != nil && n.GetErrorRespCnt() >= SYNC_MAX_ERROR_RESP_TIMES {
-				this.delNode(fromID)
-			}
 			log.Warnf("[p2p]saveBlock Height:%d AddBlock error:%s", nextBlockHeight, err)
-			reqNode := this.getNextNode(nextBlockHeight)
-			if reqNode == nil {
-				return
-			}
-			this.addFlightBlock(reqNode.GetID(), nextBlockHeight, nextBlock.Hash())
-			msg := msgpack.NewBlkDataReq(nextBlock.Hash())
-			err := this.server.Send(reqNode, msg, false)
-			if err != nil {
-				log.Warn("[p2p]require new block error:", err)
-				return
-			} else {
-				this.appendReqTime(reqNode.GetID())
-			}
+			this.onInvalidBlock(fromID, nextBlockHeight, nextBlock.Hash())
 			return
 		}
 		nextBlockHeight++
@@ -664,6 +915,63 @@ func (this *BlockSyncMgr) saveBlock() {
 	}
 }
 
+//saveCheckpointBlocks add the cached blocks not higher than the last checkpoint to ledger in one batch.
+//Return false if the blocks have not reached the last checkpoint
+func (this *BlockSyncMgr) saveCheckpointBlocks(nextBlockHeight uint32) bool {
+	lastCheckpoint := this.ledger.GetLastCheckpoint()
+	blocks := make([]*types.Block, 0, SYNC_MAX_BLOCK_CACHE_SIZE)
+	for height := nextBlockHeight; height <= lastCheckpoint; height++ {
+		fromID, block, _ := this.getBlockCache(height)
+		if block == nil {
+			break
+		}
+		blockHash := block.Hash()
+		if blockHash != this.ledger.GetBlockHash(height) {
+			this.delBlockCache(height)
+			log.Warnf("[p2p]saveCheckpointBlocks Height:%d block hash mismatch header", height)
+			this.onInvalidBlock(fromID, height, this.ledger.GetBlockHash(height))
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		return false
+	}
+	err := this.ledger.AddCheckpointBlocks(blocks)
+	for _, block := range blocks {
+		this.delBlockCache(block.Header.Height)
+	}
+	if err != nil {
+		log.Warnf("[p2p]saveCheckpointBlocks Height:%d - %d error:%s", nextBlockHeight, nextBlockHeight+uint32(len(blocks))-1, err)
+		return false
+	}
+	this.pingOutsyncNodes(this.ledger.GetCurrentBlockHeight())
+	return this.ledger.GetCurrentBlockHeight() >= lastCheckpoint
+}
+
+//onInvalidBlock penalize the node sending invalid block, and request the block from another node
+func (this *BlockSyncMgr) onInvalidBlock(fromID uint64, height uint32, blockHash common.Uint256) {
+	this.addErrorRespCnt(fromID)
+	n := this.getNodeWeight(fromID)
+	if n != nil && n.GetErrorRespCnt() >= SYNC_MAX_ERROR_RESP_TIMES {
+		this.delNode(fromID)
+	}
+	this.server.network.PenalizePeer(fromID, "", p2pComm.PENALTY_INVALID_BLOCK, "invalid block")
+	reqNode := this.getNextNode(height)
+	if reqNode == nil {
+		return
+	}
+	this.addFlightBlock(reqNode.GetID(), height, blockHash)
+	msg := msgpack.NewBlkDataReq(blockHash)
+	err := this.server.Send(reqNode, msg, false)
+	if err != nil {
+		log.Warn("[p2p]require new block error:", err)
+		return
+	} else {
+		this.appendReqTime(reqNode.GetID())
+	}
+}
+
 func (this *BlockSyncMgr) isInBlockCache(blockHeight uint32) bool {
 	this.lock.RLock()
 	defer this.lock.RUnlock()
@@ -715,6 +1023,30 @@ func (this *BlockSyncMgr) isHeaderOnFlight(height uint32) bool {
 	return flightInfo != nil
 }
 
+func (this *BlockSyncMgr) addHeaderRange(rng *SyncHeaderRange) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.headerRanges[rng.endHeight] = rng
+}
+
+func (this *BlockSyncMgr) getHeaderRange(endHeight uint32) *SyncHeaderRange {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.headerRanges[endHeight]
+}
+
+func (this *BlockSyncMgr) delHeaderRange(endHeight uint32) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.headerRanges, endHeight)
+}
+
+func (this *BlockSyncMgr) getHeaderRangeCount() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return len(this.headerRanges)
+}
+
 func (this *BlockSyncMgr) addFlightBlock(nodeId uint64, height uint32, blockHash common.Uint256) {
 	this.lock.Lock()
 	defer this.lock.Unlock()
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package p2pserver
+
+import (
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHeaders(count int) []*types.Header {
+	headers := make([]*types.Header, 0, count)
+	prev := &types.Header{Timestamp: 1}
+	headers = append(headers, prev)
+	for i := 1; i < count; i++ {
+		header := &types.Header{
+			Height:        uint32(i),
+			PrevBlockHash: prev.Hash(),
+			Timestamp:     prev.Timestamp + 1,
+		}
+		headers = append(headers, header)
+		prev = header
+	}
+	return headers
+}
+
+func TestSyncHeaderRange(t *testing.T) {
+	headers := newTestHeaders(1201)
+	rng := NewSyncHeaderRange(100, headers[100].Hash(), 1100, headers[1100].Hash())
+	assert.False(t, rng.IsCompleted())
+
+	//not the chunk ending with the checkpoint
+	assert.NotNil(t, rng.AddHeaders(headers[600:1100]))
+	//fork header
+	fork := make([]*types.Header, 500)
+	copy(fork, headers[601:1101])
+	fork[100] = &types.Header{Height: 701, PrevBlockHash: headers[700].Hash(), Timestamp: 1}
+	assert.NotNil(t, rng.AddHeaders(fork))
+
+	assert.Nil(t, rng.AddHeaders(headers[601:1101]))
+	height, hash := rng.GetNext()
+	assert.Equal(t, uint32(600), height)
+	assert.Equal(t, headers[600].Hash(), hash)
+	assert.False(t, rng.IsCompleted())
+
+	//more headers than the range
+	assert.NotNil(t, rng.AddHeaders(headers[100:601]))
+	assert.Nil(t, rng.AddHeaders(headers[101:601]))
+	assert.True(t, rng.IsCompleted())
+
+	received := rng.GetHeaders(300)
+	assert.Equal(t, 800, len(received))
+	for i, header := range received {
+		assert.Equal(t, uint32(301+i), header.Height)
+	}
+}
+
+func TestSyncHeaderRangeStart(t *testing.T) {
+	headers := newTestHeaders(200)
+	other := newTestHeaders(51)
+	other[50].Timestamp = 100
+	rng := NewSyncHeaderRange(50, other[50].Hash(), 199, headers[199].Hash())
+	assert.NotNil(t, rng.AddHeaders(headers[51:200]))
+	assert.False(t, rng.IsCompleted())
+}
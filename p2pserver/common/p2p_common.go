@@ -41,6 +41,7 @@ const (
 	REQ_INTERVAL        = 3          //single request max interval in second
 	MAX_REQ_RECORD_SIZE = 1000       //the maximum request record size
 	MAX_RESP_CACHE_SIZE = 50         //the maximum response cache
+	MAX_CMPCT_PENDING   = 16         //the maximum compact blocks waiting for txs
 )
 
 //msg cmd const
@@ -64,7 +65,7 @@ const (
 
 //info update const
 const (
-	PROTOCOL_VERSION      = 0     //protocol version
+	PROTOCOL_VERSION      = 1     //protocol version, 1 encrypts links and binds peer id to node key, not compatible with 0
 	UPDATE_RATE_PER_BLOCK = 2     //info update rate in one generate block period
 	KEEPALIVE_TIMEOUT     = 15    //contact timeout in sec
 	DIAL_TIMEOUT          = 6     //connect timeout in sec
@@ -73,6 +74,7 @@ const (
 	MAX_RETRY_COUNT       = 3     //max reconnect time of remote peer
 	CHAN_CAPABILITY       = 10000 //channel capability of recv link
 	SYNC_BLK_WAIT         = 2     //timespan for blk sync check
+	MAX_DHT_DIAL          = 8     //max dht nodes dialed in one round
 )
 
 // The peer state
@@ -87,7 +89,8 @@ const (
 
 //cap flag
 const (
-	HTTP_INFO_FLAG = 0 //peer`s http info bit in cap field
+	HTTP_INFO_FLAG   = 0 //peer`s http info bit in cap field
+	CMPCT_BLOCK_FLAG = 1 //peer`s compact block relay bit in cap field
 )
 
 //actor const
@@ -102,6 +105,27 @@ const (
 	RECENT_LIMIT     = 10 //recent contact list limit
 )
 
+//peer reputation const
+const (
+	PEER_SCORE_MAX     = 100          //initial and the maximum score of a peer
+	PEER_SCORE_RECOVER = 1            //score recovered per minute
+	PEER_BAN_THRESHOLD = 0            //peer is banned when its score drops to it
+	PEER_BAN_DURATION  = 24 * 60 * 60 //ban time in second
+	MAX_TX_PER_SECOND  = 1000         //the maximum txs per second from one peer
+	BAN_FILE_NAME      = "peers.ban"
+)
+
+//peer misbehavior penalty
+const (
+	PENALTY_MALFORMED_MSG  = 50 //bad magic, checksum or payload
+	PENALTY_INVALID_PEER   = 50 //peer id not bound to its node key
+	PENALTY_INVALID_HEADER = 20 //header rejected by ledger
+	PENALTY_INVALID_BLOCK  = 50 //block rejected by ledger
+	PENALTY_INVALID_CONS   = 20 //consensus payload fails to verify
+	PENALTY_INVALID_REQ    = 10 //request or response out of protocol
+	PENALTY_TX_SPAM        = 5  //txs over the rate limit
+)
+
 //PeerAddr represent peer`s net information
 type PeerAddr struct {
 	Time          int64    //latest timestamp
@@ -130,8 +154,20 @@ const (
 	GET_BLOCKS_TYPE  = "getblocks"  //req blks from peer
 	NOT_FOUND_TYPE   = "notfound"   //peer can`t find blk according to the hash
 	DISCONNECT_TYPE  = "disconnect" //peer disconnect info raise by link
+
+	CMPCT_BLOCK_TYPE   = "cmpctblock"  //blk header with short tx ids
+	GET_BLOCK_TXN_TYPE = "getblocktxn" //req missing txs of compact blk
+	BLOCK_TXN_TYPE     = "blocktxn"    //missing txs of compact blk
 )
 
+//BanInfo represent a banned ip or peer
+type BanInfo struct {
+	IP     string //banned ip address
+	ID     uint64 //banned peer id, 0 if unknown
+	Until  int64  //unix time when the ban expires
+	Reason string //why the peer is banned
+}
+
 type AppendPeerID struct {
 	ID uint64 // The peer id
 }
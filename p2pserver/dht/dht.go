@@ -0,0 +1,577 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+//Package dht implement the kademlia style node discovery over udp. Every node is identified by
+//its node key, the endpoint info is carried in records signed by the key
+package dht
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	TABLE_FILE_NAME     = "nodes.json" //Default routing table file name in data dir
+	ALPHA               = 3            //concurrent requests in lookup
+	RESPONSE_TIMEOUT    = 500          //request timeout in ms
+	REFRESH_INTERVAL    = 60           //interval of random lookup in sec
+	REVALIDATE_INTERVAL = 10           //interval of liveness check in sec
+	BOND_EXPIRATION     = 12 * 60 * 60 //sec an endpoint stays verified after ping/pong
+	MAX_BONDS           = 4096         //max endpoints kept verified
+	PING_WORKERS        = 4            //workers pinging the nodes not verified yet
+	PING_QUEUE_SIZE     = 64           //pending pings, more are dropped
+)
+
+var (
+	errTimeout = errors.New("[p2p]dht request timeout")
+	errClosed  = errors.New("[p2p]dht closed")
+)
+
+//Config is the option of DHT
+type Config struct {
+	Key        ed25519.PrivateKey
+	Magic      uint32   //network magic, packets of other network are dropped
+	ListenAddr string   //udp listen address
+	TCPPort    uint16   //p2p sync port announced in record, 0 for discovery only node
+	Bootnodes  []string //udp address of nodes used when the table is empty
+	TablePath  string   //file the table is persisted to, not persisted if empty
+}
+
+type reply struct {
+	pkt  packet
+	from ed25519.PublicKey
+	addr *net.UDPAddr
+}
+
+type pendingReq struct {
+	kind byte
+	key  ed25519.PublicKey //nil if the key of remote node is unknown
+	ch   chan *reply
+}
+
+//DHT is the discovery service
+type DHT struct {
+	conf    Config
+	conn    *net.UDPConn
+	self    *Record
+	selfID  NodeID
+	table   *Table
+	lock    sync.Mutex
+	pending map[uint64]*pendingReq
+	quit    chan bool
+	wg      sync.WaitGroup
+
+	bondLock  sync.Mutex
+	verified  map[string]time.Time //endpoints answered our ping
+	pinged    map[string]time.Time //endpoints pinged us
+	pingQueue chan *Node
+}
+
+//NewDHT listen on the udp address and load the saved routing table
+func NewDHT(conf Config) (*DHT, error) {
+	addr, err := net.ResolveUDPAddr("udp", conf.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	udpPort := uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+	self := NewRecord(conf.Key, udpPort, conf.TCPPort, uint64(time.Now().Unix()))
+	this := &DHT{
+		conf:    conf,
+		conn:    conn,
+		self:    self,
+		selfID:  self.ID(),
+		table:   NewTable(self.ID()),
+		pending: make(map[uint64]*pendingReq),
+		quit:    make(chan bool),
+
+		verified:  make(map[string]time.Time),
+		pinged:    make(map[string]time.Time),
+		pingQueue: make(chan *Node, PING_QUEUE_SIZE),
+	}
+	if conf.TablePath != "" {
+		err = this.table.Load(conf.TablePath)
+		if err != nil {
+			log.Warnf("[p2p]load dht table %s error:%s", conf.TablePath, err)
+		}
+	}
+	return this, nil
+}
+
+//Start serve the requests and keep the table fresh
+func (this *DHT) Start() {
+	this.wg.Add(2 + PING_WORKERS)
+	go this.readLoop()
+	go this.refreshLoop()
+	for i := 0; i < PING_WORKERS; i++ {
+		go this.pingLoop()
+	}
+}
+
+//Stop close the socket and save the table
+func (this *DHT) Stop() {
+	close(this.quit)
+	this.conn.Close()
+	this.wg.Wait()
+	this.saveTable()
+}
+
+//Self return the record of local node
+func (this *DHT) Self() *Record {
+	return this.self
+}
+
+//LocalAddr return the udp address listened
+func (this *DHT) LocalAddr() *net.UDPAddr {
+	return this.conn.LocalAddr().(*net.UDPAddr)
+}
+
+//Table return the routing table
+func (this *DHT) Table() *Table {
+	return this.table
+}
+
+//Nodes return the nodes in table accepting p2p connections in random order
+func (this *DHT) Nodes() []*Node {
+	all := this.table.Nodes()
+	nodes := make([]*Node, 0, len(all))
+	for _, n := range all {
+		if n.TCPPort != 0 {
+			nodes = append(nodes, n)
+		}
+	}
+	for i := len(nodes) - 1; i > 0; i-- {
+		j := randInt(i + 1)
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+	return nodes
+}
+
+//Bootstrap ping the boot nodes and look up self to fill the table
+func (this *DHT) Bootstrap() {
+	var wg sync.WaitGroup
+	for _, b := range this.conf.Bootnodes {
+		addr, err := net.ResolveUDPAddr("udp", b)
+		if err != nil {
+			log.Warnf("[p2p]resolve boot node %s error:%s", b, err)
+			continue
+		}
+		wg.Add(1)
+		go func(addr *net.UDPAddr) {
+			defer wg.Done()
+			_, err := this.Ping(addr, nil)
+			if err != nil {
+				log.Debugf("[p2p]ping boot node %s error:%s", addr, err)
+			}
+		}(addr)
+	}
+	wg.Wait()
+	this.Lookup(this.selfID)
+}
+
+//Ping check the node at addr is alive and add it to table. key is nil if it is unknown
+func (this *DHT) Ping(addr *net.UDPAddr, key ed25519.PublicKey) (*Node, error) {
+	nonce := newNonce()
+	rep, err := this.request(addr, key, &Ping{Nonce: nonce, Record: *this.self}, nonce, PONG_PACKET)
+	if err != nil {
+		return nil, err
+	}
+	pong := rep.pkt.(*Pong)
+	if !pong.Record.Verify() || !bytes.Equal(pong.Record.PubKey, rep.from) {
+		return nil, errBadSignature
+	}
+	n := &Node{Record: pong.Record, IP: normIP(rep.addr.IP)}
+	this.addNode(n)
+	return n, nil
+}
+
+//FindNode ask n the nodes closest to target. n only answers when it has verified our endpoint,
+//so n is pinged first if it has not pinged us recently
+func (this *DHT) FindNode(n *Node, target NodeID) ([]*Node, error) {
+	if !this.hasBond(this.pinged, n.UDPAddr()) {
+		if _, err := this.Ping(n.UDPAddr(), n.PubKey); err != nil {
+			return nil, err
+		}
+		this.waitBond(this.pinged, n.UDPAddr())
+	}
+	nonce := newNonce()
+	rep, err := this.request(n.UDPAddr(), n.PubKey, &FindNode{Nonce: nonce, Target: target}, nonce, NEIGHBORS_PACKET)
+	if err != nil {
+		return nil, err
+	}
+	this.addNode(n)
+	nodes := make([]*Node, 0, len(rep.pkt.(*Neighbors).Nodes))
+	for _, nb := range rep.pkt.(*Neighbors).Nodes {
+		if nb.IP.IsUnspecified() || !nb.Verify() {
+			continue
+		}
+		nodes = append(nodes, nb)
+	}
+	return nodes, nil
+}
+
+//Lookup iteratively query the closest nodes to target, return at most BUCKET_SIZE nodes found
+func (this *DHT) Lookup(target NodeID) []*Node {
+	type findResult struct {
+		from  *Node
+		nodes []*Node
+		err   error
+	}
+
+	seen := map[NodeID]bool{this.selfID: true}
+	asked := make(map[NodeID]bool)
+	result := this.table.Closest(target, BUCKET_SIZE)
+	for _, n := range result {
+		seen[n.ID()] = true
+	}
+
+	replies := make(chan *findResult, ALPHA)
+	pending := 0
+	for {
+		for i := 0; i < len(result) && pending < ALPHA; i++ {
+			n := result[i]
+			if asked[n.ID()] {
+				continue
+			}
+			asked[n.ID()] = true
+			pending++
+			go func(n *Node) {
+				nodes, err := this.FindNode(n, target)
+				replies <- &findResult{from: n, nodes: nodes, err: err}
+			}(n)
+		}
+		if pending == 0 {
+			break
+		}
+		rep := <-replies
+		pending--
+		if rep.err != nil {
+			log.Debugf("[p2p]dht findnode %s error:%s", rep.from.UDPAddr(), rep.err)
+			result = removeNode(result, rep.from.ID())
+			continue
+		}
+		for _, n := range rep.nodes {
+			if !seen[n.ID()] {
+				seen[n.ID()] = true
+				result = append(result, n)
+			}
+		}
+		sortByDist(target, result)
+		if len(result) > BUCKET_SIZE {
+			result = result[:BUCKET_SIZE]
+		}
+	}
+	return result
+}
+
+func removeNode(nodes []*Node, id NodeID) []*Node {
+	for i, n := range nodes {
+		if n.ID() == id {
+			return append(nodes[:i], nodes[i+1:]...)
+		}
+	}
+	return nodes
+}
+
+//addNode add n to table, if the bucket is full the least recently seen node is replaced when it
+//does not answer ping
+func (this *DHT) addNode(n *Node) {
+	old := this.table.Add(n)
+	if old == nil {
+		return
+	}
+	go func() {
+		_, err := this.Ping(old.UDPAddr(), old.PubKey)
+		if err != nil {
+			this.table.Replace(old, n)
+		}
+	}()
+}
+
+func (this *DHT) request(addr *net.UDPAddr, key ed25519.PublicKey, p packet, nonce uint64, kind byte) (*reply, error) {
+	req := &pendingReq{kind: kind, key: key, ch: make(chan *reply, 1)}
+	this.lock.Lock()
+	this.pending[nonce] = req
+	this.lock.Unlock()
+	defer func() {
+		this.lock.Lock()
+		delete(this.pending, nonce)
+		this.lock.Unlock()
+	}()
+
+	err := this.send(addr, p)
+	if err != nil {
+		return nil, err
+	}
+	t := time.NewTimer(RESPONSE_TIMEOUT * time.Millisecond)
+	defer t.Stop()
+	select {
+	case rep := <-req.ch:
+		return rep, nil
+	case <-t.C:
+		return nil, errTimeout
+	case <-this.quit:
+		return nil, errClosed
+	}
+}
+
+func (this *DHT) send(addr *net.UDPAddr, p packet) error {
+	_, err := this.conn.WriteToUDP(encodePacket(this.conf.Key, this.conf.Magic, p), addr)
+	return err
+}
+
+func (this *DHT) readLoop() {
+	defer this.wg.Done()
+	buf := make([]byte, MAX_PACKET_SIZE)
+	for {
+		n, addr, err := this.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-this.quit:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			log.Errorf("[p2p]dht read error:%s", err)
+			return
+		}
+		p, from, err := decodePacket(buf[:n], this.conf.Magic)
+		if err != nil {
+			log.Debugf("[p2p]dht invalid packet from %s:%s", addr, err)
+			continue
+		}
+		this.handle(p, from, addr)
+	}
+}
+
+func (this *DHT) handle(p packet, from ed25519.PublicKey, addr *net.UDPAddr) {
+	switch pkt := p.(type) {
+	case *Ping:
+		if !pkt.Record.Verify() || !bytes.Equal(pkt.Record.PubKey, from) {
+			return
+		}
+		this.send(addr, &Pong{Nonce: pkt.Nonce, Record: *this.self})
+		this.setBond(this.pinged, addr)
+		n := &Node{Record: pkt.Record, IP: normIP(addr.IP)}
+		known := this.table.Get(n.ID()) != nil
+		if known {
+			this.table.Add(n)
+		}
+		if !known || !this.hasBond(this.verified, addr) {
+			//check the endpoint before adding it or answering its findnode
+			this.schedulePing(n)
+		}
+	case *FindNode:
+		//the sender address may be spoofed, only the verified endpoints are answered
+		if !this.hasBond(this.verified, addr) {
+			return
+		}
+		this.send(addr, &Neighbors{Nonce: pkt.Nonce, Nodes: this.table.Closest(pkt.Target, BUCKET_SIZE)})
+	case *Pong:
+		this.deliver(pkt.Nonce, p, from, addr)
+	case *Neighbors:
+		this.deliver(pkt.Nonce, p, from, addr)
+	}
+}
+
+//schedulePing ping n by the workers, dropped if too many pings are pending
+func (this *DHT) schedulePing(n *Node) {
+	select {
+	case this.pingQueue <- n:
+	default:
+		log.Debugf("[p2p]dht drop ping to %s, queue is full", n.UDPAddr())
+	}
+}
+
+func (this *DHT) pingLoop() {
+	defer this.wg.Done()
+	for {
+		select {
+		case n := <-this.pingQueue:
+			this.Ping(n.UDPAddr(), n.PubKey)
+		case <-this.quit:
+			return
+		}
+	}
+}
+
+//setBond record the endpoint in bonds, the expired bonds are removed if there are too many
+func (this *DHT) setBond(bonds map[string]time.Time, addr *net.UDPAddr) {
+	key := addr.String()
+	now := time.Now()
+	this.bondLock.Lock()
+	defer this.bondLock.Unlock()
+	if _, ok := bonds[key]; !ok && len(bonds) >= MAX_BONDS {
+		pruneBonds(bonds, now)
+		if len(bonds) >= MAX_BONDS {
+			return
+		}
+	}
+	bonds[key] = now
+}
+
+func (this *DHT) hasBond(bonds map[string]time.Time, addr *net.UDPAddr) bool {
+	this.bondLock.Lock()
+	defer this.bondLock.Unlock()
+	t, ok := bonds[addr.String()]
+	return ok && time.Since(t) < BOND_EXPIRATION*time.Second
+}
+
+//waitBond wait at most a response timeout for the endpoint being bonded
+func (this *DHT) waitBond(bonds map[string]time.Time, addr *net.UDPAddr) {
+	for i := 0; i < 10 && !this.hasBond(bonds, addr); i++ {
+		select {
+		case <-time.After(RESPONSE_TIMEOUT / 10 * time.Millisecond):
+		case <-this.quit:
+			return
+		}
+	}
+}
+
+func (this *DHT) expireBonds() {
+	now := time.Now()
+	this.bondLock.Lock()
+	pruneBonds(this.verified, now)
+	pruneBonds(this.pinged, now)
+	this.bondLock.Unlock()
+}
+
+func pruneBonds(bonds map[string]time.Time, now time.Time) {
+	for key, t := range bonds {
+		if now.Sub(t) >= BOND_EXPIRATION*time.Second {
+			delete(bonds, key)
+		}
+	}
+}
+
+func (this *DHT) deliver(nonce uint64, p packet, from ed25519.PublicKey, addr *net.UDPAddr) {
+	this.lock.Lock()
+	req := this.pending[nonce]
+	this.lock.Unlock()
+	if req == nil || req.kind != p.Kind() || (req.key != nil && !bytes.Equal(req.key, from)) {
+		return
+	}
+	if req.kind == PONG_PACKET {
+		//bonded before handling the next packet, which may be a findnode following the pong
+		this.setBond(this.verified, addr)
+	}
+	select {
+	case req.ch <- &reply{pkt: p, from: from, addr: addr}:
+	default:
+	}
+}
+
+func (this *DHT) refreshLoop() {
+	defer this.wg.Done()
+	if this.table.Len() == 0 {
+		this.Bootstrap()
+	} else {
+		this.Lookup(this.selfID)
+	}
+	this.saveTable()
+
+	refresh := time.NewTicker(REFRESH_INTERVAL * time.Second)
+	revalidate := time.NewTicker(REVALIDATE_INTERVAL * time.Second)
+	defer refresh.Stop()
+	defer revalidate.Stop()
+	for {
+		select {
+		case <-refresh.C:
+			if this.table.Len() == 0 {
+				//all known nodes are gone, fall back to the boot nodes
+				this.Bootstrap()
+			} else {
+				this.Lookup(randomID())
+			}
+			this.saveTable()
+		case <-revalidate.C:
+			this.revalidate()
+			this.expireBonds()
+		case <-this.quit:
+			return
+		}
+	}
+}
+
+//revalidate ping the least recently seen node of a random bucket, remove it if no response
+func (this *DHT) revalidate() {
+	this.table.RLock()
+	var nonEmpty [][]*Node
+	for _, nodes := range this.table.buckets {
+		if len(nodes) > 0 {
+			nonEmpty = append(nonEmpty, nodes)
+		}
+	}
+	var oldest *Node
+	if len(nonEmpty) > 0 {
+		oldest = nonEmpty[randInt(len(nonEmpty))][0]
+	}
+	this.table.RUnlock()
+	if oldest == nil {
+		return
+	}
+	_, err := this.Ping(oldest.UDPAddr(), oldest.PubKey)
+	if err != nil {
+		log.Debugf("[p2p]dht remove dead node %s", oldest.UDPAddr())
+		this.table.Remove(oldest.ID())
+	}
+}
+
+func (this *DHT) saveTable() {
+	if this.conf.TablePath == "" {
+		return
+	}
+	err := this.table.Save(this.conf.TablePath)
+	if err != nil {
+		log.Warnf("[p2p]save dht table %s error:%s", this.conf.TablePath, err)
+	}
+}
+
+func newNonce() uint64 {
+	var buf [8]byte
+	rand.Read(buf[:])
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+func randomID() NodeID {
+	var id NodeID
+	rand.Read(id[:])
+	return id
+}
+
+func randInt(n int) int {
+	r, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0
+	}
+	return int(r.Int64())
+}
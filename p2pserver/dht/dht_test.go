@@ -0,0 +1,181 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dht
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+const testMagic = 0x12345678
+
+func newTestDHT(t *testing.T, tcpPort uint16, tablePath string, bootnodes ...string) *DHT {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	d, err := NewDHT(Config{
+		Key:        key,
+		Magic:      testMagic,
+		ListenAddr: "127.0.0.1:0",
+		TCPPort:    tcpPort,
+		Bootnodes:  bootnodes,
+		TablePath:  tablePath,
+	})
+	assert.Nil(t, err)
+	return d
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 500; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("wait for condition timeout")
+}
+
+func TestDHTLoopback(t *testing.T) {
+	const nodeNum = 30
+
+	//discovery only boot node
+	boot := newTestDHT(t, 0, "")
+	boot.Start()
+	defer boot.Stop()
+
+	nodes := make([]*DHT, 0, nodeNum)
+	for i := 0; i < nodeNum; i++ {
+		d := newTestDHT(t, uint16(30000+i), "", boot.LocalAddr().String())
+		d.Start()
+		defer d.Stop()
+		waitFor(t, func() bool { return d.Table().Len() > 0 })
+		nodes = append(nodes, d)
+	}
+
+	//every node can be found by every other node
+	for i, d := range nodes {
+		target := nodes[(i*7+3)%nodeNum]
+		found := d.Lookup(target.Self().ID())
+		assert.True(t, len(found) > 0)
+		assert.Equal(t, target.Self().ID(), found[0].ID())
+		assert.Equal(t, target.LocalAddr().String(), found[0].UDPAddr().String())
+		assert.Equal(t, "127.0.0.1:"+strconv.Itoa(int(target.Self().TCPPort)), found[0].TCPAddr())
+	}
+
+	//the boot node does not accept p2p connections
+	for _, d := range nodes {
+		for _, n := range d.Nodes() {
+			assert.NotEqual(t, boot.Self().ID(), n.ID())
+		}
+	}
+}
+
+func TestDHTPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dht")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	boot := newTestDHT(t, 0, "")
+	boot.Start()
+	defer boot.Stop()
+	other := newTestDHT(t, 20338, "", boot.LocalAddr().String())
+	other.Start()
+	defer other.Stop()
+	waitFor(t, func() bool { return other.Table().Len() > 0 })
+
+	path := filepath.Join(dir, TABLE_FILE_NAME)
+	d := newTestDHT(t, 20338, path, boot.LocalAddr().String())
+	d.Start()
+	waitFor(t, func() bool { return d.Table().Len() == 2 })
+	d.Stop()
+
+	//the saved table is used instead of the boot nodes
+	restarted := newTestDHT(t, 20338, path)
+	assert.Equal(t, 2, restarted.Table().Len())
+	restarted.Start()
+	defer restarted.Stop()
+	found := restarted.Lookup(other.Self().ID())
+	assert.Equal(t, other.Self().ID(), found[0].ID())
+}
+
+func TestDHTOtherNetwork(t *testing.T) {
+	boot := newTestDHT(t, 0, "")
+	boot.conf.Magic = testMagic + 1
+	boot.Start()
+	defer boot.Stop()
+
+	d := newTestDHT(t, 20338, "")
+	d.Start()
+	defer d.Stop()
+	_, err := d.Ping(boot.LocalAddr(), nil)
+	assert.Equal(t, errTimeout, err)
+	assert.Equal(t, 0, d.Table().Len())
+}
+
+func TestDHTFindNodeUnverified(t *testing.T) {
+	d := newTestDHT(t, 20338, "")
+	d.Start()
+	defer d.Stop()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Nil(t, err)
+	defer conn.Close()
+	buf := make([]byte, MAX_PACKET_SIZE)
+	read := func() packet {
+		conn.SetReadDeadline(time.Now().Add(RESPONSE_TIMEOUT * time.Millisecond))
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil
+		}
+		p, _, err := decodePacket(buf[:n], testMagic)
+		assert.Nil(t, err)
+		return p
+	}
+
+	//the endpoint not answering ping is not answered
+	_, err = conn.WriteToUDP(encodePacket(key, testMagic, &FindNode{Nonce: 1, Target: randomID()}), d.LocalAddr())
+	assert.Nil(t, err)
+	assert.Nil(t, read())
+
+	//the endpoint answered ping is answered
+	self := NewRecord(key, uint16(conn.LocalAddr().(*net.UDPAddr).Port), 0, 1)
+	_, err = conn.WriteToUDP(encodePacket(key, testMagic, &Ping{Nonce: 2, Record: *self}), d.LocalAddr())
+	assert.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		if ping, ok := read().(*Ping); ok {
+			_, err = conn.WriteToUDP(encodePacket(key, testMagic, &Pong{Nonce: ping.Nonce, Record: *self}), d.LocalAddr())
+			assert.Nil(t, err)
+		}
+	}
+	_, err = conn.WriteToUDP(encodePacket(key, testMagic, &FindNode{Nonce: 3, Target: randomID()}), d.LocalAddr())
+	assert.Nil(t, err)
+	nb, ok := read().(*Neighbors)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), nb.Nonce)
+}
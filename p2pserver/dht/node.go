@@ -0,0 +1,178 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dht
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"net"
+	"strconv"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"golang.org/x/crypto/ed25519"
+)
+
+//NodeID is the position of a node in the key space, the sha256 of its node key
+type NodeID [sha256.Size]byte
+
+//PubKeyToNodeID return the id of node key
+func PubKeyToNodeID(pubKey []byte) NodeID {
+	return sha256.Sum256(pubKey)
+}
+
+//LogDist return the log2 of the xor distance between a and b, 0 means a == b
+func LogDist(a, b NodeID) int {
+	for i := range a {
+		x := a[i] ^ b[i]
+		if x != 0 {
+			return (len(a)-i)*8 - bits.LeadingZeros8(x)
+		}
+	}
+	return 0
+}
+
+//DistCmp compare the xor distance of a and b to target, return -1 if a is closer, 1 if b is closer
+func DistCmp(target, a, b NodeID) int {
+	for i := range target {
+		da := a[i] ^ target[i]
+		db := b[i] ^ target[i]
+		if da < db {
+			return -1
+		} else if da > db {
+			return 1
+		}
+	}
+	return 0
+}
+
+//Record is the endpoint info signed by the node key. The ip is not part of the record,
+//it is taken from the packet source so that nodes behind NAT need not know their public address
+type Record struct {
+	PubKey  ed25519.PublicKey
+	UDPPort uint16
+	TCPPort uint16 //0 for discovery only node
+	Seq     uint64 //newer record replaces the old one
+	Sig     []byte
+}
+
+//NewRecord return a record signed by key
+func NewRecord(key ed25519.PrivateKey, udpPort, tcpPort uint16, seq uint64) *Record {
+	rec := &Record{
+		PubKey:  key.Public().(ed25519.PublicKey),
+		UDPPort: udpPort,
+		TCPPort: tcpPort,
+		Seq:     seq,
+	}
+	rec.Sig = ed25519.Sign(key, rec.signData())
+	return rec
+}
+
+func (this *Record) signData() []byte {
+	buf := make([]byte, 0, ed25519.PublicKeySize+12)
+	buf = append(buf, this.PubKey...)
+	var tmp [8]byte
+	binary.LittleEndian.PutUint16(tmp[:], this.UDPPort)
+	buf = append(buf, tmp[:2]...)
+	binary.LittleEndian.PutUint16(tmp[:], this.TCPPort)
+	buf = append(buf, tmp[:2]...)
+	binary.LittleEndian.PutUint64(tmp[:], this.Seq)
+	return append(buf, tmp[:]...)
+}
+
+//Verify check the signature of record
+func (this *Record) Verify() bool {
+	return len(this.PubKey) == ed25519.PublicKeySize && len(this.Sig) == ed25519.SignatureSize &&
+		ed25519.Verify(this.PubKey, this.signData(), this.Sig)
+}
+
+//ID return the node id of record
+func (this *Record) ID() NodeID {
+	return PubKeyToNodeID(this.PubKey)
+}
+
+func (this *Record) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteBytes(this.PubKey)
+	sink.WriteUint16(this.UDPPort)
+	sink.WriteUint16(this.TCPPort)
+	sink.WriteUint64(this.Seq)
+	sink.WriteBytes(this.Sig)
+}
+
+func (this *Record) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	var pubKey, sig []byte
+	pubKey, eof = source.NextBytes(ed25519.PublicKeySize)
+	this.UDPPort, eof = source.NextUint16()
+	this.TCPPort, eof = source.NextUint16()
+	this.Seq, eof = source.NextUint64()
+	sig, eof = source.NextBytes(ed25519.SignatureSize)
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.PubKey = append(ed25519.PublicKey(nil), pubKey...)
+	this.Sig = append([]byte(nil), sig...)
+	return nil
+}
+
+//Node is a record with the ip it is reached at
+type Node struct {
+	Record
+	IP net.IP
+}
+
+//UDPAddr return the discovery address of node
+func (this *Node) UDPAddr() *net.UDPAddr {
+	return &net.UDPAddr{IP: this.IP, Port: int(this.UDPPort)}
+}
+
+//TCPAddr return the p2p sync address of node, empty if node does not accept connections
+func (this *Node) TCPAddr() string {
+	if this.TCPPort == 0 {
+		return ""
+	}
+	return net.JoinHostPort(this.IP.String(), strconv.Itoa(int(this.TCPPort)))
+}
+
+func (this *Node) Serialization(sink *common.ZeroCopySink) {
+	ip := this.IP.To16()
+	if ip == nil {
+		ip = net.IPv6zero
+	}
+	sink.WriteBytes(ip)
+	this.Record.Serialization(sink)
+}
+
+func (this *Node) Deserialization(source *common.ZeroCopySource) error {
+	ip, eof := source.NextBytes(net.IPv6len)
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.IP = normIP(ip)
+	return this.Record.Deserialization(source)
+}
+
+//normIP return a copy of ip in 4 bytes form if it is ipv4
+func normIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return append(net.IP(nil), ip4...)
+	}
+	return append(net.IP(nil), ip...)
+}
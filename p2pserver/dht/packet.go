@@ -0,0 +1,221 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dht
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"golang.org/x/crypto/ed25519"
+)
+
+//packet type
+const (
+	PING_PACKET      = 1
+	PONG_PACKET      = 2
+	FINDNODE_PACKET  = 3
+	NEIGHBORS_PACKET = 4
+)
+
+const (
+	MAX_PACKET_SIZE   = 4096 //max size of udp packet
+	PACKET_EXPIRATION = 20   //packet expiration in sec
+	headSize          = ed25519.SignatureSize + ed25519.PublicKeySize
+)
+
+var (
+	errPacketTooSmall = errors.New("[p2p]packet too small")
+	errBadSignature   = errors.New("[p2p]invalid packet signature")
+	errWrongMagic     = errors.New("[p2p]packet of other network")
+	errExpired        = errors.New("[p2p]packet expired")
+)
+
+type packet interface {
+	Kind() byte
+	Serialization(sink *common.ZeroCopySink)
+	Deserialization(source *common.ZeroCopySource) error
+}
+
+//Ping is sent to check a node is alive, it carries the record of the sender
+type Ping struct {
+	Nonce  uint64
+	Record Record
+}
+
+//Pong is the reply of ping
+type Pong struct {
+	Nonce  uint64
+	Record Record
+}
+
+//FindNode ask the nodes closest to target
+type FindNode struct {
+	Nonce  uint64
+	Target NodeID
+}
+
+//Neighbors is the reply of findnode
+type Neighbors struct {
+	Nonce uint64
+	Nodes []*Node
+}
+
+func (this *Ping) Kind() byte { return PING_PACKET }
+
+func (this *Ping) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Nonce)
+	this.Record.Serialization(sink)
+}
+
+func (this *Ping) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.Nonce, eof = source.NextUint64()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return this.Record.Deserialization(source)
+}
+
+func (this *Pong) Kind() byte { return PONG_PACKET }
+
+func (this *Pong) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Nonce)
+	this.Record.Serialization(sink)
+}
+
+func (this *Pong) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	this.Nonce, eof = source.NextUint64()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return this.Record.Deserialization(source)
+}
+
+func (this *FindNode) Kind() byte { return FINDNODE_PACKET }
+
+func (this *FindNode) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Nonce)
+	sink.WriteBytes(this.Target[:])
+}
+
+func (this *FindNode) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	var target []byte
+	this.Nonce, eof = source.NextUint64()
+	target, eof = source.NextBytes(uint64(len(this.Target)))
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	copy(this.Target[:], target)
+	return nil
+}
+
+func (this *Neighbors) Kind() byte { return NEIGHBORS_PACKET }
+
+func (this *Neighbors) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Nonce)
+	sink.WriteUint8(uint8(len(this.Nodes)))
+	for _, n := range this.Nodes {
+		n.Serialization(sink)
+	}
+}
+
+func (this *Neighbors) Deserialization(source *common.ZeroCopySource) error {
+	var eof bool
+	var count uint8
+	this.Nonce, eof = source.NextUint64()
+	count, eof = source.NextUint8()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if int(count) > BUCKET_SIZE {
+		return fmt.Errorf("[p2p]too many neighbors:%d", count)
+	}
+	for i := 0; i < int(count); i++ {
+		n := &Node{}
+		if err := n.Deserialization(source); err != nil {
+			return err
+		}
+		this.Nodes = append(this.Nodes, n)
+	}
+	return nil
+}
+
+//encodePacket sign the packet with key. The signed body is magic|kind|expiration|payload
+func encodePacket(key ed25519.PrivateKey, magic uint32, p packet) []byte {
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteUint32(magic)
+	sink.WriteByte(p.Kind())
+	sink.WriteInt64(time.Now().Add(PACKET_EXPIRATION * time.Second).Unix())
+	p.Serialization(sink)
+	body := sink.Bytes()
+
+	buf := bytes.NewBuffer(make([]byte, 0, headSize+len(body)))
+	buf.Write(ed25519.Sign(key, body))
+	buf.Write(key.Public().(ed25519.PublicKey))
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+//decodePacket verify the signature, network and expiration of data, return the packet and the sender key
+func decodePacket(data []byte, magic uint32) (packet, ed25519.PublicKey, error) {
+	if len(data) < headSize+13 {
+		return nil, nil, errPacketTooSmall
+	}
+	sig := data[:ed25519.SignatureSize]
+	pubKey := ed25519.PublicKey(data[ed25519.SignatureSize:headSize])
+	body := data[headSize:]
+	if !ed25519.Verify(pubKey, body, sig) {
+		return nil, nil, errBadSignature
+	}
+
+	source := common.NewZeroCopySource(body)
+	m, _ := source.NextUint32()
+	kind, _ := source.NextByte()
+	expiration, _ := source.NextInt64()
+	if m != magic {
+		return nil, nil, errWrongMagic
+	}
+	if expiration < time.Now().Unix() {
+		return nil, nil, errExpired
+	}
+
+	var p packet
+	switch kind {
+	case PING_PACKET:
+		p = &Ping{}
+	case PONG_PACKET:
+		p = &Pong{}
+	case FINDNODE_PACKET:
+		p = &FindNode{}
+	case NEIGHBORS_PACKET:
+		p = &Neighbors{}
+	default:
+		return nil, nil, fmt.Errorf("[p2p]unknown packet type:%d", kind)
+	}
+	if err := p.Deserialization(source); err != nil {
+		return nil, nil, err
+	}
+	return p, append(ed25519.PublicKey(nil), pubKey...), nil
+}
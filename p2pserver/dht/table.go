@@ -0,0 +1,191 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dht
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	BUCKET_SIZE = 16                  //max nodes in one bucket
+	BUCKET_NUM  = len(NodeID{})*8 + 1 //bucket index is the log distance to self
+)
+
+//Table is the kademlia routing table. Nodes in a bucket are ordered by the last time they were
+//seen, the least recently seen node is at the front
+type Table struct {
+	sync.RWMutex
+	self    NodeID
+	buckets [BUCKET_NUM][]*Node
+}
+
+//NewTable return an empty table for self
+func NewTable(self NodeID) *Table {
+	return &Table{self: self}
+}
+
+func (this *Table) bucket(id NodeID) int {
+	return LogDist(this.self, id)
+}
+
+//Add insert n or move it to the tail of its bucket. If the bucket is full, the least recently
+//seen node is returned and n is not added, the caller should check whether it is still alive
+func (this *Table) Add(n *Node) *Node {
+	id := n.ID()
+	if id == this.self {
+		return nil
+	}
+	this.Lock()
+	defer this.Unlock()
+
+	b := this.bucket(id)
+	nodes := this.buckets[b]
+	for i, old := range nodes {
+		if old.ID() == id {
+			if n.Seq < old.Seq {
+				n = old
+			}
+			copy(nodes[i:], nodes[i+1:])
+			nodes[len(nodes)-1] = n
+			return nil
+		}
+	}
+	if len(nodes) >= BUCKET_SIZE {
+		return nodes[0]
+	}
+	this.buckets[b] = append(nodes, n)
+	return nil
+}
+
+//Replace remove old and add n at the tail of the bucket
+func (this *Table) Replace(old, n *Node) {
+	this.Remove(old.ID())
+	this.Add(n)
+}
+
+//Remove delete the node with id
+func (this *Table) Remove(id NodeID) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	b := this.bucket(id)
+	nodes := this.buckets[b]
+	for i, n := range nodes {
+		if n.ID() == id {
+			this.buckets[b] = append(nodes[:i], nodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//Get return the node with id
+func (this *Table) Get(id NodeID) *Node {
+	this.RLock()
+	defer this.RUnlock()
+
+	for _, n := range this.buckets[this.bucket(id)] {
+		if n.ID() == id {
+			return n
+		}
+	}
+	return nil
+}
+
+//Len return the count of nodes in table
+func (this *Table) Len() int {
+	this.RLock()
+	defer this.RUnlock()
+
+	cnt := 0
+	for _, nodes := range this.buckets {
+		cnt += len(nodes)
+	}
+	return cnt
+}
+
+//Nodes return all nodes in table
+func (this *Table) Nodes() []*Node {
+	this.RLock()
+	defer this.RUnlock()
+
+	all := make([]*Node, 0)
+	for _, nodes := range this.buckets {
+		all = append(all, nodes...)
+	}
+	return all
+}
+
+//Closest return at most count nodes closest to target
+func (this *Table) Closest(target NodeID, count int) []*Node {
+	all := this.Nodes()
+	sortByDist(target, all)
+	if len(all) > count {
+		all = all[:count]
+	}
+	return all
+}
+
+func sortByDist(target NodeID, nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return DistCmp(target, nodes[i].ID(), nodes[j].ID()) < 0
+	})
+}
+
+//Save write all nodes to path in json
+func (this *Table) Save(path string) error {
+	data, err := json.Marshal(this.Nodes())
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+//Load add the nodes saved in path, nodes with invalid record are dropped. It is not an error if
+//path does not exist
+func (this *Table) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var nodes []*Node
+	err = json.Unmarshal(bytes.TrimSpace(data), &nodes)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if n.Verify() && n.IP != nil {
+			n.IP = normIP(n.IP)
+			this.Add(n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package dht
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+func newTestNode(t *testing.T, port uint16) (*Node, ed25519.PrivateKey) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	return &Node{Record: *NewRecord(key, port, port, 1), IP: net.IPv4(127, 0, 0, 1).To4()}, key
+}
+
+func TestRecord(t *testing.T) {
+	n, key := newTestNode(t, 20338)
+	assert.True(t, n.Verify())
+
+	n.TCPPort = 20339
+	assert.False(t, n.Verify())
+
+	p := &Neighbors{Nonce: 7, Nodes: []*Node{{Record: *NewRecord(key, 20338, 0, 2), IP: n.IP}}}
+	data := encodePacket(key, 1, p)
+	pkt, from, err := decodePacket(data, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, key.Public(), from)
+	nb := pkt.(*Neighbors)
+	assert.Equal(t, uint64(7), nb.Nonce)
+	assert.Equal(t, 1, len(nb.Nodes))
+	assert.True(t, nb.Nodes[0].Verify())
+	assert.Equal(t, "", nb.Nodes[0].TCPAddr())
+	assert.Equal(t, "127.0.0.1:20338", nb.Nodes[0].UDPAddr().String())
+
+	_, _, err = decodePacket(data, 2)
+	assert.Equal(t, errWrongMagic, err)
+	data[len(data)-1] ^= 1
+	_, _, err = decodePacket(data, 1)
+	assert.Equal(t, errBadSignature, err)
+}
+
+func TestLogDist(t *testing.T) {
+	var a, b NodeID
+	assert.Equal(t, 0, LogDist(a, b))
+	b[len(b)-1] = 1
+	assert.Equal(t, 1, LogDist(a, b))
+	b[0] = 0x80
+	assert.Equal(t, 256, LogDist(a, b))
+
+	var c NodeID
+	c[len(c)-1] = 3
+	assert.Equal(t, 1, DistCmp(a, b, c))
+	assert.Equal(t, -1, DistCmp(a, c, b))
+}
+
+func TestTable(t *testing.T) {
+	self, _ := newTestNode(t, 1)
+	table := NewTable(self.ID())
+	assert.Nil(t, table.Add(self))
+	assert.Equal(t, 0, table.Len())
+
+	for i := 0; i < 100; i++ {
+		n, _ := newTestNode(t, uint16(i+2))
+		old := table.Add(n)
+		if old != nil {
+			//bucket is full, the least recently seen node is returned
+			b := table.bucket(n.ID())
+			assert.Equal(t, BUCKET_SIZE, len(table.buckets[b]))
+			assert.Equal(t, table.buckets[b][0], old)
+			table.Replace(old, n)
+			assert.Nil(t, table.Get(old.ID()))
+		}
+		assert.Equal(t, n, table.Get(n.ID()))
+	}
+
+	target := table.Nodes()[table.Len()/2].ID()
+	closest := table.Closest(target, 10)
+	assert.Equal(t, 10, len(closest))
+	assert.Equal(t, target, closest[0].ID())
+	for i := 1; i < len(closest); i++ {
+		assert.True(t, DistCmp(target, closest[i-1].ID(), closest[i].ID()) < 0)
+	}
+
+	dir, err := ioutil.TempDir("", "dht")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, TABLE_FILE_NAME)
+	assert.Nil(t, table.Save(path))
+	loaded := NewTable(self.ID())
+	assert.Nil(t, loaded.Load(path))
+	assert.Equal(t, table.Len(), loaded.Len())
+	assert.Equal(t, closest[0].TCPAddr(), loaded.Get(target).TCPAddr())
+
+	assert.Nil(t, NewTable(self.ID()).Load(filepath.Join(dir, "none")))
+}
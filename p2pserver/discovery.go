@@ -0,0 +1,64 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package p2pserver
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/p2pserver/dht"
+	"github.com/OnyxPay/OnyxChain/p2pserver/link"
+	"github.com/OnyxPay/OnyxChain/p2pserver/net/netserver"
+	"golang.org/x/crypto/ed25519"
+)
+
+//newDHT return the discovery service announcing tcpPort. The seeds are the boot nodes, they
+//serve discovery on the same port number as the p2p port
+func newDHT(key ed25519.PrivateKey, tcpPort uint16) (*dht.DHT, error) {
+	port := config.DefConfig.P2PNode.DHTPort
+	if port == 0 {
+		port = config.DefConfig.P2PNode.NodePort
+	}
+	return dht.NewDHT(dht.Config{
+		Key:        key,
+		Magic:      config.DefConfig.P2PNode.NetworkMagic,
+		ListenAddr: ":" + strconv.Itoa(int(port)),
+		TCPPort:    tcpPort,
+		Bootnodes:  config.DefConfig.Genesis.SeedList,
+		TablePath:  filepath.Join(config.DefConfig.Common.DataDir, dht.TABLE_FILE_NAME),
+	})
+}
+
+//StartDiscovery run the node discovery only, it is used by the boot nodes which do not
+//accept p2p connections
+func StartDiscovery() (*dht.DHT, error) {
+	key, err := link.LoadOrCreateNodeKey(netserver.NodeKeyPath())
+	if err != nil {
+		return nil, err
+	}
+	d, err := newDHT(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	d.Start()
+	log.Infof("[p2p]discovery started on %s, node key %x", d.LocalAddr(), d.Self().PubKey)
+	return d, nil
+}
@@ -37,6 +37,7 @@ type Link struct {
 	addr      string                 // The address of the node
 	conn      net.Conn               // Connect socket with the peer node
 	port      uint16                 // The server port of the node
+	pubKey    []byte                 // The authenticated node key of the peer
 	time      time.Time              // The latest time the node activity
 	recvChan  chan *types.MsgPayload //msgpayload channel
 	reqRecord map[string]int64       //Map RequestId to Timestamp, using for rejecting duplicate request in specific time
@@ -79,6 +80,16 @@ func (this *Link) SetAddr(addr string) {
 	this.addr = addr
 }
 
+//SetPubKey set the node key authenticated in handshake
+func (this *Link) SetPubKey(pubKey []byte) {
+	this.pubKey = pubKey
+}
+
+//GetPubKey return the node key authenticated in handshake
+func (this *Link) GetPubKey() []byte {
+	return this.pubKey
+}
+
 //set port number
 func (this *Link) SetPort(p uint16) {
 	this.port = p
@@ -117,10 +128,12 @@ func (this *Link) Rx() {
 
 	reader := bufio.NewReaderSize(conn, common.MAX_BUF_LEN)
 
+	malformed := false
 	for {
 		msg, payloadSize, err := types.ReadMessage(reader)
 		if err != nil {
 			log.Infof("[p2p]error read from %s :%s", this.GetAddr(), err.Error())
+			_, malformed = err.(*types.MalformedMsgError)
 			break
 		}
 
@@ -141,19 +154,19 @@ func (this *Link) Rx() {
 
 	}
 
-	this.disconnectNotify()
+	this.disconnectNotify(malformed)
 }
 
-//disconnectNotify push disconnect msg to channel
-func (this *Link) disconnectNotify() {
+//disconnectNotify push disconnect msg to channel, malformed is set if the
+//link is broken by a malformed message from peer
+func (this *Link) disconnectNotify(malformed bool) {
 	log.Debugf("[p2p]call disconnectNotify for %s", this.GetAddr())
 	this.CloseConn()
 
-	msg, _ := types.MakeEmptyMessage(common.DISCONNECT_TYPE)
 	discMsg := &types.MsgPayload{
 		Id:      this.id,
 		Addr:    this.addr,
-		Payload: msg,
+		Payload: &types.Disconnected{Malformed: malformed},
 	}
 	this.recvChan <- discMsg
 }
@@ -194,7 +207,7 @@ func (this *Link) SendRaw(rawPacket []byte) error {
 	_, err := conn.Write(rawPacket)
 	if err != nil {
 		log.Infof("[p2p]error sending messge to %s :%s", this.GetAddr(), err.Error())
-		this.disconnectNotify()
+		this.disconnectNotify(false)
 		return err
 	}
 
@@ -0,0 +1,220 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	HANDSHAKE_PROTOCOL = "OnyxChain-p2p-v1"
+	HANDSHAKE_TIMEOUT  = 10        //handshake timeout in sec
+	MAX_FRAME_SIZE     = 16 * 1024 //max plaintext size of one encrypted frame
+)
+
+//ErrSelfConnect is returned by Handshake if the remote peer has the same node key
+var ErrSelfConnect = errors.New("[p2p]handshake with itself")
+
+const (
+	roleInitiator = 1
+	roleResponder = 2
+)
+
+// SecureConn is the connection encrypted and authenticated by the keys agreed in Handshake
+type SecureConn struct {
+	net.Conn
+	remoteKey ed25519.PublicKey
+	sendAEAD  cipher.AEAD
+	recvAEAD  cipher.AEAD
+	sendNonce uint64
+	recvNonce uint64
+	writeLock sync.Mutex
+	readLock  sync.Mutex
+	readBuf   []byte
+	frameBuf  []byte
+	nonceBuf  [12]byte
+	openNonce [12]byte
+}
+
+// Handshake run the key agreement on conn. Each side sends an ephemeral x25519 key, derives the session
+// keys, then proves its node key by signing the transcript over the encrypted channel. magic binds
+// the session to the network.
+// Nodes of protocol version 0 send plaintext messages and fail the handshake, so all nodes of a network
+// have to upgrade together, there is no plaintext fallback which would defeat the authentication.
+func Handshake(conn net.Conn, key ed25519.PrivateKey, magic uint32, initiator bool) (*SecureConn, error) {
+	conn.SetDeadline(time.Now().Add(HANDSHAKE_TIMEOUT * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	var ephPriv, ephPub, remoteEph, shared [32]byte
+	if _, err := rand.Read(ephPriv[:]); err != nil {
+		return nil, err
+	}
+	curve25519.ScalarBaseMult(&ephPub, &ephPriv)
+	if _, err := conn.Write(ephPub[:]); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(conn, remoteEph[:]); err != nil {
+		return nil, err
+	}
+	curve25519.ScalarMult(&shared, &ephPriv, &remoteEph)
+	if shared == [32]byte{} {
+		return nil, errors.New("[p2p]invalid ephemeral key")
+	}
+
+	role, remoteRole := byte(roleInitiator), byte(roleResponder)
+	initEph, respEph := ephPub, remoteEph
+	if !initiator {
+		role, remoteRole = remoteRole, role
+		initEph, respEph = respEph, initEph
+	}
+	h := sha256.New()
+	h.Write([]byte(HANDSHAKE_PROTOCOL))
+	binary.Write(h, binary.LittleEndian, magic)
+	h.Write(initEph[:])
+	h.Write(respEph[:])
+	transcript := h.Sum(nil)
+
+	keys := make([]byte, 64)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared[:], transcript, []byte("keys")), keys); err != nil {
+		return nil, err
+	}
+	sendKey, recvKey := keys[:32], keys[32:]
+	if !initiator {
+		sendKey, recvKey = recvKey, sendKey
+	}
+	sc := &SecureConn{Conn: conn}
+	var err error
+	if sc.sendAEAD, err = newAEAD(sendKey); err != nil {
+		return nil, err
+	}
+	if sc.recvAEAD, err = newAEAD(recvKey); err != nil {
+		return nil, err
+	}
+
+	pubKey := key.Public().(ed25519.PublicKey)
+	auth := make([]byte, 0, ed25519.PublicKeySize+ed25519.SignatureSize)
+	auth = append(auth, pubKey...)
+	auth = append(auth, ed25519.Sign(key, authMessage(role, transcript))...)
+	if _, err = sc.Write(auth); err != nil {
+		return nil, err
+	}
+	remoteAuth := make([]byte, len(auth))
+	if _, err = io.ReadFull(sc, remoteAuth); err != nil {
+		return nil, err
+	}
+	remoteKey := ed25519.PublicKey(remoteAuth[:ed25519.PublicKeySize])
+	if bytes.Equal(remoteKey, pubKey) {
+		return nil, ErrSelfConnect
+	}
+	if !ed25519.Verify(remoteKey, authMessage(remoteRole, transcript), remoteAuth[ed25519.PublicKeySize:]) {
+		return nil, errors.New("[p2p]invalid handshake signature")
+	}
+	sc.remoteKey = remoteKey
+	return sc, nil
+}
+
+func authMessage(role byte, transcript []byte) []byte {
+	msg := append([]byte(HANDSHAKE_PROTOCOL), role)
+	return append(msg, transcript...)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// RemoteKey return the authenticated node key of remote peer
+func (this *SecureConn) RemoteKey() ed25519.PublicKey {
+	return this.remoteKey
+}
+
+// Write split data to frames of [length][sealed data]
+func (this *SecureConn) Write(data []byte) (int, error) {
+	this.writeLock.Lock()
+	defer this.writeLock.Unlock()
+	written := 0
+	for len(data) > 0 {
+		size := len(data)
+		if size > MAX_FRAME_SIZE {
+			size = MAX_FRAME_SIZE
+		}
+		binary.BigEndian.PutUint64(this.nonceBuf[4:], this.sendNonce)
+		this.sendNonce++
+		frame := make([]byte, 2, 2+size+this.sendAEAD.Overhead())
+		frame = this.sendAEAD.Seal(frame, this.nonceBuf[:], data[:size], nil)
+		binary.BigEndian.PutUint16(frame, uint16(len(frame)-2))
+		if _, err := this.Conn.Write(frame); err != nil {
+			return written, err
+		}
+		written += size
+		data = data[size:]
+	}
+	return written, nil
+}
+
+// Read return the decrypted data of frames
+func (this *SecureConn) Read(buf []byte) (int, error) {
+	this.readLock.Lock()
+	defer this.readLock.Unlock()
+	if len(this.readBuf) == 0 {
+		var header [2]byte
+		if _, err := io.ReadFull(this.Conn, header[:]); err != nil {
+			return 0, err
+		}
+		size := int(binary.BigEndian.Uint16(header[:]))
+		if size < this.recvAEAD.Overhead() || size > MAX_FRAME_SIZE+this.recvAEAD.Overhead() {
+			return 0, fmt.Errorf("[p2p]invalid frame size:%d", size)
+		}
+		if cap(this.frameBuf) < size {
+			this.frameBuf = make([]byte, MAX_FRAME_SIZE+this.recvAEAD.Overhead())
+		}
+		frame := this.frameBuf[:size]
+		if _, err := io.ReadFull(this.Conn, frame); err != nil {
+			return 0, err
+		}
+		binary.BigEndian.PutUint64(this.openNonce[4:], this.recvNonce)
+		this.recvNonce++
+		plain, err := this.recvAEAD.Open(frame[:0], this.openNonce[:], frame, nil)
+		if err != nil {
+			return 0, errors.New("[p2p]frame authentication failed")
+		}
+		this.readBuf = plain
+	}
+	n := copy(buf, this.readBuf)
+	this.readBuf = this.readBuf[n:]
+	return n, nil
+}
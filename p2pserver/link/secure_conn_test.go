@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package link
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+)
+
+type handshakeResult struct {
+	conn *SecureConn
+	err  error
+}
+
+func secureConnPair(t *testing.T, cliKey, srvKey ed25519.PrivateKey, cliMagic, srvMagic uint32) (*SecureConn, *SecureConn, error, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	srvResult := make(chan handshakeResult, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			srvResult <- handshakeResult{err: err}
+			return
+		}
+		sc, err := Handshake(conn, srvKey, srvMagic, false)
+		if err != nil {
+			conn.Close()
+		}
+		srvResult <- handshakeResult{sc, err}
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err)
+	cli, cliErr := Handshake(conn, cliKey, cliMagic, true)
+	if cliErr != nil {
+		conn.Close()
+	}
+	srv := <-srvResult
+	return cli, srv.conn, cliErr, srv.err
+}
+
+func TestHandshake(t *testing.T) {
+	cliKey, _ := NewNodeKey()
+	srvKey, _ := NewNodeKey()
+	cli, srv, cliErr, srvErr := secureConnPair(t, cliKey, srvKey, 1, 1)
+	assert.Nil(t, cliErr)
+	assert.Nil(t, srvErr)
+	defer cli.Close()
+	defer srv.Close()
+	assert.Equal(t, srvKey.Public(), cli.RemoteKey())
+	assert.Equal(t, cliKey.Public(), srv.RemoteKey())
+
+	data := make([]byte, 3*MAX_FRAME_SIZE+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	go func() {
+		cli.Write(data)
+		srv.Write([]byte("pong"))
+	}()
+	buf := make([]byte, len(data))
+	_, err := io.ReadFull(srv, buf)
+	assert.Nil(t, err)
+	assert.True(t, bytes.Equal(data, buf))
+	buf = make([]byte, 4)
+	_, err = io.ReadFull(cli, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "pong", string(buf))
+}
+
+func TestHandshakeFailed(t *testing.T) {
+	cliKey, _ := NewNodeKey()
+	srvKey, _ := NewNodeKey()
+	_, _, cliErr, srvErr := secureConnPair(t, cliKey, srvKey, 1, 2)
+	assert.NotNil(t, cliErr)
+	assert.NotNil(t, srvErr)
+
+	_, _, cliErr, srvErr = secureConnPair(t, cliKey, cliKey, 1, 1)
+	assert.Equal(t, ErrSelfConnect, cliErr)
+	assert.Equal(t, ErrSelfConnect, srvErr)
+}
+
+func TestSecureConnTampered(t *testing.T) {
+	cliKey, _ := NewNodeKey()
+	srvKey, _ := NewNodeKey()
+	cli, srv, _, _ := secureConnPair(t, cliKey, srvKey, 1, 1)
+	defer cli.Close()
+	defer srv.Close()
+
+	//write a frame sealed by another key
+	fake := &SecureConn{Conn: cli.Conn, sendAEAD: cli.recvAEAD}
+	_, err := fake.Write([]byte("hello"))
+	assert.Nil(t, err)
+	_, err = srv.Read(make([]byte, 5))
+	assert.NotNil(t, err)
+}
+
+func TestNodeKey(t *testing.T) {
+	path := "./nodekey_test"
+	defer os.Remove(path)
+	key1, err := LoadOrCreateNodeKey(path)
+	assert.Nil(t, err)
+	key2, err := LoadOrCreateNodeKey(path)
+	assert.Nil(t, err)
+	assert.Equal(t, key1, key2)
+	pub := []byte(key1.Public().(ed25519.PublicKey))
+	assert.Equal(t, PeerIDFromPubKey(pub), PeerIDFromPubKey(pub))
+}
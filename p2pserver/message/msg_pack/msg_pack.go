@@ -19,6 +19,8 @@
 package msgpack
 
 import (
+	"crypto/rand"
+	"encoding/binary"
 	"time"
 
 	"github.com/OnyxPay/OnyxChain/common"
@@ -56,6 +58,45 @@ func NewBlock(bk *ct.Block, merkleRoot common.Uint256) mt.Message {
 	return &blk
 }
 
+//compact block package
+func NewCmpctBlock(bk *ct.Block, merkleRoot common.Uint256) mt.Message {
+	log.Trace()
+	var nonce [8]byte
+	rand.Read(nonce[:])
+
+	var blk mt.CmpctBlock
+	blk.Header = bk.Header
+	blk.MerkleRoot = merkleRoot
+	blk.Nonce = binary.LittleEndian.Uint64(nonce[:])
+	blkHash := bk.Hash()
+	blk.ShortIDs = make([]uint64, 0, len(bk.Transactions))
+	for _, tx := range bk.Transactions {
+		blk.ShortIDs = append(blk.ShortIDs, mt.CmpctShortID(blkHash, blk.Nonce, tx.Hash()))
+	}
+
+	return &blk
+}
+
+//compact block missing txs request package
+func NewGetBlockTxn(blkHash common.Uint256, indexes []uint32) mt.Message {
+	log.Trace()
+	var req mt.GetBlockTxn
+	req.BlockHash = blkHash
+	req.Indexes = indexes
+
+	return &req
+}
+
+//compact block missing txs package
+func NewBlockTxn(blkHash common.Uint256, txs []*ct.Transaction) mt.Message {
+	log.Trace()
+	var blkTxn mt.BlockTxn
+	blkTxn.BlockHash = blkHash
+	blkTxn.Txs = txs
+
+	return &blkTxn
+}
+
 //blk hdr package
 func NewHeaders(headers []*ct.Header) mt.Message {
 	log.Trace()
@@ -75,6 +116,17 @@ func NewHeadersReq(curHdrHash common.Uint256) mt.Message {
 	return &h
 }
 
+//NewHeadersRangeReq request the headers after stopHdrHash, ending with startHdrHash
+func NewHeadersRangeReq(startHdrHash, stopHdrHash common.Uint256) mt.Message {
+	log.Trace()
+	var h mt.HeadersReq
+	h.Len = 1
+	h.HashStart = startHdrHash
+	h.HashEnd = stopHdrHash
+
+	return &h
+}
+
 ////Consensus info package
 func NewConsensus(cp *mt.ConsensusPayload) mt.Message {
 	log.Trace()
@@ -163,6 +215,7 @@ func NewVersion(n p2pnet.P2P, isCons bool, height uint32) mt.Message {
 		StartHeight:  uint64(height),
 		TimeStamp:    time.Now().UnixNano(),
 		SoftVersion:  config.Version,
+		PubKey:       n.GetPubKey(),
 	}
 
 	if n.GetRelay() {
@@ -175,6 +228,7 @@ func NewVersion(n p2pnet.P2P, isCons bool, height uint32) mt.Message {
 	} else {
 		version.P.Cap[msgCommon.HTTP_INFO_FLAG] = 0x00
 	}
+	version.P.Cap[msgCommon.CMPCT_BLOCK_FLAG] = 0x01
 	return &version
 }
 
@@ -198,6 +252,16 @@ func NewBlkDataReq(hash common.Uint256) mt.Message {
 	return &dataReq
 }
 
+//compact block request package
+func NewCmpctBlkDataReq(hash common.Uint256) mt.Message {
+	log.Trace()
+	var dataReq mt.DataReq
+	dataReq.DataType = common.CMPCT_BLOCK
+	dataReq.Hash = hash
+
+	return &dataReq
+}
+
 //consensus request package
 func NewConsensusDataReq(hash common.Uint256) mt.Message {
 	log.Trace()
@@ -0,0 +1,207 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	ct "github.com/OnyxPay/OnyxChain/core/types"
+	comm "github.com/OnyxPay/OnyxChain/p2pserver/common"
+)
+
+//CMPCT_SHORT_ID_LEN is the byte length of a short tx id on the wire
+const CMPCT_SHORT_ID_LEN = 6
+
+//CmpctBlock carries a block header and the short ids of its transactions,
+//the receiver rebuilds the block from its tx pool
+type CmpctBlock struct {
+	Header     *ct.Header
+	MerkleRoot common.Uint256
+	Nonce      uint64
+	ShortIDs   []uint64
+}
+
+//CmpctShortID returns the short id of a tx in the compact block,
+//salted with the block hash and the sender chosen nonce
+func CmpctShortID(blockHash common.Uint256, nonce uint64, txHash common.Uint256) uint64 {
+	var buf [common.UINT256_SIZE*2 + 8]byte
+	copy(buf[:], blockHash[:])
+	binary.LittleEndian.PutUint64(buf[common.UINT256_SIZE:], nonce)
+	copy(buf[common.UINT256_SIZE+8:], txHash[:])
+	sum := sha256.Sum256(buf[:])
+
+	var id [8]byte
+	copy(id[:], sum[:CMPCT_SHORT_ID_LEN])
+	return binary.LittleEndian.Uint64(id[:])
+}
+
+//Serialize message payload
+func (this *CmpctBlock) Serialization(sink *common.ZeroCopySink) error {
+	err := this.Header.Serialization(sink)
+	if err != nil {
+		return fmt.Errorf("serialize compact block header error: %v", err)
+	}
+	sink.WriteHash(this.MerkleRoot)
+	sink.WriteUint64(this.Nonce)
+	sink.WriteVarUint(uint64(len(this.ShortIDs)))
+	var id [8]byte
+	for _, shortID := range this.ShortIDs {
+		binary.LittleEndian.PutUint64(id[:], shortID)
+		sink.WriteBytes(id[:CMPCT_SHORT_ID_LEN])
+	}
+	return nil
+}
+
+func (this *CmpctBlock) CmdType() string {
+	return comm.CMPCT_BLOCK_TYPE
+}
+
+//Deserialize message payload
+func (this *CmpctBlock) Deserialization(source *common.ZeroCopySource) error {
+	this.Header = new(ct.Header)
+	err := this.Header.Deserialization(source)
+	if err != nil {
+		return fmt.Errorf("deserialize compact block header error: %v", err)
+	}
+	var eof, irregular bool
+	this.MerkleRoot, eof = source.NextHash()
+	this.Nonce, eof = source.NextUint64()
+	count, _, irregular, eof := source.NextVarUint()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if count > source.Len()/CMPCT_SHORT_ID_LEN {
+		return io.ErrUnexpectedEOF
+	}
+
+	this.ShortIDs = make([]uint64, 0, count)
+	var id [8]byte
+	for i := uint64(0); i < count; i++ {
+		buf, eof := source.NextBytes(CMPCT_SHORT_ID_LEN)
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		copy(id[:], buf)
+		this.ShortIDs = append(this.ShortIDs, binary.LittleEndian.Uint64(id[:]))
+	}
+	return nil
+}
+
+//GetBlockTxn requests the txs at the given indexes of a compact block
+type GetBlockTxn struct {
+	BlockHash common.Uint256
+	Indexes   []uint32
+}
+
+//Serialize message payload
+func (this *GetBlockTxn) Serialization(sink *common.ZeroCopySink) error {
+	sink.WriteHash(this.BlockHash)
+	sink.WriteVarUint(uint64(len(this.Indexes)))
+	for _, index := range this.Indexes {
+		sink.WriteUint32(index)
+	}
+	return nil
+}
+
+func (this *GetBlockTxn) CmdType() string {
+	return comm.GET_BLOCK_TXN_TYPE
+}
+
+//Deserialize message payload
+func (this *GetBlockTxn) Deserialization(source *common.ZeroCopySource) error {
+	var eof, irregular bool
+	this.BlockHash, eof = source.NextHash()
+	count, _, irregular, eof := source.NextVarUint()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if count > source.Len()/common.UINT32_SIZE {
+		return io.ErrUnexpectedEOF
+	}
+
+	this.Indexes = make([]uint32, 0, count)
+	for i := uint64(0); i < count; i++ {
+		index, eof := source.NextUint32()
+		if eof {
+			return io.ErrUnexpectedEOF
+		}
+		this.Indexes = append(this.Indexes, index)
+	}
+	return nil
+}
+
+//BlockTxn answers GetBlockTxn with the requested txs in index order
+type BlockTxn struct {
+	BlockHash common.Uint256
+	Txs       []*ct.Transaction
+}
+
+//Serialize message payload
+func (this *BlockTxn) Serialization(sink *common.ZeroCopySink) error {
+	sink.WriteHash(this.BlockHash)
+	sink.WriteVarUint(uint64(len(this.Txs)))
+	for _, tx := range this.Txs {
+		err := tx.Serialization(sink)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *BlockTxn) CmdType() string {
+	return comm.BLOCK_TXN_TYPE
+}
+
+//Deserialize message payload
+func (this *BlockTxn) Deserialization(source *common.ZeroCopySource) error {
+	var eof, irregular bool
+	this.BlockHash, eof = source.NextHash()
+	count, _, irregular, eof := source.NextVarUint()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if count > source.Len() {
+		return errors.New("too many txs in block txn message")
+	}
+
+	for i := uint64(0); i < count; i++ {
+		tx := new(ct.Transaction)
+		err := tx.Deserialization(source)
+		if err != nil {
+			return err
+		}
+		this.Txs = append(this.Txs, tx)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/payload"
+	ct "github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmpctBlockSerializationDeserialization(t *testing.T) {
+	var msg CmpctBlock
+	msg.Header = &ct.Header{
+		Version:          1,
+		Height:           100,
+		Timestamp:        1234567,
+		ConsensusPayload: []byte("payload"),
+	}
+	msg.MerkleRoot = common.Uint256{1, 2, 3}
+	msg.Nonce = 0x123456789
+	msg.ShortIDs = []uint64{1, 0xffffffffffff, 0x0102030405}
+
+	MessageTest(t, &msg)
+}
+
+func TestGetBlockTxnSerializationDeserialization(t *testing.T) {
+	var msg GetBlockTxn
+	msg.BlockHash = common.Uint256{4, 5, 6}
+	msg.Indexes = []uint32{0, 3, 100}
+
+	MessageTest(t, &msg)
+}
+
+func TestBlockTxnSerializationDeserialization(t *testing.T) {
+	var msg BlockTxn
+	msg.BlockHash = common.Uint256{7, 8, 9}
+	for i := 0; i < 2; i++ {
+		mutable := &ct.MutableTransaction{
+			TxType:  ct.Invoke,
+			Nonce:   uint32(i),
+			Payload: &payload.InvokeCode{Code: []byte("onyx")},
+		}
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		msg.Txs = append(msg.Txs, tx)
+	}
+
+	sink := common.NewZeroCopySink(nil)
+	err := WriteMessage(sink, &msg)
+	assert.Nil(t, err)
+	demsg, _, err := ReadMessage(bytes.NewBuffer(sink.Bytes()))
+	assert.Nil(t, err)
+
+	blkTxn := demsg.(*BlockTxn)
+	assert.Equal(t, msg.BlockHash, blkTxn.BlockHash)
+	assert.Equal(t, len(msg.Txs), len(blkTxn.Txs))
+	for i, tx := range msg.Txs {
+		assert.Equal(t, tx.Hash(), blkTxn.Txs[i].Hash())
+	}
+}
+
+func TestCmpctShortID(t *testing.T) {
+	blkHash := common.Uint256{1}
+	txHash := common.Uint256{2}
+
+	id := CmpctShortID(blkHash, 1, txHash)
+	assert.True(t, id < 1<<(8*CMPCT_SHORT_ID_LEN))
+	assert.Equal(t, id, CmpctShortID(blkHash, 1, txHash))
+	assert.NotEqual(t, id, CmpctShortID(blkHash, 2, txHash))
+	assert.NotEqual(t, id, CmpctShortID(common.Uint256{3}, 1, txHash))
+}
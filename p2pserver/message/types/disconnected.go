@@ -23,7 +23,9 @@ import (
 	"github.com/OnyxPay/OnyxChain/p2pserver/common"
 )
 
-type Disconnected struct{}
+type Disconnected struct {
+	Malformed bool //link broken by malformed message, never on wire
+}
 
 //Serialize message payload
 func (this Disconnected) Serialization(sink *comm.ZeroCopySink) error {
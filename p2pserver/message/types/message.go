@@ -84,6 +84,15 @@ func newMessageHeader(cmd string, length uint32, checksum [common.CHECKSUM_LEN]b
 	return msgh
 }
 
+//MalformedMsgError is returned by ReadMessage if the message breaks the protocol
+type MalformedMsgError struct {
+	Err error
+}
+
+func (this *MalformedMsgError) Error() string {
+	return this.Err.Error()
+}
+
 func WriteMessage(sink *comm.ZeroCopySink, msg Message) error {
 	pstart := sink.Size()
 	sink.NextBytes(common.MSG_HDR_LEN) // can not save the buf, since it may reallocate in sink
@@ -115,12 +124,12 @@ func ReadMessage(reader io.Reader) (Message, uint32, error) {
 
 	magic := config.DefConfig.P2PNode.NetworkMagic
 	if hdr.Magic != magic {
-		return nil, 0, fmt.Errorf("unmatched magic number %d, expected %d", hdr.Magic, magic)
+		return nil, 0, &MalformedMsgError{fmt.Errorf("unmatched magic number %d, expected %d", hdr.Magic, magic)}
 	}
 
 	if hdr.Length > common.MAX_PAYLOAD_LEN {
-		return nil, 0, fmt.Errorf("msg payload length:%d exceed max payload size: %d",
-			hdr.Length, common.MAX_PAYLOAD_LEN)
+		return nil, 0, &MalformedMsgError{fmt.Errorf("msg payload length:%d exceed max payload size: %d",
+			hdr.Length, common.MAX_PAYLOAD_LEN)}
 	}
 
 	buf := make([]byte, hdr.Length)
@@ -131,20 +140,20 @@ func ReadMessage(reader io.Reader) (Message, uint32, error) {
 
 	checksum := common.Checksum(buf)
 	if checksum != hdr.Checksum {
-		return nil, 0, fmt.Errorf("message checksum mismatch: %x != %x ", hdr.Checksum, checksum)
+		return nil, 0, &MalformedMsgError{fmt.Errorf("message checksum mismatch: %x != %x ", hdr.Checksum, checksum)}
 	}
 
 	cmdType := string(bytes.TrimRight(hdr.CMD[:], string(0)))
 	msg, err := MakeEmptyMessage(cmdType)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, &MalformedMsgError{err}
 	}
 
 	// the buf is referenced by msg to avoid reallocation, so can not reused
 	source := comm.NewZeroCopySource(buf)
 	err = msg.Deserialization(source)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, &MalformedMsgError{err}
 	}
 
 	return msg, hdr.Length, nil
@@ -184,6 +193,12 @@ func MakeEmptyMessage(cmdType string) (Message, error) {
 		return &Disconnected{}, nil
 	case common.GET_BLOCKS_TYPE:
 		return &BlocksReq{}, nil
+	case common.CMPCT_BLOCK_TYPE:
+		return &CmpctBlock{}, nil
+	case common.GET_BLOCK_TXN_TYPE:
+		return &GetBlockTxn{}, nil
+	case common.BLOCK_TXN_TYPE:
+		return &BlockTxn{}, nil
 	default:
 		return nil, errors.New("unsupported cmd type:" + cmdType)
 	}
@@ -38,6 +38,7 @@ type VersionPayload struct {
 	Relay        uint8
 	IsConsensus  bool
 	SoftVersion  string
+	PubKey       []byte //node key bound to Nonce
 }
 
 type Version struct {
@@ -58,6 +59,7 @@ func (this *Version) Serialization(sink *comm.ZeroCopySink) error {
 	sink.WriteUint8(this.P.Relay)
 	sink.WriteBool(this.P.IsConsensus)
 	sink.WriteString(this.P.SoftVersion)
+	sink.WriteVarBytes(this.P.PubKey)
 
 	return nil
 }
@@ -93,6 +95,11 @@ func (this *Version) Deserialization(source *comm.ZeroCopySource) error {
 	this.P.SoftVersion, _, irregular, eof = source.NextString()
 	if eof || irregular {
 		this.P.SoftVersion = ""
+		return nil
+	}
+	this.P.PubKey, _, irregular, eof = source.NextVarBytes()
+	if eof || irregular {
+		this.P.PubKey = nil
 	}
 
 	return nil
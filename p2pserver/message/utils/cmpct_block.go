@@ -0,0 +1,122 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	msgCommon "github.com/OnyxPay/OnyxChain/p2pserver/common"
+	msgTypes "github.com/OnyxPay/OnyxChain/p2pserver/message/types"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+//cmpctPending is a compact block waiting for its missing txs
+type cmpctPending struct {
+	fromID     uint64
+	size       uint32
+	nonce      uint64
+	shortIDs   []uint64
+	missing    []uint32
+	block      *types.Block
+	merkleRoot common.Uint256
+}
+
+//cmpctCache keeps the compact blocks waiting for missing txs
+var cmpctCache, _ = lru.New(msgCommon.MAX_CMPCT_PENDING)
+
+//rebuildCmpctBlock fills the compact block with txs from pool, and returns
+//the indexes of txs not found
+func rebuildCmpctBlock(cmpct *msgTypes.CmpctBlock, pool []*types.Transaction) (*types.Block, []uint32, error) {
+	if cmpct.Header == nil {
+		return nil, nil, errors.New("compact block without header")
+	}
+	blkHash := cmpct.Header.Hash()
+	index := make(map[uint64]int, len(cmpct.ShortIDs))
+	for i, id := range cmpct.ShortIDs {
+		if _, ok := index[id]; ok {
+			return nil, nil, fmt.Errorf("duplicated short id in compact block %s", blkHash.ToHexString())
+		}
+		index[id] = i
+	}
+
+	txs := make([]*types.Transaction, len(cmpct.ShortIDs))
+	collided := make(map[int]bool)
+	for _, tx := range pool {
+		i, ok := index[msgTypes.CmpctShortID(blkHash, cmpct.Nonce, tx.Hash())]
+		if !ok {
+			continue
+		}
+		//two pool txs share the short id, leave it to the sender
+		if txs[i] != nil && txs[i].Hash() != tx.Hash() {
+			collided[i] = true
+		}
+		txs[i] = tx
+	}
+
+	var missing []uint32
+	for i, tx := range txs {
+		if tx == nil || collided[i] {
+			txs[i] = nil
+			missing = append(missing, uint32(i))
+		}
+	}
+	return &types.Block{Header: cmpct.Header, Transactions: txs}, missing, nil
+}
+
+//fillCmpctBlock puts the requested txs into the pending block
+func fillCmpctBlock(pending *cmpctPending, txs []*types.Transaction) error {
+	if len(txs) != len(pending.missing) {
+		return fmt.Errorf("expect %d txs, got %d", len(pending.missing), len(txs))
+	}
+	blkHash := pending.block.Hash()
+	for i, tx := range txs {
+		index := pending.missing[i]
+		txHash := tx.Hash()
+		if msgTypes.CmpctShortID(blkHash, pending.nonce, txHash) != pending.shortIDs[index] {
+			return fmt.Errorf("tx %s mismatches short id at index %d", txHash.ToHexString(), index)
+		}
+		pending.block.Transactions[index] = tx
+	}
+	pending.missing = nil
+	return nil
+}
+
+//checkCmpctBlock checks the rebuilt txs against the header tx root
+func checkCmpctBlock(block *types.Block) error {
+	hashes := make([]common.Uint256, 0, len(block.Transactions))
+	mask := make(map[common.Uint256]bool, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		if tx == nil {
+			return errors.New("missing transaction in block")
+		}
+		txHash := tx.Hash()
+		if mask[txHash] {
+			return errors.New("duplicated transaction in block")
+		}
+		mask[txHash] = true
+		hashes = append(hashes, txHash)
+	}
+	if common.ComputeMerkleRoot(hashes) != block.Header.TransactionsRoot {
+		return errors.New("mismatched transaction root")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/p2pserver/message/msg_pack"
+	msgTypes "github.com/OnyxPay/OnyxChain/p2pserver/message/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCmpctTestTxs(t *testing.T, n int) []*types.Transaction {
+	txs := make([]*types.Transaction, 0, n)
+	for i := 0; i < n; i++ {
+		mutable := &types.MutableTransaction{
+			TxType:  types.Invoke,
+			Nonce:   uint32(i),
+			Payload: &payload.InvokeCode{Code: []byte("onyx")},
+		}
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func TestRebuildCmpctBlock(t *testing.T) {
+	txs := newCmpctTestTxs(t, 5)
+	block := &types.Block{
+		Header:       &types.Header{Height: 10},
+		Transactions: txs[:4],
+	}
+	block.RebuildMerkleRoot()
+	cmpct := msgpack.NewCmpctBlock(block, block.Header.TransactionsRoot).(*msgTypes.CmpctBlock)
+	assert.Equal(t, 4, len(cmpct.ShortIDs))
+
+	//all txs in pool
+	rebuilt, missing, err := rebuildCmpctBlock(cmpct, txs)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(missing))
+	assert.Nil(t, checkCmpctBlock(rebuilt))
+	assert.Equal(t, block.Hash(), rebuilt.Hash())
+
+	//some txs missing from pool
+	rebuilt, missing, err = rebuildCmpctBlock(cmpct, []*types.Transaction{txs[2], txs[0], txs[4]})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{1, 3}, missing)
+	assert.NotNil(t, checkCmpctBlock(rebuilt))
+
+	pending := &cmpctPending{
+		nonce:    cmpct.Nonce,
+		shortIDs: cmpct.ShortIDs,
+		missing:  missing,
+		block:    rebuilt,
+	}
+	err = fillCmpctBlock(pending, []*types.Transaction{txs[3], txs[1]})
+	assert.NotNil(t, err)
+	err = fillCmpctBlock(pending, []*types.Transaction{txs[1]})
+	assert.NotNil(t, err)
+	err = fillCmpctBlock(pending, []*types.Transaction{txs[1], txs[3]})
+	assert.Nil(t, err)
+	assert.Nil(t, checkCmpctBlock(pending.block))
+	for i, tx := range pending.block.Transactions {
+		assert.Equal(t, txs[i].Hash(), tx.Hash())
+	}
+}
+
+func TestRebuildCmpctBlockDuplicatedID(t *testing.T) {
+	txs := newCmpctTestTxs(t, 1)
+	block := &types.Block{
+		Header:       &types.Header{Height: 10},
+		Transactions: txs,
+	}
+	block.RebuildMerkleRoot()
+	cmpct := msgpack.NewCmpctBlock(block, block.Header.TransactionsRoot).(*msgTypes.CmpctBlock)
+	cmpct.ShortIDs = append(cmpct.ShortIDs, cmpct.ShortIDs[0])
+
+	_, _, err := rebuildCmpctBlock(cmpct, txs)
+	assert.NotNil(t, err)
+}
@@ -19,11 +19,11 @@
 package utils
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net"
 	"strconv"
-	"strings"
 	"time"
 
 	lru "github.com/hashicorp/golang-lru"
@@ -35,6 +35,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/core/types"
 	actor "github.com/OnyxPay/OnyxChain/p2pserver/actor/req"
 	msgCommon "github.com/OnyxPay/OnyxChain/p2pserver/common"
+	msgConn "github.com/OnyxPay/OnyxChain/p2pserver/link"
 	"github.com/OnyxPay/OnyxChain/p2pserver/message/msg_pack"
 	msgTypes "github.com/OnyxPay/OnyxChain/p2pserver/message/types"
 	"github.com/OnyxPay/OnyxChain/p2pserver/net/protocol"
@@ -171,6 +172,120 @@ func BlockHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args
 	}
 }
 
+// CmpctBlockHandle rebuilds the compact block from txnpool, and requests
+// the missing txs from peer
+func CmpctBlockHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive compact block message from ", data.Addr, data.Id)
+
+	var cmpct = data.Payload.(*msgTypes.CmpctBlock)
+	blkHash := cmpct.Header.Hash()
+	pool, err := actor.GetTxnList()
+	if err != nil {
+		log.Warn(err)
+		reqFullBlock(p2p, data.Id, blkHash)
+		return
+	}
+	block, missing, err := rebuildCmpctBlock(cmpct, pool)
+	if err != nil {
+		log.Warnf("[p2p]rebuild compact block error: %s", err)
+		p2p.PenalizePeer(data.Id, data.Addr, msgCommon.PENALTY_INVALID_REQ, err.Error())
+		reqFullBlock(p2p, data.Id, blkHash)
+		return
+	}
+	pending := &cmpctPending{
+		fromID:     data.Id,
+		size:       data.PayloadSize,
+		nonce:      cmpct.Nonce,
+		shortIDs:   cmpct.ShortIDs,
+		missing:    missing,
+		block:      block,
+		merkleRoot: cmpct.MerkleRoot,
+	}
+	if len(missing) == 0 {
+		appendCmpctBlock(p2p, pid, pending)
+		return
+	}
+
+	remotePeer := p2p.GetPeer(data.Id)
+	if remotePeer == nil {
+		log.Debug("[p2p]remotePeer invalid in CmpctBlockHandle")
+		return
+	}
+	log.Debugf("[p2p]compact block %s missing %d of %d txs", blkHash.ToHexString(),
+		len(missing), len(cmpct.ShortIDs))
+	cmpctCache.Add(blkHash, pending)
+	msg := msgpack.NewGetBlockTxn(blkHash, missing)
+	err = p2p.Send(remotePeer, msg, false)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+}
+
+// GetBlockTxnHandle handles the missing txs request of compact block from peer
+func GetBlockTxnHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive get block txn message from ", data.Addr, data.Id)
+
+	var req = data.Payload.(*msgTypes.GetBlockTxn)
+	remotePeer := p2p.GetPeer(data.Id)
+	if remotePeer == nil {
+		log.Debug("[p2p]remotePeer invalid in GetBlockTxnHandle")
+		return
+	}
+	var msg msgTypes.Message
+	block, err := ledger.DefLedger.GetBlockByHash(req.BlockHash)
+	if err != nil || block == nil {
+		log.Debug("[p2p]can't get block by hash: ", req.BlockHash,
+			" ,send not found message")
+		msg = msgpack.NewNotFound(req.BlockHash)
+	} else {
+		txs := make([]*types.Transaction, 0, len(req.Indexes))
+		for _, index := range req.Indexes {
+			if int(index) >= len(block.Transactions) {
+				log.Warnf("[p2p]invalid tx index %d of block %s", index, req.BlockHash.ToHexString())
+				p2p.PenalizePeer(data.Id, data.Addr, msgCommon.PENALTY_INVALID_REQ, "invalid block txn index")
+				return
+			}
+			txs = append(txs, block.Transactions[index])
+		}
+		msg = msgpack.NewBlockTxn(req.BlockHash, txs)
+	}
+	err = p2p.Send(remotePeer, msg, false)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+}
+
+// BlockTxnHandle completes the pending compact block with the txs from peer
+func BlockTxnHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
+	log.Trace("[p2p]receive block txn message from ", data.Addr, data.Id)
+
+	var blkTxn = data.Payload.(*msgTypes.BlockTxn)
+	value, ok := cmpctCache.Get(blkTxn.BlockHash)
+	if !ok {
+		log.Debug("[p2p]no pending compact block: ", blkTxn.BlockHash)
+		return
+	}
+	pending := value.(*cmpctPending)
+	if pending.fromID != data.Id {
+		log.Debugf("[p2p]block txn of %s from unexpected peer %d",
+			blkTxn.BlockHash.ToHexString(), data.Id)
+		return
+	}
+	cmpctCache.Remove(blkTxn.BlockHash)
+
+	err := fillCmpctBlock(pending, blkTxn.Txs)
+	if err != nil {
+		log.Warnf("[p2p]fill compact block error: %s", err)
+		p2p.PenalizePeer(data.Id, data.Addr, msgCommon.PENALTY_INVALID_REQ, err.Error())
+		reqFullBlock(p2p, data.Id, blkTxn.BlockHash)
+		return
+	}
+	pending.size += data.PayloadSize
+	appendCmpctBlock(p2p, pid, pending)
+}
+
 // ConsensusHandle handles the consensus message from peer
 func ConsensusHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
 	log.Debugf("[p2p]receive consensus message:%v,%d", data.Addr, data.Id)
@@ -179,6 +294,7 @@ func ConsensusHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID,
 		var consensus = data.Payload.(*msgTypes.Consensus)
 		if err := consensus.Cons.Verify(); err != nil {
 			log.Warn(err)
+			p2p.PenalizePeer(data.Id, data.Addr, msgCommon.PENALTY_INVALID_CONS, "invalid consensus payload")
 			return
 		}
 		consensus.Cons.PeerId = data.Id
@@ -197,6 +313,10 @@ func TransactionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID
 	log.Trace("[p2p]receive transaction message", data.Addr, data.Id)
 
 	var trn = data.Payload.(*msgTypes.Trn)
+	if p2p.GetNp().CountTx(data.Id) {
+		p2p.PenalizePeer(data.Id, data.Addr, msgCommon.PENALTY_TX_SPAM, "tx rate exceeded")
+		return
+	}
 	actor.AddTransaction(trn.Txn)
 	log.Trace("[p2p]receive Transaction message hash", trn.Txn.Hash())
 
@@ -222,22 +342,23 @@ func VersionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 	}
 	nodeAddr := addrIp + ":" +
 		strconv.Itoa(int(version.P.SyncPort))
-	if config.DefConfig.P2PNode.ReservedPeersOnly && len(config.DefConfig.P2PNode.ReservedCfg.ReservedPeers) > 0 {
-		found := false
-		for _, addr := range config.DefConfig.P2PNode.ReservedCfg.ReservedPeers {
-			if strings.HasPrefix(data.Addr, addr) {
-				log.Debug("[p2p]peer in reserved list", data.Addr)
-				found = true
-				break
-			}
-		}
-		if !found {
-			remotePeer.CloseSync()
-			remotePeer.CloseCons()
-			log.Debug("[p2p]peer not in reserved list,close", data.Addr)
-			return
-		}
-
+	linkKey := remotePeer.SyncLink.GetPubKey()
+	if version.P.IsConsensus {
+		linkKey = remotePeer.ConsLink.GetPubKey()
+	}
+	if !p2p.PeerValid(data.Addr, linkKey) {
+		remotePeer.CloseSync()
+		remotePeer.CloseCons()
+		log.Debug("[p2p]peer not in reserved list,close", data.Addr)
+		return
+	}
+	//peer id must be bound to the node key authenticated by link
+	if !bytes.Equal(version.P.PubKey, linkKey) || msgConn.PeerIDFromPubKey(linkKey) != version.P.Nonce {
+		p2p.PenalizePeer(data.Id, data.Addr, msgCommon.PENALTY_INVALID_PEER, "peer id not bound to node key")
+		remotePeer.CloseSync()
+		remotePeer.CloseCons()
+		log.Warnf("[p2p]peer id %d not bound to node key %x, close %s", version.P.Nonce, linkKey, data.Addr)
+		return
 	}
 
 	if version.P.IsConsensus == true {
@@ -357,6 +478,7 @@ func VersionHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 		} else {
 			remotePeer.SetHttpInfoState(false)
 		}
+		remotePeer.SetCmpctBlockState(version.P.Cap[msgCommon.CMPCT_BLOCK_FLAG] == 0x01)
 		remotePeer.SetHttpInfoPort(version.P.HttpInfoPort)
 
 		remotePeer.UpdateInfo(time.Now(), version.P.Version,
@@ -503,7 +625,7 @@ func DataReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 	reqType := common.InventoryType(dataReq.DataType)
 	hash := dataReq.Hash
 	switch reqType {
-	case common.BLOCK:
+	case common.BLOCK, common.CMPCT_BLOCK:
 		reqID := fmt.Sprintf("%x%s", reqType, hash.ToHexString())
 		data := getRespCacheValue(reqID)
 		var msg msgTypes.Message
@@ -511,6 +633,8 @@ func DataReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 			switch data.(type) {
 			case *msgTypes.Block:
 				msg = data.(*msgTypes.Block)
+			case *msgTypes.CmpctBlock:
+				msg = data.(*msgTypes.CmpctBlock)
 			}
 		}
 		if msg == nil {
@@ -539,7 +663,11 @@ func DataReqHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, ar
 				}
 				return
 			}
-			msg = msgpack.NewBlock(block, merkleRoot)
+			if reqType == common.CMPCT_BLOCK {
+				msg = msgpack.NewCmpctBlock(block, merkleRoot)
+			} else {
+				msg = msgpack.NewBlock(block, merkleRoot)
+			}
 			saveRespCache(reqID, msg)
 		}
 		err := p2p.Send(remotePeer, msg, false)
@@ -618,7 +746,12 @@ func InvHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args .
 				msgTypes.LastInvHash = id
 				// send the block request
 				log.Infof("[p2p]inv request block hash: %x", id)
-				msg := msgpack.NewBlkDataReq(id)
+				var msg msgTypes.Message
+				if remotePeer.GetCmpctBlockState() {
+					msg = msgpack.NewCmpctBlkDataReq(id)
+				} else {
+					msg = msgpack.NewBlkDataReq(id)
+				}
 				err = p2p.Send(remotePeer, msg, false)
 				if err != nil {
 					log.Warn(err)
@@ -637,6 +770,7 @@ func InvHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args .
 		}
 	default:
 		log.Warn("[p2p]receive unknown inventory message")
+		p2p.PenalizePeer(data.Id, data.Addr, msgCommon.PENALTY_INVALID_REQ, "unknown inventory type")
 	}
 
 }
@@ -644,6 +778,9 @@ func InvHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args .
 // DisconnectHandle handles the disconnect events
 func DisconnectHandle(data *msgTypes.MsgPayload, p2p p2p.P2P, pid *evtActor.PID, args ...interface{}) {
 	log.Debug("[p2p]receive disconnect message", data.Addr, data.Id)
+	if data.Payload.(*msgTypes.Disconnected).Malformed {
+		p2p.PenalizePeer(data.Id, data.Addr, msgCommon.PENALTY_MALFORMED_MSG, "malformed message")
+	}
 	p2p.RemoveFromInConnRecord(data.Addr)
 	p2p.RemoveFromOutConnRecord(data.Addr)
 	remotePeer := p2p.GetPeer(data.Id)
@@ -737,6 +874,40 @@ func GetHeadersFromHash(startHash common.Uint256, stopHash common.Uint256) ([]*t
 	return headers, nil
 }
 
+//appendCmpctBlock hands the rebuilt block to the block sync, or falls
+//back to the full block if it mismatches the header
+func appendCmpctBlock(p2p p2p.P2P, pid *evtActor.PID, pending *cmpctPending) {
+	err := checkCmpctBlock(pending.block)
+	if err != nil {
+		log.Warnf("[p2p]check compact block error: %s", err)
+		reqFullBlock(p2p, pending.fromID, pending.block.Hash())
+		return
+	}
+	if pid != nil {
+		input := &msgCommon.AppendBlock{
+			FromID:     pending.fromID,
+			BlockSize:  pending.size,
+			Block:      pending.block,
+			MerkleRoot: pending.merkleRoot,
+		}
+		pid.Tell(input)
+	}
+}
+
+//reqFullBlock requests the full block from peer
+func reqFullBlock(p2p p2p.P2P, id uint64, hash common.Uint256) {
+	remotePeer := p2p.GetPeer(id)
+	if remotePeer == nil {
+		log.Debug("[p2p]remotePeer invalid in reqFullBlock")
+		return
+	}
+	msg := msgpack.NewBlkDataReq(hash)
+	err := p2p.Send(remotePeer, msg, false)
+	if err != nil {
+		log.Warn(err)
+	}
+}
+
 //getRespCacheValue get response data from cache
 func getRespCacheValue(key string) interface{} {
 	if respCache == nil {
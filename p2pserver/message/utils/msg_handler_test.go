@@ -35,12 +35,14 @@ import (
 	ct "github.com/OnyxPay/OnyxChain/core/types"
 	"github.com/OnyxPay/OnyxChain/events"
 	msgCommon "github.com/OnyxPay/OnyxChain/p2pserver/common"
+	"github.com/OnyxPay/OnyxChain/p2pserver/link"
 	"github.com/OnyxPay/OnyxChain/p2pserver/message/msg_pack"
 	"github.com/OnyxPay/OnyxChain/p2pserver/message/types"
 	"github.com/OnyxPay/OnyxChain/p2pserver/net/netserver"
 	"github.com/OnyxPay/OnyxChain/p2pserver/net/protocol"
 	"github.com/OnyxPay/OnyxChain/p2pserver/peer"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
 )
 
 var (
@@ -86,16 +88,18 @@ func TestVersionHandle(t *testing.T) {
 
 	network.AddPeerSyncAddress("127.0.0.1:50010", remotePeer)
 
-	var testID uint64
-	_, testPub, _ := keypair.GenerateKeyPair(keypair.PK_ECDSA, keypair.P256)
-	key := keypair.SerializePublicKey(testPub)
-	err := binary.Read(bytes.NewBuffer(key[:8]), binary.LittleEndian, &(testID))
+	// The node key authenticated by link
+	nodeKey, err := link.NewNodeKey()
 	assert.Nil(t, err)
+	pubKey := []byte(nodeKey.Public().(ed25519.PublicKey))
+	remotePeer.SyncLink.SetPubKey(pubKey)
+	testID := link.PeerIDFromPubKey(pubKey)
 
 	// Construct a version packet
 	buf := msgpack.NewVersion(network, false, 12345)
 	version := buf.(*types.Version)
-	version.P.Nonce = testID
+	version.P.Nonce = testID + 1
+	version.P.PubKey = pubKey
 
 	msg := &types.MsgPayload{
 		Id:      testID,
@@ -103,6 +107,16 @@ func TestVersionHandle(t *testing.T) {
 		Payload: buf,
 	}
 
+	// Peer id not bound to node key is rejected, and the peer is closed
+	VersionHandle(msg, network, nil)
+	assert.Nil(t, network.GetPeer(testID+1))
+
+	// A new connection of the same node with the bound peer id
+	remotePeer = peer.NewPeer()
+	remotePeer.SyncLink.SetPubKey(pubKey)
+	network.AddPeerSyncAddress("127.0.0.1:50010", remotePeer)
+	version.P.Nonce = testID
+
 	// Invoke VersionHandle to handle the msg
 	VersionHandle(msg, network, nil)
 
@@ -73,6 +73,9 @@ func (this *MessageRouter) init(p2p p2p.P2P) {
 	this.RegisterMsgHandler(msgCommon.NOT_FOUND_TYPE, NotFoundHandle)
 	this.RegisterMsgHandler(msgCommon.TX_TYPE, TransactionHandle)
 	this.RegisterMsgHandler(msgCommon.DISCONNECT_TYPE, DisconnectHandle)
+	this.RegisterMsgHandler(msgCommon.CMPCT_BLOCK_TYPE, CmpctBlockHandle)
+	this.RegisterMsgHandler(msgCommon.GET_BLOCK_TXN_TYPE, GetBlockTxnHandle)
+	this.RegisterMsgHandler(msgCommon.BLOCK_TXN_TYPE, BlockTxnHandle)
 }
 
 // RegisterMsgHandler registers msg handler with the msg type
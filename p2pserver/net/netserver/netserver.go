@@ -19,9 +19,11 @@
 package netserver
 
 import (
+	"encoding/hex"
 	"errors"
-	"math/rand"
+	"fmt"
 	"net"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -30,10 +32,12 @@ import (
 	"github.com/OnyxPay/OnyxChain/common/log"
 	"github.com/OnyxPay/OnyxChain/core/ledger"
 	"github.com/OnyxPay/OnyxChain/p2pserver/common"
+	"github.com/OnyxPay/OnyxChain/p2pserver/link"
 	"github.com/OnyxPay/OnyxChain/p2pserver/message/msg_pack"
 	"github.com/OnyxPay/OnyxChain/p2pserver/message/types"
 	"github.com/OnyxPay/OnyxChain/p2pserver/net/protocol"
 	"github.com/OnyxPay/OnyxChain/p2pserver/peer"
+	"golang.org/x/crypto/ed25519"
 )
 
 //NewNetServer return the net object in p2p
@@ -64,6 +68,8 @@ type NetServer struct {
 	inConnRecord  InConnectionRecord
 	outConnRecord OutConnectionRecord
 	OwnAddress    string //network`s own address(ip : sync port),which get from version check
+	nodeKey       ed25519.PrivateKey
+	banFile       string //file persisting the banned peers
 }
 
 //InConnectionRecord include all addr connected
@@ -121,15 +127,30 @@ func (this *NetServer) init() error {
 
 	this.base.SetRelay(true)
 
-	rand.Seed(time.Now().UnixNano())
-	id := rand.Uint64()
+	keyPath := NodeKeyPath()
+	key, err := link.LoadOrCreateNodeKey(keyPath)
+	if err != nil {
+		log.Warnf("[p2p]load node key %s error:%s, use a temporary key", keyPath, err)
+		key, err = link.NewNodeKey()
+		if err != nil {
+			return err
+		}
+	}
+	this.nodeKey = key
+	id := link.PeerIDFromPubKey(this.GetPubKey())
 
 	this.base.SetID(id)
 
-	log.Infof("[p2p]init peer ID to %d", this.base.GetID())
+	log.Infof("[p2p]init peer ID to %d, node key %x", this.base.GetID(), this.GetPubKey())
 	this.Np = &peer.NbrPeers{}
 	this.Np.Init()
 
+	this.banFile = filepath.Join(config.DefConfig.Common.DataDir, common.BAN_FILE_NAME)
+	err = this.Np.LoadBanList(this.banFile)
+	if err != nil {
+		log.Warnf("[p2p]load ban list %s error:%s", this.banFile, err)
+	}
+
 	return nil
 }
 
@@ -138,6 +159,24 @@ func (this *NetServer) Start() {
 	this.startListening()
 }
 
+//NodeKeyPath return the node key file in config, <DataDir>/nodekey by default
+func NodeKeyPath() string {
+	if config.DefConfig.P2PNode.NodeKeyPath != "" {
+		return config.DefConfig.P2PNode.NodeKeyPath
+	}
+	return filepath.Join(config.DefConfig.Common.DataDir, link.NODE_KEY_FILE)
+}
+
+//GetNodeKey return the private node key
+func (this *NetServer) GetNodeKey() ed25519.PrivateKey {
+	return this.nodeKey
+}
+
+//GetPubKey return the public node key bound to peer id
+func (this *NetServer) GetPubKey() []byte {
+	return this.nodeKey.Public().(ed25519.PublicKey)
+}
+
 //GetVersion return self peer`s version
 func (this *NetServer) GetVersion() uint32 {
 	return this.base.GetVersion()
@@ -275,6 +314,10 @@ func (this *NetServer) Connect(addr string, isConsensus bool) error {
 	if !this.AddrValid(addr) {
 		return nil
 	}
+	if this.IsBanned(addr) {
+		log.Debugf("[p2p]Address: %s is banned", addr)
+		return nil
+	}
 
 	this.connectLock.Lock()
 	connCount := uint(this.GetOutConnRecordLen())
@@ -320,12 +363,24 @@ func (this *NetServer) Connect(addr string, isConsensus bool) error {
 		conn.LocalAddr().String(), conn.RemoteAddr().String(),
 		conn.RemoteAddr().Network())
 
+	secConn, err := this.handshake(conn, addr, true)
+	if err != nil {
+		if err == link.ErrSelfConnect {
+			this.SetOwnAddress(addr)
+		}
+		this.RemoveFromConnectingList(addr)
+		log.Debugf("[p2p]handshake with %s failed:%s", addr, err.Error())
+		return err
+	}
+	conn = secConn
+
 	if !isConsensus {
 		this.AddOutConnRecord(addr)
 		remotePeer = peer.NewPeer()
 		this.AddPeerSyncAddress(addr, remotePeer)
 		remotePeer.SyncLink.SetAddr(addr)
 		remotePeer.SyncLink.SetConn(conn)
+		remotePeer.SyncLink.SetPubKey(secConn.RemoteKey())
 		remotePeer.AttachSyncChan(this.SyncChan)
 		go remotePeer.SyncLink.Rx()
 		remotePeer.SetSyncState(common.HAND)
@@ -335,6 +390,7 @@ func (this *NetServer) Connect(addr string, isConsensus bool) error {
 		this.AddPeerConsAddress(addr, remotePeer)
 		remotePeer.ConsLink.SetAddr(addr)
 		remotePeer.ConsLink.SetConn(conn)
+		remotePeer.ConsLink.SetPubKey(secConn.RemoteKey())
 		remotePeer.AttachConsChan(this.ConsChan)
 		go remotePeer.ConsLink.Rx()
 		remotePeer.SetConsState(common.HAND)
@@ -446,6 +502,11 @@ func (this *NetServer) startSyncAccept(listener net.Listener) {
 			conn.Close()
 			continue
 		}
+		if this.IsBanned(conn.RemoteAddr().String()) {
+			log.Debugf("[p2p]remote %s is banned, close it ", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
 
 		if this.IsAddrInInConnRecord(conn.RemoteAddr().String()) {
 			conn.Close()
@@ -474,17 +535,28 @@ func (this *NetServer) startSyncAccept(listener net.Listener) {
 			continue
 		}
 
-		remotePeer := peer.NewPeer()
 		addr := conn.RemoteAddr().String()
 		this.AddInConnRecord(addr)
+		go this.acceptSyncConn(conn, addr)
+	}
+}
 
-		this.AddPeerSyncAddress(addr, remotePeer)
-
-		remotePeer.SyncLink.SetAddr(addr)
-		remotePeer.SyncLink.SetConn(conn)
-		remotePeer.AttachSyncChan(this.SyncChan)
-		go remotePeer.SyncLink.Rx()
+//acceptSyncConn finish the handshake of inbound sync connection and start receiving
+func (this *NetServer) acceptSyncConn(conn net.Conn, addr string) {
+	secConn, err := this.handshake(conn, addr, false)
+	if err != nil {
+		log.Debugf("[p2p]handshake with %s failed:%s", addr, err.Error())
+		this.RemoveFromInConnRecord(addr)
+		return
 	}
+	remotePeer := peer.NewPeer()
+	this.AddPeerSyncAddress(addr, remotePeer)
+
+	remotePeer.SyncLink.SetAddr(addr)
+	remotePeer.SyncLink.SetConn(secConn)
+	remotePeer.SyncLink.SetPubKey(secConn.RemoteKey())
+	remotePeer.AttachSyncChan(this.SyncChan)
+	go remotePeer.SyncLink.Rx()
 }
 
 //startConsAccept accepts the consensus connnection from the inbound peer
@@ -502,6 +574,11 @@ func (this *NetServer) startConsAccept(listener net.Listener) {
 			conn.Close()
 			continue
 		}
+		if this.IsBanned(conn.RemoteAddr().String()) {
+			log.Debugf("[p2p]remote %s is banned, close it ", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
 
 		remoteIp, err := common.ParseIPAddr(conn.RemoteAddr().String())
 		if err != nil {
@@ -514,15 +591,43 @@ func (this *NetServer) startConsAccept(listener net.Listener) {
 			continue
 		}
 
-		remotePeer := peer.NewPeer()
-		addr := conn.RemoteAddr().String()
-		this.AddPeerConsAddress(addr, remotePeer)
+		go this.acceptConsConn(conn, conn.RemoteAddr().String())
+	}
+}
 
-		remotePeer.ConsLink.SetAddr(addr)
-		remotePeer.ConsLink.SetConn(conn)
-		remotePeer.AttachConsChan(this.ConsChan)
-		go remotePeer.ConsLink.Rx()
+//acceptConsConn finish the handshake of inbound consensus connection and start receiving
+func (this *NetServer) acceptConsConn(conn net.Conn, addr string) {
+	secConn, err := this.handshake(conn, addr, false)
+	if err != nil {
+		log.Debugf("[p2p]handshake with %s failed:%s", addr, err.Error())
+		return
 	}
+	remotePeer := peer.NewPeer()
+	this.AddPeerConsAddress(addr, remotePeer)
+
+	remotePeer.ConsLink.SetAddr(addr)
+	remotePeer.ConsLink.SetConn(secConn)
+	remotePeer.ConsLink.SetPubKey(secConn.RemoteKey())
+	remotePeer.AttachConsChan(this.ConsChan)
+	go remotePeer.ConsLink.Rx()
+}
+
+//handshake authenticate the node key of remote peer and encrypt the connection, conn is closed on failure
+func (this *NetServer) handshake(conn net.Conn, addr string, initiator bool) (*link.SecureConn, error) {
+	secConn, err := link.Handshake(conn, this.nodeKey, config.DefConfig.P2PNode.NetworkMagic, initiator)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s, remote may run protocol version lower than %d", err, common.PROTOCOL_VERSION)
+	}
+	if !this.PeerValid(addr, secConn.RemoteKey()) {
+		conn.Close()
+		return nil, fmt.Errorf("[p2p]remote %s key %x not in reserved list", addr, []byte(secConn.RemoteKey()))
+	}
+	if this.Np.IsBanned("", link.PeerIDFromPubKey(secConn.RemoteKey())) {
+		conn.Close()
+		return nil, fmt.Errorf("[p2p]remote %s key %x is banned", addr, []byte(secConn.RemoteKey()))
+	}
+	return secConn, nil
 }
 
 //record the peer which is going to be dialed and sent version message but not in establish state
@@ -774,20 +879,45 @@ func (this *NetServer) GetOutConnRecordLen() int {
 	return len(this.outConnRecord.OutConnectingAddrs)
 }
 
-//AddrValid whether the addr could be connect or accept
+//AddrValid whether the addr could be connect or accept. If reserved keys are configured,
+//the decision is deferred to PeerValid after handshake
 func (this *NetServer) AddrValid(addr string) bool {
-	if config.DefConfig.P2PNode.ReservedPeersOnly && len(config.DefConfig.P2PNode.ReservedCfg.ReservedPeers) > 0 {
-		for _, ip := range config.DefConfig.P2PNode.ReservedCfg.ReservedPeers {
-			if strings.HasPrefix(addr, ip) {
-				log.Info("[p2p]found reserved peer :", addr)
-				return true
-			}
-		}
-		return false
+	rsvCfg := config.DefConfig.P2PNode.ReservedCfg
+	if config.DefConfig.P2PNode.ReservedPeersOnly && len(rsvCfg.ReservedPeers) > 0 && len(rsvCfg.ReservedKeys) == 0 {
+		return isReservedAddr(addr)
 	}
 	return true
 }
 
+//PeerValid whether the peer of addr and authenticated node key could be connect or accept
+func (this *NetServer) PeerValid(addr string, pubKey []byte) bool {
+	rsvCfg := config.DefConfig.P2PNode.ReservedCfg
+	if !config.DefConfig.P2PNode.ReservedPeersOnly || (len(rsvCfg.ReservedPeers) == 0 && len(rsvCfg.ReservedKeys) == 0) {
+		return true
+	}
+	if isReservedAddr(addr) {
+		return true
+	}
+	keyStr := hex.EncodeToString(pubKey)
+	for _, key := range rsvCfg.ReservedKeys {
+		if strings.EqualFold(key, keyStr) {
+			log.Info("[p2p]found reserved key :", keyStr)
+			return true
+		}
+	}
+	return false
+}
+
+func isReservedAddr(addr string) bool {
+	for _, ip := range config.DefConfig.P2PNode.ReservedCfg.ReservedPeers {
+		if strings.HasPrefix(addr, ip) {
+			log.Info("[p2p]found reserved peer :", addr)
+			return true
+		}
+	}
+	return false
+}
+
 //check own network address
 func (this *NetServer) IsOwnAddress(addr string) bool {
 	if addr == this.OwnAddress {
@@ -804,3 +934,102 @@ func (this *NetServer) SetOwnAddress(addr string) {
 	}
 
 }
+
+//IsBanned whether the ip of addr is banned
+func (this *NetServer) IsBanned(addr string) bool {
+	ip, err := common.ParseIPAddr(addr)
+	if err != nil {
+		return false
+	}
+	return this.Np.IsBanned(ip, 0)
+}
+
+//PenalizePeer lower the score of peer for misbehavior, the peer is banned and
+//disconnected once its score drops to the threshold. addr is used to find the
+//peer before version handshake
+func (this *NetServer) PenalizePeer(id uint64, addr string, penalty int, reason string) {
+	var p *peer.Peer
+	if id != 0 {
+		p = this.GetPeer(id)
+	}
+	if p == nil && addr != "" {
+		p = this.GetPeerFromAddr(addr)
+	}
+	if p != nil {
+		if addr == "" {
+			addr = p.GetAddr()
+		}
+		if id == 0 {
+			//the peer id is bound to the key authenticated by link
+			key := p.SyncLink.GetPubKey()
+			if p.ConsLink.GetAddr() == addr {
+				key = p.ConsLink.GetPubKey()
+			}
+			if len(key) > 0 {
+				id = link.PeerIDFromPubKey(key)
+			}
+		}
+	}
+	if id == 0 {
+		log.Debugf("[p2p]can not penalize unknown peer %s: %s", addr, reason)
+		return
+	}
+
+	log.Infof("[p2p]penalize peer %d %s by %d: %s", id, addr, penalty, reason)
+	if !this.Np.Penalize(id, penalty) {
+		return
+	}
+	if isReservedAddr(addr) {
+		log.Warnf("[p2p]reserved peer %d %s misbehaves, not banned", id, addr)
+		return
+	}
+	ip, _ := common.ParseIPAddr(addr)
+	this.BanPeer(ip, id, common.PEER_BAN_DURATION*time.Second, reason)
+}
+
+//BanPeer ban the ip and peer id for duration, and disconnect the banned peers.
+//Empty ip or zero id is not banned
+func (this *NetServer) BanPeer(ip string, id uint64, duration time.Duration, reason string) error {
+	if ip == "" && id == 0 {
+		return errors.New("[p2p]no ip or peer id to ban")
+	}
+	info := common.BanInfo{
+		IP:     ip,
+		ID:     id,
+		Until:  time.Now().Add(duration).Unix(),
+		Reason: reason,
+	}
+	this.Np.Ban(info)
+	log.Warnf("[p2p]ban peer %d ip %s for %s: %s", id, ip, duration, reason)
+
+	var banned []*peer.Peer
+	this.PeerAddrMap.RLock()
+	for _, addrs := range []map[string]*peer.Peer{this.PeerSyncAddress, this.PeerConsAddress} {
+		for addr, p := range addrs {
+			if (id != 0 && p.GetID() == id) || (ip != "" && strings.HasPrefix(addr, ip+":")) {
+				banned = append(banned, p)
+			}
+		}
+	}
+	this.PeerAddrMap.RUnlock()
+	for _, p := range banned {
+		p.CloseSync()
+		p.CloseCons()
+	}
+
+	return this.Np.SaveBanList(this.banFile)
+}
+
+//UnbanPeer lift the ban of ip or peer id
+func (this *NetServer) UnbanPeer(key string) (bool, error) {
+	if !this.Np.Unban(key) {
+		return false, nil
+	}
+	log.Infof("[p2p]unban peer %s", key)
+	return true, this.Np.SaveBanList(this.banFile)
+}
+
+//GetBanList return the banned peers
+func (this *NetServer) GetBanList() []common.BanInfo {
+	return this.Np.GetBanList()
+}
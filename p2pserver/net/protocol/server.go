@@ -20,9 +20,12 @@
 package p2p
 
 import (
+	"time"
+
 	"github.com/OnyxPay/OnyxChain/p2pserver/common"
 	"github.com/OnyxPay/OnyxChain/p2pserver/message/types"
 	"github.com/OnyxPay/OnyxChain/p2pserver/peer"
+	"golang.org/x/crypto/ed25519"
 )
 
 //P2P represent the net interface of p2p package
@@ -31,6 +34,8 @@ type P2P interface {
 	Halt()
 	Connect(addr string, isConsensus bool) error
 	GetID() uint64
+	GetPubKey() []byte
+	GetNodeKey() ed25519.PrivateKey
 	GetVersion() uint32
 	GetSyncPort() uint16
 	GetConsPort() uint16
@@ -66,4 +71,10 @@ type P2P interface {
 	SetOwnAddress(addr string)
 	IsOwnAddress(addr string) bool
 	IsAddrFromConnecting(addr string) bool
+	PeerValid(addr string, pubKey []byte) bool
+	IsBanned(addr string) bool
+	PenalizePeer(id uint64, addr string, penalty int, reason string)
+	BanPeer(ip string, id uint64, duration time.Duration, reason string) error
+	UnbanPeer(key string) (bool, error)
+	GetBanList() []common.BanInfo
 }
@@ -38,6 +38,8 @@ import (
 	"github.com/OnyxPay/OnyxChain/core/ledger"
 	"github.com/OnyxPay/OnyxChain/core/types"
 	"github.com/OnyxPay/OnyxChain/p2pserver/common"
+	"github.com/OnyxPay/OnyxChain/p2pserver/dht"
+	"github.com/OnyxPay/OnyxChain/p2pserver/link"
 	"github.com/OnyxPay/OnyxChain/p2pserver/message/msg_pack"
 	msgtypes "github.com/OnyxPay/OnyxChain/p2pserver/message/types"
 	"github.com/OnyxPay/OnyxChain/p2pserver/message/utils"
@@ -53,6 +55,7 @@ type P2PServer struct {
 	pid       *evtActor.PID
 	blockSync *BlockSyncMgr
 	ledger    *ledger.Ledger
+	dht       *dht.DHT
 	ReconnectAddrs
 	recentPeers    map[uint32][]string
 	quitSyncRecent chan bool
@@ -101,6 +104,15 @@ func (this *P2PServer) Start() error {
 	} else {
 		return errors.New("[p2p]msg router invalid")
 	}
+	if !config.DefConfig.P2PNode.ReservedPeersOnly {
+		d, err := newDHT(this.network.GetNodeKey(), this.network.GetSyncPort())
+		if err != nil {
+			log.Warnf("[p2p]start dht error:%s, connect seeds only", err)
+		} else {
+			this.dht = d
+			this.dht.Start()
+		}
+	}
 	this.tryRecentPeers()
 	go this.connectSeedService()
 	go this.syncUpRecentPeers()
@@ -113,6 +125,9 @@ func (this *P2PServer) Start() error {
 //Stop halt all service by send signal to channels
 func (this *P2PServer) Stop() {
 	this.network.Halt()
+	if this.dht != nil {
+		this.dht.Stop()
+	}
 	this.quitSyncRecent <- true
 	this.quitOnline <- true
 	this.quitHeartBeat <- true
@@ -344,6 +359,30 @@ func (this *P2PServer) connectSeeds() {
 	}
 }
 
+//connectDHTNodes connect the nodes found by dht until the out connections reach the limit,
+//return false if no node is known
+func (this *P2PServer) connectDHTNodes() bool {
+	nodes := this.dht.Nodes()
+	if len(nodes) == 0 {
+		return false
+	}
+	dialed := 0
+	for _, n := range nodes {
+		if dialed >= common.MAX_DHT_DIAL ||
+			uint(this.network.GetOutConnRecordLen()) >= config.DefConfig.P2PNode.MaxConnOutBound {
+			break
+		}
+		addr := n.TCPAddr()
+		if this.network.GetPeer(link.PeerIDFromPubKey(n.PubKey)) != nil ||
+			this.network.IsAddrFromConnecting(addr) || this.network.IsOwnAddress(addr) {
+			continue
+		}
+		dialed++
+		go this.network.Connect(addr, false)
+	}
+	return true
+}
+
 //reachMinConnection return whether net layer have enough link under different config
 func (this *P2PServer) reachMinConnection() bool {
 	if config.DefConfig.Consensus.EnableConsensus == false {
@@ -361,7 +400,8 @@ func (this *P2PServer) reachMinConnection() bool {
 		minCount = config.SOLO_MIN_NODE_NUM
 	case "vbft":
 		minCount = config.VBFT_MIN_NODE_NUM
-
+	case "sbft":
+		minCount = config.SBFT_MIN_NODE_NUM
 	}
 	return int(this.GetConnectionCnt())+1 >= minCount
 }
@@ -452,7 +492,9 @@ func (this *P2PServer) connectSeedService() {
 	for {
 		select {
 		case <-t.C:
-			this.connectSeeds()
+			if this.dht == nil || !this.connectDHTNodes() {
+				this.connectSeeds()
+			}
 			t.Stop()
 			if this.reachMinConnection() {
 				t.Reset(time.Second * time.Duration(10*common.CONN_MONITOR))
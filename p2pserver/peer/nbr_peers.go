@@ -21,6 +21,7 @@ package peer
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	comm "github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/log"
@@ -31,7 +32,10 @@ import (
 //NbrPeers: The neigbor list
 type NbrPeers struct {
 	sync.RWMutex
-	List map[uint64]*Peer
+	List    map[uint64]*Peer
+	scores  map[uint64]*peerScore     //reputation of misbehaved peers
+	txRates map[uint64]*txRate        //tx rate of nbr peers
+	bans    map[string]common.BanInfo //banned ip or peer id
 }
 
 //Broadcast tranfer msg buffer to all establish peer
@@ -91,12 +95,18 @@ func (this *NbrPeers) DelNbrNode(id uint64) (*Peer, bool) {
 		return nil, false
 	}
 	delete(this.List, id)
+	delete(this.txRates, id)
+	//the score of penalized peer is kept until recovered, so reconnecting does not reset it
+	this.expireScores(time.Now())
 	return n, true
 }
 
 //initialize nbr list
 func (this *NbrPeers) Init() {
 	this.List = make(map[uint64]*Peer)
+	this.scores = make(map[uint64]*peerScore)
+	this.txRates = make(map[uint64]*txRate)
+	this.bans = make(map[string]common.BanInfo)
 }
 
 //NodeEstablished whether peer established according to id
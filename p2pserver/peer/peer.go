@@ -312,6 +312,11 @@ func (this *Peer) GetAddr() string {
 	return this.SyncLink.GetAddr()
 }
 
+//GetPubKey return peer`s node key authenticated on sync link
+func (this *Peer) GetPubKey() []byte {
+	return this.SyncLink.GetPubKey()
+}
+
 //GetAddr16 return peer`s sync link address in []byte
 func (this *Peer) GetAddr16() ([16]byte, error) {
 	var result [16]byte
@@ -376,6 +381,20 @@ func (this *Peer) GetHttpInfoState() bool {
 	return this.cap[common.HTTP_INFO_FLAG] == 1
 }
 
+//SetCmpctBlockState set whether peer accepts compact blocks
+func (this *Peer) SetCmpctBlockState(cmpct bool) {
+	if cmpct {
+		this.cap[common.CMPCT_BLOCK_FLAG] = 0x01
+	} else {
+		this.cap[common.CMPCT_BLOCK_FLAG] = 0x00
+	}
+}
+
+//GetCmpctBlockState return whether peer accepts compact blocks
+func (this *Peer) GetCmpctBlockState() bool {
+	return this.cap[common.CMPCT_BLOCK_FLAG] == 1
+}
+
 //GetHttpInfoPort return peer`s httpinfo port
 func (this *Peer) GetHttpInfoPort() uint16 {
 	return this.base.GetHttpInfoPort()
@@ -0,0 +1,249 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package peer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	comm "github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/p2pserver/common"
+)
+
+//peerScore is the reputation of a misbehaved peer
+type peerScore struct {
+	score   int
+	updated time.Time
+}
+
+//txRate counts the txs received from a peer in the current second
+type txRate struct {
+	second int64
+	count  int
+}
+
+//recover raises the score for the time passed since last update
+func (this *peerScore) recover(now time.Time) {
+	minutes := int(now.Sub(this.updated) / time.Minute)
+	if minutes <= 0 {
+		return
+	}
+	this.score += minutes * common.PEER_SCORE_RECOVER
+	if this.score > common.PEER_SCORE_MAX {
+		this.score = common.PEER_SCORE_MAX
+	}
+	this.updated = this.updated.Add(time.Duration(minutes) * time.Minute)
+}
+
+//Penalize lowers the score of peer, returns true if the peer should be banned
+func (this *NbrPeers) Penalize(id uint64, penalty int) bool {
+	return this.penalize(id, penalty, time.Now())
+}
+
+func (this *NbrPeers) penalize(id uint64, penalty int, now time.Time) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	s, ok := this.scores[id]
+	if !ok {
+		s = &peerScore{score: common.PEER_SCORE_MAX, updated: now}
+		this.scores[id] = s
+	}
+	s.recover(now)
+	s.score -= penalty
+	return s.score <= common.PEER_BAN_THRESHOLD
+}
+
+//GetScore return the current score of peer
+func (this *NbrPeers) GetScore(id uint64) int {
+	return this.getScore(id, time.Now())
+}
+
+func (this *NbrPeers) getScore(id uint64, now time.Time) int {
+	this.Lock()
+	defer this.Unlock()
+
+	s, ok := this.scores[id]
+	if !ok {
+		return common.PEER_SCORE_MAX
+	}
+	s.recover(now)
+	if s.score >= common.PEER_SCORE_MAX {
+		delete(this.scores, id)
+	}
+	return s.score
+}
+
+//expireScores remove the scores fully recovered, caller must hold the lock
+func (this *NbrPeers) expireScores(now time.Time) {
+	for id, s := range this.scores {
+		s.recover(now)
+		if s.score >= common.PEER_SCORE_MAX {
+			delete(this.scores, id)
+		}
+	}
+}
+
+//CountTx records a tx from peer, returns true if the peer exceeds the tx rate
+func (this *NbrPeers) CountTx(id uint64) bool {
+	return this.countTx(id, time.Now())
+}
+
+func (this *NbrPeers) countTx(id uint64, now time.Time) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	rate, ok := this.txRates[id]
+	if !ok {
+		//the rate of removed peer is not tracked, or it is never deleted
+		if _, ok := this.List[id]; !ok {
+			return false
+		}
+		rate = &txRate{}
+		this.txRates[id] = rate
+	}
+	if rate.second != now.Unix() {
+		rate.second = now.Unix()
+		rate.count = 0
+	}
+	rate.count++
+	return rate.count > common.MAX_TX_PER_SECOND
+}
+
+//banKey return the key of ban, the ip or the peer id if ip unknown
+func banKey(ip string, id uint64) string {
+	if ip != "" {
+		return ip
+	}
+	return strconv.FormatUint(id, 10)
+}
+
+//Ban add the ip and peer id of info to ban list, the score of peer is reset
+func (this *NbrPeers) Ban(info common.BanInfo) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.bans[banKey(info.IP, info.ID)] = info
+	delete(this.scores, info.ID)
+}
+
+//Unban remove the ban of ip or peer id, returns false if not banned
+func (this *NbrPeers) Unban(key string) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	if _, ok := this.bans[key]; ok {
+		delete(this.bans, key)
+		return true
+	}
+	id, err := strconv.ParseUint(key, 10, 64)
+	if err != nil || id == 0 {
+		return false
+	}
+	found := false
+	for k, info := range this.bans {
+		if info.ID == id {
+			delete(this.bans, k)
+			found = true
+		}
+	}
+	return found
+}
+
+//IsBanned whether the ip or the peer id is banned, empty ip or zero id is skipped
+func (this *NbrPeers) IsBanned(ip string, id uint64) bool {
+	return this.isBanned(ip, id, time.Now())
+}
+
+func (this *NbrPeers) isBanned(ip string, id uint64, now time.Time) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	this.expireBans(now)
+	if ip != "" {
+		if _, ok := this.bans[ip]; ok {
+			return true
+		}
+	}
+	if id != 0 {
+		for _, info := range this.bans {
+			if info.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//GetBanList return all bans not expired
+func (this *NbrPeers) GetBanList() []common.BanInfo {
+	this.Lock()
+	defer this.Unlock()
+
+	this.expireBans(time.Now())
+	list := make([]common.BanInfo, 0, len(this.bans))
+	for _, info := range this.bans {
+		list = append(list, info)
+	}
+	return list
+}
+
+//expireBans remove the expired bans, caller must hold the lock
+func (this *NbrPeers) expireBans(now time.Time) {
+	for k, info := range this.bans {
+		if info.Until <= now.Unix() {
+			delete(this.bans, k)
+		}
+	}
+}
+
+//LoadBanList load the bans persisted in file
+func (this *NbrPeers) LoadBanList(path string) error {
+	if !comm.FileExisted(path) {
+		return nil
+	}
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var list []common.BanInfo
+	err = json.Unmarshal(buf, &list)
+	if err != nil {
+		return err
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	for _, info := range list {
+		this.bans[banKey(info.IP, info.ID)] = info
+	}
+	this.expireBans(time.Now())
+	return nil
+}
+
+//SaveBanList persist the bans to file
+func (this *NbrPeers) SaveBanList(path string) error {
+	buf, err := json.Marshal(this.GetBanList())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf, 0600)
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package peer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain/p2pserver/common"
+)
+
+func TestPenalize(t *testing.T) {
+	np := &NbrPeers{}
+	np.Init()
+	now := time.Now()
+
+	if np.getScore(1, now) != common.PEER_SCORE_MAX {
+		t.Fatal("unknown peer should have the max score")
+	}
+	if np.penalize(1, common.PEER_SCORE_MAX/2, now) {
+		t.Fatal("peer should not be banned by half penalty")
+	}
+	if np.getScore(1, now) != common.PEER_SCORE_MAX/2 {
+		t.Fatal("score not lowered by penalty")
+	}
+
+	later := now.Add(10 * time.Minute)
+	if np.getScore(1, later) != common.PEER_SCORE_MAX/2+10*common.PEER_SCORE_RECOVER {
+		t.Fatal("score not recovered over time")
+	}
+	if !np.penalize(1, common.PEER_SCORE_MAX, later) {
+		t.Fatal("peer should be banned below the threshold")
+	}
+
+	np.penalize(2, 1, now)
+	np.getScore(2, now.Add(time.Hour))
+	if _, ok := np.scores[2]; ok {
+		t.Fatal("fully recovered score should be removed")
+	}
+
+	np.List[3] = NewPeer()
+	np.penalize(3, 1, now.Add(-time.Hour))
+	np.penalize(4, 1, now)
+	np.DelNbrNode(3)
+	if _, ok := np.scores[3]; ok {
+		t.Fatal("recovered score should be removed with peer")
+	}
+	if _, ok := np.scores[4]; !ok {
+		t.Fatal("penalized score should be kept after peer removed")
+	}
+}
+
+func TestCountTx(t *testing.T) {
+	np := &NbrPeers{}
+	np.Init()
+	np.List[1] = NewPeer()
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < common.MAX_TX_PER_SECOND; i++ {
+		if np.countTx(1, now) {
+			t.Fatal("tx rate should not be exceeded")
+		}
+	}
+	if !np.countTx(1, now) {
+		t.Fatal("tx rate should be exceeded")
+	}
+	if np.countTx(1, now.Add(time.Second)) {
+		t.Fatal("tx rate should be reset in next second")
+	}
+
+	np.DelNbrNode(1)
+	if np.countTx(1, now) || len(np.txRates) != 0 {
+		t.Fatal("tx rate of removed peer should not be tracked")
+	}
+}
+
+func TestBanList(t *testing.T) {
+	np := &NbrPeers{}
+	np.Init()
+	now := time.Now()
+
+	np.penalize(5, 10, now)
+	np.Ban(common.BanInfo{IP: "1.2.3.4", ID: 5, Until: now.Add(time.Hour).Unix()})
+	np.Ban(common.BanInfo{ID: 6, Until: now.Add(time.Hour).Unix()})
+	np.Ban(common.BanInfo{IP: "5.6.7.8", Until: now.Add(-time.Second).Unix()})
+
+	if np.getScore(5, now) != common.PEER_SCORE_MAX {
+		t.Fatal("score should be reset by ban")
+	}
+	if !np.isBanned("1.2.3.4", 0, now) || !np.isBanned("", 5, now) || !np.isBanned("", 6, now) {
+		t.Fatal("ip or peer id should be banned")
+	}
+	if np.isBanned("5.6.7.8", 0, now) {
+		t.Fatal("expired ban should be removed")
+	}
+	if len(np.GetBanList()) != 2 {
+		t.Fatal("ban list should have 2 bans")
+	}
+
+	dir, err := ioutil.TempDir("", "ban")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, common.BAN_FILE_NAME)
+	if err := np.SaveBanList(path); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Fatal("ban list should be readable by owner only")
+	}
+	loaded := &NbrPeers{}
+	loaded.Init()
+	if err := loaded.LoadBanList(path); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsBanned("1.2.3.4", 0) || !loaded.IsBanned("", 6) {
+		t.Fatal("bans should be persisted")
+	}
+
+	if !np.Unban("1.2.3.4") || np.IsBanned("1.2.3.4", 5) {
+		t.Fatal("ban should be lifted by ip")
+	}
+	if !np.Unban("6") || np.IsBanned("", 6) {
+		t.Fatal("ban should be lifted by peer id")
+	}
+	if np.Unban("9.9.9.9") {
+		t.Fatal("unknown ban should not be lifted")
+	}
+}
@@ -38,6 +38,7 @@ type ContextRef interface {
 	CheckWitness(address common.Address) bool
 	PushNotifications(notifications []*event.NotifyEventInfo)
 	NewExecuteEngine(code []byte) (Engine, error)
+	NewWasmExecuteEngine(input []byte) (Engine, error)
 	CheckUseGas(gas uint64) bool
 	CheckExecStep() bool
 }
@@ -50,4 +51,5 @@ type Engine interface {
 type Context struct {
 	ContractAddress common.Address
 	Code            []byte
+	Method          string // method of native contract
 }
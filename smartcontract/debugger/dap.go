@@ -0,0 +1,483 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"sync"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/log"
+)
+
+const (
+	DAP_THREAD_ID = 1 //neovm execution has only one thread
+
+	scopeEvaluationStack = 1
+	scopeAltStack        = 2
+	scopeStorage         = 3
+	scopeCount           = 4
+)
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+type dapSource struct {
+	Name            string `json:"name"`
+	SourceReference int    `json:"sourceReference"`
+}
+
+type dapVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+//dapContract is the disassembly source of contract, each line is an instruction
+type dapContract struct {
+	address common.Address
+	instrs  []*Instruction
+	lines   map[int]int // instruction offset => line
+}
+
+//DAPServer serve a debug session over Debug Adapter Protocol. The source of contract is its disassembly
+//with one instruction per line, so breakpoints and stack frames are located by lines of disassembly
+type DAPServer struct {
+	config      *Config
+	conn        io.ReadWriteCloser
+	reader      *bufio.Reader
+	writeLock   sync.Mutex
+	seq         int
+	session     *Session
+	contracts   []*dapContract // index + 1 is source reference
+	variables   map[int][]*StackItem
+	stopOnEntry bool
+}
+
+//ListenDAP accept debug adapter connections on address, and serve a new session of config for each connection
+func ListenDAP(address string, config *Config) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		log.Infof("debug adapter connected from %s", conn.RemoteAddr())
+		if err := NewDAPServer(conn, config).Serve(); err != nil {
+			log.Warnf("debug adapter connection error:%s", err)
+		}
+	}
+}
+
+//NewDAPServer return a debug adapter server on conn
+func NewDAPServer(conn io.ReadWriteCloser, config *Config) *DAPServer {
+	return &DAPServer{
+		config:    config,
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		variables: make(map[int][]*StackItem),
+	}
+}
+
+//Serve handle the requests until the client disconnects
+func (self *DAPServer) Serve() error {
+	defer self.conn.Close()
+	defer func() {
+		if self.session != nil {
+			self.session.Terminate()
+		}
+	}()
+	for {
+		req, err := self.readRequest()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		body, err := self.handle(req)
+		resp := &dapResponse{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+		if err != nil {
+			resp.Message = err.Error()
+		}
+		if err := self.send(resp); err != nil {
+			return err
+		}
+		switch req.Command {
+		case "launch":
+			if resp.Success {
+				err = self.sendEvent("initialized", nil)
+			}
+		case "configurationDone":
+			go self.wait(func() *Event { return self.session.Start(self.stopOnEntry) })
+		case "disconnect", "terminate":
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (self *DAPServer) readRequest() (*dapRequest, error) {
+	header, err := textproto.NewReader(self.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid content length:%s", header.Get("Content-Length"))
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(self.reader, buf); err != nil {
+		return nil, err
+	}
+	req := &dapRequest{}
+	if err := json.Unmarshal(buf, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (self *DAPServer) handle(req *dapRequest) (interface{}, error) {
+	switch req.Command {
+	case "initialize", "launch", "disconnect", "terminate":
+	default:
+		if self.session == nil {
+			return nil, fmt.Errorf("%s", "debug session not launched")
+		}
+	}
+	switch req.Command {
+	case "continue", "next", "stepIn", "stepOut":
+		// references of nested variables are only valid when stopped
+		self.variables = make(map[int][]*StackItem)
+	}
+	switch req.Command {
+	case "initialize":
+		return map[string]interface{}{"supportsConfigurationDoneRequest": true}, nil
+	case "launch":
+		return nil, self.launch(req.Arguments)
+	case "setBreakpoints":
+		return self.setBreakpoints(req.Arguments)
+	case "setExceptionBreakpoints", "configurationDone", "disconnect", "terminate":
+		return nil, nil
+	case "threads":
+		return map[string]interface{}{"threads": []interface{}{map[string]interface{}{"id": DAP_THREAD_ID, "name": "neovm"}}}, nil
+	case "stackTrace":
+		return self.stackTrace()
+	case "scopes":
+		return self.scopes(req.Arguments)
+	case "variables":
+		return self.getVariables(req.Arguments)
+	case "source":
+		return self.source(req.Arguments)
+	case "continue":
+		go self.wait(self.session.Continue)
+		return map[string]interface{}{"allThreadsContinued": true}, nil
+	case "next":
+		go self.wait(self.session.StepOver)
+		return nil, nil
+	case "stepIn":
+		go self.wait(self.session.StepInto)
+		return nil, nil
+	case "stepOut":
+		go self.wait(self.session.StepOut)
+		return nil, nil
+	case "pause":
+		self.session.Pause()
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported command %s", req.Command)
+	}
+}
+
+func (self *DAPServer) launch(arguments json.RawMessage) error {
+	args := struct {
+		StopOnEntry bool `json:"stopOnEntry"`
+	}{}
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return err
+		}
+	}
+	session, err := NewSession(self.config)
+	if err != nil {
+		return err
+	}
+	self.session = session
+	self.stopOnEntry = args.StopOnEntry
+	_, err = self.contractSource(session.Contract, self.config.Code)
+	return err
+}
+
+//contractSource return the source reference of contract, the disassembly is added at first use
+func (self *DAPServer) contractSource(address common.Address, code []byte) (int, error) {
+	for i, contract := range self.contracts {
+		if contract.address == address {
+			return i + 1, nil
+		}
+	}
+	instrs, err := Disassemble(code)
+	if err != nil {
+		return 0, err
+	}
+	contract := &dapContract{address: address, instrs: instrs, lines: make(map[int]int, len(instrs))}
+	for i, instr := range instrs {
+		contract.lines[instr.Offset] = i + 1
+	}
+	self.contracts = append(self.contracts, contract)
+	return len(self.contracts), nil
+}
+
+func (self *DAPServer) getContract(ref int) (*dapContract, error) {
+	if ref < 1 || ref > len(self.contracts) {
+		return nil, fmt.Errorf("unknown source reference %d", ref)
+	}
+	return self.contracts[ref-1], nil
+}
+
+func (self *DAPServer) setBreakpoints(arguments json.RawMessage) (interface{}, error) {
+	args := struct {
+		Source      dapSource `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	ref := args.Source.SourceReference
+	if ref == 0 {
+		// breakpoints of source path are on the debugged contract
+		ref = 1
+	}
+	contract, err := self.getContract(ref)
+	if err != nil {
+		return nil, err
+	}
+	self.session.ClearBreakpoints(contract.address)
+	breakpoints := make([]interface{}, 0, len(args.Breakpoints))
+	for _, bp := range args.Breakpoints {
+		verified := bp.Line >= 1 && bp.Line <= len(contract.instrs)
+		if verified {
+			self.session.SetBreakpoint(contract.address, contract.instrs[bp.Line-1].Offset)
+		}
+		breakpoints = append(breakpoints, map[string]interface{}{"verified": verified, "line": bp.Line})
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func (self *DAPServer) stackTrace() (interface{}, error) {
+	frames := self.session.CallStack()
+	stackFrames := make([]interface{}, 0, len(frames))
+	for i, frame := range frames {
+		ref, err := self.contractSource(frame.Contract, frame.Code)
+		if err != nil {
+			return nil, err
+		}
+		contract := self.contracts[ref-1]
+		line := contract.lines[frame.Offset]
+		if line == 0 {
+			// returned to the end of code
+			line = len(contract.instrs)
+		}
+		stackFrames = append(stackFrames, map[string]interface{}{
+			"id":     i + 1,
+			"name":   fmt.Sprintf("%s:%04X", frame.Contract.ToHexString(), frame.Offset),
+			"source": dapSource{Name: frame.Contract.ToHexString() + ".avm", SourceReference: ref},
+			"line":   line,
+			"column": 1,
+		})
+	}
+	return map[string]interface{}{"stackFrames": stackFrames, "totalFrames": len(stackFrames)}, nil
+}
+
+func (self *DAPServer) scopes(arguments json.RawMessage) (interface{}, error) {
+	args := struct {
+		FrameId int `json:"frameId"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	scope := func(name string, kind int) map[string]interface{} {
+		return map[string]interface{}{"name": name, "variablesReference": args.FrameId*scopeCount + kind, "expensive": false}
+	}
+	return map[string]interface{}{"scopes": []interface{}{
+		scope("Evaluation Stack", scopeEvaluationStack),
+		scope("Alt Stack", scopeAltStack),
+		scope("Storage", scopeStorage),
+	}}, nil
+}
+
+//getVariables return variables of scope or nested stack items. References of scope are frame id * scopeCount + scope kind,
+//and references of nested items are allocated after them
+func (self *DAPServer) getVariables(arguments json.RawMessage) (interface{}, error) {
+	args := struct {
+		VariablesReference int `json:"variablesReference"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	ref := args.VariablesReference
+	var items []*StackItem
+	var names []string
+	if nested, ok := self.variables[ref]; ok {
+		items = nested
+	} else {
+		frames := self.session.CallStack()
+		id := ref / scopeCount
+		if id < 1 || id > len(frames) {
+			return nil, fmt.Errorf("unknown variables reference %d", ref)
+		}
+		frame := frames[id-1]
+		switch ref % scopeCount {
+		case scopeEvaluationStack:
+			items = frame.EvaluationStack()
+		case scopeAltStack:
+			items = frame.AltStack()
+		case scopeStorage:
+			storage, err := self.session.Storage(frame.Contract)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range storage {
+				names = append(names, item.Key)
+				items = append(items, &StackItem{Type: "ByteArray", Value: item.Value})
+			}
+		default:
+			return nil, fmt.Errorf("unknown variables reference %d", ref)
+		}
+	}
+	variables := make([]*dapVariable, 0, len(items))
+	for i, item := range items {
+		v := &dapVariable{Name: strconv.Itoa(i), Value: item.String(), Type: item.Type}
+		if names != nil {
+			v.Name = names[i]
+		} else if item.Key != nil {
+			v.Name = item.Key.String()
+		}
+		if len(item.Items) > 0 {
+			v.VariablesReference = self.allocVariables(item.Items)
+		}
+		variables = append(variables, v)
+	}
+	return map[string]interface{}{"variables": variables}, nil
+}
+
+func (self *DAPServer) allocVariables(items []*StackItem) int {
+	ref := (len(self.session.CallStack())+1)*scopeCount + len(self.variables)
+	self.variables[ref] = items
+	return ref
+}
+
+func (self *DAPServer) source(arguments json.RawMessage) (interface{}, error) {
+	args := struct {
+		SourceReference int `json:"sourceReference"`
+	}{}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil, err
+	}
+	contract, err := self.getContract(args.SourceReference)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"content": Listing(contract.instrs)}, nil
+}
+
+//wait run the step function and send the stopped or exited event to client
+func (self *DAPServer) wait(step func() *Event) {
+	event := step()
+	var err error
+	if event.Reason != STOP_EXIT {
+		err = self.sendEvent("stopped", map[string]interface{}{"reason": event.Reason, "threadId": DAP_THREAD_ID, "allThreadsStopped": true})
+	} else {
+		exitCode := 0
+		output := fmt.Sprintf("gas consumed:%d\n", self.session.GasConsumed())
+		if event.Err != nil {
+			exitCode = 1
+			output += fmt.Sprintf("execution failed:%s\n", event.Err)
+		} else if event.Result != nil {
+			output += fmt.Sprintf("result:%s\n", event.Result)
+		}
+		err = self.sendEvent("output", map[string]interface{}{"category": "console", "output": output})
+		if err == nil {
+			err = self.sendEvent("exited", map[string]interface{}{"exitCode": exitCode})
+		}
+		if err == nil {
+			err = self.sendEvent("terminated", nil)
+		}
+	}
+	if err != nil {
+		log.Warnf("send debug adapter event error:%s", err)
+	}
+}
+
+func (self *DAPServer) sendEvent(event string, body interface{}) error {
+	return self.send(&dapEvent{Type: "event", Event: event, Body: body})
+}
+
+func (self *DAPServer) send(msg interface{}) error {
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+	self.seq++
+	switch m := msg.(type) {
+	case *dapResponse:
+		m.Seq = self.seq
+	case *dapEvent:
+		m.Seq = self.seq
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(self.conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
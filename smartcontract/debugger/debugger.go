@@ -0,0 +1,313 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package debugger provides an interactive debugger of neovm smart contract
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
+	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
+	"github.com/OnyxPay/OnyxChain/vm/neovm/types"
+)
+
+const (
+	STOP_ENTRY      = "entry"
+	STOP_STEP       = "step"
+	STOP_BREAKPOINT = "breakpoint"
+	STOP_PAUSE      = "pause"
+	STOP_EXIT       = "exit"
+)
+
+type stepMode int
+
+const (
+	modeContinue stepMode = iota
+	modeStepInto
+	modeStepOver
+	modeStepOut
+)
+
+var ErrTerminated = errors.New("debug session terminated")
+
+//Event is sent when the execution is stopped or exited
+type Event struct {
+	Reason   string
+	Contract common.Address //contract of stopped instruction
+	Offset   int            //offset of stopped instruction
+	Result   *StackItem     //result of execution when exited
+	Err      error          //error of execution when exited
+}
+
+//Frame is an execution context in the call stack, contract calls and function calls in contract both have their frames
+type Frame struct {
+	Contract common.Address
+	Code     []byte
+	Offset   int //offset of next instruction
+	engine   *vm.ExecutionEngine
+}
+
+//EvaluationStack return evaluation stack of the frame from top to bottom, function calls in the same contract share the stacks
+func (self *Frame) EvaluationStack() []*StackItem {
+	return StackItems(self.engine.EvaluationStack)
+}
+
+//AltStack return alt stack of the frame from top to bottom
+func (self *Frame) AltStack() []*StackItem {
+	return StackItems(self.engine.AltStack)
+}
+
+//StorageItem is a storage item of contract
+type StorageItem struct {
+	Key   string
+	Value string
+}
+
+//Debugger control the neovm execution by breakpoints and stepping. The execution run in its own goroutine,
+//and the inspect methods can only be called when the execution is stopped
+type Debugger struct {
+	cache       *storage.CacheDB
+	lock        sync.Mutex
+	breakpoints map[common.Address]map[int]bool
+	frames      []*neovm.NeoVmService // contracts in call stack
+	mode        stepMode
+	depth       int // call depth when stepping started
+	paused      int32
+	terminated  int32
+	commands    chan stepMode
+	events      chan *Event
+	stopOnce    sync.Once
+}
+
+//NewDebugger return a debugger of execution on cache
+func NewDebugger(cache *storage.CacheDB) *Debugger {
+	return &Debugger{
+		cache:       cache,
+		breakpoints: make(map[common.Address]map[int]bool),
+		commands:    make(chan stepMode),
+		events:      make(chan *Event, 2),
+	}
+}
+
+//SetBreakpoint set a breakpoint at the instruction offset of contract
+func (self *Debugger) SetBreakpoint(contract common.Address, offset int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.breakpoints[contract] == nil {
+		self.breakpoints[contract] = make(map[int]bool)
+	}
+	self.breakpoints[contract][offset] = true
+}
+
+//ClearBreakpoint remove the breakpoint at the instruction offset of contract
+func (self *Debugger) ClearBreakpoint(contract common.Address, offset int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	delete(self.breakpoints[contract], offset)
+}
+
+//ClearBreakpoints remove all breakpoints of contract
+func (self *Debugger) ClearBreakpoints(contract common.Address) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	delete(self.breakpoints, contract)
+}
+
+//Breakpoints return the sorted breakpoint offsets of contract
+func (self *Debugger) Breakpoints(contract common.Address) []int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	offsets := make([]int, 0, len(self.breakpoints[contract]))
+	for offset := range self.breakpoints[contract] {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	return offsets
+}
+
+func (self *Debugger) hasBreakpoint(contract common.Address, offset int) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.breakpoints[contract][offset]
+}
+
+//Start run the execution in a new goroutine, and wait until it is stopped or exited
+func (self *Debugger) Start(run func() (interface{}, error), stopOnEntry bool) *Event {
+	if stopOnEntry {
+		self.mode = modeStepInto
+	}
+	go func() {
+		result, err := self.execute(run)
+		event := &Event{Reason: STOP_EXIT, Err: err}
+		if result != nil && err == nil {
+			if item, ok := result.(types.StackItems); ok {
+				event.Result = NewStackItem(item)
+			}
+		}
+		self.frames = nil
+		self.events <- event
+	}()
+	event := <-self.events
+	if stopOnEntry && event.Reason == STOP_STEP {
+		event.Reason = STOP_ENTRY
+	}
+	return event
+}
+
+func (self *Debugger) execute(run func() (interface{}, error)) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("execution panic: %v", r)
+		}
+	}()
+	return run()
+}
+
+//Continue resume the execution until next breakpoint
+func (self *Debugger) Continue() *Event {
+	return self.resume(modeContinue)
+}
+
+//StepInto execute next instruction
+func (self *Debugger) StepInto() *Event {
+	return self.resume(modeStepInto)
+}
+
+//StepOver execute next instruction, function calls and contract calls are executed as a whole
+func (self *Debugger) StepOver() *Event {
+	return self.resume(modeStepOver)
+}
+
+//StepOut execute until current function or contract returns
+func (self *Debugger) StepOut() *Event {
+	return self.resume(modeStepOut)
+}
+
+func (self *Debugger) resume(mode stepMode) *Event {
+	self.commands <- mode
+	return <-self.events
+}
+
+//Pause stop the running execution at next instruction
+func (self *Debugger) Pause() {
+	atomic.StoreInt32(&self.paused, 1)
+}
+
+//Terminate abort the execution, the stopped execution can not be resumed after terminated
+func (self *Debugger) Terminate() {
+	atomic.StoreInt32(&self.terminated, 1)
+	self.stopOnce.Do(func() {
+		close(self.commands)
+	})
+}
+
+//OnStep implement neovm.DebugHook, the execution is paused here when stopped
+func (self *Debugger) OnStep(service *neovm.NeoVmService) error {
+	if atomic.LoadInt32(&self.terminated) == 1 {
+		return ErrTerminated
+	}
+	self.enter(service)
+	depth := self.callDepth()
+	contract := common.AddressFromVmCode(service.Code)
+	offset := service.Engine.Context.GetInstructionPointer()
+
+	var reason string
+	if atomic.CompareAndSwapInt32(&self.paused, 1, 0) {
+		reason = STOP_PAUSE
+	} else if self.hasBreakpoint(contract, offset) {
+		reason = STOP_BREAKPOINT
+	} else if self.mode == modeStepInto ||
+		(self.mode == modeStepOver && depth <= self.depth) ||
+		(self.mode == modeStepOut && depth < self.depth) {
+		reason = STOP_STEP
+	}
+	if reason == "" {
+		return nil
+	}
+	self.events <- &Event{Reason: reason, Contract: contract, Offset: offset}
+	mode, ok := <-self.commands
+	if !ok {
+		return ErrTerminated
+	}
+	self.mode = mode
+	self.depth = depth
+	return nil
+}
+
+//enter update the contract call stack, the returned contracts are removed
+func (self *Debugger) enter(service *neovm.NeoVmService) {
+	for i := len(self.frames) - 1; i >= 0; i-- {
+		if self.frames[i] == service {
+			self.frames = self.frames[:i+1]
+			return
+		}
+	}
+	self.frames = append(self.frames, service)
+}
+
+func (self *Debugger) callDepth() int {
+	depth := 0
+	for _, service := range self.frames {
+		depth += len(service.Engine.Contexts)
+	}
+	return depth
+}
+
+//CallStack return the frames of execution from innermost to outermost
+func (self *Debugger) CallStack() []*Frame {
+	var frames []*Frame
+	for i := len(self.frames) - 1; i >= 0; i-- {
+		service := self.frames[i]
+		contract := common.AddressFromVmCode(service.Code)
+		for j := len(service.Engine.Contexts) - 1; j >= 0; j-- {
+			context := service.Engine.Contexts[j]
+			frames = append(frames, &Frame{
+				Contract: contract,
+				Code:     context.Code,
+				Offset:   context.GetInstructionPointer(),
+				engine:   service.Engine,
+			})
+		}
+	}
+	return frames
+}
+
+//Storage return the storage items of contract, including uncommitted changes of execution
+func (self *Debugger) Storage(contract common.Address) ([]*StorageItem, error) {
+	iter := neovm.NewStorageIterator(self.cache.NewIterator(contract[:]))
+	var items []*StorageItem
+	for {
+		has, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			return items, nil
+		}
+		key, _ := iter.Key()
+		value, _ := iter.Value()
+		items = append(items, &StorageItem{Key: common.ToHexString(key), Value: common.ToHexString(value)})
+	}
+}
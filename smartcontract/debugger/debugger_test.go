@@ -0,0 +1,215 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/states"
+	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
+	"github.com/stretchr/testify/assert"
+)
+
+// PUSH2 PUSH3 ADD RET
+var testCode = []byte{byte(vm.PUSH2), byte(vm.PUSH3), byte(vm.ADD), byte(vm.RET)}
+
+func TestDisassemble(t *testing.T) {
+	code := []byte{0x02, 0x01, 0x02, byte(vm.JMP), 0xfd, 0xff, byte(vm.SYSCALL), 0x04, 'T', 'e', 's', 't', byte(vm.APPCALL)}
+	code = append(code, common.ADDRESS_EMPTY[:]...)
+	instrs, err := Disassemble(code)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(instrs))
+	assert.Equal(t, "PUSHBYTES2 0102", instrs[0].String())
+	assert.Equal(t, 3, instrs[1].Offset)
+	assert.Equal(t, "JMP 0000", instrs[1].String())
+	assert.Equal(t, "SYSCALL Test", instrs[2].String())
+	assert.Equal(t, "APPCALL "+common.ADDRESS_EMPTY.ToHexString(), instrs[3].String())
+
+	_, err = Disassemble(code[:2])
+	assert.NotNil(t, err)
+}
+
+func TestBreakpointAndStep(t *testing.T) {
+	session, err := NewSession(&Config{Code: testCode})
+	assert.Nil(t, err)
+	session.SetBreakpoint(session.Contract, 2)
+	assert.Equal(t, []int{2}, session.Breakpoints(session.Contract))
+
+	event := session.Start(false)
+	assert.Equal(t, STOP_BREAKPOINT, event.Reason)
+	assert.Equal(t, session.Contract, event.Contract)
+	assert.Equal(t, 2, event.Offset)
+	frames := session.CallStack()
+	assert.Equal(t, 2, len(frames))
+	assert.Equal(t, session.Contract, frames[0].Contract)
+	assert.Equal(t, len(session.Script), frames[1].Offset)
+	stack := frames[0].EvaluationStack()
+	assert.Equal(t, 2, len(stack))
+	assert.Equal(t, "Integer(3)", stack[0].String())
+	assert.Equal(t, "Integer(2)", stack[1].String())
+
+	event = session.StepInto()
+	assert.Equal(t, STOP_STEP, event.Reason)
+	assert.Equal(t, 3, event.Offset)
+	assert.Equal(t, "Integer(5)", session.CallStack()[0].EvaluationStack()[0].String())
+
+	event = session.Continue()
+	assert.Equal(t, STOP_EXIT, event.Reason)
+	assert.Nil(t, event.Err)
+	assert.Equal(t, "Integer(5)", event.Result.String())
+}
+
+func TestStepOverAndOut(t *testing.T) {
+	session, err := NewSession(&Config{Code: testCode})
+	assert.Nil(t, err)
+	event := session.Start(true)
+	assert.Equal(t, STOP_ENTRY, event.Reason)
+	assert.Equal(t, common.AddressFromVmCode(session.Script), event.Contract)
+	event = session.StepOver()
+	assert.Equal(t, STOP_EXIT, event.Reason)
+	assert.Equal(t, "Integer(5)", event.Result.String())
+
+	session, err = NewSession(&Config{Code: testCode})
+	assert.Nil(t, err)
+	session.Start(true)
+	event = session.StepInto()
+	assert.Equal(t, STOP_STEP, event.Reason)
+	assert.Equal(t, session.Contract, event.Contract)
+	assert.Equal(t, 0, event.Offset)
+	event = session.StepOut()
+	assert.Equal(t, STOP_EXIT, event.Reason)
+}
+
+func TestTerminate(t *testing.T) {
+	session, err := NewSession(&Config{Code: testCode})
+	assert.Nil(t, err)
+	session.Start(true)
+	session.Terminate()
+	event := <-session.events
+	assert.Equal(t, STOP_EXIT, event.Reason)
+	assert.NotNil(t, event.Err)
+}
+
+func TestStorage(t *testing.T) {
+	session, err := NewSession(&Config{Code: testCode})
+	assert.Nil(t, err)
+	key := append(session.Contract[:], []byte("key")...)
+	session.cache.Put(key, states.GenRawStorageItem([]byte("value")))
+	items, err := session.Storage(session.Contract)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, common.ToHexString([]byte("key")), items[0].Key)
+	assert.Equal(t, common.ToHexString([]byte("value")), items[0].Value)
+}
+
+type dapClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	seq    int
+}
+
+func (self *dapClient) request(command string, args interface{}) error {
+	self.seq++
+	data, err := json.Marshal(map[string]interface{}{"seq": self.seq, "type": "request", "command": command, "arguments": args})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(self.conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+func (self *dapClient) read() (map[string]interface{}, error) {
+	header, err := textproto.NewReader(self.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(self.reader, buf); err != nil {
+		return nil, err
+	}
+	msg := make(map[string]interface{})
+	return msg, json.Unmarshal(buf, &msg)
+}
+
+//expect read messages until the response of command or the event
+func (self *dapClient) expect(t *testing.T, name string) map[string]interface{} {
+	for {
+		msg, err := self.read()
+		assert.Nil(t, err)
+		if msg["command"] == name {
+			assert.Equal(t, true, msg["success"])
+			return msg
+		}
+		if msg["event"] == name {
+			return msg
+		}
+	}
+}
+
+func TestDAPServer(t *testing.T) {
+	server, conn := net.Pipe()
+	go NewDAPServer(server, &Config{Code: testCode}).Serve()
+	client := &dapClient{conn: conn, reader: bufio.NewReader(conn)}
+	defer conn.Close()
+
+	assert.Nil(t, client.request("initialize", map[string]interface{}{"adapterID": "neovm"}))
+	client.expect(t, "initialize")
+	assert.Nil(t, client.request("launch", map[string]interface{}{"stopOnEntry": false}))
+	client.expect(t, "launch")
+	client.expect(t, "initialized")
+	bps := []interface{}{map[string]interface{}{"line": 3}}
+	assert.Nil(t, client.request("setBreakpoints", map[string]interface{}{"source": map[string]interface{}{"sourceReference": 1}, "breakpoints": bps}))
+	resp := client.expect(t, "setBreakpoints")
+	bp := resp["body"].(map[string]interface{})["breakpoints"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, bp["verified"])
+	assert.Nil(t, client.request("configurationDone", nil))
+	event := client.expect(t, "stopped")
+	assert.Equal(t, STOP_BREAKPOINT, event["body"].(map[string]interface{})["reason"])
+
+	assert.Nil(t, client.request("stackTrace", map[string]interface{}{"threadId": DAP_THREAD_ID}))
+	resp = client.expect(t, "stackTrace")
+	frames := resp["body"].(map[string]interface{})["stackFrames"].([]interface{})
+	assert.Equal(t, 2, len(frames))
+	assert.Equal(t, float64(3), frames[0].(map[string]interface{})["line"])
+
+	assert.Nil(t, client.request("variables", map[string]interface{}{"variablesReference": scopeCount + scopeEvaluationStack}))
+	resp = client.expect(t, "variables")
+	variables := resp["body"].(map[string]interface{})["variables"].([]interface{})
+	assert.Equal(t, 2, len(variables))
+	assert.Equal(t, "Integer(3)", variables[0].(map[string]interface{})["value"])
+
+	assert.Nil(t, client.request("continue", map[string]interface{}{"threadId": DAP_THREAD_ID}))
+	event = client.expect(t, "exited")
+	assert.Equal(t, float64(0), event["body"].(map[string]interface{})["exitCode"])
+	client.expect(t, "terminated")
+	assert.Nil(t, client.request("disconnect", nil))
+	client.expect(t, "disconnect")
+}
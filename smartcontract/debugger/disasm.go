@@ -0,0 +1,136 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package debugger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
+	"github.com/OnyxPay/OnyxChain/vm/neovm/utils"
+)
+
+//Instruction is a disassembled neovm instruction
+type Instruction struct {
+	Offset  int
+	OpCode  vm.OpCode
+	Operand []byte
+}
+
+//Name return the name of opcode
+func (self *Instruction) Name() string {
+	if self.OpCode >= vm.PUSHBYTES1 && self.OpCode <= vm.PUSHBYTES75 {
+		return fmt.Sprintf("PUSHBYTES%d", self.OpCode)
+	}
+	if name := vm.OpExecList[self.OpCode].Name; name != "" {
+		return name
+	}
+	return fmt.Sprintf("0x%02X", byte(self.OpCode))
+}
+
+//String return the instruction in assembly form
+func (self *Instruction) String() string {
+	switch self.OpCode {
+	case vm.JMP, vm.JMPIF, vm.JMPIFNOT, vm.CALL:
+		return fmt.Sprintf("%s %04X", self.Name(), self.Offset+int(int16(binary.LittleEndian.Uint16(self.Operand))))
+	case vm.SYSCALL:
+		return fmt.Sprintf("%s %s", self.Name(), self.Operand)
+	case vm.APPCALL, vm.TAILCALL:
+		addr, err := common.AddressParseFromBytes(self.Operand)
+		if err == nil {
+			return fmt.Sprintf("%s %s", self.Name(), addr.ToHexString())
+		}
+	}
+	if len(self.Operand) == 0 {
+		return self.Name()
+	}
+	return fmt.Sprintf("%s %s", self.Name(), common.ToHexString(self.Operand))
+}
+
+//Disassemble decode neovm code to instructions
+func Disassemble(code []byte) ([]*Instruction, error) {
+	reader := utils.NewVmReader(code)
+	var instrs []*Instruction
+	for reader.Position() < len(code) {
+		instr := &Instruction{Offset: reader.Position()}
+		op, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		instr.OpCode = vm.OpCode(op)
+		var size int
+		switch {
+		case instr.OpCode >= vm.PUSHBYTES1 && instr.OpCode <= vm.PUSHBYTES75:
+			size = int(instr.OpCode)
+		case instr.OpCode == vm.PUSHDATA1:
+			n, err := reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			size = int(n)
+		case instr.OpCode == vm.PUSHDATA2:
+			n, err := reader.ReadUint16()
+			if err != nil {
+				return nil, err
+			}
+			size = int(n)
+		case instr.OpCode == vm.PUSHDATA4:
+			n, err := reader.ReadUint32()
+			if err != nil {
+				return nil, err
+			}
+			if n > vm.MAX_BYTEARRAY_SIZE {
+				return nil, fmt.Errorf("push data size %d over max limit at offset %d", n, instr.Offset)
+			}
+			size = int(n)
+		case instr.OpCode == vm.JMP || instr.OpCode == vm.JMPIF || instr.OpCode == vm.JMPIFNOT || instr.OpCode == vm.CALL:
+			size = 2
+		case instr.OpCode == vm.APPCALL || instr.OpCode == vm.TAILCALL:
+			size = common.ADDR_LEN
+		case instr.OpCode == vm.SYSCALL:
+			name, err := reader.ReadVarString(vm.MAX_BYTEARRAY_SIZE)
+			if err != nil {
+				return nil, fmt.Errorf("read syscall name at offset %d error:%s", instr.Offset, err)
+			}
+			instr.Operand = []byte(name)
+		}
+		if size > len(code)-reader.Position() {
+			return nil, fmt.Errorf("operand of %s at offset %d out of code", instr.Name(), instr.Offset)
+		}
+		if size > 0 {
+			instr.Operand, err = reader.ReadBytes(size)
+			if err != nil {
+				return nil, err
+			}
+		}
+		instrs = append(instrs, instr)
+	}
+	return instrs, nil
+}
+
+//Listing return the assembly text of instructions, one instruction per line
+func Listing(instrs []*Instruction) string {
+	lines := make([]string, 0, len(instrs))
+	for _, instr := range instrs {
+		lines = append(lines, fmt.Sprintf("%04X  %s", instr.Offset, instr))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package debugger
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/store"
+	"github.com/OnyxPay/OnyxChain/core/store/ledgerstore"
+	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/core/utils"
+	"github.com/OnyxPay/OnyxChain/smartcontract"
+	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
+)
+
+//Config is the configuration of debug session
+type Config struct {
+	Code      []byte               //neovm code of contract to debug
+	Params    []interface{}        //params to invoke contract
+	Witnesses []common.Address     //addresses pass the witness check
+	Ledger    store.LedgerStore    //ledger for blockchain apis, blockchain apis fail if nil
+	Overlay   *overlaydb.OverlayDB //state of ledger, an empty in-memory state is used if nil
+}
+
+//Session debug a contract invoked by params. The contract is deployed to the cache of session,
+//and the state changes of execution are never committed
+type Session struct {
+	*Debugger
+	Contract common.Address
+	Script   []byte //invoke script of contract
+	sc       *smartcontract.SmartContract
+}
+
+//NewSession return a debug session of config
+func NewSession(config *Config) (*Session, error) {
+	if len(config.Code) == 0 {
+		return nil, fmt.Errorf("contract code is empty")
+	}
+	if _, err := Disassemble(config.Code); err != nil {
+		return nil, fmt.Errorf("invalid contract code:%s", err)
+	}
+	overlay := config.Overlay
+	if overlay == nil {
+		overlay = ledgerstore.NewMemStateStore(0).NewOverlayDB()
+	}
+	cache := storage.NewCacheDB(overlay)
+	deploy := &payload.DeployCode{Code: config.Code, NeedStorage: true, VmType: payload.NEOVM_TYPE}
+	if err := cache.PutContract(deploy); err != nil {
+		return nil, err
+	}
+	contract := deploy.Address()
+	builder := vm.NewParamsBuilder(new(bytes.Buffer))
+	if err := utils.BuildNeoVMParam(builder, config.Params); err != nil {
+		return nil, err
+	}
+	builder.EmitPushCall(contract[:])
+
+	scConfig := &smartcontract.Config{
+		Time: uint32(time.Now().Unix()),
+		Tx:   &types.Transaction{SignedAddr: config.Witnesses},
+	}
+	if config.Ledger != nil {
+		scConfig.Height = config.Ledger.GetCurrentBlockHeight() + 1
+		scConfig.BlockHash = config.Ledger.GetCurrentBlockHash()
+	}
+	debugger := NewDebugger(cache)
+	return &Session{
+		Debugger: debugger,
+		Contract: contract,
+		Script:   builder.ToArray(),
+		sc: &smartcontract.SmartContract{
+			Config:   scConfig,
+			CacheDB:  cache,
+			Store:    config.Ledger,
+			Gas:      math.MaxUint64,
+			PreExec:  true,
+			Debugger: debugger,
+		},
+	}, nil
+}
+
+//Start run the invoke script, and wait until it is stopped or exited
+func (self *Session) Start(stopOnEntry bool) *Event {
+	return self.Debugger.Start(self.run, stopOnEntry)
+}
+
+func (self *Session) run() (interface{}, error) {
+	engine, err := self.sc.NewExecuteEngine(self.Script)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Invoke()
+}
+
+//GasConsumed return the gas consumed by execution
+func (self *Session) GasConsumed() uint64 {
+	return math.MaxUint64 - self.sc.Gas
+}
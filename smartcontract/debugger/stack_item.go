@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package debugger
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
+	"github.com/OnyxPay/OnyxChain/vm/neovm/types"
+)
+
+const (
+	MAX_ITEM_DEPTH = 16   //max nested depth of array, struct and map to inspect
+	MAX_ITEM_COUNT = 1024 //max item count of stack to inspect
+)
+
+//StackItem is the typed value of neovm stack item
+type StackItem struct {
+	Type  string
+	Value string       `json:",omitempty"` //hex of byte array, decimal of integer, true or false of boolean
+	Key   *StackItem   `json:",omitempty"` //key of map entry
+	Items []*StackItem `json:",omitempty"` //elements of array and struct, entries of map
+}
+
+//String return the value of stack item in readable form
+func (self *StackItem) String() string {
+	if self.Items == nil {
+		return fmt.Sprintf("%s(%s)", self.Type, self.Value)
+	}
+	s := self.Type + "["
+	for i, item := range self.Items {
+		if i > 0 {
+			s += ", "
+		}
+		if item.Key != nil {
+			s += item.Key.String() + ": "
+		}
+		s += item.String()
+	}
+	return s + "]"
+}
+
+//NewStackItem convert neovm stack item to typed value
+func NewStackItem(item types.StackItems) *StackItem {
+	count := 0
+	return newStackItem(item, 0, &count)
+}
+
+//StackItems return the items of stack from top to bottom
+func StackItems(stack *vm.RandomAccessStack) []*StackItem {
+	items := make([]*StackItem, 0, stack.Count())
+	for i := 0; i < stack.Count() && i < MAX_ITEM_COUNT; i++ {
+		items = append(items, NewStackItem(stack.Peek(i)))
+	}
+	return items
+}
+
+func newStackItem(item types.StackItems, depth int, count *int) *StackItem {
+	*count++
+	switch v := item.(type) {
+	case nil:
+		return &StackItem{Type: "Null"}
+	case *types.ByteArray:
+		arr, _ := v.GetByteArray()
+		return &StackItem{Type: "ByteArray", Value: common.ToHexString(arr)}
+	case *types.Integer:
+		i, _ := v.GetBigInteger()
+		return &StackItem{Type: "Integer", Value: i.String()}
+	case *types.Boolean:
+		b, _ := v.GetBoolean()
+		return &StackItem{Type: "Boolean", Value: fmt.Sprint(b)}
+	case *types.Interop:
+		it, _ := v.GetInterface()
+		return &StackItem{Type: "Interop", Value: fmt.Sprintf("%T", it)}
+	case *types.Array:
+		arr, _ := v.GetArray()
+		return newCollectionItem("Array", arr, depth, count)
+	case *types.Struct:
+		arr, _ := v.GetStruct()
+		return newCollectionItem("Struct", arr, depth, count)
+	case *types.Map:
+		m, _ := v.GetMap()
+		rsp := &StackItem{Type: "Map", Items: []*StackItem{}}
+		if depth >= MAX_ITEM_DEPTH {
+			rsp.Value = "..."
+			return rsp
+		}
+		for key, value := range m {
+			if *count >= MAX_ITEM_COUNT {
+				break
+			}
+			entry := newStackItem(value, depth+1, count)
+			entry.Key = newStackItem(key, depth+1, count)
+			rsp.Items = append(rsp.Items, entry)
+		}
+		// map iteration is in random order
+		sort.Slice(rsp.Items, func(i, j int) bool {
+			return rsp.Items[i].Key.String() < rsp.Items[j].Key.String()
+		})
+		return rsp
+	default:
+		return &StackItem{Type: fmt.Sprintf("%T", item)}
+	}
+}
+
+func newCollectionItem(typ string, arr []types.StackItems, depth int, count *int) *StackItem {
+	rsp := &StackItem{Type: typ, Items: []*StackItem{}}
+	if depth >= MAX_ITEM_DEPTH {
+		rsp.Value = "..."
+		return rsp
+	}
+	for _, v := range arr {
+		if *count >= MAX_ITEM_COUNT {
+			break
+		}
+		rsp.Items = append(rsp.Items, newStackItem(v, depth+1, count))
+	}
+	return rsp
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package event
+
+import (
+	"github.com/OnyxPay/OnyxChain/common"
+)
+
+// EventFilter describe a query of event notify in height range [FromHeight, ToHeight].
+// Empty Contracts or Topics match any contract or topic
+type EventFilter struct {
+	FromHeight uint32
+	ToHeight   uint32
+	Contracts  []common.Address
+	Topics     []string
+	Offset     uint32
+	Limit      uint32
+}
+
+// NotifyEventLog describe an event notify with its position in chain
+type NotifyEventLog struct {
+	Height      uint32
+	TxIndex     uint32
+	TxHash      common.Uint256
+	NotifyIndex uint32
+	Notify      *NotifyEventInfo
+}
+
+// NotifyTopic return the topic of event notify, which is the first notify state when it is a string
+func NotifyTopic(states interface{}) (string, bool) {
+	switch v := states.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		if len(v) > 0 {
+			topic, ok := v[0].(string)
+			return topic, ok
+		}
+	case []string:
+		if len(v) > 0 {
+			return v[0], true
+		}
+	}
+	return "", false
+}
+
+// Match return whether the event notify satisfy the contract and topic condition of filter
+func (this *EventFilter) Match(notify *NotifyEventInfo) bool {
+	if len(this.Contracts) > 0 {
+		found := false
+		for _, addr := range this.Contracts {
+			if addr == notify.ContractAddress {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(this.Topics) > 0 {
+		topic, ok := NotifyTopic(notify.States)
+		if !ok {
+			return false
+		}
+		for _, t := range this.Topics {
+			if t == topic {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
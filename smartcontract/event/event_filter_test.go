@@ -0,0 +1,41 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package event
+
+import (
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFilterMatch(t *testing.T) {
+	contract := common.Address{1}
+	notify := &NotifyEventInfo{ContractAddress: contract, States: []interface{}{"transfer", "from", "to"}}
+
+	assert.True(t, (&EventFilter{}).Match(notify))
+	assert.True(t, (&EventFilter{Contracts: []common.Address{{2}, contract}}).Match(notify))
+	assert.False(t, (&EventFilter{Contracts: []common.Address{{2}}}).Match(notify))
+	assert.True(t, (&EventFilter{Topics: []string{"approve", "transfer"}}).Match(notify))
+	assert.False(t, (&EventFilter{Contracts: []common.Address{contract}, Topics: []string{"approve"}}).Match(notify))
+
+	notify.States = []interface{}{uint64(1)}
+	assert.False(t, (&EventFilter{Topics: []string{"transfer"}}).Match(notify))
+	assert.True(t, (&EventFilter{Contracts: []common.Address{contract}}).Match(notify))
+}
@@ -0,0 +1,446 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package asset
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
+)
+
+const (
+	//function name
+	CREATE_ASSET_NAME = "createAsset"
+	TRANSFER_NAME     = onx.TRANSFER_NAME
+	TRANSFERFROM_NAME = onx.TRANSFERFROM_NAME
+	APPROVE_NAME      = onx.APPROVE_NAME
+	ALLOWANCE_NAME    = onx.ALLOWANCE_NAME
+	BALANCEOF_NAME    = onx.BALANCEOF_NAME
+	NAME_NAME         = onx.NAME_NAME
+	SYMBOL_NAME       = onx.SYMBOL_NAME
+	DECIMALS_NAME     = onx.DECIMALS_NAME
+	TOTALSUPPLY_NAME  = onx.TOTALSUPPLY_NAME
+	GET_ASSET_NAME    = "getAsset"
+	MINT_NAME         = "mint"
+	BURN_NAME         = "burn"
+	FREEZE_NAME       = "freeze"
+	UNFREEZE_NAME     = "unfreeze"
+	SET_ADMIN_NAME    = "setAdmin"
+)
+
+func InitAsset() {
+	native.Contracts[utils.AssetContractAddress] = RegisterAssetContract
+}
+
+func RegisterAssetContract(native *native.NativeService) {
+	native.Register(CREATE_ASSET_NAME, CreateAsset)
+	native.Register(TRANSFER_NAME, AssetTransfer)
+	native.Register(TRANSFERFROM_NAME, AssetTransferFrom)
+	native.Register(APPROVE_NAME, AssetApprove)
+	native.Register(ALLOWANCE_NAME, AssetAllowance)
+	native.Register(BALANCEOF_NAME, AssetBalanceOf)
+	native.Register(NAME_NAME, AssetName)
+	native.Register(SYMBOL_NAME, AssetSymbol)
+	native.Register(DECIMALS_NAME, AssetDecimals)
+	native.Register(TOTALSUPPLY_NAME, AssetTotalSupply)
+	native.Register(GET_ASSET_NAME, GetAssetInfo)
+	native.Register(MINT_NAME, AssetMint)
+	native.Register(BURN_NAME, AssetBurn)
+	native.Register(FREEZE_NAME, AssetFreeze)
+	native.Register(UNFREEZE_NAME, AssetUnfreeze)
+	native.Register(SET_ADMIN_NAME, AssetSetAdmin)
+}
+
+//CreateAsset create a new asset signed by creator onx id, the whole supply is issued to admin, return the asset id
+func CreateAsset(native *native.NativeService) ([]byte, error) {
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	param := new(CreateAssetParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("createAsset, deserialize param error: %v", err)
+	}
+	if len(param.Name) == 0 || len(param.Name) > MAX_NAME_SIZE {
+		return utils.BYTE_FALSE, fmt.Errorf("createAsset, invalid name size %d", len(param.Name))
+	}
+	if len(param.Symbol) == 0 || len(param.Symbol) > MAX_SYMBOL_SIZE {
+		return utils.BYTE_FALSE, fmt.Errorf("createAsset, invalid symbol size %d", len(param.Symbol))
+	}
+	if param.Decimals > MAX_DECIMALS {
+		return utils.BYTE_FALSE, fmt.Errorf("createAsset, decimals %d over %d", param.Decimals, MAX_DECIMALS)
+	}
+	if param.Admin == common.ADDRESS_EMPTY {
+		return utils.BYTE_FALSE, fmt.Errorf("createAsset, admin is empty")
+	}
+	if err := verifyOnxID(native, param.Creator, param.KeyNo); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("createAsset, %v", err)
+	}
+
+	id, err := utils.GetStorageUInt64(native, genNextIdKey(contract))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("createAsset, get next id error: %v", err)
+	}
+	id++
+	info := &AssetInfo{
+		Id:       id,
+		Name:     param.Name,
+		Symbol:   param.Symbol,
+		Decimals: param.Decimals,
+		Admin:    param.Admin,
+		Creator:  param.Creator,
+	}
+	putAsset(native, info)
+	native.CacheDB.Put(genNextIdKey(contract), utils.GenUInt64StorageItem(id).ToArray())
+
+	assetAddr := GenAssetAddress(id)
+	if param.Supply > 0 {
+		native.CacheDB.Put(onx.GenTotalSupplyKey(assetAddr), utils.GenUInt64StorageItem(param.Supply).ToArray())
+		native.CacheDB.Put(onx.GenBalanceKey(assetAddr, param.Admin), utils.GenUInt64StorageItem(param.Supply).ToArray())
+	}
+
+	addNotify(native, CREATE_ASSET_NAME, id, assetAddr.ToHexString(), param.Name, param.Symbol, param.Decimals,
+		param.Supply, param.Admin.ToBase58())
+	if param.Supply > 0 {
+		addTransferNotify(native, id, &onx.State{To: param.Admin, Value: param.Supply})
+	}
+	return common.BigIntToNeoBytes(new(big.Int).SetUint64(id)), nil
+}
+
+//AssetTransfer transfer asset between addresses not frozen, the same as onx transfer
+func AssetTransfer(native *native.NativeService) ([]byte, error) {
+	param := new(TransferParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("transfer, deserialize param error: %v", err)
+	}
+	if _, err := getAssetNotNil(native, param.AssetId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("transfer, %v", err)
+	}
+	assetAddr := GenAssetAddress(param.AssetId)
+	totalSupply, err := utils.GetStorageUInt64(native, onx.GenTotalSupplyKey(assetAddr))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("transfer, get total supply error: %v", err)
+	}
+	for _, v := range param.Transfers.States {
+		if v.Value == 0 {
+			continue
+		}
+		if v.Value > totalSupply {
+			return utils.BYTE_FALSE, fmt.Errorf("transfer, amount:%d over totalSupply:%d", v.Value, totalSupply)
+		}
+		if err := utils.ValidateOwner(native, v.From); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("transfer, checkWitness error: %v", err)
+		}
+		if err := checkNotFrozen(native, assetAddr, v.From, v.To); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("transfer, %v", err)
+		}
+		if _, _, err := onx.Transfer(native, assetAddr, &v); err != nil {
+			return utils.BYTE_FALSE, err
+		}
+		addTransferNotify(native, param.AssetId, &v)
+	}
+	return utils.BYTE_TRUE, nil
+}
+
+//AssetTransferFrom transfer approved asset, sender, from and to should not be frozen
+func AssetTransferFrom(native *native.NativeService) ([]byte, error) {
+	param := new(TransferFromParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("transferFrom, deserialize param error: %v", err)
+	}
+	if _, err := getAssetNotNil(native, param.AssetId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("transferFrom, %v", err)
+	}
+	state := &param.State
+	if state.Value == 0 {
+		return utils.BYTE_FALSE, nil
+	}
+	assetAddr := GenAssetAddress(param.AssetId)
+	if err := checkNotFrozen(native, assetAddr, state.Sender, state.From, state.To); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("transferFrom, %v", err)
+	}
+	if _, _, err := onx.TransferedFrom(native, assetAddr, state); err != nil {
+		return utils.BYTE_FALSE, err
+	}
+	addTransferNotify(native, param.AssetId, &onx.State{From: state.From, To: state.To, Value: state.Value})
+	return utils.BYTE_TRUE, nil
+}
+
+//AssetApprove approve spender to transfer asset of from
+func AssetApprove(native *native.NativeService) ([]byte, error) {
+	param := new(StateParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("approve, deserialize param error: %v", err)
+	}
+	if _, err := getAssetNotNil(native, param.AssetId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("approve, %v", err)
+	}
+	assetAddr := GenAssetAddress(param.AssetId)
+	totalSupply, err := utils.GetStorageUInt64(native, onx.GenTotalSupplyKey(assetAddr))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("approve, get total supply error: %v", err)
+	}
+	state := &param.State
+	if state.Value > totalSupply {
+		return utils.BYTE_FALSE, fmt.Errorf("approve, amount:%d over totalSupply:%d", state.Value, totalSupply)
+	}
+	if err := utils.ValidateOwner(native, state.From); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("approve, checkWitness error: %v", err)
+	}
+	if err := checkNotFrozen(native, assetAddr, state.From); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("approve, %v", err)
+	}
+	native.CacheDB.Put(onx.GenApproveKey(assetAddr, state.From, state.To), utils.GenUInt64StorageItem(state.Value).ToArray())
+	return utils.BYTE_TRUE, nil
+}
+
+func AssetAllowance(native *native.NativeService) ([]byte, error) {
+	param := new(StateParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("allowance, deserialize param error: %v", err)
+	}
+	assetAddr := GenAssetAddress(param.AssetId)
+	return getUInt64Value(native, onx.GenApproveKey(assetAddr, param.State.From, param.State.To))
+}
+
+func AssetBalanceOf(native *native.NativeService) ([]byte, error) {
+	param := new(AddressParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("balanceOf, deserialize param error: %v", err)
+	}
+	assetAddr := GenAssetAddress(param.AssetId)
+	return getUInt64Value(native, onx.GenBalanceKey(assetAddr, param.Address))
+}
+
+func AssetName(native *native.NativeService) ([]byte, error) {
+	info, err := getAssetByInput(native)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("name, %v", err)
+	}
+	return []byte(info.Name), nil
+}
+
+func AssetSymbol(native *native.NativeService) ([]byte, error) {
+	info, err := getAssetByInput(native)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("symbol, %v", err)
+	}
+	return []byte(info.Symbol), nil
+}
+
+func AssetDecimals(native *native.NativeService) ([]byte, error) {
+	info, err := getAssetByInput(native)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("decimals, %v", err)
+	}
+	return common.BigIntToNeoBytes(new(big.Int).SetUint64(info.Decimals)), nil
+}
+
+func AssetTotalSupply(native *native.NativeService) ([]byte, error) {
+	info, err := getAssetByInput(native)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("totalSupply, %v", err)
+	}
+	return getUInt64Value(native, onx.GenTotalSupplyKey(GenAssetAddress(info.Id)))
+}
+
+//GetAssetInfo return the serialized asset info of id, empty if not exist
+func GetAssetInfo(native *native.NativeService) ([]byte, error) {
+	id, err := utils.DecodeVarUint(common.NewZeroCopySource(native.Input))
+	if err != nil {
+		return nil, fmt.Errorf("getAsset, deserialize id error: %v", err)
+	}
+	info, err := GetAsset(native, id)
+	if err != nil {
+		return nil, fmt.Errorf("getAsset, %v", err)
+	}
+	if info == nil {
+		return []byte{}, nil
+	}
+	sink := common.NewZeroCopySink(nil)
+	info.Serialization(sink)
+	return sink.Bytes(), nil
+}
+
+//AssetMint issue new asset to address by admin
+func AssetMint(native *native.NativeService) ([]byte, error) {
+	param := new(AddressParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("mint, deserialize param error: %v", err)
+	}
+	assetAddr, err := validateAdmin(native, param.AssetId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("mint, %v", err)
+	}
+	if param.Value == 0 {
+		return utils.BYTE_FALSE, fmt.Errorf("mint, amount is zero")
+	}
+	if err := checkNotFrozen(native, assetAddr, param.Address); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("mint, %v", err)
+	}
+	totalSupply, err := utils.GetStorageUInt64(native, onx.GenTotalSupplyKey(assetAddr))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("mint, get total supply error: %v", err)
+	}
+	totalSupply, overflow := common.SafeAdd(totalSupply, param.Value)
+	if overflow {
+		return utils.BYTE_FALSE, fmt.Errorf("mint, total supply overflow")
+	}
+	balanceKey := onx.GenBalanceKey(assetAddr, param.Address)
+	balance, err := utils.GetStorageUInt64(native, balanceKey)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("mint, get balance error: %v", err)
+	}
+	putUInt64(native, onx.GenTotalSupplyKey(assetAddr), totalSupply)
+	putUInt64(native, balanceKey, balance+param.Value)
+
+	addTransferNotify(native, param.AssetId, &onx.State{To: param.Address, Value: param.Value})
+	return utils.BYTE_TRUE, nil
+}
+
+//AssetBurn destroy asset of admin's own balance, address should be the admin
+func AssetBurn(native *native.NativeService) ([]byte, error) {
+	param := new(AddressParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("burn, deserialize param error: %v", err)
+	}
+	info, err := getAssetNotNil(native, param.AssetId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("burn, %v", err)
+	}
+	if param.Address != info.Admin {
+		return utils.BYTE_FALSE, fmt.Errorf("burn, only balance of admin can be burned")
+	}
+	assetAddr, err := validateAdmin(native, param.AssetId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("burn, %v", err)
+	}
+	if param.Value == 0 {
+		return utils.BYTE_FALSE, fmt.Errorf("burn, amount is zero")
+	}
+	balanceKey := onx.GenBalanceKey(assetAddr, param.Address)
+	balance, err := utils.GetStorageUInt64(native, balanceKey)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("burn, get balance error: %v", err)
+	}
+	if balance < param.Value {
+		return utils.BYTE_FALSE, fmt.Errorf("burn, balance insufficient, have %d, got %d", balance, param.Value)
+	}
+	totalSupply, err := utils.GetStorageUInt64(native, onx.GenTotalSupplyKey(assetAddr))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("burn, get total supply error: %v", err)
+	}
+	putUInt64(native, onx.GenTotalSupplyKey(assetAddr), totalSupply-param.Value)
+	putUInt64(native, balanceKey, balance-param.Value)
+
+	addTransferNotify(native, param.AssetId, &onx.State{From: param.Address, Value: param.Value})
+	return utils.BYTE_TRUE, nil
+}
+
+//AssetFreeze forbid address to send, receive and approve the asset
+func AssetFreeze(native *native.NativeService) ([]byte, error) {
+	param := new(AddressParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("freeze, deserialize param error: %v", err)
+	}
+	assetAddr, err := validateAdmin(native, param.AssetId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("freeze, %v", err)
+	}
+	utils.PutBytes(native, genFrozenKey(assetAddr, param.Address), utils.BYTE_TRUE)
+
+	addNotify(native, FREEZE_NAME, param.AssetId, param.Address.ToBase58())
+	return utils.BYTE_TRUE, nil
+}
+
+func AssetUnfreeze(native *native.NativeService) ([]byte, error) {
+	param := new(AddressParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("unfreeze, deserialize param error: %v", err)
+	}
+	assetAddr, err := validateAdmin(native, param.AssetId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("unfreeze, %v", err)
+	}
+	native.CacheDB.Delete(genFrozenKey(assetAddr, param.Address))
+
+	addNotify(native, UNFREEZE_NAME, param.AssetId, param.Address.ToBase58())
+	return utils.BYTE_TRUE, nil
+}
+
+//AssetSetAdmin transfer the admin right of asset to a new address
+func AssetSetAdmin(native *native.NativeService) ([]byte, error) {
+	param := new(AddressParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("setAdmin, deserialize param error: %v", err)
+	}
+	if param.Address == common.ADDRESS_EMPTY {
+		return utils.BYTE_FALSE, fmt.Errorf("setAdmin, admin is empty")
+	}
+	if _, err := validateAdmin(native, param.AssetId); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("setAdmin, %v", err)
+	}
+	info, err := getAssetNotNil(native, param.AssetId)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("setAdmin, %v", err)
+	}
+	info.Admin = param.Address
+	putAsset(native, info)
+
+	addNotify(native, SET_ADMIN_NAME, param.AssetId, param.Address.ToBase58())
+	return utils.BYTE_TRUE, nil
+}
+
+func getAssetByInput(native *native.NativeService) (*AssetInfo, error) {
+	id, err := utils.DecodeVarUint(common.NewZeroCopySource(native.Input))
+	if err != nil {
+		return nil, fmt.Errorf("deserialize id error: %v", err)
+	}
+	return getAssetNotNil(native, id)
+}
+
+//validateAdmin check witness of asset admin, return the storage prefix address of asset
+func validateAdmin(native *native.NativeService, id uint64) (common.Address, error) {
+	info, err := getAssetNotNil(native, id)
+	if err != nil {
+		return common.ADDRESS_EMPTY, err
+	}
+	if err := utils.ValidateOwner(native, info.Admin); err != nil {
+		return common.ADDRESS_EMPTY, fmt.Errorf("checkWitness of admin error: %v", err)
+	}
+	return GenAssetAddress(id), nil
+}
+
+func getUInt64Value(native *native.NativeService, key []byte) ([]byte, error) {
+	amount, err := utils.GetStorageUInt64(native, key)
+	if err != nil {
+		return utils.BYTE_FALSE, err
+	}
+	return common.BigIntToNeoBytes(new(big.Int).SetUint64(amount)), nil
+}
+
+//putUInt64 put the value of key, delete the key if value is zero
+func putUInt64(native *native.NativeService, key []byte, value uint64) {
+	if value == 0 {
+		native.CacheDB.Delete(key)
+		return
+	}
+	native.CacheDB.Put(key, utils.GenUInt64StorageItem(value).ToArray())
+}
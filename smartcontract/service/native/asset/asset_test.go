@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package asset
+
+import (
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/store/leveldbstore"
+	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
+	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAssetParam_Serialization(t *testing.T) {
+	param := CreateAssetParam{
+		Creator:  []byte("did:onx:AXmQDzzvpEtPkNwBEFsREzApTTDZFW6frD"),
+		KeyNo:    1,
+		Name:     "Test Asset",
+		Symbol:   "TST",
+		Decimals: 8,
+		Supply:   1000000000,
+		Admin:    common.AddressFromVmCode([]byte{1, 2, 3}),
+	}
+	sink := common.NewZeroCopySink(nil)
+	param.Serialization(sink)
+
+	param2 := CreateAssetParam{}
+	assert.Nil(t, param2.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, param, param2)
+	assert.NotNil(t, param2.Deserialization(common.NewZeroCopySource(sink.Bytes()[:20])))
+}
+
+func TestAssetInfo_Serialization(t *testing.T) {
+	info := AssetInfo{
+		Id:       3,
+		Name:     "Test Asset",
+		Symbol:   "TST",
+		Decimals: 8,
+		Admin:    common.AddressFromVmCode([]byte{1, 2, 3}),
+		Creator:  []byte("did:onx:AXmQDzzvpEtPkNwBEFsREzApTTDZFW6frD"),
+	}
+	sink := common.NewZeroCopySink(nil)
+	info.Serialization(sink)
+
+	info2 := AssetInfo{}
+	assert.Nil(t, info2.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, info, info2)
+}
+
+func TestAddressParam_Deserialization(t *testing.T) {
+	addr := common.AddressFromVmCode([]byte{1, 2, 3})
+	sink := common.NewZeroCopySink(nil)
+	utils.EncodeVarUint(sink, 1)
+	utils.EncodeAddress(sink, addr)
+
+	param := AddressParam{}
+	assert.Nil(t, param.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, AddressParam{AssetId: 1, Address: addr}, param)
+
+	utils.EncodeVarUint(sink, 100)
+	assert.Nil(t, param.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, AddressParam{AssetId: 1, Address: addr, Value: 100}, param)
+}
+
+func TestGenAssetAddress(t *testing.T) {
+	assert.Equal(t, GenAssetAddress(1), GenAssetAddress(1))
+	assert.NotEqual(t, GenAssetAddress(1), GenAssetAddress(2))
+	assert.NotEqual(t, utils.AssetContractAddress, GenAssetAddress(0))
+}
+
+func TestFrozen(t *testing.T) {
+	store, _ := leveldbstore.NewMemLevelDBStore()
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(store))}
+	assetAddr := GenAssetAddress(1)
+	addr1 := common.AddressFromVmCode([]byte{1})
+	addr2 := common.AddressFromVmCode([]byte{2})
+
+	assert.Nil(t, checkNotFrozen(service, assetAddr, addr1, addr2))
+	utils.PutBytes(service, genFrozenKey(assetAddr, addr2), utils.BYTE_TRUE)
+	assert.Nil(t, checkNotFrozen(service, assetAddr, addr1))
+	assert.NotNil(t, checkNotFrozen(service, assetAddr, addr1, addr2))
+	assert.Nil(t, checkNotFrozen(service, GenAssetAddress(2), addr2))
+}
@@ -0,0 +1,248 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package asset
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
+)
+
+//AssetInfo is the meta data of asset created by factory
+type AssetInfo struct {
+	Id       uint64
+	Name     string
+	Symbol   string
+	Decimals uint64
+	Admin    common.Address //admin can mint, burn and freeze the asset
+	Creator  []byte         //onx id of creator
+}
+
+func (this *AssetInfo) Serialization(sink *common.ZeroCopySink) {
+	utils.EncodeVarUint(sink, this.Id)
+	sink.WriteString(this.Name)
+	sink.WriteString(this.Symbol)
+	utils.EncodeVarUint(sink, this.Decimals)
+	utils.EncodeAddress(sink, this.Admin)
+	sink.WriteVarBytes(this.Creator)
+}
+
+func (this *AssetInfo) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.Id, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize id error:%v", err)
+	}
+	this.Name, err = decodeString(source)
+	if err != nil {
+		return fmt.Errorf("deserialize name error:%v", err)
+	}
+	this.Symbol, err = decodeString(source)
+	if err != nil {
+		return fmt.Errorf("deserialize symbol error:%v", err)
+	}
+	this.Decimals, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize decimals error:%v", err)
+	}
+	this.Admin, err = utils.DecodeAddress(source)
+	if err != nil {
+		return fmt.Errorf("deserialize admin error:%v", err)
+	}
+	this.Creator, err = decodeBytes(source)
+	if err != nil {
+		return fmt.Errorf("deserialize creator error:%v", err)
+	}
+	return nil
+}
+
+//CreateAssetParam is the param of createAsset, the signature of creator onx id is verified with key of KeyNo
+type CreateAssetParam struct {
+	Creator  []byte
+	KeyNo    uint64
+	Name     string
+	Symbol   string
+	Decimals uint64
+	Supply   uint64
+	Admin    common.Address
+}
+
+func (this *CreateAssetParam) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteVarBytes(this.Creator)
+	utils.EncodeVarUint(sink, this.KeyNo)
+	sink.WriteString(this.Name)
+	sink.WriteString(this.Symbol)
+	utils.EncodeVarUint(sink, this.Decimals)
+	utils.EncodeVarUint(sink, this.Supply)
+	utils.EncodeAddress(sink, this.Admin)
+}
+
+func (this *CreateAssetParam) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.Creator, err = decodeBytes(source)
+	if err != nil {
+		return fmt.Errorf("deserialize creator error:%v", err)
+	}
+	this.KeyNo, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize keyNo error:%v", err)
+	}
+	this.Name, err = decodeString(source)
+	if err != nil {
+		return fmt.Errorf("deserialize name error:%v", err)
+	}
+	this.Symbol, err = decodeString(source)
+	if err != nil {
+		return fmt.Errorf("deserialize symbol error:%v", err)
+	}
+	this.Decimals, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize decimals error:%v", err)
+	}
+	this.Supply, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize supply error:%v", err)
+	}
+	this.Admin, err = utils.DecodeAddress(source)
+	if err != nil {
+		return fmt.Errorf("deserialize admin error:%v", err)
+	}
+	return nil
+}
+
+//TransferParam is the param of transfer
+type TransferParam struct {
+	AssetId   uint64
+	Transfers onx.Transfers
+}
+
+func (this *TransferParam) Serialization(sink *common.ZeroCopySink) {
+	utils.EncodeVarUint(sink, this.AssetId)
+	this.Transfers.Serialization(sink)
+}
+
+func (this *TransferParam) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.AssetId, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize asset id error:%v", err)
+	}
+	return this.Transfers.Deserialization(source)
+}
+
+//TransferFromParam is the param of transferFrom
+type TransferFromParam struct {
+	AssetId uint64
+	State   onx.TransferFrom
+}
+
+func (this *TransferFromParam) Serialization(sink *common.ZeroCopySink) {
+	utils.EncodeVarUint(sink, this.AssetId)
+	this.State.Serialization(sink)
+}
+
+func (this *TransferFromParam) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.AssetId, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize asset id error:%v", err)
+	}
+	return this.State.Deserialization(source)
+}
+
+//StateParam is the param of approve and allowance, value of state is ignored by allowance
+type StateParam struct {
+	AssetId uint64
+	State   onx.State
+}
+
+func (this *StateParam) Serialization(sink *common.ZeroCopySink) {
+	utils.EncodeVarUint(sink, this.AssetId)
+	this.State.Serialization(sink)
+}
+
+func (this *StateParam) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.AssetId, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize asset id error:%v", err)
+	}
+	this.State.From, err = utils.DecodeAddress(source)
+	if err != nil {
+		return fmt.Errorf("deserialize from error:%v", err)
+	}
+	this.State.To, err = utils.DecodeAddress(source)
+	if err != nil {
+		return fmt.Errorf("deserialize to error:%v", err)
+	}
+	if source.Len() == 0 {
+		return nil
+	}
+	this.State.Value, err = utils.DecodeVarUint(source)
+	return err
+}
+
+//AddressParam is the param of methods operating on an address of asset, value is used by mint and burn only
+type AddressParam struct {
+	AssetId uint64
+	Address common.Address
+	Value   uint64
+}
+
+func (this *AddressParam) Serialization(sink *common.ZeroCopySink) {
+	utils.EncodeVarUint(sink, this.AssetId)
+	utils.EncodeAddress(sink, this.Address)
+	utils.EncodeVarUint(sink, this.Value)
+}
+
+func (this *AddressParam) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.AssetId, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize asset id error:%v", err)
+	}
+	this.Address, err = utils.DecodeAddress(source)
+	if err != nil {
+		return fmt.Errorf("deserialize address error:%v", err)
+	}
+	if source.Len() == 0 {
+		return nil
+	}
+	this.Value, err = utils.DecodeVarUint(source)
+	return err
+}
+
+func decodeString(source *common.ZeroCopySource) (string, error) {
+	data, err := decodeBytes(source)
+	return string(data), err
+}
+
+func decodeBytes(source *common.ZeroCopySource) ([]byte, error) {
+	data, _, irregular, eof := source.NextVarBytes()
+	if irregular {
+		return nil, common.ErrIrregularData
+	}
+	if eof {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
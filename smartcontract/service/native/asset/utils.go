@@ -0,0 +1,157 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package asset
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/common/serialization"
+	"github.com/OnyxPay/OnyxChain/smartcontract/event"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
+)
+
+const (
+	//key prefix
+	NEXT_ID = "nextId"
+	ASSET   = "asset"
+	FROZEN  = "frozen"
+
+	MAX_DECIMALS    = 18
+	MAX_NAME_SIZE   = 64
+	MAX_SYMBOL_SIZE = 16
+)
+
+//GenAssetAddress return the storage prefix address of asset id, balances and allowances of the asset are stored
+//with the same layout as onx under this prefix
+func GenAssetAddress(id uint64) common.Address {
+	sink := common.NewZeroCopySink(append(utils.AssetContractAddress[:], ASSET...))
+	sink.WriteUint64(id)
+	hash := sha256.Sum256(sink.Bytes())
+	addr, _ := common.AddressParseFromBytes(hash[:common.ADDR_LEN])
+	return addr
+}
+
+func genNextIdKey(contract common.Address) []byte {
+	return append(contract[:], NEXT_ID...)
+}
+
+func genAssetKey(contract common.Address, id uint64) []byte {
+	sink := common.NewZeroCopySink(append(contract[:], ASSET...))
+	sink.WriteUint64(id)
+	return sink.Bytes()
+}
+
+func genFrozenKey(assetAddr, addr common.Address) []byte {
+	temp := append(assetAddr[:], FROZEN...)
+	return append(temp, addr[:]...)
+}
+
+//GetAsset return the asset info of id, nil if not exist
+func GetAsset(native *native.NativeService, id uint64) (*AssetInfo, error) {
+	item, err := utils.GetStorageItem(native, genAssetKey(utils.AssetContractAddress, id))
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, nil
+	}
+	info := new(AssetInfo)
+	if err := info.Deserialization(common.NewZeroCopySource(item.Value)); err != nil {
+		return nil, fmt.Errorf("deserialize asset info error: %v", err)
+	}
+	return info, nil
+}
+
+func getAssetNotNil(native *native.NativeService, id uint64) (*AssetInfo, error) {
+	info, err := GetAsset(native, id)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("asset %d not found", id)
+	}
+	return info, nil
+}
+
+func putAsset(native *native.NativeService, info *AssetInfo) {
+	sink := common.NewZeroCopySink(nil)
+	info.Serialization(sink)
+	utils.PutBytes(native, genAssetKey(utils.AssetContractAddress, info.Id), sink.Bytes())
+}
+
+func isFrozen(native *native.NativeService, assetAddr, addr common.Address) (bool, error) {
+	item, err := utils.GetStorageItem(native, genFrozenKey(assetAddr, addr))
+	if err != nil {
+		return false, err
+	}
+	return item != nil, nil
+}
+
+func checkNotFrozen(native *native.NativeService, assetAddr common.Address, addrs ...common.Address) error {
+	for _, addr := range addrs {
+		frozen, err := isFrozen(native, assetAddr, addr)
+		if err != nil {
+			return err
+		}
+		if frozen {
+			return fmt.Errorf("address %s is frozen", addr.ToBase58())
+		}
+	}
+	return nil
+}
+
+func verifyOnxID(native *native.NativeService, onxID []byte, keyNo uint64) error {
+	bf := new(bytes.Buffer)
+	if err := serialization.WriteVarBytes(bf, onxID); err != nil {
+		return err
+	}
+	if err := utils.WriteVarUint(bf, keyNo); err != nil {
+		return err
+	}
+	ret, err := native.NativeCall(utils.OnxIDContractAddress, "verifySignature", bf.Bytes())
+	if err != nil {
+		return err
+	}
+	valid, ok := ret.([]byte)
+	if !ok || !bytes.Equal(valid, utils.BYTE_TRUE) {
+		return fmt.Errorf("verify signature of %s failed", string(onxID))
+	}
+	return nil
+}
+
+func addTransferNotify(native *native.NativeService, id uint64, state *onx.State) {
+	addNotify(native, onx.TRANSFER_NAME, id, state.From.ToBase58(), state.To.ToBase58(), state.Value)
+}
+
+func addNotify(native *native.NativeService, states ...interface{}) {
+	if !config.DefConfig.Common.EnableEventLog {
+		return
+	}
+	native.Notifications = append(native.Notifications,
+		&event.NotifyEventInfo{
+			ContractAddress: utils.AssetContractAddress,
+			States:          states,
+		})
+}
@@ -77,6 +77,16 @@ func getStorageParam(native *native.NativeService, key []byte) (Params, error) {
 	return params, err
 }
 
+//GetParamValue return the current value of global param, empty if not set
+func GetParamValue(native *native.NativeService, name string) (string, error) {
+	params, err := getStorageParam(native, generateParamKey(utils.ParamContractAddress, CURRENT_VALUE))
+	if err != nil {
+		return "", err
+	}
+	_, param := params.GetParam(name)
+	return param.Value, nil
+}
+
 func GetStorageRole(native *native.NativeService, key []byte) (common.Address, error) {
 	item, err := utils.GetStorageItem(native, key)
 	var role common.Address
@@ -23,12 +23,14 @@ import (
 	"math/big"
 
 	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/asset"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/auth"
 	params "github.com/OnyxPay/OnyxChain/smartcontract/service/native/global_params"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/governance"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/oxg"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onxid"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/scheduler"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
 	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
@@ -45,6 +47,8 @@ func init() {
 	onxid.Init()
 	auth.Init()
 	governance.InitGovernance()
+	scheduler.InitScheduler()
+	asset.InitAsset()
 }
 
 func InitBytes(addr common.Address, method string) []byte {
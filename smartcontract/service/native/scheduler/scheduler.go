@@ -0,0 +1,172 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package scheduler
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
+)
+
+const (
+	//function name
+	SCHEDULE_NAME  = "schedule"
+	CANCEL_NAME    = "cancel"
+	GET_ENTRY_NAME = "getEntry"
+
+	//event name
+	EXECUTE_NAME = "execute"
+
+	MAX_EXECUTE_PER_BLOCK = 64        //max entries executed in one block, the rest are delayed to next block
+	MAX_ENTRY_GAS_LIMIT   = 20000000  //max gas limit of an entry
+	MAX_GAS_PER_BLOCK     = 200000000 //max sum of gas limit of entries executed in one block
+)
+
+func InitScheduler() {
+	native.Contracts[utils.SchedulerContractAddress] = RegisterSchedulerContract
+}
+
+func RegisterSchedulerContract(native *native.NativeService) {
+	native.Register(SCHEDULE_NAME, Schedule)
+	native.Register(CANCEL_NAME, Cancel)
+	native.Register(GET_ENTRY_NAME, GetEntryInfo)
+}
+
+//Schedule charge the min transaction gas for queueing, which is not refunded on cancel, and escrow the fee of owner,
+//then schedule the invocation at execute height or time, return the id of entry
+func Schedule(native *native.NativeService) ([]byte, error) {
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	param := new(ScheduleParam)
+	if err := param.Deserialization(common.NewZeroCopySource(native.Input)); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, deserialize param error: %v", err)
+	}
+	if (param.ExecuteHeight == 0) == (param.ExecuteTime == 0) {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, one and only one of execute height and time should be set")
+	}
+	if param.ExecuteHeight != 0 && param.ExecuteHeight <= native.Height {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, execute height %d is not in future", param.ExecuteHeight)
+	}
+	if param.ExecuteTime != 0 && param.ExecuteTime <= native.Time {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, execute time %d is not in future", param.ExecuteTime)
+	}
+	if len(param.Code) == 0 || len(param.Code) > MAX_CODE_SIZE {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, invalid code size %d", len(param.Code))
+	}
+	if param.GasLimit < neovm.MIN_TRANSACTION_GAS || param.GasLimit > MAX_ENTRY_GAS_LIMIT {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, gas limit %d out of range [%d, %d]", param.GasLimit,
+			neovm.MIN_TRANSACTION_GAS, MAX_ENTRY_GAS_LIMIT)
+	}
+	minGasPrice, err := GetMinGasPrice(native)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, %v", err)
+	}
+	if param.GasPrice == 0 || param.GasPrice < minGasPrice {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, gas price %d less than %d", param.GasPrice, minGasPrice)
+	}
+	fee, overflow := common.SafeMul(param.GasLimit, param.GasPrice)
+	if overflow {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, fee overflow")
+	}
+	scheduleFee := neovm.MIN_TRANSACTION_GAS * param.GasPrice
+	if err := utils.ValidateOwner(native, param.Owner); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, checkWitness error: %v", err)
+	}
+
+	id, err := utils.GetStorageUInt64(native, genNextIdKey(contract))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, get next id error: %v", err)
+	}
+	id++
+	entry := &Entry{
+		Id:            id,
+		Owner:         param.Owner,
+		Code:          param.Code,
+		ExecuteHeight: param.ExecuteHeight,
+		ExecuteTime:   param.ExecuteTime,
+		GasLimit:      param.GasLimit,
+		GasPrice:      param.GasPrice,
+	}
+	if err := pushQueue(native, entry); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, %v", err)
+	}
+	if err := appCallTransferOxg(native, param.Owner, utils.GovernanceContractAddress, scheduleFee); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, charge schedule fee error: %v", err)
+	}
+	if err := appCallTransferOxg(native, param.Owner, contract, fee); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("schedule, escrow fee error: %v", err)
+	}
+	putEntry(native, entry)
+	native.CacheDB.Put(genNextIdKey(contract), utils.GenUInt64StorageItem(id).ToArray())
+
+	addNotify(native, SCHEDULE_NAME, id, param.Owner.ToBase58(), param.ExecuteHeight, param.ExecuteTime, fee, scheduleFee)
+	return common.BigIntToNeoBytes(new(big.Int).SetUint64(id)), nil
+}
+
+//Cancel remove the entry not executed yet and refund the fee to owner
+func Cancel(native *native.NativeService) ([]byte, error) {
+	contract := native.ContextRef.CurrentContext().ContractAddress
+	id, err := utils.DecodeVarUint(common.NewZeroCopySource(native.Input))
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("cancel, deserialize id error: %v", err)
+	}
+	entry, err := GetEntry(native, id)
+	if err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("cancel, get entry error: %v", err)
+	}
+	if entry == nil {
+		return utils.BYTE_FALSE, fmt.Errorf("cancel, entry %d not found", id)
+	}
+	if err := utils.ValidateOwner(native, entry.Owner); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("cancel, checkWitness error: %v", err)
+	}
+	if err := removeQueue(native, entry); err != nil {
+		return utils.BYTE_FALSE, fmt.Errorf("cancel, %v", err)
+	}
+	if fee := entry.Fee(); fee > 0 {
+		if err := appCallTransferOxg(native, contract, entry.Owner, fee); err != nil {
+			return utils.BYTE_FALSE, fmt.Errorf("cancel, refund fee error: %v", err)
+		}
+	}
+	deleteEntry(native, id)
+
+	addNotify(native, CANCEL_NAME, id, entry.Owner.ToBase58())
+	return utils.BYTE_TRUE, nil
+}
+
+//GetEntryInfo return the serialized entry of id, empty if not exist
+func GetEntryInfo(native *native.NativeService) ([]byte, error) {
+	id, err := utils.DecodeVarUint(common.NewZeroCopySource(native.Input))
+	if err != nil {
+		return nil, fmt.Errorf("getEntry, deserialize id error: %v", err)
+	}
+	entry, err := GetEntry(native, id)
+	if err != nil {
+		return nil, fmt.Errorf("getEntry, get entry error: %v", err)
+	}
+	if entry == nil {
+		return []byte{}, nil
+	}
+	sink := common.NewZeroCopySink(nil)
+	entry.Serialization(sink)
+	return sink.Bytes(), nil
+}
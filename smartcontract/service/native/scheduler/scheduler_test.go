@@ -0,0 +1,161 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package scheduler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	cstates "github.com/OnyxPay/OnyxChain/core/states"
+	"github.com/OnyxPay/OnyxChain/core/store/leveldbstore"
+	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/global_params"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
+	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleParam_Serialization(t *testing.T) {
+	param := ScheduleParam{
+		Owner:         common.AddressFromVmCode([]byte{1, 2, 3}),
+		Code:          []byte{0x51},
+		ExecuteHeight: 100,
+		GasLimit:      20000,
+		GasPrice:      500,
+	}
+	sink := common.NewZeroCopySink(nil)
+	param.Serialization(sink)
+
+	param2 := ScheduleParam{}
+	assert.Nil(t, param2.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, param, param2)
+}
+
+func TestEntry_Serialization(t *testing.T) {
+	entry := Entry{
+		Id:          1,
+		Owner:       common.AddressFromVmCode([]byte{1, 2, 3}),
+		Code:        []byte{0x51},
+		ExecuteTime: 1000,
+		GasLimit:    20000,
+		GasPrice:    500,
+	}
+	sink := common.NewZeroCopySink(nil)
+	entry.Serialization(sink)
+
+	entry2 := Entry{}
+	assert.Nil(t, entry2.Deserialization(common.NewZeroCopySource(sink.Bytes())))
+	assert.Equal(t, entry, entry2)
+	assert.NotNil(t, entry2.Deserialization(common.NewZeroCopySource(sink.Bytes()[:10])))
+}
+
+func TestGetMinGasPrice(t *testing.T) {
+	store, _ := leveldbstore.NewMemLevelDBStore()
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(store))}
+	gasPrice, err := GetMinGasPrice(service)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), gasPrice)
+
+	params := global_params.Params{{Key: "gasPrice", Value: "500"}}
+	bf := new(bytes.Buffer)
+	assert.Nil(t, params.Serialize(bf))
+	key := append(utils.ParamContractAddress[:], global_params.PARAM...)
+	key = append(key, byte(global_params.CURRENT_VALUE))
+	service.CacheDB.Put(key, (&cstates.StorageItem{Value: bf.Bytes()}).ToArray())
+
+	defGasPrice := config.DefConfig.Common.GasPrice
+	defer func() { config.DefConfig.Common.GasPrice = defGasPrice }()
+	for _, nodeGasPrice := range []uint64{0, 500, 2500} {
+		config.DefConfig.Common.GasPrice = nodeGasPrice
+		gasPrice, err = GetMinGasPrice(service)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(500), gasPrice)
+	}
+}
+
+func TestPopDueEntries(t *testing.T) {
+	store, _ := leveldbstore.NewMemLevelDBStore()
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(store))}
+	entries := []*Entry{
+		{Id: 1, ExecuteHeight: 10},
+		{Id: 2, ExecuteHeight: 12},
+		{Id: 3, ExecuteTime: 1000},
+		{Id: 4, ExecuteTime: 1100},
+		{Id: 5, ExecuteHeight: 12},
+	}
+	for _, entry := range entries {
+		putEntry(service, entry)
+		assert.Nil(t, pushQueue(service, entry))
+	}
+	assert.Nil(t, removeQueue(service, entries[4]))
+	deleteEntry(service, entries[4].Id)
+
+	popIds := func(height, time uint32, limit int) []uint64 {
+		popped, err := PopDueEntries(service, height, time, limit, MAX_GAS_PER_BLOCK)
+		assert.Nil(t, err)
+		ids := make([]uint64, 0, len(popped))
+		for _, entry := range popped {
+			ids = append(ids, entry.Id)
+		}
+		return ids
+	}
+	assert.Equal(t, []uint64{}, popIds(9, 900, MAX_EXECUTE_PER_BLOCK))
+	assert.Equal(t, []uint64{1, 3}, popIds(11, 1010, MAX_EXECUTE_PER_BLOCK))
+	assert.Equal(t, []uint64{}, popIds(11, 1020, MAX_EXECUTE_PER_BLOCK))
+	assert.Equal(t, []uint64{2}, popIds(13, 1200, 1))
+	assert.Equal(t, []uint64{4}, popIds(14, 1210, MAX_EXECUTE_PER_BLOCK))
+
+	for _, entry := range entries {
+		stored, err := GetEntry(service, entry.Id)
+		assert.Nil(t, err)
+		assert.Nil(t, stored)
+	}
+}
+
+func TestPopDueEntriesGasLimit(t *testing.T) {
+	store, _ := leveldbstore.NewMemLevelDBStore()
+	service := &native.NativeService{CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(store))}
+	entries := []*Entry{
+		{Id: 1, ExecuteHeight: 10, GasLimit: MAX_ENTRY_GAS_LIMIT},
+		{Id: 2, ExecuteHeight: 10, GasLimit: MAX_ENTRY_GAS_LIMIT},
+		{Id: 3, ExecuteHeight: 11, GasLimit: 20000},
+	}
+	for _, entry := range entries {
+		putEntry(service, entry)
+		assert.Nil(t, pushQueue(service, entry))
+	}
+
+	popped, err := PopDueEntries(service, 11, 1000, MAX_EXECUTE_PER_BLOCK, MAX_ENTRY_GAS_LIMIT+20000)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(popped))
+	assert.Equal(t, uint64(1), popped[0].Id)
+	assert.Equal(t, uint64(3), popped[1].Id)
+
+	popped, err = PopDueEntries(service, 12, 1000, MAX_EXECUTE_PER_BLOCK, MAX_GAS_PER_BLOCK)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(popped))
+	assert.Equal(t, uint64(2), popped[0].Id)
+
+	popped, err = PopDueEntries(service, 13, 1000, MAX_EXECUTE_PER_BLOCK, MAX_GAS_PER_BLOCK)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(popped))
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package scheduler
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
+)
+
+//ScheduleParam is the param of schedule method
+type ScheduleParam struct {
+	Owner         common.Address
+	Code          []byte //neovm invoke code executed on behalf of owner
+	ExecuteHeight uint32 //execute at the block of this height, exclusive with ExecuteTime
+	ExecuteTime   uint32 //execute at the first block whose timestamp not less than this time
+	GasLimit      uint64
+	GasPrice      uint64
+}
+
+func (this *ScheduleParam) Serialization(sink *common.ZeroCopySink) {
+	utils.EncodeAddress(sink, this.Owner)
+	sink.WriteVarBytes(this.Code)
+	utils.EncodeVarUint(sink, uint64(this.ExecuteHeight))
+	utils.EncodeVarUint(sink, uint64(this.ExecuteTime))
+	utils.EncodeVarUint(sink, this.GasLimit)
+	utils.EncodeVarUint(sink, this.GasPrice)
+}
+
+func (this *ScheduleParam) Deserialization(source *common.ZeroCopySource) error {
+	var err error
+	this.Owner, err = utils.DecodeAddress(source)
+	if err != nil {
+		return fmt.Errorf("deserialize owner error:%v", err)
+	}
+	code, _, irregular, eof := source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	this.Code = code
+	height, err := utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize execute height error:%v", err)
+	}
+	if height > math.MaxUint32 {
+		return fmt.Errorf("execute height larger than max of uint32")
+	}
+	this.ExecuteHeight = uint32(height)
+	time, err := utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize execute time error:%v", err)
+	}
+	if time > math.MaxUint32 {
+		return fmt.Errorf("execute time larger than max of uint32")
+	}
+	this.ExecuteTime = uint32(time)
+	this.GasLimit, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize gas limit error:%v", err)
+	}
+	this.GasPrice, err = utils.DecodeVarUint(source)
+	if err != nil {
+		return fmt.Errorf("deserialize gas price error:%v", err)
+	}
+	return nil
+}
+
+//Entry is a scheduled invocation waiting for execution
+type Entry struct {
+	Id            uint64
+	Owner         common.Address
+	Code          []byte
+	ExecuteHeight uint32
+	ExecuteTime   uint32
+	GasLimit      uint64
+	GasPrice      uint64
+}
+
+//Fee return the escrowed oxg of entry
+func (this *Entry) Fee() uint64 {
+	return this.GasLimit * this.GasPrice
+}
+
+//IsDue return whether the entry should be executed in block of height and time
+func (this *Entry) IsDue(height, time uint32) bool {
+	if this.ExecuteHeight != 0 {
+		return this.ExecuteHeight <= height
+	}
+	return this.ExecuteTime <= time
+}
+
+//Transaction return the transaction executing the entry, it is paid by owner and has no signature
+func (this *Entry) Transaction() (*types.Transaction, error) {
+	mutable := &types.MutableTransaction{
+		TxType:   types.Invoke,
+		Nonce:    uint32(this.Id),
+		GasPrice: this.GasPrice,
+		GasLimit: this.GasLimit,
+		Payer:    this.Owner,
+		Payload:  &payload.InvokeCode{Code: this.Code},
+		Sigs:     make([]types.Sig, 0),
+	}
+	return mutable.IntoImmutable()
+}
+
+func (this *Entry) Serialization(sink *common.ZeroCopySink) {
+	sink.WriteUint64(this.Id)
+	sink.WriteAddress(this.Owner)
+	sink.WriteVarBytes(this.Code)
+	sink.WriteUint32(this.ExecuteHeight)
+	sink.WriteUint32(this.ExecuteTime)
+	sink.WriteUint64(this.GasLimit)
+	sink.WriteUint64(this.GasPrice)
+}
+
+func (this *Entry) Deserialization(source *common.ZeroCopySource) error {
+	var eof, irregular bool
+	this.Id, eof = source.NextUint64()
+	this.Owner, eof = source.NextAddress()
+	this.Code, _, irregular, eof = source.NextVarBytes()
+	if irregular {
+		return common.ErrIrregularData
+	}
+	this.ExecuteHeight, eof = source.NextUint32()
+	this.ExecuteTime, eof = source.NextUint32()
+	this.GasLimit, eof = source.NextUint64()
+	this.GasPrice, eof = source.NextUint64()
+	if eof {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package scheduler
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/config"
+	"github.com/OnyxPay/OnyxChain/smartcontract/event"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/global_params"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native/utils"
+)
+
+const (
+	//key prefix
+	NEXT_ID       = "nextId"
+	ENTRY         = "entry"
+	HEIGHT_QUEUE  = "heightQueue"
+	TIME_QUEUE    = "timeQueue"
+	QUEUE_CURSOR  = "cursor"
+	TIME_BUCKET   = 60  //seconds of a time queue bucket
+	MAX_BUCKET    = 256 //max entries of a queue bucket
+	MAX_CODE_SIZE = 64 * 1024
+)
+
+func genNextIdKey(contract common.Address) []byte {
+	return append(contract[:], NEXT_ID...)
+}
+
+func genEntryKey(contract common.Address, id uint64) []byte {
+	sink := common.NewZeroCopySink(append(contract[:], ENTRY...))
+	sink.WriteUint64(id)
+	return sink.Bytes()
+}
+
+func genBucketKey(contract common.Address, queue string, bucket uint32) []byte {
+	sink := common.NewZeroCopySink(append(contract[:], queue...))
+	sink.WriteUint32(bucket)
+	return sink.Bytes()
+}
+
+func genCursorKey(contract common.Address, queue string) []byte {
+	temp := append(contract[:], queue...)
+	return append(temp, QUEUE_CURSOR...)
+}
+
+//EntryHash return the hash under which the execution notify of entry is saved
+func EntryHash(id uint64) common.Uint256 {
+	return common.Uint256(sha256.Sum256(genEntryKey(utils.SchedulerContractAddress, id)))
+}
+
+//GetEntry return the scheduled entry of id, nil if not exist
+func GetEntry(native *native.NativeService, id uint64) (*Entry, error) {
+	item, err := utils.GetStorageItem(native, genEntryKey(utils.SchedulerContractAddress, id))
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, nil
+	}
+	entry := new(Entry)
+	if err := entry.Deserialization(common.NewZeroCopySource(item.Value)); err != nil {
+		return nil, fmt.Errorf("deserialize entry %d error:%v", id, err)
+	}
+	return entry, nil
+}
+
+func putEntry(native *native.NativeService, entry *Entry) {
+	sink := common.NewZeroCopySink(nil)
+	entry.Serialization(sink)
+	utils.PutBytes(native, genEntryKey(utils.SchedulerContractAddress, entry.Id), sink.Bytes())
+}
+
+func deleteEntry(native *native.NativeService, id uint64) {
+	native.CacheDB.Delete(genEntryKey(utils.SchedulerContractAddress, id))
+}
+
+//entryQueue return the queue and bucket of entry
+func entryQueue(entry *Entry) (string, uint32) {
+	if entry.ExecuteHeight != 0 {
+		return HEIGHT_QUEUE, entry.ExecuteHeight
+	}
+	return TIME_QUEUE, entry.ExecuteTime / TIME_BUCKET
+}
+
+func getBucket(native *native.NativeService, queue string, bucket uint32) ([]uint64, error) {
+	item, err := utils.GetStorageItem(native, genBucketKey(utils.SchedulerContractAddress, queue, bucket))
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, nil
+	}
+	source := common.NewZeroCopySource(item.Value)
+	n, _, irregular, eof := source.NextVarUint()
+	if irregular || eof {
+		return nil, fmt.Errorf("deserialize %s bucket %d error", queue, bucket)
+	}
+	ids := make([]uint64, 0, n)
+	for i := uint64(0); i < n; i++ {
+		id, eof := source.NextUint64()
+		if eof {
+			return nil, fmt.Errorf("deserialize %s bucket %d error", queue, bucket)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func putBucket(native *native.NativeService, queue string, bucket uint32, ids []uint64) {
+	key := genBucketKey(utils.SchedulerContractAddress, queue, bucket)
+	if len(ids) == 0 {
+		native.CacheDB.Delete(key)
+		return
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteVarUint(uint64(len(ids)))
+	for _, id := range ids {
+		sink.WriteUint64(id)
+	}
+	utils.PutBytes(native, key, sink.Bytes())
+}
+
+func pushQueue(native *native.NativeService, entry *Entry) error {
+	queue, bucket := entryQueue(entry)
+	ids, err := getBucket(native, queue, bucket)
+	if err != nil {
+		return err
+	}
+	if len(ids) >= MAX_BUCKET {
+		return fmt.Errorf("too many entries scheduled at %s bucket %d", queue, bucket)
+	}
+	putBucket(native, queue, bucket, append(ids, entry.Id))
+
+	cursorKey := genCursorKey(utils.SchedulerContractAddress, queue)
+	cursor, err := utils.GetStorageUInt32(native, cursorKey)
+	if err != nil {
+		return err
+	}
+	if cursor == 0 || bucket < cursor {
+		native.CacheDB.Put(cursorKey, utils.GenUInt32StorageItem(bucket).ToArray())
+	}
+	return nil
+}
+
+func removeQueue(native *native.NativeService, entry *Entry) error {
+	queue, bucket := entryQueue(entry)
+	ids, err := getBucket(native, queue, bucket)
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if id == entry.Id {
+			putBucket(native, queue, bucket, append(ids[:i], ids[i+1:]...))
+			break
+		}
+	}
+	return nil
+}
+
+//GetMinGasPrice return the min gas price of entry in global params of chain, 0 if not set
+func GetMinGasPrice(native *native.NativeService) (uint64, error) {
+	value, err := global_params.GetParamValue(native, "gasPrice")
+	if err != nil {
+		return 0, fmt.Errorf("get gas price param error: %v", err)
+	}
+	if value == "" {
+		return 0, nil
+	}
+	gasPrice, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse gas price param %s error: %v", value, err)
+	}
+	return gasPrice, nil
+}
+
+//popQueue remove the due entries from buckets between cursor and current, and append them to entries until limit
+//reached, an entry whose gas limit exceeds the remaining gas stays in queue. The cursor is moved to the lowest bucket
+//with entries left, so they are popped in next block
+func popQueue(native *native.NativeService, queue string, current, height, time uint32, entries []*Entry,
+	limit int, gas *uint64) ([]*Entry, error) {
+	cursorKey := genCursorKey(utils.SchedulerContractAddress, queue)
+	cursor, err := utils.GetStorageUInt32(native, cursorKey)
+	if err != nil {
+		return entries, err
+	}
+	if cursor == 0 || cursor > current {
+		return entries, nil
+	}
+	bucket, next := cursor, uint32(0)
+	for ; ; bucket++ {
+		ids, err := getBucket(native, queue, bucket)
+		if err != nil {
+			return entries, err
+		}
+		remain := make([]uint64, 0, len(ids))
+		for _, id := range ids {
+			entry, err := GetEntry(native, id)
+			if err != nil {
+				return entries, err
+			}
+			if entry == nil {
+				continue
+			}
+			if len(entries) < limit && entry.GasLimit <= *gas && entry.IsDue(height, time) {
+				deleteEntry(native, id)
+				*gas -= entry.GasLimit
+				entries = append(entries, entry)
+			} else {
+				remain = append(remain, id)
+			}
+		}
+		if len(remain) != len(ids) {
+			putBucket(native, queue, bucket, remain)
+		}
+		if len(remain) != 0 && next == 0 {
+			next = bucket
+		}
+		if len(entries) >= limit || bucket == current {
+			break
+		}
+	}
+	if next == 0 {
+		next = bucket
+	}
+	native.CacheDB.Put(cursorKey, utils.GenUInt32StorageItem(next).ToArray())
+	return entries, nil
+}
+
+//PopDueEntries remove at most limit entries due in block of height and time, whose sum of gas limit not exceeds gas,
+//from storage and return them. The fee of popped entries stays escrowed until settled, so they can not be cancelled
+//during execution
+func PopDueEntries(native *native.NativeService, height, time uint32, limit int, gas uint64) ([]*Entry, error) {
+	entries, err := popQueue(native, HEIGHT_QUEUE, height, height, time, nil, limit, &gas)
+	if err != nil {
+		return nil, err
+	}
+	return popQueue(native, TIME_QUEUE, time/TIME_BUCKET, height, time, entries, limit, &gas)
+}
+
+//Settle pay the gas cost of popped entry to governance contract and refund the rest of fee to owner.
+//The witness of scheduler contract must be granted to native service
+func Settle(native *native.NativeService, entry *Entry, cost uint64, success bool) error {
+	contract := utils.SchedulerContractAddress
+	fee := entry.Fee()
+	if cost > fee {
+		cost = fee
+	}
+	if cost > 0 {
+		if err := appCallTransferOxg(native, contract, utils.GovernanceContractAddress, cost); err != nil {
+			return err
+		}
+	}
+	if fee > cost {
+		if err := appCallTransferOxg(native, contract, entry.Owner, fee-cost); err != nil {
+			return err
+		}
+	}
+	addNotify(native, EXECUTE_NAME, entry.Id, entry.Owner.ToBase58(), success, cost)
+	return nil
+}
+
+func appCallTransferOxg(native *native.NativeService, from, to common.Address, amount uint64) error {
+	transfers := onx.Transfers{States: []onx.State{{From: from, To: to, Value: amount}}}
+	sink := common.NewZeroCopySink(nil)
+	transfers.Serialization(sink)
+	if _, err := native.NativeCall(utils.OxgContractAddress, onx.TRANSFER_NAME, sink.Bytes()); err != nil {
+		return fmt.Errorf("appCallTransferOxg, appCall error: %v", err)
+	}
+	return nil
+}
+
+func addNotify(native *native.NativeService, states ...interface{}) {
+	if !config.DefConfig.Common.EnableEventLog {
+		return
+	}
+	native.Notifications = append(native.Notifications,
+		&event.NotifyEventInfo{
+			ContractAddress: utils.SchedulerContractAddress,
+			States:          states,
+		})
+}
@@ -35,6 +35,10 @@ var (
 	STORAGE_GET_GAS               uint64 = 200
 	STORAGE_PUT_GAS               uint64 = 4000
 	STORAGE_DELETE_GAS            uint64 = 100
+	STORAGE_FIND_GAS              uint64 = 200
+	ITERATOR_NEXT_GAS             uint64 = 200
+	ITERATOR_KEY_GAS              uint64 = 10
+	ITERATOR_VALUE_GAS            uint64 = 10
 	RUNTIME_CHECKWITNESS_GAS      uint64 = 200
 	RUNTIME_ADDRESSTOBASE58_GAS   uint64 = 40
 	RUNTIME_BASE58TOADDRESS_GAS   uint64 = 30
@@ -89,9 +93,14 @@ var (
 	STORAGE_DELETE_NAME             = "System.Storage.Delete"
 	STORAGE_GETCONTEXT_NAME         = "System.Storage.GetContext"
 	STORAGE_GETREADONLYCONTEXT_NAME = "System.Storage.GetReadOnlyContext"
+	STORAGE_FIND_NAME               = "System.Storage.Find"
 
 	STORAGECONTEXT_ASREADONLY_NAME = "System.StorageContext.AsReadOnly"
 
+	ITERATOR_NEXT_NAME  = "System.Iterator.Next"
+	ITERATOR_KEY_NAME   = "System.Iterator.Key"
+	ITERATOR_VALUE_NAME = "System.Iterator.Value"
+
 	RUNTIME_GETTIME_NAME             = "System.Runtime.GetTime"
 	RUNTIME_CHECKWITNESS_NAME        = "System.Runtime.CheckWitness"
 	RUNTIME_NOTIFY_NAME              = "System.Runtime.Notify"
@@ -131,6 +140,10 @@ var (
 		STORAGE_GET_NAME,
 		STORAGE_PUT_NAME,
 		STORAGE_DELETE_NAME,
+		STORAGE_FIND_NAME,
+		ITERATOR_NEXT_NAME,
+		ITERATOR_KEY_NAME,
+		ITERATOR_VALUE_NAME,
 		RUNTIME_CHECKWITNESS_NAME,
 		NATIVE_INVOKE_NAME,
 		APPCALL_NAME,
@@ -177,6 +190,10 @@ func initGAS_TABLE() *sync.Map {
 	m.Store(STORAGE_GET_NAME, STORAGE_GET_GAS)
 	m.Store(STORAGE_PUT_NAME, STORAGE_PUT_GAS)
 	m.Store(STORAGE_DELETE_NAME, STORAGE_DELETE_GAS)
+	m.Store(STORAGE_FIND_NAME, STORAGE_FIND_GAS)
+	m.Store(ITERATOR_NEXT_NAME, ITERATOR_NEXT_GAS)
+	m.Store(ITERATOR_KEY_NAME, ITERATOR_KEY_GAS)
+	m.Store(ITERATOR_VALUE_NAME, ITERATOR_VALUE_GAS)
 	m.Store(RUNTIME_CHECKWITNESS_NAME, RUNTIME_CHECKWITNESS_GAS)
 	m.Store(NATIVE_INVOKE_NAME, NATIVE_INVOKE_GAS)
 	m.Store(APPCALL_NAME, APPCALL_GAS)
@@ -25,6 +25,7 @@ import (
 	"github.com/OnyxPay/OnyxChain-crypto/keypair"
 	scommon "github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/core/payload"
 	"github.com/OnyxPay/OnyxChain/core/signature"
 	"github.com/OnyxPay/OnyxChain/core/store"
 	"github.com/OnyxPay/OnyxChain/core/types"
@@ -32,6 +33,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/smartcontract/context"
 	"github.com/OnyxPay/OnyxChain/smartcontract/event"
 	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	"github.com/OnyxPay/OnyxChain/smartcontract/trace"
 	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
 	ntypes "github.com/OnyxPay/OnyxChain/vm/neovm/types"
 )
@@ -79,7 +81,11 @@ var (
 		STORAGE_DELETE_NAME:                  {Execute: StorageDelete},
 		STORAGE_GETCONTEXT_NAME:              {Execute: StorageGetContext},
 		STORAGE_GETREADONLYCONTEXT_NAME:      {Execute: StorageGetReadOnlyContext},
+		STORAGE_FIND_NAME:                    {Execute: StorageFind, Validator: validatorStorageFind},
 		STORAGECONTEXT_ASREADONLY_NAME:       {Execute: StorageContextAsReadOnly, Validator: validatorContextAsReadOnly},
+		ITERATOR_NEXT_NAME:                   {Execute: IteratorNext, Validator: validatorIterator},
+		ITERATOR_KEY_NAME:                    {Execute: IteratorKey, Validator: validatorIterator},
+		ITERATOR_VALUE_NAME:                  {Execute: IteratorValue, Validator: validatorIterator},
 		GETSCRIPTCONTAINER_NAME:              {Execute: GetCodeContainer},
 		GETEXECUTINGSCRIPTHASH_NAME:          {Execute: GetExecutingAddress},
 		GETCALLINGSCRIPTHASH_NAME:            {Execute: GetCallingAddress},
@@ -99,6 +105,7 @@ var (
 	CONTRACT_NOT_EXIST    = errors.NewErr("[NeoVmService] Get contract code from db fail")
 	DEPLOYCODE_TYPE_ERROR = errors.NewErr("[NeoVmService] DeployCode type error!")
 	VM_EXEC_FAULT         = errors.NewErr("[NeoVmService] vm execute state fault!")
+	VM_TYPE_ERROR         = errors.NewErr("[NeoVmService] appcall target is not a neovm contract!")
 )
 
 var (
@@ -115,6 +122,12 @@ type Service struct {
 	Validator Validator
 }
 
+// DebugHook is called before each instruction is executed, the execution is paused until OnStep returns
+// and aborted if OnStep returns error
+type DebugHook interface {
+	OnStep(service *NeoVmService) error
+}
+
 // NeoVmService is a struct for smart contract provide interop service
 type NeoVmService struct {
 	Store         store.LedgerStore
@@ -128,10 +141,35 @@ type NeoVmService struct {
 	BlockHash     scommon.Uint256
 	Engine        *vm.ExecutionEngine
 	PreExec       bool
+	Tracer        *trace.Tracer // record the execution if not nil
+	Debugger      DebugHook     // debug the execution if not nil
+	offset        int           // offset of current instruction, only kept when tracing
+	iterators     []*StorageIterator
 }
 
 // Invoke a smart contract
 func (this *NeoVmService) Invoke() (interface{}, error) {
+	defer this.releaseIterators()
+	result, err := this.invoke()
+	if err != nil && this.Tracer != nil {
+		opCode := vm.OpExecList[this.Engine.OpCode].Name
+		if this.Engine.OpCode >= vm.PUSHBYTES1 && this.Engine.OpCode <= vm.PUSHBYTES75 {
+			opCode = "PUSHBYTES"
+		}
+		this.Tracer.SetFault(scommon.AddressFromVmCode(this.Code), this.offset, opCode, err)
+	}
+	return result, err
+}
+
+// releaseIterators release the storage iterators created by the invocation, which may not be exhausted
+func (this *NeoVmService) releaseIterators() {
+	for _, iter := range this.iterators {
+		iter.Release()
+	}
+	this.iterators = nil
+}
+
+func (this *NeoVmService) invoke() (interface{}, error) {
 	if len(this.Code) == 0 {
 		return nil, ERR_EXECUTE_CODE
 	}
@@ -148,6 +186,14 @@ func (this *NeoVmService) Invoke() (interface{}, error) {
 		if this.Engine.Context.GetInstructionPointer() >= len(this.Engine.Context.Code) {
 			break
 		}
+		if this.Tracer != nil {
+			this.offset = this.Engine.Context.GetInstructionPointer()
+		}
+		if this.Debugger != nil {
+			if err := this.Debugger.OnStep(this); err != nil {
+				return nil, err
+			}
+		}
 		if err := this.Engine.ExecuteCode(); err != nil {
 			return nil, err
 		}
@@ -160,6 +206,9 @@ func (this *NeoVmService) Invoke() (interface{}, error) {
 			if !this.ContextRef.CheckUseGas(OPCODE_GAS) {
 				return nil, ERR_GAS_INSUFFICIENT
 			}
+			if this.Tracer != nil {
+				this.Tracer.UseGas("PUSHBYTES", OPCODE_GAS)
+			}
 		} else {
 			if err := this.Engine.ValidateOp(); err != nil {
 				return nil, err
@@ -171,6 +220,9 @@ func (this *NeoVmService) Invoke() (interface{}, error) {
 			if !this.ContextRef.CheckUseGas(price) {
 				return nil, ERR_GAS_INSUFFICIENT
 			}
+			if this.Tracer != nil {
+				this.Tracer.UseGas(this.Engine.OpExec.Name, price)
+			}
 		}
 		switch this.Engine.OpCode {
 		case vm.VERIFY:
@@ -281,6 +333,9 @@ func (this *NeoVmService) SystemCall(engine *vm.ExecutionEngine) error {
 	if !this.ContextRef.CheckUseGas(price) {
 		return ERR_GAS_INSUFFICIENT
 	}
+	if this.Tracer != nil {
+		this.Tracer.UseGas(serviceName, price)
+	}
 	if err := service.Execute(this, engine); err != nil {
 		return errors.NewDetailErr(err, errors.ErrNoCode, "[SystemCall] service execute error!")
 	}
@@ -296,6 +351,9 @@ func (this *NeoVmService) getContract(address scommon.Address) ([]byte, error) {
 	if dep == nil {
 		return nil, CONTRACT_NOT_EXIST
 	}
+	if dep.VmType != payload.NEOVM_TYPE {
+		return nil, VM_TYPE_ERROR
+	}
 	return dep.Code, nil
 }
 
@@ -114,6 +114,25 @@ func StorageGet(service *NeoVmService, engine *vm.ExecutionEngine) error {
 	return nil
 }
 
+// StorageFind push the iterator of smart contract storage items with key prefix to vm stack
+func StorageFind(service *NeoVmService, engine *vm.ExecutionEngine) error {
+	context, err := getContext(engine)
+	if err != nil {
+		return errors.NewDetailErr(err, errors.ErrNoCode, "[StorageFind] get pop context error!")
+	}
+	prefix, err := vm.PopByteArray(engine)
+	if err != nil {
+		return err
+	}
+	if len(prefix) > 1024 {
+		return errors.NewErr("[StorageFind] Storage key prefix to long")
+	}
+	iter := NewStorageIterator(service.CacheDB.NewIterator(genStorageKey(context.Address, prefix)))
+	service.iterators = append(service.iterators, iter)
+	vm.PushData(engine, iter)
+	return nil
+}
+
 // StorageGetContext push smart contract storage context to vm stack
 func StorageGetContext(service *NeoVmService, engine *vm.ExecutionEngine) error {
 	vm.PushData(engine, NewStorageContext(service.ContextRef.CurrentContext().ContractAddress))
@@ -0,0 +1,167 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package neovm
+
+import (
+	"fmt"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/states"
+	scom "github.com/OnyxPay/OnyxChain/core/store/common"
+	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
+)
+
+// StorageIterator iterate smart contract storage items with key prefix.
+// The underlying store iterator is released when the iteration is exhausted, or when the invocation
+// creating it returns
+type StorageIterator struct {
+	iter     scom.StoreIterator
+	started  bool
+	done     bool
+	released bool
+	key      []byte
+	value    []byte
+}
+
+// NewStorageIterator return a new smart contract storage iterator
+func NewStorageIterator(iter scom.StoreIterator) *StorageIterator {
+	return &StorageIterator{iter: iter}
+}
+
+// ToArray return empty byte array, storage iterator has no byte array form
+func (this *StorageIterator) ToArray() []byte {
+	return []byte{}
+}
+
+// Next move to next storage item, return false when there are no more items
+func (this *StorageIterator) Next() (bool, error) {
+	if this.released {
+		return false, fmt.Errorf("%s", "storage iterator is released")
+	}
+	if this.done {
+		return false, nil
+	}
+	var has bool
+	if this.started {
+		has = this.iter.Next()
+	} else {
+		has = this.iter.First()
+		this.started = true
+	}
+	if !has {
+		err := this.iter.Error()
+		this.release()
+		return false, err
+	}
+	key := this.iter.Key()
+	if len(key) < common.ADDR_LEN {
+		return false, fmt.Errorf("invalid storage key %x", key)
+	}
+	value, err := states.GetValueFromRawStorageItem(this.iter.Value())
+	if err != nil {
+		return false, err
+	}
+	this.key = append([]byte{}, key[common.ADDR_LEN:]...)
+	this.value = value
+	return true, nil
+}
+
+// Release release the underlying store iterator, the storage iterator can not be used after released
+func (this *StorageIterator) Release() {
+	this.release()
+	this.released = true
+}
+
+func (this *StorageIterator) release() {
+	if !this.done {
+		this.done = true
+		this.key, this.value = nil, nil
+		this.iter.Release()
+	}
+}
+
+// Key return the storage key of current item, without contract address
+func (this *StorageIterator) Key() ([]byte, error) {
+	if !this.started || this.done {
+		return nil, fmt.Errorf("%s", "storage iterator has no current item")
+	}
+	return this.key, nil
+}
+
+// Value return the storage value of current item
+func (this *StorageIterator) Value() ([]byte, error) {
+	if !this.started || this.done {
+		return nil, fmt.Errorf("%s", "storage iterator has no current item")
+	}
+	return this.value, nil
+}
+
+// IteratorNext move the storage iterator to next item, push whether the item exists to vm stack
+func IteratorNext(service *NeoVmService, engine *vm.ExecutionEngine) error {
+	iter, err := popStorageIterator(engine)
+	if err != nil {
+		return err
+	}
+	has, err := iter.Next()
+	if err != nil {
+		return err
+	}
+	vm.PushData(engine, has)
+	return nil
+}
+
+// IteratorKey push the storage key of current item to vm stack
+func IteratorKey(service *NeoVmService, engine *vm.ExecutionEngine) error {
+	iter, err := popStorageIterator(engine)
+	if err != nil {
+		return err
+	}
+	key, err := iter.Key()
+	if err != nil {
+		return err
+	}
+	vm.PushData(engine, key)
+	return nil
+}
+
+// IteratorValue push the storage value of current item to vm stack
+func IteratorValue(service *NeoVmService, engine *vm.ExecutionEngine) error {
+	iter, err := popStorageIterator(engine)
+	if err != nil {
+		return err
+	}
+	value, err := iter.Value()
+	if err != nil {
+		return err
+	}
+	vm.PushData(engine, value)
+	return nil
+}
+
+func popStorageIterator(engine *vm.ExecutionEngine) (*StorageIterator, error) {
+	data, err := vm.PopInteropInterface(engine)
+	if err != nil {
+		return nil, err
+	}
+	iter, ok := data.(*StorageIterator)
+	if !ok {
+		return nil, fmt.Errorf("%s", "pop storage iterator type invalid")
+	}
+	return iter, nil
+}
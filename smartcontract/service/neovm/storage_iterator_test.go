@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package neovm
+
+import (
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/states"
+	"github.com/OnyxPay/OnyxChain/core/store/leveldbstore"
+	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
+	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageFind(t *testing.T) {
+	store, _ := leveldbstore.NewMemLevelDBStore()
+	cache := storage.NewCacheDB(overlaydb.NewOverlayDB(store))
+	addr1 := common.Address{1}
+	addr2 := common.Address{2}
+	cache.Put(genStorageKey(addr1, []byte("a1")), states.GenRawStorageItem([]byte{1}))
+	cache.Put(genStorageKey(addr1, []byte("a2")), states.GenRawStorageItem([]byte{2}))
+	cache.Put(genStorageKey(addr1, []byte("a3")), states.GenRawStorageItem([]byte{3}))
+	cache.Put(genStorageKey(addr1, []byte("b1")), states.GenRawStorageItem([]byte{4}))
+	cache.Put(genStorageKey(addr2, []byte("a4")), states.GenRawStorageItem([]byte{5}))
+	cache.Delete(genStorageKey(addr1, []byte("a2")))
+
+	service := &NeoVmService{CacheDB: cache}
+	engine := vm.NewExecutionEngine()
+	vm.PushData(engine, []byte("a"))
+	vm.PushData(engine, NewStorageContext(addr1))
+	assert.Nil(t, StorageFind(service, engine))
+	iter, err := vm.PopInteropInterface(engine)
+	assert.Nil(t, err)
+
+	vm.PushData(engine, iter)
+	assert.NotNil(t, IteratorKey(service, engine))
+
+	var keys, values [][]byte
+	for {
+		vm.PushData(engine, iter)
+		assert.Nil(t, IteratorNext(service, engine))
+		has, err := vm.PopBoolean(engine)
+		assert.Nil(t, err)
+		if !has {
+			break
+		}
+		vm.PushData(engine, iter)
+		assert.Nil(t, IteratorKey(service, engine))
+		key, err := vm.PopByteArray(engine)
+		assert.Nil(t, err)
+		vm.PushData(engine, iter)
+		assert.Nil(t, IteratorValue(service, engine))
+		value, err := vm.PopByteArray(engine)
+		assert.Nil(t, err)
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	assert.Equal(t, [][]byte{[]byte("a1"), []byte("a3")}, keys)
+	assert.Equal(t, [][]byte{{1}, {3}}, values)
+
+	vm.PushData(engine, iter)
+	assert.Nil(t, IteratorNext(service, engine))
+	has, err := vm.PopBoolean(engine)
+	assert.Nil(t, err)
+	assert.False(t, has)
+}
+
+func TestStorageIteratorRelease(t *testing.T) {
+	store, _ := leveldbstore.NewMemLevelDBStore()
+	cache := storage.NewCacheDB(overlaydb.NewOverlayDB(store))
+	addr := common.Address{1}
+	cache.Put(genStorageKey(addr, []byte("a1")), states.GenRawStorageItem([]byte{1}))
+	cache.Put(genStorageKey(addr, []byte("a2")), states.GenRawStorageItem([]byte{2}))
+
+	service := &NeoVmService{CacheDB: cache}
+	engine := vm.NewExecutionEngine()
+	vm.PushData(engine, []byte("a"))
+	vm.PushData(engine, NewStorageContext(addr))
+	assert.Nil(t, StorageFind(service, engine))
+	iter, err := vm.PopInteropInterface(engine)
+	assert.Nil(t, err)
+	vm.PushData(engine, iter)
+	assert.Nil(t, IteratorNext(service, engine))
+	has, err := vm.PopBoolean(engine)
+	assert.Nil(t, err)
+	assert.True(t, has)
+	assert.Equal(t, 1, len(service.iterators))
+
+	// stop reading early, the iterator is released when invocation returns
+	service.releaseIterators()
+	assert.Equal(t, 0, len(service.iterators))
+	vm.PushData(engine, iter)
+	assert.NotNil(t, IteratorNext(service, engine))
+	vm.PushData(engine, iter)
+	assert.NotNil(t, IteratorKey(service, engine))
+}
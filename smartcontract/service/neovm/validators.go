@@ -195,6 +195,20 @@ func validatorContextAsReadOnly(engine *vm.ExecutionEngine) error {
 	return nil
 }
 
+func validatorStorageFind(engine *vm.ExecutionEngine) error {
+	if vm.EvaluationStackCount(engine) < 2 {
+		return errors.NewErr("[validatorStorageFind] Too few input parameters ")
+	}
+	return nil
+}
+
+func validatorIterator(engine *vm.ExecutionEngine) error {
+	if vm.EvaluationStackCount(engine) < 1 {
+		return errors.NewErr("[validatorIterator] Too few input parameters ")
+	}
+	return nil
+}
+
 func peekBlock(engine *vm.ExecutionEngine) (*types.Block, error) {
 	d, err := vm.PeekInteropInterface(engine)
 	if err != nil {
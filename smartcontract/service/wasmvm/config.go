@@ -0,0 +1,87 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wasmvm
+
+import "github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
+
+var (
+	// wasm contracts are always executed with the production calling
+	// convention: invoke(method, args) exported as "invoke"
+	CONTRACT_VERSION byte = 1
+
+	// API Name
+	CALL_CONTRACT_NAME = "ONX_CallContract"
+
+	RUNTIME_CHECKWITNESS_NAME = "ONX_Runtime_CheckWitness"
+	RUNTIME_NOTIFY_NAME       = "ONX_Runtime_Notify"
+	RUNTIME_CHECKSIG_NAME     = "ONX_Runtime_CheckSig"
+	RUNTIME_GETTIME_NAME      = "ONX_Runtime_GetTime"
+	RUNTIME_LOG_NAME          = "ONX_Runtime_Log"
+
+	ATTRIBUTE_GETUSAGE_NAME = "ONX_Attribute_GetUsage"
+	ATTRIBUTE_GETDATA_NAME  = "ONX_Attribute_GetData"
+
+	BLOCK_GETCURRENTHEADERHASH_NAME   = "ONX_Block_GetCurrentHeaderHash"
+	BLOCK_GETCURRENTHEADERHEIGHT_NAME = "ONX_Block_GetCurrentHeaderHeight"
+	BLOCK_GETCURRENTBLOCKHASH_NAME    = "ONX_Block_GetCurrentBlockHash"
+	BLOCK_GETCURRENTBLOCKHEIGHT_NAME  = "ONX_Block_GetCurrentBlockHeight"
+	BLOCK_GETTRANSACTIONBYHASH_NAME   = "ONX_Block_GetTransactionByHash"
+	BLOCK_GETTRANSACTIONCOUNT_NAME    = "ONX_Block_GetTransactionCount"
+	BLOCK_GETTRANSACTIONS_NAME        = "ONX_Block_GetTransactions"
+
+	BLOCKCHAIN_GETHEIGHT_NAME         = "ONX_BlockChain_GetHeight"
+	BLOCKCHAIN_GETHEADERBYHEIGHT_NAME = "ONX_BlockChain_GetHeaderByHeight"
+	BLOCKCHAIN_GETHEADERBYHASH_NAME   = "ONX_BlockChain_GetHeaderByHash"
+	BLOCKCHAIN_GETBLOCKBYHEIGHT_NAME  = "ONX_BlockChain_GetBlockByHeight"
+	BLOCKCHAIN_GETBLOCKBYHASH_NAME    = "ONX_BlockChain_GetBlockByHash"
+	BLOCKCHAIN_GETCONTRACT_NAME       = "ONX_BlockChain_GetContract"
+
+	HEADER_GETHASH_NAME          = "ONX_Header_GetHash"
+	HEADER_GETVERSION_NAME       = "ONX_Header_GetVersion"
+	HEADER_GETPREVHASH_NAME      = "ONX_Header_GetPrevHash"
+	HEADER_GETMERKLEROOT_NAME    = "ONX_Header_GetMerkleRoot"
+	HEADER_GETINDEX_NAME         = "ONX_Header_GetIndex"
+	HEADER_GETTIMESTAMP_NAME     = "ONX_Header_GetTimestamp"
+	HEADER_GETCONSENSUSDATA_NAME = "ONX_Header_GetConsensusData"
+	HEADER_GETNEXTCONSENSUS_NAME = "ONX_Header_GetNextConsensus"
+
+	STORAGE_PUT_NAME    = "ONX_Storage_Put"
+	STORAGE_GET_NAME    = "ONX_Storage_Get"
+	STORAGE_DELETE_NAME = "ONX_Storage_Delete"
+
+	TRANSACTION_GETHASH_NAME       = "ONX_Transaction_GetHash"
+	TRANSACTION_GETTYPE_NAME       = "ONX_Transaction_GetType"
+	TRANSACTION_GETATTRIBUTES_NAME = "ONX_Transaction_GetAttributes"
+
+	// host functions are charged with the neovm GAS_TABLE price of the
+	// equivalent syscall, so governance updates apply to both vms
+	GAS_TABLE_NAMES = map[string]string{
+		CALL_CONTRACT_NAME:                neovm.APPCALL_NAME,
+		RUNTIME_CHECKWITNESS_NAME:         neovm.RUNTIME_CHECKWITNESS_NAME,
+		BLOCKCHAIN_GETHEADERBYHEIGHT_NAME: neovm.BLOCKCHAIN_GETHEADER_NAME,
+		BLOCKCHAIN_GETHEADERBYHASH_NAME:   neovm.BLOCKCHAIN_GETHEADER_NAME,
+		BLOCKCHAIN_GETBLOCKBYHEIGHT_NAME:  neovm.BLOCKCHAIN_GETBLOCK_NAME,
+		BLOCKCHAIN_GETBLOCKBYHASH_NAME:    neovm.BLOCKCHAIN_GETBLOCK_NAME,
+		BLOCKCHAIN_GETCONTRACT_NAME:       neovm.BLOCKCHAIN_GETCONTRACT_NAME,
+		BLOCK_GETTRANSACTIONBYHASH_NAME:   neovm.BLOCKCHAIN_GETTRANSACTION_NAME,
+		STORAGE_GET_NAME:                  neovm.STORAGE_GET_NAME,
+		STORAGE_PUT_NAME:                  neovm.STORAGE_PUT_NAME,
+		STORAGE_DELETE_NAME:               neovm.STORAGE_DELETE_NAME,
+	}
+)
@@ -0,0 +1,64 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package wasmvm
+
+import (
+	"fmt"
+
+	"github.com/OnyxPay/OnyxChain/errors"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
+	"github.com/OnyxPay/OnyxChain/vm/wasmvm/exec"
+)
+
+func StoreGasCost(engine *exec.ExecutionEngine) (uint64, error) {
+	vm := engine.GetVM()
+	params := vm.GetEnvCall().GetParams()
+	if len(params) != 2 {
+		return 0, errors.NewErr("[StoreGasCost] parameter count error")
+	}
+	key, err := vm.GetPointerMemory(params[0])
+	if err != nil {
+		return 0, err
+	}
+	value, err := vm.GetPointerMemory(params[1])
+	if err != nil {
+		return 0, err
+	}
+	if putCost, ok := neovm.GAS_TABLE.Load(neovm.STORAGE_PUT_NAME); ok {
+		return uint64(((len(key)+len(value)-1)/1024 + 1)) * putCost.(uint64), nil
+	} else {
+		return uint64(0), errors.NewErr("[StoreGasCost] get STORAGE_PUT_NAME gas failed")
+	}
+}
+
+func GasPrice(engine *exec.ExecutionEngine, name string) (uint64, error) {
+	switch name {
+	case STORAGE_PUT_NAME:
+		return StoreGasCost(engine)
+	default:
+		tableName, ok := GAS_TABLE_NAMES[name]
+		if !ok {
+			return neovm.OPCODE_GAS, nil
+		}
+		if value, ok := neovm.GAS_TABLE.Load(tableName); ok {
+			return value.(uint64), nil
+		}
+		return 0, errors.NewErr(fmt.Sprintf("[GasPrice] get %s gas failed", tableName))
+	}
+}
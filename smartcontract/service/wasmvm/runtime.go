@@ -18,6 +18,7 @@
 package wasmvm
 
 import (
+	"github.com/OnyxPay/OnyxChain-crypto/keypair"
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/core/signature"
 	"github.com/OnyxPay/OnyxChain/errors"
@@ -74,8 +75,8 @@ func (this *WasmVmService) runtimeCheckSig(engine *exec.ExecutionEngine) (bool,
 		return false, err
 	}
 	res := 0
-	err = signature.Verify(pubKey, data, sig)
-	if err == nil {
+	key, err := keypair.DeserializePublicKey(pubKey)
+	if err == nil && signature.Verify(key, data, sig) == nil {
 		res = 1
 	}
 
@@ -18,7 +18,6 @@
 package wasmvm
 
 import (
-	"bytes"
 	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/core/states"
 	"github.com/OnyxPay/OnyxChain/errors"
@@ -48,10 +47,7 @@ func (this *WasmVmService) putstore(engine *exec.ExecutionEngine) (bool, error)
 	if err != nil {
 		return false, err
 	}
-	k, err := serializeStorageKey(vm.ContractAddress, []byte(util.TrimBuffToString(key)))
-	if err != nil {
-		return false, err
-	}
+	k := genStorageKey(vm.ContractAddress, []byte(util.TrimBuffToString(key)))
 	this.CacheDB.Put(k, states.GenRawStorageItem(value))
 
 	vm.RestoreCtx()
@@ -72,10 +68,7 @@ func (this *WasmVmService) getstore(engine *exec.ExecutionEngine) (bool, error)
 	if err != nil {
 		return false, err
 	}
-	k, err := serializeStorageKey(vm.ContractAddress, []byte(util.TrimBuffToString(key)))
-	if err != nil {
-		return false, err
-	}
+	k := genStorageKey(vm.ContractAddress, []byte(util.TrimBuffToString(key)))
 	item, err := this.CacheDB.Get(k)
 	if err != nil {
 		return false, err
@@ -119,10 +112,7 @@ func (this *WasmVmService) deletestore(engine *exec.ExecutionEngine) (bool, erro
 		return false, err
 	}
 
-	k, err := serializeStorageKey(vm.ContractAddress, []byte(util.TrimBuffToString(key)))
-	if err != nil {
-		return false, err
-	}
+	k := genStorageKey(vm.ContractAddress, []byte(util.TrimBuffToString(key)))
 
 	this.CacheDB.Delete(k)
 	vm.RestoreCtx()
@@ -130,11 +120,10 @@ func (this *WasmVmService) deletestore(engine *exec.ExecutionEngine) (bool, erro
 	return true, nil
 }
 
-func serializeStorageKey(contractAddress common.Address, key []byte) ([]byte, error) {
-	bf := new(bytes.Buffer)
-	storageKey := &states.StorageKey{ContractAddress: contractAddress, Key: key}
-	if _, err := storageKey.Serialize(bf); err != nil {
-		return []byte{}, errors.NewErr("[serializeStorageKey] StorageKey serialize error!")
-	}
-	return bf.Bytes(), nil
+// genStorageKey use the same key layout as neovm storage, address followed by raw key
+func genStorageKey(contractAddress common.Address, key []byte) []byte {
+	res := make([]byte, 0, len(contractAddress[:])+len(key))
+	res = append(res, contractAddress[:]...)
+	res = append(res, key...)
+	return res
 }
@@ -18,29 +18,38 @@
 package wasmvm
 
 import (
-	//"bytes"
-	//"encoding/binary"
-	//"encoding/hex"
-	//"fmt"
-	//"math/big"
-	//"strconv"
-	//"strings"
+	"bytes"
+	"encoding/binary"
+	"fmt"
 
-	//"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	"github.com/OnyxPay/OnyxChain/core/payload"
 	"github.com/OnyxPay/OnyxChain/core/store"
 	"github.com/OnyxPay/OnyxChain/core/types"
-	//"github.com/OnyxPay/OnyxChain/errors"
-	//sccommon "github.com/OnyxPay/OnyxChain/smartcontract/common"
+	"github.com/OnyxPay/OnyxChain/errors"
 	"github.com/OnyxPay/OnyxChain/smartcontract/context"
 	"github.com/OnyxPay/OnyxChain/smartcontract/event"
-	//nstates "github.com/OnyxPay/OnyxChain/smartcontract/service/native/onx"
-	//"github.com/OnyxPay/OnyxChain/smartcontract/states"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/native"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
+	"github.com/OnyxPay/OnyxChain/smartcontract/states"
 	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
-	//"github.com/OnyxPay/OnyxChain/vm/neovm"
-	//"github.com/OnyxPay/OnyxChain/vm/wasmvm/exec"
-	//"github.com/OnyxPay/OnyxChain/vm/wasmvm/util"
+	nvm "github.com/OnyxPay/OnyxChain/vm/neovm"
+	ntypes "github.com/OnyxPay/OnyxChain/vm/neovm/types"
+	"github.com/OnyxPay/OnyxChain/vm/wasmvm/exec"
+	"github.com/OnyxPay/OnyxChain/vm/wasmvm/util"
 )
 
+var (
+	ERR_EXECUTE_CODE     = errors.NewErr("[WasmVmService] invoke param invalid!")
+	ERR_GAS_INSUFFICIENT = errors.NewErr("[WasmVmService] gas insufficient")
+	VM_EXEC_STEP_EXCEED  = errors.NewErr("[WasmVmService] vm execute step exceed!")
+	CONTRACT_NOT_EXIST   = errors.NewErr("[WasmVmService] Get contract code from db fail")
+	VM_TYPE_ERROR        = errors.NewErr("[WasmVmService] contract is not a wasm contract!")
+)
+
+// WasmVmService is a struct for wasm smart contract provide interop service
+// Code is a serialized states.ContractInvokeParam
 type WasmVmService struct {
 	Store         store.LedgerStore
 	CacheDB       *storage.CacheDB
@@ -49,440 +58,257 @@ type WasmVmService struct {
 	Code          []byte
 	Tx            *types.Transaction
 	Time          uint32
+	Height        uint32
+	BlockHash     common.Uint256
+	PreExec       bool
 }
 
-//
-//func (this *WasmVmService) Invoke() (interface{}, error) {
-//	stateMachine := NewWasmStateMachine()
-//	//register the "CallContract" function
-//	stateMachine.Register("ONX_CallContract", this.callContract)
-//	stateMachine.Register("ONX_MarshalNativeParams", this.marshalNativeParams)
-//	stateMachine.Register("ONX_MarshalNeoParams", this.marshalNeoParams)
-//	//runtime
-//	stateMachine.Register("ONX_Runtime_CheckWitness", this.runtimeCheckWitness)
-//	stateMachine.Register("ONX_Runtime_Notify", this.runtimeNotify)
-//	stateMachine.Register("ONX_Runtime_CheckSig", this.runtimeCheckSig)
-//	stateMachine.Register("ONX_Runtime_GetTime", this.runtimeGetTime)
-//	stateMachine.Register("ONX_Runtime_Log", this.runtimeLog)
-//	//attribute
-//	stateMachine.Register("ONX_Attribute_GetUsage", this.attributeGetUsage)
-//	stateMachine.Register("ONX_Attribute_GetData", this.attributeGetData)
-//	//block
-//	stateMachine.Register("ONX_Block_GetCurrentHeaderHash", this.blockGetCurrentHeaderHash)
-//	stateMachine.Register("ONX_Block_GetCurrentHeaderHeight", this.blockGetCurrentHeaderHeight)
-//	stateMachine.Register("ONX_Block_GetCurrentBlockHash", this.blockGetCurrentBlockHash)
-//	stateMachine.Register("ONX_Block_GetCurrentBlockHeight", this.blockGetCurrentBlockHeight)
-//	stateMachine.Register("ONX_Block_GetTransactionByHash", this.blockGetTransactionByHash)
-//	stateMachine.Register("ONX_Block_GetTransactionCount", this.blockGetTransactionCount)
-//	stateMachine.Register("ONX_Block_GetTransactions", this.blockGetTransactions)
-//
-//	//blockchain
-//	stateMachine.Register("ONX_BlockChain_GetHeight", this.blockChainGetHeight)
-//	stateMachine.Register("ONX_BlockChain_GetHeaderByHeight", this.blockChainGetHeaderByHeight)
-//	stateMachine.Register("ONX_BlockChain_GetHeaderByHash", this.blockChainGetHeaderByHash)
-//	stateMachine.Register("ONX_BlockChain_GetBlockByHeight", this.blockChainGetBlockByHeight)
-//	stateMachine.Register("ONX_BlockChain_GetBlockByHash", this.blockChainGetBlockByHash)
-//	stateMachine.Register("ONX_BlockChain_GetContract", this.blockChainGetContract)
-//
-//	//header
-//	stateMachine.Register("ONX_Header_GetHash", this.headerGetHash)
-//	stateMachine.Register("ONX_Header_GetVersion", this.headerGetVersion)
-//	stateMachine.Register("ONX_Header_GetPrevHash", this.headerGetPrevHash)
-//	stateMachine.Register("ONX_Header_GetMerkleRoot", this.headerGetMerkleRoot)
-//	stateMachine.Register("ONX_Header_GetIndex", this.headerGetIndex)
-//	stateMachine.Register("ONX_Header_GetTimestamp", this.headerGetTimestamp)
-//	stateMachine.Register("ONX_Header_GetConsensusData", this.headerGetConsensusData)
-//	stateMachine.Register("ONX_Header_GetNextConsensus", this.headerGetNextConsensus)
-//
-//	//storage
-//	stateMachine.Register("ONX_Storage_Put", this.putstore)
-//	stateMachine.Register("ONX_Storage_Get", this.getstore)
-//	stateMachine.Register("ONX_Storage_Delete", this.deletestore)
-//
-//	//transaction
-//	stateMachine.Register("ONX_Transaction_GetHash", this.transactionGetHash)
-//	stateMachine.Register("ONX_Transaction_GetType", this.transactionGetType)
-//	stateMachine.Register("ONX_Transaction_GetAttributes", this.transactionGetAttributes)
-//
-//	engine := exec.NewExecutionEngine(
-//		this.Tx,
-//		new(util.ECDsaCrypto),
-//		stateMachine,
-//	)
-//
-//	contract := &states.Contract{}
-//	contract.Deserialize(bytes.NewBuffer(this.Code))
-//	addr := contract.Address
-//	if contract.Code == nil {
-//		dpcode, err := this.GetContractCodeFromAddress(addr)
-//		if err != nil {
-//			return nil, errors.NewErr("get contract  error")
-//		}
-//		contract.Code = dpcode
-//	}
-//
-//	var caller common.Address
-//	if this.ContextRef.CallingContext() == nil {
-//		caller = common.Address{}
-//	} else {
-//		caller = this.ContextRef.CallingContext().ContractAddress
-//	}
-//	this.ContextRef.PushContext(&context.Context{ContractAddress: contract.Address})
-//	res, err := engine.Call(caller, contract.Code, contract.Method, contract.Args, contract.Version)
-//
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	//get the return message
-//	result, err := engine.GetVM().GetPointerMemory(uint64(binary.LittleEndian.Uint32(res)))
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	this.ContextRef.PopContext()
-//	this.ContextRef.PushNotifications(this.Notifications)
-//	return result, nil
-//}
-//
-//func (this *WasmVmService) marshalNeoParams(engine *exec.ExecutionEngine) (bool, error) {
-//	vm := engine.GetVM()
-//	envCall := vm.GetEnvCall()
-//	params := envCall.GetParams()
-//	if len(params) != 1 {
-//		return false, errors.NewErr("[marshalNeoParams]parameter count error while call marshalNativeParams")
-//	}
-//	argbytes, err := vm.GetPointerMemory(params[0])
-//	if err != nil {
-//		return false, err
-//	}
-//	bytesLen := len(argbytes)
-//	args := make([]interface{}, bytesLen/8)
-//	icount := 0
-//	for i := 0; i < bytesLen; i += 8 {
-//		tmpBytes := argbytes[i : i+8]
-//		ptype, err := vm.GetPointerMemory(uint64(binary.LittleEndian.Uint32(tmpBytes[:4])))
-//		if err != nil {
-//			return false, err
-//		}
-//		pvalue, err := vm.GetPointerMemory(uint64(binary.LittleEndian.Uint32(tmpBytes[4:8])))
-//		if err != nil {
-//			return false, err
-//		}
-//		switch strings.ToLower(util.TrimBuffToString(ptype)) {
-//		case "string":
-//			args[icount] = util.TrimBuffToString(pvalue)
-//		case "int":
-//			args[icount], err = strconv.Atoi(util.TrimBuffToString(pvalue))
-//			if err != nil {
-//				return false, err
-//			}
-//		case "int64":
-//			args[icount], err = strconv.ParseInt(util.TrimBuffToString(pvalue), 10, 64)
-//			if err != nil {
-//				return false, err
-//			}
-//		default:
-//			args[icount] = util.TrimBuffToString(pvalue)
-//		}
-//		icount++
-//	}
-//	builder := neovm.NewParamsBuilder(bytes.NewBuffer(nil))
-//	err = buildNeoVMParamInter(builder, []interface{}{args})
-//	if err != nil {
-//		return false, err
-//	}
-//	neoargs := builder.ToArray()
-//	idx, err := vm.SetPointerMemory(neoargs)
-//	if err != nil {
-//		return false, err
-//	}
-//	vm.RestoreCtx()
-//	vm.PushResult(uint64(idx))
-//	return true, nil
-//
-//}
-//
-//// marshalNativeParams
-//// make parameter bytes for call native contract
-//func (this *WasmVmService) marshalNativeParams(engine *exec.ExecutionEngine) (bool, error) {
-//	vm := engine.GetVM()
-//	envCall := vm.GetEnvCall()
-//	params := envCall.GetParams()
-//	if len(params) != 1 {
-//		return false, errors.NewErr("[callContract]parameter count error while call marshalNativeParams")
-//	}
-//
-//	transferbytes, err := vm.GetPointerMemory(params[0])
-//	if err != nil {
-//		return false, err
-//	}
-//	//transferbytes is a nested struct with states.Transfer
-//	//type Transfers struct {
-//	//	States  []*State		   -------->i32 pointer 4 bytes
-//	//}
-//	if len(transferbytes) != 4 {
-//		return false, errors.NewErr("[callContract]parameter format error while call marshalNativeParams")
-//	}
-//	transfer := &nstates.Transfers{}
-//
-//	statesAddr := binary.LittleEndian.Uint32(transferbytes[:4])
-//	statesbytes, err := vm.GetPointerMemory(uint64(statesAddr))
-//	if err != nil {
-//		return false, err
-//	}
-//
-//	//statesbytes is slice of struct with states.
-//	//type State struct {
-//	//	From    common.Address  -------->i32 pointer 4 bytes
-//	//	To      common.Address  -------->i32 pointer 4 bytes
-//	//	Value   *big.Int        -------->i64 8 bytes
-//	//}
-//	//total is 4 + 4 + 8 = 24 bytes
-//	statecnt := len(statesbytes) / 16
-//	states := make([]*nstates.State, statecnt)
-//
-//	for i := 0; i < statecnt; i++ {
-//		tmpbytes := statesbytes[i*16 : (i+1)*16]
-//		state := &nstates.State{}
-//		fromAddessBytes, err := vm.GetPointerMemory(uint64(binary.LittleEndian.Uint32(tmpbytes[:4])))
-//		if err != nil {
-//			return false, err
-//		}
-//		fromAddress, err := common.AddressFromBase58(util.TrimBuffToString(fromAddessBytes))
-//		if err != nil {
-//			return false, err
-//		}
-//		state.From = fromAddress
-//
-//		toAddressBytes, err := vm.GetPointerMemory(uint64(binary.LittleEndian.Uint32(tmpbytes[4:8])))
-//		if err != nil {
-//			return false, err
-//		}
-//		toAddress, err := common.AddressFromBase58(util.TrimBuffToString(toAddressBytes))
-//		state.To = toAddress
-//		//tmpbytes[12:16] is padding
-//		amount := binary.LittleEndian.Uint64(tmpbytes[8:])
-//		state.Value = amount
-//		states[i] = state
-//
-//	}
-//
-//	transfer.States = states
-//	tbytes := new(bytes.Buffer)
-//	transfer.Serialize(tbytes)
-//
-//	result, err := vm.SetPointerMemory(tbytes.Bytes())
-//	if err != nil {
-//		return false, err
-//	}
-//	vm.RestoreCtx()
-//	vm.PushResult(uint64(result))
-//	return true, nil
-//}
-//
-//// callContract
-//// need 4 parameters
-////0: contract address
-////1: contract code
-////2: method name
-////3: args
-//func (this *WasmVmService) callContract(engine *exec.ExecutionEngine) (bool, error) {
-//	vm := engine.GetVM()
-//	envCall := vm.GetEnvCall()
-//	params := envCall.GetParams()
-//	if len(params) != 4 {
-//		return false, errors.NewErr("[callContract]parameter count error while call readMessage")
-//	}
-//	var contractAddress common.Address
-//	var contractBytes []byte
-//	//get contract address
-//	contractAddressIdx := params[0]
-//	addr, err := vm.GetPointerMemory(contractAddressIdx)
-//	if err != nil {
-//		return false, errors.NewErr("[callContract]get Contract address failed:" + err.Error())
-//	}
-//
-//	if addr != nil {
-//		addrbytes, err := common.HexToBytes(util.TrimBuffToString(addr))
-//		if err != nil {
-//			return false, errors.NewErr("[callContract]get contract address error:" + err.Error())
-//		}
-//		contractAddress, err = common.AddressParseFromBytes(addrbytes)
-//		if err != nil {
-//			return false, errors.NewErr("[callContract]get contract address error:" + err.Error())
-//		}
-//
-//	}
-//
-//	//get contract code
-//	codeIdx := params[1]
-//
-//	offchainContractCode, err := vm.GetPointerMemory(codeIdx)
-//	if err != nil {
-//		return false, errors.NewErr("[callContract]get Contract address failed:" + err.Error())
-//	}
-//	if offchainContractCode != nil {
-//		contractBytes, err = common.HexToBytes(util.TrimBuffToString(offchainContractCode))
-//		if err != nil {
-//			return false, err
-//
-//		}
-//		//compute the offchain code address
-//		codestring := util.TrimBuffToString(offchainContractCode)
-//		contractAddress = GetContractAddress(codestring, vmtypes.WASMVM)
-//	}
-//	//get method
-//	methodName, err := vm.GetPointerMemory(params[2])
-//	if err != nil {
-//		return false, errors.NewErr("[callContract]get Contract methodName failed:" + err.Error())
-//	}
-//	//get args
-//	arg, err := vm.GetPointerMemory(params[3])
-//
-//	if err != nil {
-//		return false, errors.NewErr("[callContract]get Contract arg failed:" + err.Error())
-//	}
-//	this.ContextRef.PushContext(&context.Context{
-//		Code:            vm.VMCode,
-//		ContractAddress: vm.ContractAddress})
-//	result, err := this.ContextRef.AppCall(contractAddress, util.TrimBuffToString(methodName), contractBytes, arg)
-//
-//	this.ContextRef.PopContext()
-//	if err != nil {
-//		return false, errors.NewErr("[callContract]AppCall failed:" + err.Error())
-//	}
-//	vm.RestoreCtx()
-//	var res string
-//	if envCall.GetReturns() {
-//		if contractAddress[0] == byte(vmtypes.NEOVM) {
-//			result = sccommon.ConvertNeoVmReturnTypes(result)
-//			switch result.(type) {
-//			case int:
-//				res = strconv.Itoa(result.(int))
-//			case int64:
-//				res = strconv.FormatInt(result.(int64), 10)
-//			case string:
-//				res = result.(string)
-//			case []byte:
-//				tmp := result.([]byte)
-//				if len(tmp) == 1 {
-//					if tmp[0] == byte(1) {
-//						res = "true"
-//					}
-//					if tmp[0] == byte(0) {
-//						res = "false"
-//					}
-//				} else {
-//					res = string(result.([]byte))
-//				}
-//			default:
-//				res = fmt.Sprintf("%s", result)
-//			}
-//
-//		}
-//		if contractAddress[0] == byte(vmtypes.Native) {
-//			bresult := result.(bool)
-//			if bresult == true {
-//				res = "true"
-//			} else {
-//				res = "false"
-//			}
-//
-//		}
-//		if contractAddress[0] == byte(vmtypes.WASMVM) {
-//			res = fmt.Sprintf("%s", result)
-//		}
-//
-//		idx, err := vm.SetPointerMemory(res)
-//		if err != nil {
-//			return false, errors.NewErr("[callContract]SetPointerMemory failed:" + err.Error())
-//		}
-//		vm.PushResult(uint64(idx))
-//	}
-//
-//	return true, nil
-//}
-//
-//func (this *WasmVmService) GetContractCodeFromAddress(address common.Address) ([]byte, error) {
-//
-//	dcode, err := this.Store.GetContractState(address)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if dcode == nil {
-//		return nil, errors.NewErr("[GetContractCodeFromAddress] deployed code is nil")
-//	}
-//
-//	return dcode.Code.Code, nil
-//
-//}
-//
-//func (this *WasmVmService) getContractFromAddr(addr []byte) ([]byte, error) {
-//	addrbytes, err := common.HexToBytes(util.TrimBuffToString(addr))
-//	if err != nil {
-//		return nil, errors.NewErr("get contract address error")
-//	}
-//	contactaddress, err := common.AddressParseFromBytes(addrbytes)
-//	if err != nil {
-//		return nil, errors.NewErr("get contract address error")
-//	}
-//	dpcode, err := this.GetContractCodeFromAddress(contactaddress)
-//	if err != nil {
-//		return nil, errors.NewErr("get contract  error")
-//	}
-//	return dpcode, nil
-//}
-//
-////GetContractAddress return contract address
-//func GetContractAddress(code string, vmType vmtypes.VmType) common.Address {
-//	data, _ := hex.DecodeString(code)
-//	vmCode := &vmtypes.VmCode{
-//		VmType: vmType,
-//		Code:   data,
-//	}
-//	return vmCode.AddressFromVmCode()
-//}
-//
-////buildNeoVMParamInter build neovm invoke param code
-//func buildNeoVMParamInter(builder *neovm.ParamsBuilder, smartContractParams []interface{}) error {
-//	//VM load params in reverse order
-//	for i := len(smartContractParams) - 1; i >= 0; i-- {
-//		switch v := smartContractParams[i].(type) {
-//		case bool:
-//			builder.EmitPushBool(v)
-//		case int:
-//			builder.EmitPushInteger(big.NewInt(int64(v)))
-//		case uint:
-//			builder.EmitPushInteger(big.NewInt(int64(v)))
-//		case int32:
-//			builder.EmitPushInteger(big.NewInt(int64(v)))
-//		case uint32:
-//			builder.EmitPushInteger(big.NewInt(int64(v)))
-//		case int64:
-//			builder.EmitPushInteger(big.NewInt(int64(v)))
-//		case common.Fixed64:
-//			builder.EmitPushInteger(big.NewInt(int64(v.GetData())))
-//		case uint64:
-//			val := big.NewInt(0)
-//			builder.EmitPushInteger(val.SetUint64(uint64(v)))
-//		case string:
-//			builder.EmitPushByteArray([]byte(v))
-//		case *big.Int:
-//			builder.EmitPushInteger(v)
-//		case []byte:
-//			builder.EmitPushByteArray(v)
-//		case []interface{}:
-//			err := buildNeoVMParamInter(builder, v)
-//			if err != nil {
-//				return err
-//			}
-//			builder.EmitPushInteger(big.NewInt(int64(len(v))))
-//			builder.Emit(neovm.PACK)
-//		default:
-//			return fmt.Errorf("unsupported param:%s", v)
-//		}
-//	}
-//	return nil
-//}
+// Invoke a wasm smart contract
+func (this *WasmVmService) Invoke() (interface{}, error) {
+	if len(this.Code) == 0 {
+		return nil, ERR_EXECUTE_CODE
+	}
+	param := new(states.ContractInvokeParam)
+	if err := param.Deserialization(common.NewZeroCopySource(this.Code)); err != nil {
+		return nil, errors.NewDetailErr(err, errors.ErrNoCode, "[WasmVmService] deserialize invoke param error!")
+	}
+	code, err := this.getContract(param.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := exec.NewExecutionEngine(nil, new(util.ECDsaCrypto), this.newStateMachine())
+	engine.SetGasChecker(this.checkUseGas, neovm.OPCODE_GAS)
+
+	var caller common.Address
+	if current := this.ContextRef.CurrentContext(); current != nil {
+		caller = current.ContractAddress
+	}
+	this.ContextRef.PushContext(&context.Context{ContractAddress: param.Address, Code: code})
+	// the context is popped on error too, so the caller and the tracer see the failed call exited
+	defer this.ContextRef.PopContext()
+	res, err := engine.Call(caller, code, param.Method, param.Args, CONTRACT_VERSION)
+	if err != nil {
+		return nil, err
+	}
+
+	// an i32 result is a pointer to the returned bytes
+	var result []byte
+	if len(res) == 4 {
+		result, err = engine.GetVM().GetPointerMemory(uint64(binary.LittleEndian.Uint32(res)))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		result = res
+	}
+
+	this.ContextRef.PushNotifications(this.Notifications)
+	return result, nil
+}
+
+func (this *WasmVmService) newStateMachine() *WasmStateMachine {
+	stateMachine := NewWasmStateMachine()
+	register := func(name string, handler func(*exec.ExecutionEngine) (bool, error)) {
+		stateMachine.Register(name, this.chargeGas(name, handler))
+	}
+	//cross contract call
+	register(CALL_CONTRACT_NAME, this.callContract)
+	//runtime
+	register(RUNTIME_CHECKWITNESS_NAME, this.runtimeCheckWitness)
+	register(RUNTIME_NOTIFY_NAME, this.runtimeNotify)
+	register(RUNTIME_CHECKSIG_NAME, this.runtimeCheckSig)
+	register(RUNTIME_GETTIME_NAME, this.runtimeGetTime)
+	register(RUNTIME_LOG_NAME, this.runtimeLog)
+	//attribute
+	register(ATTRIBUTE_GETUSAGE_NAME, this.attributeGetUsage)
+	register(ATTRIBUTE_GETDATA_NAME, this.attributeGetData)
+	//block
+	register(BLOCK_GETCURRENTHEADERHASH_NAME, this.blockGetCurrentHeaderHash)
+	register(BLOCK_GETCURRENTHEADERHEIGHT_NAME, this.blockGetCurrentHeaderHeight)
+	register(BLOCK_GETCURRENTBLOCKHASH_NAME, this.blockGetCurrentBlockHash)
+	register(BLOCK_GETCURRENTBLOCKHEIGHT_NAME, this.blockGetCurrentBlockHeight)
+	register(BLOCK_GETTRANSACTIONBYHASH_NAME, this.blockGetTransactionByHash)
+	register(BLOCK_GETTRANSACTIONCOUNT_NAME, this.blockGetTransactionCount)
+	register(BLOCK_GETTRANSACTIONS_NAME, this.blockGetTransactions)
+	//blockchain
+	register(BLOCKCHAIN_GETHEIGHT_NAME, this.blockChainGetHeight)
+	register(BLOCKCHAIN_GETHEADERBYHEIGHT_NAME, this.blockChainGetHeaderByHeight)
+	register(BLOCKCHAIN_GETHEADERBYHASH_NAME, this.blockChainGetHeaderByHash)
+	register(BLOCKCHAIN_GETBLOCKBYHEIGHT_NAME, this.blockChainGetBlockByHeight)
+	register(BLOCKCHAIN_GETBLOCKBYHASH_NAME, this.blockChainGetBlockByHash)
+	register(BLOCKCHAIN_GETCONTRACT_NAME, this.blockChainGetContract)
+	//header
+	register(HEADER_GETHASH_NAME, this.headerGetHash)
+	register(HEADER_GETVERSION_NAME, this.headerGetVersion)
+	register(HEADER_GETPREVHASH_NAME, this.headerGetPrevHash)
+	register(HEADER_GETMERKLEROOT_NAME, this.headerGetMerkleRoot)
+	register(HEADER_GETINDEX_NAME, this.headerGetIndex)
+	register(HEADER_GETTIMESTAMP_NAME, this.headerGetTimestamp)
+	register(HEADER_GETCONSENSUSDATA_NAME, this.headerGetConsensusData)
+	register(HEADER_GETNEXTCONSENSUS_NAME, this.headerGetNextConsensus)
+	//storage
+	register(STORAGE_PUT_NAME, this.putstore)
+	register(STORAGE_GET_NAME, this.getstore)
+	register(STORAGE_DELETE_NAME, this.deletestore)
+	//transaction
+	register(TRANSACTION_GETHASH_NAME, this.transactionGetHash)
+	register(TRANSACTION_GETTYPE_NAME, this.transactionGetType)
+	register(TRANSACTION_GETATTRIBUTES_NAME, this.transactionGetAttributes)
+	return stateMachine
+}
+
+// chargeGas wrap a host function so its GAS_TABLE price is paid before it runs
+func (this *WasmVmService) chargeGas(name string, handler func(*exec.ExecutionEngine) (bool, error)) func(*exec.ExecutionEngine) (bool, error) {
+	return func(engine *exec.ExecutionEngine) (bool, error) {
+		price, err := GasPrice(engine, name)
+		if err != nil {
+			return false, err
+		}
+		if err := engine.UseGas(price); err != nil {
+			return false, err
+		}
+		return handler(engine)
+	}
+}
+
+func (this *WasmVmService) checkUseGas(gas uint64) error {
+	if this.PreExec && !this.ContextRef.CheckExecStep() {
+		return VM_EXEC_STEP_EXCEED
+	}
+	if !this.ContextRef.CheckUseGas(gas) {
+		return ERR_GAS_INSUFFICIENT
+	}
+	return nil
+}
+
+// callContract
+// need 3 parameters
+//0: contract address in base58
+//1: method name
+//2: args
+func (this *WasmVmService) callContract(engine *exec.ExecutionEngine) (bool, error) {
+	vm := engine.GetVM()
+	envCall := vm.GetEnvCall()
+	params := envCall.GetParams()
+	if len(params) != 3 {
+		return false, errors.NewErr("[callContract]parameter count error")
+	}
+	addr, err := vm.GetPointerMemory(params[0])
+	if err != nil {
+		return false, errors.NewErr("[callContract]get contract address failed:" + err.Error())
+	}
+	contractAddress, err := common.AddressFromBase58(util.TrimBuffToString(addr))
+	if err != nil {
+		return false, errors.NewErr("[callContract]get contract address error:" + err.Error())
+	}
+	methodName, err := vm.GetPointerMemory(params[1])
+	if err != nil {
+		return false, errors.NewErr("[callContract]get contract methodName failed:" + err.Error())
+	}
+	args, err := vm.GetPointerMemory(params[2])
+	if err != nil {
+		return false, errors.NewErr("[callContract]get contract arg failed:" + err.Error())
+	}
+
+	result, err := this.appCall(contractAddress, util.TrimBuffToString(methodName), args)
+	if err != nil {
+		return false, errors.NewErr("[callContract]AppCall failed:" + err.Error())
+	}
+	vm.RestoreCtx()
+	if envCall.GetReturns() {
+		idx, err := vm.SetPointerMemory(result)
+		if err != nil {
+			return false, errors.NewErr("[callContract]SetPointerMemory failed:" + err.Error())
+		}
+		vm.PushResult(uint64(idx))
+	}
+	return true, nil
+}
+
+// appCall invoke a native, neovm or wasm contract and return its result as bytes
+// neovm contracts receive args as a serialized stack item and return a serialized stack item
+func (this *WasmVmService) appCall(address common.Address, method string, args []byte) ([]byte, error) {
+	if _, ok := native.Contracts[address]; ok {
+		service := &native.NativeService{
+			CacheDB:     this.CacheDB,
+			InvokeParam: states.ContractInvokeParam{Address: address, Method: method, Args: args},
+			Tx:          this.Tx,
+			Height:      this.Height,
+			Time:        this.Time,
+			BlockHash:   this.BlockHash,
+			ContextRef:  this.ContextRef,
+			ServiceMap:  make(map[string]native.Handler),
+		}
+		result, err := service.Invoke()
+		if err != nil {
+			return nil, err
+		}
+		res, ok := result.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("native contract %s return type error", address.ToHexString())
+		}
+		return res, nil
+	}
+
+	dep, err := this.CacheDB.GetContract(address)
+	if err != nil {
+		return nil, errors.NewErr("[appCall] Get contract context error!")
+	}
+	if dep == nil {
+		return nil, CONTRACT_NOT_EXIST
+	}
+	switch dep.VmType {
+	case payload.WASMVM_TYPE:
+		param := states.ContractInvokeParam{Version: CONTRACT_VERSION, Address: address, Method: method, Args: args}
+		sink := common.NewZeroCopySink(nil)
+		param.Serialization(sink)
+		service, err := this.ContextRef.NewWasmExecuteEngine(sink.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		result, err := service.Invoke()
+		if err != nil {
+			return nil, err
+		}
+		return result.([]byte), nil
+	default:
+		service, err := this.ContextRef.NewExecuteEngine(dep.Code)
+		if err != nil {
+			return nil, err
+		}
+		neoService := service.(*neovm.NeoVmService)
+		var argItem ntypes.StackItems = ntypes.NewArray(nil)
+		if len(args) != 0 {
+			argItem, err = neovm.DeserializeStackItem(bytes.NewReader(args))
+			if err != nil {
+				return nil, err
+			}
+		}
+		nvm.PushData(neoService.Engine, argItem)
+		nvm.PushData(neoService.Engine, []byte(method))
+		result, err := neoService.Invoke()
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			return nil, nil
+		}
+		return neovm.SerializeStackItem(result.(ntypes.StackItems))
+	}
+}
+
+func (this *WasmVmService) getContract(address common.Address) ([]byte, error) {
+	dep, err := this.CacheDB.GetContract(address)
+	if err != nil {
+		return nil, errors.NewErr("[getContract] Get contract context error!")
+	}
+	log.Debugf("invoke wasm contract address:%s", address.ToHexString())
+	if dep == nil {
+		return nil, CONTRACT_NOT_EXIST
+	}
+	if dep.VmType != payload.WASMVM_TYPE {
+		return nil, VM_TYPE_ERROR
+	}
+	return dep.Code, nil
+}
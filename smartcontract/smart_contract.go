@@ -28,7 +28,9 @@ import (
 	"github.com/OnyxPay/OnyxChain/smartcontract/event"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/native"
 	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/wasmvm"
 	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	"github.com/OnyxPay/OnyxChain/smartcontract/trace"
 	vm "github.com/OnyxPay/OnyxChain/vm/neovm"
 )
 
@@ -46,6 +48,10 @@ type SmartContract struct {
 	Gas           uint64
 	ExecStep      int
 	PreExec       bool
+	SkipWitness   bool             // treat every witness as present, used to estimate gas of unsigned transaction
+	Witnesses     []common.Address // addresses authorized besides the signers of transaction
+	Tracer        *trace.Tracer    // record the execution if not nil
+	Debugger      neovm.DebugHook  // debug the neovm execution if not nil
 }
 
 // Config describe smart contract need parameters configuration
@@ -59,6 +65,9 @@ type Config struct {
 // PushContext push current context to smart contract
 func (this *SmartContract) PushContext(context *context.Context) {
 	this.Contexts = append(this.Contexts, context)
+	if this.Tracer != nil {
+		this.Tracer.Enter(context.ContractAddress, context.Method, this.Gas)
+	}
 }
 
 // CurrentContext return smart contract current context
@@ -90,6 +99,9 @@ func (this *SmartContract) PopContext() {
 	if len(this.Contexts) > 1 {
 		this.Contexts = this.Contexts[:len(this.Contexts)-1]
 	}
+	if this.Tracer != nil {
+		this.Tracer.Exit(this.Gas)
+	}
 }
 
 // PushNotifications push smart contract event info
@@ -137,6 +149,27 @@ func (this *SmartContract) NewExecuteEngine(code []byte) (context.Engine, error)
 		BlockHash:  this.Config.BlockHash,
 		Engine:     vm.NewExecutionEngine(),
 		PreExec:    this.PreExec,
+		Tracer:     this.Tracer,
+		Debugger:   this.Debugger,
+	}
+	return service, nil
+}
+
+// NewWasmExecuteEngine launch a wasm service for input, a serialized ContractInvokeParam
+func (this *SmartContract) NewWasmExecuteEngine(input []byte) (context.Engine, error) {
+	if !this.checkContexts() {
+		return nil, fmt.Errorf("%s", "engine over max limit!")
+	}
+	service := &wasmvm.WasmVmService{
+		Store:      this.Store,
+		CacheDB:    this.CacheDB,
+		ContextRef: this,
+		Code:       input,
+		Tx:         this.Config.Tx,
+		Time:       this.Config.Time,
+		Height:     this.Config.Height,
+		BlockHash:  this.Config.BlockHash,
+		PreExec:    this.PreExec,
 	}
 	return service, nil
 }
@@ -162,6 +195,14 @@ func (this *SmartContract) NewNativeService() (*native.NativeService, error) {
 // Else check whether address is calling contract address
 // Param address: wallet address or contract address
 func (this *SmartContract) CheckWitness(address common.Address) bool {
+	if this.SkipWitness {
+		return true
+	}
+	for _, v := range this.Witnesses {
+		if v == address {
+			return true
+		}
+	}
 	if this.checkAccountAddress(address) || this.checkContractAddress(address) {
 		return true
 	}
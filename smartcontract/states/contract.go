@@ -25,6 +25,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/common/serialization"
 	"github.com/OnyxPay/OnyxChain/errors"
 	"github.com/OnyxPay/OnyxChain/smartcontract/event"
+	"github.com/OnyxPay/OnyxChain/smartcontract/trace"
 )
 
 // Invoke smart contract struct
@@ -115,4 +116,14 @@ type PreExecResult struct {
 	Gas    uint64
 	Result interface{}
 	Notify []*event.NotifyEventInfo
+	Trace  *trace.Tracer
+}
+
+//GasEstimate is the gas breakdown of a pre-executed transaction
+type GasEstimate struct {
+	CodeLenGas uint64 //gas charged for the length of transaction code
+	CreateGas  uint64 //gas charged for contract deployment
+	ExecGas    uint64 //gas consumed by contract execution
+	MinGas     uint64 //minimum gas charged for any transaction
+	GasLimit   uint64 //recommended gas limit of transaction, with a margin over the execution gas
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/OnyxPay/OnyxChain/core/payload"
 	"github.com/OnyxPay/OnyxChain/core/store/common"
 	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
+	"github.com/OnyxPay/OnyxChain/smartcontract/trace"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
@@ -32,6 +33,7 @@ type CacheDB struct {
 	memdb      *overlaydb.MemDB
 	backend    *overlaydb.OverlayDB
 	keyScratch []byte
+	tracer     *trace.Tracer
 }
 
 const initCap = 16 * 1024
@@ -49,6 +51,11 @@ func (self *CacheDB) Reset() {
 	self.memdb.Reset()
 }
 
+// SetTracer record the storage access to tracer, nil tracer disable the record
+func (self *CacheDB) SetTracer(tracer *trace.Tracer) {
+	self.tracer = tracer
+}
+
 func ensureBuffer(b []byte, n int) []byte {
 	if cap(b) < n {
 		return make([]byte, n)
@@ -75,6 +82,10 @@ func (self *CacheDB) Commit() {
 }
 
 func (self *CacheDB) Put(key []byte, value []byte) {
+	if self.tracer != nil {
+		old, _ := self.get(common.ST_STORAGE, key)
+		self.tracer.StoragePut(key, old, value)
+	}
 	self.put(common.ST_STORAGE, key, value)
 }
 
@@ -119,7 +130,11 @@ func (self *CacheDB) DeleteContract(address comm.Address) {
 }
 
 func (self *CacheDB) Get(key []byte) ([]byte, error) {
-	return self.get(common.ST_STORAGE, key)
+	value, err := self.get(common.ST_STORAGE, key)
+	if err == nil && self.tracer != nil {
+		self.tracer.StorageGet(key, value)
+	}
+	return value, err
 }
 
 func (self *CacheDB) get(prefix common.DataEntryPrefix, key []byte) ([]byte, error) {
@@ -137,6 +152,10 @@ func (self *CacheDB) get(prefix common.DataEntryPrefix, key []byte) ([]byte, err
 }
 
 func (self *CacheDB) Delete(key []byte) {
+	if self.tracer != nil {
+		old, _ := self.get(common.ST_STORAGE, key)
+		self.tracer.StorageDelete(key, old)
+	}
 	self.delete(common.ST_STORAGE, key)
 }
 
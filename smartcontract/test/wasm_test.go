@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package test
+
+import (
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/states"
+	"github.com/OnyxPay/OnyxChain/core/store/leveldbstore"
+	"github.com/OnyxPay/OnyxChain/core/store/overlaydb"
+	"github.com/OnyxPay/OnyxChain/smartcontract"
+	"github.com/OnyxPay/OnyxChain/smartcontract/context"
+	"github.com/OnyxPay/OnyxChain/smartcontract/service/neovm"
+	sstates "github.com/OnyxPay/OnyxChain/smartcontract/states"
+	"github.com/OnyxPay/OnyxChain/smartcontract/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+//(module
+//  (type (;0;) (func (param i32 i32) (result i32)))
+//  (type (;1;) (func (param i32 i32)))
+//  (import "env" "memory" (memory (;0;) 1))
+//  (import "env" "ONX_Storage_Put" (func (;0;) (type 1)))
+//  (func (;1;) (type 0) (param i32 i32) (result i32)
+//    get_local 0
+//    get_local 1
+//    call 0
+//    get_local 1)
+//  (export "invoke" (func 1)))
+const wasmStoreCode = `0061736d01000000010c0260027f7f017f60027f7f0002250203656e76066d656d6f727902000103656e760f4f4e585f53746f726167655f507574000103020100070a0106696e766f6b6500010a0c010a0020002001100020010b`
+
+//(module
+//  (type (;0;) (func (param i32 i32) (result i32)))
+//  (import "env" "memory" (memory (;0;) 1))
+//  (func (;0;) (type 0) (param i32 i32) (result i32)
+//    loop  ;; label = @1
+//      br 0 (;@1;)
+//    end
+//    i32.const 0)
+//  (export "invoke" (func 0)))
+const wasmLoopCode = `0061736d0100000001070160027f7f017f020f0103656e76066d656d6f727902000103020100070a0106696e766f6b6500000a0b01090003400c000b41000b`
+
+func deployWasm(t *testing.T, cache *storage.CacheDB, code string) common.Address {
+	hex, err := common.HexToBytes(code)
+	if err != nil {
+		t.Fatal("hex to byte error:", err)
+	}
+	dep := &payload.DeployCode{Code: hex, VmType: payload.WASMVM_TYPE}
+	assert.Nil(t, cache.PutContract(dep))
+	return dep.Address()
+}
+
+func invokeWasm(sc *smartcontract.SmartContract, address common.Address, method string, args []byte) (interface{}, error) {
+	param := sstates.ContractInvokeParam{Version: 1, Address: address, Method: method, Args: args}
+	sink := common.NewZeroCopySink(nil)
+	param.Serialization(sink)
+	engine, err := sc.NewWasmExecuteEngine(sink.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return engine.Invoke()
+}
+
+func newWasmTestContract(gas uint64) *smartcontract.SmartContract {
+	store, _ := leveldbstore.NewMemLevelDBStore()
+	return &smartcontract.SmartContract{
+		Config:  &smartcontract.Config{Time: 10, Height: 10},
+		CacheDB: storage.NewCacheDB(overlaydb.NewOverlayDB(store)),
+		Gas:     gas,
+	}
+}
+
+func TestWasmInvoke(t *testing.T) {
+	sc := newWasmTestContract(100000)
+	address := deployWasm(t, sc.CacheDB, wasmStoreCode)
+
+	// invoke returns the pointer of args, and stores args under method name by host function
+	result, err := invokeWasm(sc, address, "key", []byte("value"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), result)
+
+	raw, err := sc.CacheDB.Get(append(address[:], []byte("key")...))
+	assert.Nil(t, err)
+	value, err := states.GetValueFromRawStorageItem(raw)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	// every instruction costs OPCODE_GAS, the host function costs its GAS_TABLE price
+	assert.Equal(t, uint64(100000)-5*neovm.OPCODE_GAS-neovm.STORAGE_PUT_GAS, sc.Gas)
+}
+
+func TestWasmInvokeGasInsufficient(t *testing.T) {
+	sc := newWasmTestContract(neovm.STORAGE_PUT_GAS)
+	address := deployWasm(t, sc.CacheDB, wasmStoreCode)
+	_, err := invokeWasm(sc, address, "key", []byte("value"))
+	assert.NotNil(t, err)
+	raw, err := sc.CacheDB.Get(append(address[:], []byte("key")...))
+	assert.Nil(t, err)
+	assert.Nil(t, raw)
+}
+
+func TestWasmInvokeOutOfGas(t *testing.T) {
+	sc := newWasmTestContract(1000)
+	address := deployWasm(t, sc.CacheDB, wasmLoopCode)
+	caller := &context.Context{ContractAddress: common.Address{1}}
+	sc.PushContext(caller)
+
+	_, err := invokeWasm(sc, address, "loop", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(0), sc.Gas)
+	// the context of failed invocation is popped
+	assert.Equal(t, caller, sc.CurrentContext())
+	assert.Equal(t, 1, len(sc.Contexts))
+}
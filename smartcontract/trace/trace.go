@@ -0,0 +1,184 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package trace records the call tree, storage access and gas consumption of smart contract execution
+package trace
+
+import (
+	"github.com/OnyxPay/OnyxChain/common"
+)
+
+const (
+	STORAGE_GET    = "get"
+	STORAGE_PUT    = "put"
+	STORAGE_DELETE = "delete"
+)
+
+// CallFrame is a contract call in the call tree of execution
+type CallFrame struct {
+	Contract string
+	Method   string           `json:",omitempty"` // method of native contract
+	Gas      uint64           // gas consumed by the call, including nested calls
+	Error    string           `json:",omitempty"`
+	Storage  []*StorageAccess `json:",omitempty"`
+	Calls    []*CallFrame     `json:",omitempty"`
+	gasStart uint64
+}
+
+// StorageAccess is a storage read or write of contract call. Key is prefixed by contract address
+type StorageAccess struct {
+	Op  string
+	Key string
+	Old string
+	New string `json:",omitempty"`
+}
+
+// StorageChange is the change of storage item after execution
+type StorageChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// Fault is the position where neovm execution failed
+type Fault struct {
+	Contract string
+	Offset   int
+	OpCode   string
+	Error    string
+}
+
+// Tracer record the execution of smart contract. Tracer is not safe for concurrent use
+type Tracer struct {
+	Calls   []*CallFrame      // top level calls
+	Gas     map[string]uint64 // gas consumed by opcode and syscall
+	Changes []*StorageChange  // storage changes in order of first write
+	Fault   *Fault            `json:",omitempty"`
+	stack   []*CallFrame
+	changes map[string]*StorageChange
+}
+
+// NewTracer return a new execution tracer
+func NewTracer() *Tracer {
+	return &Tracer{
+		Gas:     make(map[string]uint64),
+		changes: make(map[string]*StorageChange),
+	}
+}
+
+// Enter start a contract call with the gas left
+func (self *Tracer) Enter(contract common.Address, method string, gas uint64) {
+	frame := &CallFrame{Contract: contract.ToHexString(), Method: method, gasStart: gas}
+	if parent := self.current(); parent != nil {
+		parent.Calls = append(parent.Calls, frame)
+	} else {
+		self.Calls = append(self.Calls, frame)
+	}
+	self.stack = append(self.stack, frame)
+}
+
+// Exit finish current contract call with the gas left
+func (self *Tracer) Exit(gas uint64) {
+	frame := self.current()
+	if frame == nil {
+		return
+	}
+	frame.Gas = frame.gasStart - gas
+	self.stack = self.stack[:len(self.stack)-1]
+}
+
+// Finish close the unfinished contract calls with the gas left. The calls are failed if err is not nil
+func (self *Tracer) Finish(gas uint64, err error) {
+	for len(self.stack) > 0 {
+		if err != nil {
+			self.current().Error = err.Error()
+		}
+		self.Exit(gas)
+	}
+}
+
+// UseGas add the gas consumed by opcode or syscall
+func (self *Tracer) UseGas(name string, gas uint64) {
+	self.Gas[name] += gas
+}
+
+// SetFault record the failed neovm instruction, only the innermost fault is kept
+func (self *Tracer) SetFault(contract common.Address, offset int, opCode string, err error) {
+	if self.Fault != nil {
+		return
+	}
+	self.Fault = &Fault{
+		Contract: contract.ToHexString(),
+		Offset:   offset,
+		OpCode:   opCode,
+		Error:    err.Error(),
+	}
+}
+
+// StorageGet record storage read of current contract call
+func (self *Tracer) StorageGet(key, value []byte) {
+	self.addStorageAccess(&StorageAccess{Op: STORAGE_GET, Key: common.ToHexString(key), Old: common.ToHexString(value)})
+}
+
+// StoragePut record storage write of current contract call
+func (self *Tracer) StoragePut(key, old, value []byte) {
+	access := &StorageAccess{
+		Op:  STORAGE_PUT,
+		Key: common.ToHexString(key),
+		Old: common.ToHexString(old),
+		New: common.ToHexString(value),
+	}
+	if self.addStorageAccess(access) {
+		self.addStorageChange(access)
+	}
+}
+
+// StorageDelete record storage delete of current contract call
+func (self *Tracer) StorageDelete(key, old []byte) {
+	access := &StorageAccess{Op: STORAGE_DELETE, Key: common.ToHexString(key), Old: common.ToHexString(old)}
+	if self.addStorageAccess(access) {
+		self.addStorageChange(access)
+	}
+}
+
+func (self *Tracer) current() *CallFrame {
+	if len(self.stack) == 0 {
+		return nil
+	}
+	return self.stack[len(self.stack)-1]
+}
+
+// addStorageAccess add access to current contract call, storage access outside of contract call is ignored
+func (self *Tracer) addStorageAccess(access *StorageAccess) bool {
+	frame := self.current()
+	if frame == nil {
+		return false
+	}
+	frame.Storage = append(frame.Storage, access)
+	return true
+}
+
+func (self *Tracer) addStorageChange(access *StorageAccess) {
+	change, ok := self.changes[access.Key]
+	if !ok {
+		change = &StorageChange{Key: access.Key, Old: access.Old}
+		self.changes[access.Key] = change
+		self.Changes = append(self.Changes, change)
+	}
+	change.New = access.New
+}
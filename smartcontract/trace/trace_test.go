@@ -0,0 +1,78 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package trace
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracerCallTree(t *testing.T) {
+	tracer := NewTracer()
+	a := common.Address{1}
+	b := common.Address{2}
+
+	tracer.Enter(a, "", 1000)
+	tracer.StorageGet([]byte("k1"), []byte("v1"))
+	tracer.Enter(b, "transfer", 900)
+	tracer.StoragePut([]byte("k2"), nil, []byte("v2"))
+	tracer.Exit(700)
+	tracer.StorageDelete([]byte("k1"), []byte("v1"))
+	tracer.Exit(600)
+
+	assert.Equal(t, 1, len(tracer.Calls))
+	root := tracer.Calls[0]
+	assert.Equal(t, a.ToHexString(), root.Contract)
+	assert.Equal(t, uint64(400), root.Gas)
+	assert.Equal(t, 2, len(root.Storage))
+	assert.Equal(t, 1, len(root.Calls))
+	assert.Equal(t, "transfer", root.Calls[0].Method)
+	assert.Equal(t, uint64(200), root.Calls[0].Gas)
+	assert.Equal(t, STORAGE_PUT, root.Calls[0].Storage[0].Op)
+
+	assert.Equal(t, 2, len(tracer.Changes))
+	assert.Equal(t, common.ToHexString([]byte("k2")), tracer.Changes[0].Key)
+	assert.Equal(t, common.ToHexString([]byte("v2")), tracer.Changes[0].New)
+	assert.Equal(t, common.ToHexString([]byte("v1")), tracer.Changes[1].Old)
+	assert.Equal(t, "", tracer.Changes[1].New)
+}
+
+func TestTracerFinish(t *testing.T) {
+	tracer := NewTracer()
+	tracer.StoragePut([]byte("k"), nil, []byte("v"))
+	tracer.Enter(common.Address{1}, "", 1000)
+	tracer.Enter(common.Address{2}, "", 800)
+	tracer.UseGas("PUSH1", 1)
+	tracer.UseGas("PUSH1", 1)
+	tracer.SetFault(common.Address{2}, 10, "THROW", errors.New("inner"))
+	tracer.SetFault(common.Address{1}, 20, "APPCALL", errors.New("outer"))
+	tracer.Finish(500, errors.New("failed"))
+
+	assert.Equal(t, 0, len(tracer.Changes))
+	assert.Equal(t, uint64(2), tracer.Gas["PUSH1"])
+	assert.Equal(t, 10, tracer.Fault.Offset)
+	root := tracer.Calls[0]
+	assert.Equal(t, uint64(500), root.Gas)
+	assert.Equal(t, "failed", root.Error)
+	assert.Equal(t, uint64(300), root.Calls[0].Gas)
+	assert.Equal(t, "failed", root.Calls[0].Error)
+}
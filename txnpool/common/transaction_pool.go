@@ -46,22 +46,71 @@ type TXEntry struct {
 // enter the pool when they are valid from the network,
 // consensus or submitted. They exit the pool when they are included
 // in the ledger.
+//
+// At most one transaction of the same payer and nonce is kept in the
+// pool. A transaction with the same payer and nonce replaces the one in
+// the pool only if its gas price is higher.
+//
+// The transactions are indexed by gas price, and the ones of the same gas
+// price are ordered by their arrival, so that the highest paying ones are
+// packed into block first.
 type TXPool struct {
 	sync.RWMutex
-	txList map[common.Uint256]*TXEntry // Transactions which have been verified
+	txList  map[common.Uint256]*TXEntry   // Transactions which have been verified
+	nonces  map[PayerNonce]common.Uint256 // Transaction hash indexed by payer and nonce
+	payers  map[common.Address]int        // Transaction count of payers
+	seqs    map[common.Uint256]uint64     // Arrival sequence of transactions
+	ordered []txItem                      // Transactions ordered by gas price desc and arrival asc
+	seq     uint64                        // Arrival sequence of the last transaction
+}
+
+// txItem is the entry of the priority index of the pool
+type txItem struct {
+	entry *TXEntry
+	seq   uint64
+}
+
+// before returns whether the item has higher priority than the other one
+func (this txItem) before(other txItem) bool {
+	if this.entry.Tx.GasPrice != other.entry.Tx.GasPrice {
+		return this.entry.Tx.GasPrice > other.entry.Tx.GasPrice
+	}
+	return this.seq < other.seq
+}
+
+// PayerNonce identifies transactions of a payer which replace each other
+type PayerNonce struct {
+	Payer common.Address
+	Nonce uint32
+}
+
+// GetPayerNonce returns the payer and nonce of the transaction
+func GetPayerNonce(tx *types.Transaction) PayerNonce {
+	return PayerNonce{Payer: tx.Payer, Nonce: tx.Nonce}
 }
 
 // Init creates a new transaction pool to gather.
 func (tp *TXPool) Init() {
 	tp.Lock()
 	defer tp.Unlock()
+	tp.reset()
+}
+
+// reset clears the pool, the caller should hold the lock.
+func (tp *TXPool) reset() {
 	tp.txList = make(map[common.Uint256]*TXEntry)
+	tp.nonces = make(map[PayerNonce]common.Uint256)
+	tp.payers = make(map[common.Address]int)
+	tp.seqs = make(map[common.Uint256]uint64)
+	tp.ordered = nil
 }
 
 // AddTxList adds a valid transaction to the transaction pool. If the
-// transaction is already in the pool, just return false. Parameter
-// txEntry includes transaction, fee, and verified information(height,
-// validator, error code).
+// transaction is already in the pool, or a transaction of the same payer
+// and nonce with no lower gas price is in the pool, just return false.
+// A transaction of the same payer and nonce with lower gas price is
+// replaced. Parameter txEntry includes transaction, fee, and verified
+// information(height, validator, error code).
 func (tp *TXPool) AddTxList(txEntry *TXEntry) bool {
 	tp.Lock()
 	defer tp.Unlock()
@@ -72,22 +121,151 @@ func (tp *TXPool) AddTxList(txEntry *TXEntry) bool {
 		return false
 	}
 
-	tp.txList[txHash] = txEntry
+	replaced, errCode := tp.checkReplacement(txEntry.Tx)
+	if errCode != errors.ErrNoError {
+		log.Infof("AddTxList: transaction %x is rejected: %s",
+			txHash, errCode.Error())
+		return false
+	}
+	if replaced != nil {
+		log.Infof("AddTxList: transaction %x is replaced by %x",
+			replaced.Hash(), txHash)
+		tp.delTx(replaced.Hash())
+	}
+
+	tp.addTx(txEntry)
 	return true
 }
 
+// addTx adds the transaction to the pool and its indexes, the caller
+// should hold the lock.
+func (tp *TXPool) addTx(txEntry *TXEntry) {
+	txHash := txEntry.Tx.Hash()
+	tp.seq++
+	item := txItem{entry: txEntry, seq: tp.seq}
+	pos := sort.Search(len(tp.ordered), func(i int) bool {
+		return item.before(tp.ordered[i])
+	})
+	tp.ordered = append(tp.ordered, txItem{})
+	copy(tp.ordered[pos+1:], tp.ordered[pos:])
+	tp.ordered[pos] = item
+
+	tp.txList[txHash] = txEntry
+	tp.seqs[txHash] = item.seq
+	tp.nonces[GetPayerNonce(txEntry.Tx)] = txHash
+	tp.payers[txEntry.Tx.Payer]++
+}
+
+// CheckReplacement checks whether the transaction can be added to the
+// pool with respect to the transaction of the same payer and nonce in
+// the pool, and returns the transaction to be replaced if any.
+func (tp *TXPool) CheckReplacement(tx *types.Transaction) (*types.Transaction, errors.ErrCode) {
+	tp.RLock()
+	defer tp.RUnlock()
+	return tp.checkReplacement(tx)
+}
+
+func (tp *TXPool) checkReplacement(tx *types.Transaction) (*types.Transaction, errors.ErrCode) {
+	hash, ok := tp.nonces[GetPayerNonce(tx)]
+	if !ok {
+		return nil, errors.ErrNoError
+	}
+	old := tp.txList[hash]
+	if old == nil {
+		return nil, errors.ErrNoError
+	}
+	if hash == tx.Hash() {
+		return nil, errors.ErrDuplicateInput
+	}
+	if tx.GasPrice <= old.Tx.GasPrice {
+		return nil, errors.ErrReplaceUnderpriced
+	}
+	return old.Tx, errors.ErrNoError
+}
+
+// delTx removes the transaction from the pool, the caller should hold
+// the lock.
+func (tp *TXPool) delTx(txHash common.Uint256) {
+	item, ok := tp.unindexTx(txHash)
+	if !ok {
+		return
+	}
+	pos := sort.Search(len(tp.ordered), func(i int) bool {
+		return !tp.ordered[i].before(item)
+	})
+	if pos < len(tp.ordered) && tp.ordered[pos].seq == item.seq {
+		copy(tp.ordered[pos:], tp.ordered[pos+1:])
+		tp.ordered[len(tp.ordered)-1] = txItem{}
+		tp.ordered = tp.ordered[:len(tp.ordered)-1]
+	}
+}
+
+// delTxs removes the transactions from the pool with one pass of the
+// priority index, the caller should hold the lock.
+func (tp *TXPool) delTxs(txHashes []common.Uint256) {
+	seqs := make(map[uint64]bool, len(txHashes))
+	for _, txHash := range txHashes {
+		if item, ok := tp.unindexTx(txHash); ok {
+			seqs[item.seq] = true
+		}
+	}
+	if len(seqs) == 0 {
+		return
+	}
+	ordered := tp.ordered[:0]
+	for _, item := range tp.ordered {
+		if !seqs[item.seq] {
+			ordered = append(ordered, item)
+		}
+	}
+	for i := len(ordered); i < len(tp.ordered); i++ {
+		tp.ordered[i] = txItem{}
+	}
+	tp.ordered = ordered
+}
+
+// unindexTx removes the transaction from the pool except the priority
+// index, and returns its item in the priority index.
+func (tp *TXPool) unindexTx(txHash common.Uint256) (txItem, bool) {
+	txEntry, ok := tp.txList[txHash]
+	if !ok {
+		return txItem{}, false
+	}
+	item := txItem{entry: txEntry, seq: tp.seqs[txHash]}
+	delete(tp.txList, txHash)
+	delete(tp.seqs, txHash)
+	key := GetPayerNonce(txEntry.Tx)
+	if tp.nonces[key] == txHash {
+		delete(tp.nonces, key)
+	}
+	payer := txEntry.Tx.Payer
+	if tp.payers[payer] <= 1 {
+		delete(tp.payers, payer)
+	} else {
+		tp.payers[payer]--
+	}
+	return item, true
+}
+
 // CleanTransactionList cleans the transaction list included in the ledger.
+// The transactions of the same payer and nonce are cleaned too, since
+// they are replaced by the one in the ledger.
 func (tp *TXPool) CleanTransactionList(txs []*types.Transaction) error {
 	cleaned := 0
 	txsNum := len(txs)
 	tp.Lock()
 	defer tp.Unlock()
+	txHashes := make([]common.Uint256, 0, len(txs))
 	for _, tx := range txs {
 		if _, ok := tp.txList[tx.Hash()]; ok {
-			delete(tp.txList, tx.Hash())
+			txHashes = append(txHashes, tx.Hash())
+			cleaned++
+		} else if hash, ok := tp.nonces[GetPayerNonce(tx)]; ok {
+			txHashes = append(txHashes, hash)
 			cleaned++
 		}
 	}
+	tp.delTxs(txHashes)
 
 	log.Debugf("CleanTransactionList: transaction %d requested,%d cleaned, remains %d in TxPool",
 		txsNum, cleaned, len(tp.txList))
@@ -102,7 +280,7 @@ func (tp *TXPool) DelTxList(tx *types.Transaction) bool {
 	if _, ok := tp.txList[txHash]; !ok {
 		return false
 	}
-	delete(tp.txList, txHash)
+	tp.delTx(txHash)
 	return true
 }
 
@@ -127,12 +305,6 @@ func (tp *TXPool) GetTxPool(byCount bool, height uint32) ([]*TXEntry,
 	tp.RLock()
 	defer tp.RUnlock()
 
-	orderByFee := make([]*TXEntry, 0, len(tp.txList))
-	for _, txEntry := range tp.txList {
-		orderByFee = append(orderByFee, txEntry)
-	}
-	sort.Sort(OrderByNetWorkFee(orderByFee))
-
 	count := int(config.DefConfig.Consensus.MaxTxInBlock)
 	if count <= 0 {
 		byCount = false
@@ -144,7 +316,8 @@ func (tp *TXPool) GetTxPool(byCount bool, height uint32) ([]*TXEntry,
 	var num int
 	txList := make([]*TXEntry, 0, count)
 	oldTxList := make([]*types.Transaction, 0)
-	for _, txEntry := range orderByFee {
+	for _, item := range tp.ordered {
+		txEntry := item.entry
 		if !tp.compareTxHeight(txEntry, height) {
 			oldTxList = append(oldTxList, txEntry.Tx)
 			continue
@@ -186,6 +359,28 @@ func (tp *TXPool) GetTxStatus(hash common.Uint256) *TxStatus {
 	return ret
 }
 
+// GetTxList returns the entries of the pool paid by the payer in the
+// order of priority. All the entries are returned if payer is empty.
+func (tp *TXPool) GetTxList(payer common.Address) []*TXEntry {
+	tp.RLock()
+	defer tp.RUnlock()
+	ret := make([]*TXEntry, 0)
+	for _, item := range tp.ordered {
+		if payer != common.ADDRESS_EMPTY && item.entry.Tx.Payer != payer {
+			continue
+		}
+		ret = append(ret, item.entry)
+	}
+	return ret
+}
+
+// GetPayerTxCount returns the tx number of the payer in the pool.
+func (tp *TXPool) GetPayerTxCount(payer common.Address) int {
+	tp.RLock()
+	defer tp.RUnlock()
+	return tp.payers[payer]
+}
+
 // GetTransactionCount returns the tx number of the pool.
 func (tp *TXPool) GetTransactionCount() int {
 	tp.RLock()
@@ -205,6 +400,7 @@ func (tp *TXPool) GetUnverifiedTxs(txs []*types.Transaction,
 		UnverifiedTxs: make([]*types.Transaction, 0),
 		OldTxs:        make([]*types.Transaction, 0),
 	}
+	oldTxHashes := make([]common.Uint256, 0)
 	for _, tx := range txs {
 		txEntry := tp.txList[tx.Hash()]
 		if txEntry == nil {
@@ -214,7 +410,7 @@ func (tp *TXPool) GetUnverifiedTxs(txs []*types.Transaction,
 		}
 
 		if !tp.compareTxHeight(txEntry, height) {
-			delete(tp.txList, tx.Hash())
+			oldTxHashes = append(oldTxHashes, tx.Hash())
 			res.OldTxs = append(res.OldTxs, txEntry.Tx)
 			continue
 		}
@@ -232,6 +428,7 @@ func (tp *TXPool) GetUnverifiedTxs(txs []*types.Transaction,
 			}
 		}
 	}
+	tp.delTxs(oldTxHashes)
 
 	return res
 }
@@ -240,11 +437,15 @@ func (tp *TXPool) GetUnverifiedTxs(txs []*types.Transaction,
 func (tp *TXPool) RemoveTxsBelowGasPrice(gasPrice uint64) {
 	tp.Lock()
 	defer tp.Unlock()
-	for _, txEntry := range tp.txList {
-		if txEntry.Tx.GasPrice < gasPrice {
-			delete(tp.txList, txEntry.Tx.Hash())
-		}
+	// the transactions below the gas price are at the tail of the index
+	pos := sort.Search(len(tp.ordered), func(i int) bool {
+		return tp.ordered[i].entry.Tx.GasPrice < gasPrice
+	})
+	txHashes := make([]common.Uint256, 0, len(tp.ordered)-pos)
+	for _, item := range tp.ordered[pos:] {
+		txHashes = append(txHashes, item.entry.Tx.Hash())
 	}
+	tp.delTxs(txHashes)
 }
 
 // Remain returns the remaining tx list to cleanup
@@ -252,11 +453,11 @@ func (tp *TXPool) Remain() []*types.Transaction {
 	tp.Lock()
 	defer tp.Unlock()
 
-	txList := make([]*types.Transaction, 0, len(tp.txList))
-	for _, txEntry := range tp.txList {
-		txList = append(txList, txEntry.Tx)
-		delete(tp.txList, txEntry.Tx.Hash())
+	txList := make([]*types.Transaction, 0, len(tp.ordered))
+	for _, item := range tp.ordered {
+		txList = append(txList, item.entry.Tx)
 	}
+	tp.reset()
 
 	return txList
 }
@@ -19,9 +19,11 @@
 package common
 
 import (
+	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/log"
 	"github.com/OnyxPay/OnyxChain/core/payload"
 	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/OnyxPay/OnyxChain/errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -92,9 +94,119 @@ func TestTxPool(t *testing.T) {
 	count := txPool.GetTransactionCount()
 	assert.Equal(t, count, 1)
 
+	txList = txPool.GetTxList(common.ADDRESS_EMPTY)
+	assert.Equal(t, 1, len(txList))
+	txList = txPool.GetTxList(txn.Payer)
+	assert.Equal(t, 1, len(txList))
+	txList = txPool.GetTxList(common.Address{1})
+	assert.Equal(t, 0, len(txList))
+
 	err := txPool.CleanTransactionList([]*types.Transaction{txn})
 	if err != nil {
 		t.Error("Failed to clean transaction list")
 		return
 	}
 }
+
+func TestTxPoolReplacement(t *testing.T) {
+	txPool := &TXPool{}
+	txPool.Init()
+
+	newTx := func(nonce uint32, gasPrice uint64) *types.Transaction {
+		mutable := &types.MutableTransaction{
+			TxType:   types.Invoke,
+			Nonce:    nonce,
+			GasPrice: gasPrice,
+			Payer:    common.Address{1},
+			Payload:  &payload.InvokeCode{Code: []byte{}},
+		}
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		return tx
+	}
+
+	tx1 := newTx(1, 500)
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx1}))
+
+	underpriced := newTx(1, 400)
+	_, errCode := txPool.CheckReplacement(underpriced)
+	assert.Equal(t, errors.ErrReplaceUnderpriced, errCode)
+	assert.False(t, txPool.AddTxList(&TXEntry{Tx: underpriced}))
+
+	_, errCode = txPool.CheckReplacement(tx1)
+	assert.Equal(t, errors.ErrDuplicateInput, errCode)
+
+	tx2 := newTx(1, 600)
+	replaced, errCode := txPool.CheckReplacement(tx2)
+	assert.Equal(t, errors.ErrNoError, errCode)
+	assert.Equal(t, tx1.Hash(), replaced.Hash())
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx2}))
+	assert.Nil(t, txPool.GetTransaction(tx1.Hash()))
+	assert.NotNil(t, txPool.GetTransaction(tx2.Hash()))
+
+	tx3 := newTx(2, 500)
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx3}))
+	assert.Equal(t, 2, txPool.GetTransactionCount())
+
+	// the transaction of the same payer and nonce in the ledger cleans tx2
+	err := txPool.CleanTransactionList([]*types.Transaction{tx1})
+	assert.Nil(t, err)
+	assert.Nil(t, txPool.GetTransaction(tx2.Hash()))
+	assert.Equal(t, 1, txPool.GetTransactionCount())
+
+	assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx1}))
+}
+
+func TestTxPoolOrder(t *testing.T) {
+	txPool := &TXPool{}
+	txPool.Init()
+
+	gasPrices := []uint64{500, 700, 500, 600, 700, 500}
+	txs := make([]*types.Transaction, 0, len(gasPrices))
+	for i, gasPrice := range gasPrices {
+		mutable := &types.MutableTransaction{
+			TxType:   types.Invoke,
+			Nonce:    uint32(i),
+			GasPrice: gasPrice,
+			Payer:    common.Address{byte(i % 2)},
+			Payload:  &payload.InvokeCode{Code: []byte{}},
+		}
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		assert.True(t, txPool.AddTxList(&TXEntry{Tx: tx}))
+		txs = append(txs, tx)
+	}
+
+	// ordered by gas price desc, then by arrival
+	expected := []int{1, 4, 3, 0, 2, 5}
+	txList, _ := txPool.GetTxPool(false, 0)
+	assert.Equal(t, len(expected), len(txList))
+	for i, idx := range expected {
+		assert.Equal(t, txs[idx].Hash(), txList[i].Tx.Hash())
+	}
+	assert.Equal(t, 3, txPool.GetPayerTxCount(common.Address{0}))
+	assert.Equal(t, 3, txPool.GetPayerTxCount(common.Address{1}))
+
+	txPool.DelTxList(txs[4])
+	err := txPool.CleanTransactionList([]*types.Transaction{txs[1], txs[2]})
+	assert.Nil(t, err)
+	expected = []int{3, 0, 5}
+	txList = txPool.GetTxList(common.ADDRESS_EMPTY)
+	assert.Equal(t, len(expected), len(txList))
+	for i, idx := range expected {
+		assert.Equal(t, txs[idx].Hash(), txList[i].Tx.Hash())
+	}
+	assert.Equal(t, 1, txPool.GetPayerTxCount(common.Address{0}))
+	assert.Equal(t, 2, txPool.GetPayerTxCount(common.Address{1}))
+
+	txPool.RemoveTxsBelowGasPrice(600)
+	txList = txPool.GetTxList(common.ADDRESS_EMPTY)
+	assert.Equal(t, 1, len(txList))
+	assert.Equal(t, txs[3].Hash(), txList[0].Tx.Hash())
+	assert.Equal(t, 0, txPool.GetPayerTxCount(common.Address{0}))
+
+	remain := txPool.Remain()
+	assert.Equal(t, 1, len(remain))
+	assert.Equal(t, 0, txPool.GetTransactionCount())
+	assert.Equal(t, 0, txPool.GetPayerTxCount(common.Address{1}))
+}
@@ -37,6 +37,8 @@ const (
 	MAX_LIMITATION   = 10000                            // The length of pending tx from net and http
 	UPDATE_FREQUENCY = 100                              // The frequency to update gas price from global params
 	MAX_TX_SIZE      = 1024 * 1024                      // The max size of a transaction to prevent DOS attacks
+	JOURNAL_FILE     = "txpool.journal"                 // The file name of tx journal in data dir
+	JOURNAL_ROTATE   = 1000                             // The count of appended txs to rotate the journal
 )
 
 // ActorType enumerates the kind of actor
@@ -55,9 +57,10 @@ const (
 type SenderType uint8
 
 const (
-	NilSender  SenderType = iota
-	NetSender             // Net sends tx req
-	HttpSender            // Http sends tx req
+	NilSender     SenderType = iota
+	NetSender                // Net sends tx req
+	HttpSender               // Http sends tx req
+	JournalSender            // Tx reloaded from journal
 )
 
 func (sender SenderType) Sender() string {
@@ -68,6 +71,8 @@ func (sender SenderType) Sender() string {
 		return "net sender"
 	case HttpSender:
 		return "http sender"
+	case JournalSender:
+		return "journal sender"
 	default:
 		return "unknown sender"
 	}
@@ -181,6 +186,16 @@ type GetTxnCountRsp struct {
 	Count []uint32
 }
 
+// GetGasPriceReq specifies the api that how to get the minimum gas price
+// accepted by the pool
+type GetGasPriceReq struct {
+}
+
+// GetGasPriceRsp returns the minimum gas price for GetGasPriceReq.
+type GetGasPriceRsp struct {
+	GasPrice uint64
+}
+
 // GetPendingTxnReq specifies the api that how to get a pending tx list
 // in the pool.
 type GetPendingTxnReq struct {
@@ -192,6 +207,20 @@ type GetPendingTxnRsp struct {
 	Txs []*types.Transaction
 }
 
+// GetTxnListReq specifies the api that how to list the verified and
+// pending transactions in the pool without changing the pool.
+// Input: the payer of transactions, empty payer means any payer.
+type GetTxnListReq struct {
+	Payer common.Address
+}
+
+// GetTxnListRsp returns the verified and pending transactions with
+// their verified result for GetTxnListReq.
+type GetTxnListRsp struct {
+	Verified []*TXEntry
+	Pending  []*TXEntry
+}
+
 // consensus messages
 // GetTxnPoolReq specifies the api that how to get the valid transaction list.
 type GetTxnPoolReq struct {
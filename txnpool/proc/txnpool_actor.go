@@ -131,6 +131,7 @@ func (ta *TxActor) handleTransaction(sender tc.SenderType, self *actor.PID,
 		return
 	}
 
+	replace, errCode := ta.server.checkReplacement(txn)
 	if ta.server.getTransaction(txn.Hash()) != nil {
 		log.Debugf("handleTransaction: transaction %x already in the txn pool",
 			txn.Hash())
@@ -140,7 +141,17 @@ func (ta *TxActor) handleTransaction(sender tc.SenderType, self *actor.PID,
 			replyTxResult(txResultCh, txn.Hash(), errors.ErrDuplicateInput,
 				fmt.Sprintf("transaction %x is already in the tx pool", txn.Hash()))
 		}
-	} else if ta.server.getTransactionCount() >= tc.MAX_CAPACITY {
+	} else if errCode != errors.ErrNoError {
+		log.Debugf("handleTransaction: transaction %x can't replace the one with the same payer and nonce: %s",
+			txn.Hash(), errCode.Error())
+
+		ta.server.increaseStats(tc.DuplicateStats)
+		if sender == tc.HttpSender && txResultCh != nil {
+			replyTxResult(txResultCh, txn.Hash(), errCode,
+				fmt.Sprintf("transaction of payer %s nonce %d with no lower gas price is already in the tx pool",
+					txn.Payer.ToBase58(), txn.Nonce))
+		}
+	} else if !replace && ta.server.getTransactionCount() >= tc.MAX_CAPACITY {
 		log.Debugf("handleTransaction: transaction pool is full for tx %x",
 			txn.Hash())
 
@@ -149,6 +160,16 @@ func (ta *TxActor) handleTransaction(sender tc.SenderType, self *actor.PID,
 			replyTxResult(txResultCh, txn.Hash(), errors.ErrTxPoolFull,
 				"transaction pool is full")
 		}
+	} else if !replace && ta.server.exceedPayerLimit(txn.Payer) {
+		log.Debugf("handleTransaction: too many transactions of payer %s for tx %x",
+			txn.Payer.ToBase58(), txn.Hash())
+
+		ta.server.increaseStats(tc.FailureStats)
+		if sender == tc.HttpSender && txResultCh != nil {
+			replyTxResult(txResultCh, txn.Hash(), errors.ErrPayerTxLimit,
+				fmt.Sprintf("payer %s has reached the limit of %d transactions in the tx pool",
+					txn.Payer.ToBase58(), config.DefConfig.Common.MaxTxPerPayer))
+		}
 	} else {
 		if _, overflow := common.SafeMul(txn.GasLimit, txn.GasPrice); overflow {
 			log.Debugf("handleTransaction: gasLimit %v, gasPrice %v overflow",
@@ -279,6 +300,27 @@ func (ta *TxActor) Receive(context actor.Context) {
 				context.Self())
 		}
 
+	case *tc.GetGasPriceReq:
+		sender := context.Sender()
+
+		log.Debugf("txpool-tx actor receives getting gas price req from %v", sender)
+
+		if sender != nil {
+			sender.Request(&tc.GetGasPriceRsp{GasPrice: ta.server.getGasPrice()},
+				context.Self())
+		}
+
+	case *tc.GetTxnListReq:
+		sender := context.Sender()
+
+		log.Debugf("txpool-tx actor receives getting tx list req from %v", sender)
+
+		verified, pending := ta.server.getTxList(msg.Payer)
+		if sender != nil {
+			sender.Request(&tc.GetTxnListRsp{Verified: verified,
+				Pending: pending}, context.Self())
+		}
+
 	default:
 		log.Debugf("txpool-tx actor: unknown msg %v type %v", msg, reflect.TypeOf(msg))
 	}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package proc
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/OnyxPay/OnyxChain/common"
+	"github.com/OnyxPay/OnyxChain/common/log"
+	tx "github.com/OnyxPay/OnyxChain/core/types"
+)
+
+// txJournal is a rotating log of transactions accepted by the pool, so
+// that they can be reloaded after the node restarts. Every record is the
+// var bytes of a serialized transaction.
+type txJournal struct {
+	mu       sync.Mutex
+	path     string   // Filesystem path to store the transactions
+	writer   *os.File // Output stream to append new transactions
+	appended int      // The count of txs appended since last rotation
+}
+
+// newTxJournal creates a tx journal at the path
+func newTxJournal(path string) *txJournal {
+	return &txJournal{
+		path: path,
+	}
+}
+
+// load parses the journal and calls add for each transaction. A broken
+// record at the end of journal, left by a crash, is ignored.
+func (j *txJournal) load(add func(*tx.Transaction)) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	data, err := ioutil.ReadFile(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	source := common.NewZeroCopySource(data)
+	count := 0
+	for source.Len() > 0 {
+		raw, _, irregular, eof := source.NextVarBytes()
+		if irregular || eof {
+			log.Warnf("txJournal: broken record at offset %d of %s", source.Pos(), j.path)
+			break
+		}
+		t, err := tx.TransactionFromRawBytes(raw)
+		if err != nil {
+			log.Warnf("txJournal: invalid transaction in %s: %s", j.path, err)
+			continue
+		}
+		add(t)
+		count++
+	}
+	return count, nil
+}
+
+// insert appends a transaction to the journal, it is ignored if the
+// journal is closed.
+func (j *txJournal) insert(t *tx.Transaction) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.writer == nil {
+		return nil
+	}
+	sink := common.NewZeroCopySink(nil)
+	sink.WriteVarBytes(t.Raw)
+	if _, err := j.writer.Write(sink.Bytes()); err != nil {
+		return err
+	}
+	j.appended++
+	return nil
+}
+
+// needRotate returns whether enough transactions are appended since last
+// rotation.
+func (j *txJournal) needRotate(threshold int) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.appended >= threshold
+}
+
+// rotate regenerates the journal with the transactions returned by
+// getTxs, and reopens it for appending. getTxs is called with the journal
+// locked, so that no transaction accepted meanwhile is lost.
+func (j *txJournal) rotate(getTxs func() []*tx.Transaction) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	txs := getTxs()
+	if j.writer != nil {
+		if err := j.writer.Close(); err != nil {
+			return err
+		}
+		j.writer = nil
+	}
+	tmp := j.path + ".new"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	for _, t := range txs {
+		sink := common.NewZeroCopySink(nil)
+		sink.WriteVarBytes(t.Raw)
+		if _, err = writer.Write(sink.Bytes()); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	j.writer, err = os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	j.appended = 0
+	return nil
+}
+
+// close flushes the journal and closes it
+func (j *txJournal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.writer == nil {
+		return nil
+	}
+	err := j.writer.Close()
+	j.writer = nil
+	return err
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (C) 2019 The onyxchain Authors
+ * This file is part of The onyxchain library.
+ *
+ * The onyxchain is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The onyxchain is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The onyxchain.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package proc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OnyxPay/OnyxChain/core/payload"
+	"github.com/OnyxPay/OnyxChain/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txjournal")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "txpool.journal")
+
+	txs := make([]*types.Transaction, 0)
+	for i := 0; i < 3; i++ {
+		mutable := &types.MutableTransaction{
+			TxType:  types.Invoke,
+			Nonce:   uint32(i),
+			Payload: &payload.InvokeCode{Code: []byte("onyx")},
+		}
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		txs = append(txs, tx)
+	}
+	load := func(journal *txJournal) []*types.Transaction {
+		loaded := make([]*types.Transaction, 0)
+		_, err := journal.load(func(tx *types.Transaction) {
+			loaded = append(loaded, tx)
+		})
+		assert.Nil(t, err)
+		return loaded
+	}
+
+	journal := newTxJournal(path)
+	assert.Equal(t, 0, len(load(journal)))
+	err = journal.rotate(func() []*types.Transaction { return txs[:1] })
+	assert.Nil(t, err)
+	assert.Nil(t, journal.insert(txs[1]))
+	assert.Nil(t, journal.insert(txs[2]))
+	assert.True(t, journal.needRotate(2))
+	assert.Nil(t, journal.close())
+	// insert is ignored after the journal is closed
+	assert.Nil(t, journal.insert(txs[0]))
+
+	loaded := load(newTxJournal(path))
+	assert.Equal(t, len(txs), len(loaded))
+	for i, tx := range loaded {
+		assert.Equal(t, txs[i].Hash(), tx.Hash())
+	}
+
+	// a broken record at the end is ignored
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	_, err = file.Write([]byte{0xfd, 0xff})
+	assert.Nil(t, err)
+	file.Close()
+	journal = newTxJournal(path)
+	assert.Equal(t, len(txs), len(load(journal)))
+
+	err = journal.rotate(func() []*types.Transaction { return txs[2:] })
+	assert.Nil(t, err)
+	assert.False(t, journal.needRotate(1))
+	assert.Nil(t, journal.close())
+	loaded = load(newTxJournal(path))
+	assert.Equal(t, 1, len(loaded))
+	assert.Equal(t, txs[2].Hash(), loaded[0].Hash())
+}
@@ -77,6 +77,7 @@ type TXPoolServer struct {
 	workers               []txPoolWorker                      // Worker pool
 	txPool                *tc.TXPool                          // The tx pool that holds the valid transaction
 	allPendingTxs         map[common.Uint256]*serverPendingTx // The txs that server is processing
+	pendingPayers         map[common.Address]int              // The count of txs that server is processing by payer
 	pendingBlock          *pendingBlock                       // The block that server is processing
 	actors                map[tc.ActorType]*actor.PID         // The actors running in the server
 	validators            *registerValidators                 // The registered validators
@@ -86,6 +87,7 @@ type TXPoolServer struct {
 	gasPrice              uint64                              // Gas price to enforce for acceptance into the pool
 	disablePreExec        bool                                // Disbale PreExecute a transaction
 	disableBroadcastNetTx bool                                // Disable broadcast tx from network
+	journal               *txJournal                          // Journal of accepted txs, nil if disabled
 }
 
 // NewTxPoolServer creates a new tx pool server to schedule workers to
@@ -154,6 +156,7 @@ func (s *TXPoolServer) init(num uint8, disablePreExec, disableBroadcastNetTx boo
 	s.txPool = &tc.TXPool{}
 	s.txPool.Init()
 	s.allPendingTxs = make(map[common.Uint256]*serverPendingTx)
+	s.pendingPayers = make(map[common.Address]int)
 	s.actors = make(map[tc.ActorType]*actor.PID)
 
 	s.validators = &registerValidators{
@@ -267,6 +270,7 @@ func (s *TXPoolServer) removePendingTx(hash common.Uint256,
 	}
 
 	if err == errors.ErrNoError && ((pt.sender == tc.HttpSender) ||
+		(pt.sender == tc.JournalSender) ||
 		(pt.sender == tc.NetSender && !s.disableBroadcastNetTx)) {
 		pid := s.GetPID(tc.NetActor)
 		if pid != nil {
@@ -279,6 +283,11 @@ func (s *TXPoolServer) removePendingTx(hash common.Uint256,
 	}
 
 	delete(s.allPendingTxs, hash)
+	if s.pendingPayers[pt.tx.Payer] <= 1 {
+		delete(s.pendingPayers, pt.tx.Payer)
+	} else {
+		s.pendingPayers[pt.tx.Payer]--
+	}
 
 	if len(s.allPendingTxs) < tc.MAX_LIMITATION {
 		select {
@@ -290,6 +299,16 @@ func (s *TXPoolServer) removePendingTx(hash common.Uint256,
 
 	s.mu.Unlock()
 
+	// Journal the new valid transaction, the re-verified and reloaded
+	// ones are already in the journal
+	if err == errors.ErrNoError && s.journal != nil &&
+		(pt.sender == tc.HttpSender || pt.sender == tc.NetSender) {
+		if e := s.journal.insert(pt.tx); e != nil {
+			log.Warnf("removePendingTx: journal transaction %x error %s",
+				hash, e)
+		}
+	}
+
 	// Check if the tx is in the pending block and
 	// the pending block is verified
 	s.checkPendingBlockOk(hash, err)
@@ -315,6 +334,7 @@ func (s *TXPoolServer) setPendingTx(tx *tx.Transaction,
 	}
 
 	s.allPendingTxs[tx.Hash()] = pt
+	s.pendingPayers[tx.Payer]++
 	return true
 }
 
@@ -471,8 +491,72 @@ func (s *TXPoolServer) getNextValidatorPID(key types.VerifyType) *actor.PID {
 	return entries[next].Sender
 }
 
+// OpenJournal reloads the transactions journaled at the path to the pool,
+// and journals the accepted transactions from now on. The reloaded ones
+// are verified again, and dropped if they are already in the ledger.
+// It should be called after the validators are registered.
+func (s *TXPoolServer) OpenJournal(path string) error {
+	pid := s.GetPID(tc.TxActor)
+	if pid == nil {
+		return fmt.Errorf("TxActor not exist")
+	}
+	journal := newTxJournal(path)
+	txs := make([]*tx.Transaction, 0)
+	dropped := 0
+	_, err := journal.load(func(t *tx.Transaction) {
+		if exist, _ := ledger.DefLedger.IsContainTransaction(t.Hash()); exist {
+			dropped++
+			return
+		}
+		txs = append(txs, t)
+	})
+	if err != nil {
+		return fmt.Errorf("load tx journal error %s", err)
+	}
+	err = journal.rotate(func() []*tx.Transaction { return txs })
+	if err != nil {
+		return fmt.Errorf("rotate tx journal error %s", err)
+	}
+	s.journal = journal
+	log.Infof("tx journal %s: %d transactions reloaded, %d dropped", path, len(txs), dropped)
+
+	for _, t := range txs {
+		pid.Tell(&tc.TxReq{Tx: t, Sender: tc.JournalSender})
+	}
+	return nil
+}
+
+// CloseJournal regenerates the journal with the transactions in the pool
+// and closes it.
+func (s *TXPoolServer) CloseJournal() error {
+	if s.journal == nil {
+		return nil
+	}
+	err := s.rotateJournal()
+	if err != nil {
+		return err
+	}
+	return s.journal.close()
+}
+
+// rotateJournal regenerates the journal with the verified and pending txs.
+// The workers are not locked here, since they journal txs with lock held.
+func (s *TXPoolServer) rotateJournal() error {
+	return s.journal.rotate(func() []*tx.Transaction {
+		verified := s.txPool.GetTxList(common.ADDRESS_EMPTY)
+		txs := make([]*tx.Transaction, 0, len(verified))
+		for _, entry := range verified {
+			txs = append(txs, entry.Tx)
+		}
+		return append(txs, s.getPendingTxs(false)...)
+	})
+}
+
 // Stop stops server and workers.
 func (s *TXPoolServer) Stop() {
+	if err := s.CloseJournal(); err != nil {
+		log.Errorf("close tx journal error %s", err)
+	}
 	for _, v := range s.actors {
 		v.Stop()
 	}
@@ -506,6 +590,28 @@ func (s *TXPoolServer) getTxPool(byCount bool, height uint32) []*tc.TXEntry {
 	return avlTxList
 }
 
+// checkReplacement checks whether the transaction can replace the one
+// of the same payer and nonce in the tx pool, and returns whether there
+// is one to be replaced.
+func (s *TXPoolServer) checkReplacement(t *tx.Transaction) (bool, errors.ErrCode) {
+	replaced, errCode := s.txPool.CheckReplacement(t)
+	return replaced != nil, errCode
+}
+
+// exceedPayerLimit checks whether the payer has reached the configured
+// max tx number in the pool, including pending and verified.
+func (s *TXPoolServer) exceedPayerLimit(payer common.Address) bool {
+	limit := config.DefConfig.Common.MaxTxPerPayer
+	if limit == 0 {
+		return false
+	}
+	s.mu.RLock()
+	count := s.pendingPayers[payer]
+	s.mu.RUnlock()
+	count += s.txPool.GetPayerTxCount(payer)
+	return uint(count) >= limit
+}
+
 // getTxCount returns current tx count, including pending and verified
 func (s *TXPoolServer) getTxCount() []uint32 {
 	ret := make([]uint32, 0)
@@ -525,6 +631,32 @@ func (s *TXPoolServer) getPendingTxs(byCount bool) []*tx.Transaction {
 	return ret
 }
 
+// getTxList returns the verified and pending tx list paid by the payer.
+// Unlike getTxPool, the tx pool is not changed.
+func (s *TXPoolServer) getTxList(payer common.Address) ([]*tc.TXEntry, []*tc.TXEntry) {
+	verified := s.txPool.GetTxList(payer)
+
+	s.mu.RLock()
+	txs := make([]*tx.Transaction, 0, len(s.allPendingTxs))
+	for _, v := range s.allPendingTxs {
+		if payer != common.ADDRESS_EMPTY && v.tx.Payer != payer {
+			continue
+		}
+		txs = append(txs, v.tx)
+	}
+	s.mu.RUnlock()
+
+	pending := make([]*tc.TXEntry, 0, len(txs))
+	for _, t := range txs {
+		entry := &tc.TXEntry{Tx: t}
+		if status := s.getTxStatusReq(t.Hash()); status != nil {
+			entry.Attrs = status.Attrs
+		}
+		pending = append(pending, entry)
+	}
+	return verified, pending
+}
+
 // cleanTransactionList cleans the txs in the block from the ledger
 func (s *TXPoolServer) cleanTransactionList(txs []*tx.Transaction, height uint32) {
 	s.txPool.CleanTransactionList(txs)
@@ -557,6 +689,12 @@ func (s *TXPoolServer) cleanTransactionList(txs []*tx.Transaction, height uint32
 			s.reVerifyStateful(t, tc.NilSender)
 		}
 	}
+
+	if s.journal != nil && s.journal.needRotate(tc.JOURNAL_ROTATE) {
+		if err := s.rotateJournal(); err != nil {
+			log.Warnf("cleanTransactionList: rotate tx journal error %s", err)
+		}
+	}
 }
 
 // delTransaction deletes a transaction in the tx pool.
@@ -23,10 +23,12 @@ import (
 	"time"
 
 	"github.com/OnyxPay/OnyxChain-eventbus/actor"
+	"github.com/OnyxPay/OnyxChain/common"
 	"github.com/OnyxPay/OnyxChain/common/log"
 	"github.com/OnyxPay/OnyxChain/core/payload"
 	"github.com/OnyxPay/OnyxChain/core/types"
 	"github.com/OnyxPay/OnyxChain/errors"
+	httpcom "github.com/OnyxPay/OnyxChain/http/base/common"
 	tc "github.com/OnyxPay/OnyxChain/txnpool/common"
 	"github.com/OnyxPay/OnyxChain/validator/stateless"
 	vt "github.com/OnyxPay/OnyxChain/validator/types"
@@ -70,6 +72,24 @@ func startActor(obj interface{}) *actor.PID {
 	return pid
 }
 
+func TestSameSecondTxsOfPayer(t *testing.T) {
+	txPool := &tc.TXPool{}
+	txPool.Init()
+
+	payer := common.Address{1}
+	for i := 0; i < 2; i++ {
+		mutable, err := httpcom.NewSmartContractTransaction(500, 20000, []byte("onyx"))
+		assert.Nil(t, err)
+		mutable.Payer = payer
+		tx, err := mutable.IntoImmutable()
+		assert.Nil(t, err)
+		_, errCode := txPool.CheckReplacement(tx)
+		assert.Equal(t, errors.ErrNoError, errCode)
+		assert.True(t, txPool.AddTxList(&tc.TXEntry{Tx: tx}))
+	}
+	assert.Equal(t, 2, txPool.GetPayerTxCount(payer))
+}
+
 func TestTxn(t *testing.T) {
 	t.Log("Starting test tx")
 	var s *TXPoolServer
@@ -27,14 +27,22 @@ import (
 	"github.com/OnyxPay/OnyxChain/core/types"
 )
 
-// IncrementValidator do increment check of transaction
+// IncrementValidator do increment check of transaction. A transaction is
+// duplicated if the transaction or another one of the same payer and nonce
+// is in the checked blocks, which is consistent with the tx pool.
 type IncrementValidator struct {
 	mutex      sync.Mutex
 	blocks     []map[common.Uint256]bool
+	nonces     []map[payerNonce]bool
 	baseHeight uint32
 	maxBlocks  int
 }
 
+type payerNonce struct {
+	payer common.Address
+	nonce uint32
+}
+
 func NewIncrementValidator(maxBlocks int) *IncrementValidator {
 	if maxBlocks <= 0 {
 		maxBlocks = 20
@@ -47,6 +55,7 @@ func NewIncrementValidator(maxBlocks int) *IncrementValidator {
 func (self *IncrementValidator) Clean() {
 	self.mutex.Lock()
 	self.blocks = nil
+	self.nonces = nil
 	self.baseHeight = 0
 	self.mutex.Unlock()
 }
@@ -79,13 +88,17 @@ func (self *IncrementValidator) AddBlock(block *types.Block) {
 
 	if len(self.blocks) >= self.maxBlocks {
 		self.blocks = self.blocks[1:]
+		self.nonces = self.nonces[1:]
 		self.baseHeight += 1
 	}
 	txHashes := make(map[common.Uint256]bool)
+	nonces := make(map[payerNonce]bool)
 	for _, tx := range block.Transactions {
 		txHashes[tx.Hash()] = true
+		nonces[payerNonce{tx.Payer, tx.Nonce}] = true
 	}
 	self.blocks = append(self.blocks, txHashes)
+	self.nonces = append(self.nonces, nonces)
 }
 
 // Verfiy does increment check start at startHeight
@@ -96,10 +109,14 @@ func (self *IncrementValidator) Verify(tx *types.Transaction, startHeight uint32
 		return fmt.Errorf("can not do increment validation: startHeight %v < self.baseHeight %v", startHeight, self.baseHeight)
 	}
 
+	key := payerNonce{tx.Payer, tx.Nonce}
 	for i := int(startHeight - self.baseHeight); i < len(self.blocks); i++ {
 		if _, ok := self.blocks[i][tx.Hash()]; ok {
 			return fmt.Errorf("tx duplicated")
 		}
+		if _, ok := self.nonces[i][key]; ok {
+			return fmt.Errorf("tx of payer %s nonce %d duplicated", tx.Payer.ToBase58(), tx.Nonce)
+		}
 	}
 
 	return nil
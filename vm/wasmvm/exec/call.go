@@ -24,6 +24,8 @@ package exec
 
 import (
 	"errors"
+	"fmt"
+
 	"github.com/OnyxPay/OnyxChain/common/log"
 )
 
@@ -63,8 +65,13 @@ func (vm *VM) doCall(compiled compiledFunction, index int64) {
 		v, ok := vm.Services[compiled.name]
 		if ok {
 			rtn, err := v(vm.Engine)
-			if err != nil || !rtn {
+			if err != nil {
+				log.Errorf("call method :%s failed: %s", compiled.name, err)
+				panic(err)
+			}
+			if !rtn {
 				log.Errorf("call method :%s failed\n", compiled.name)
+				panic(fmt.Errorf("call method :%s failed", compiled.name))
 			}
 		} else {
 			vm.ctx = prevCtxt
@@ -86,12 +86,32 @@ func NewExecutionEngine(container interfaces.CodeContainer, crypto interfaces.Cr
 	return engine
 }
 
+// GasChecker is called with the cost of every executed instruction,
+// a non nil error traps the running vm
+type GasChecker func(gas uint64) error
+
 type ExecutionEngine struct {
 	crypto        interfaces.Crypto
 	service       *InteropService
 	CodeContainer interfaces.CodeContainer
 	vm            *VM
 	backupVM      *vmstack
+	gasChecker    GasChecker
+	opcodeGas     uint64
+}
+
+//SetGasChecker charge opcodeGas through checker for every executed instruction
+func (e *ExecutionEngine) SetGasChecker(checker GasChecker, opcodeGas uint64) {
+	e.gasChecker = checker
+	e.opcodeGas = opcodeGas
+}
+
+//UseGas charge gas for host service calls, it is a no-op without gas checker
+func (e *ExecutionEngine) UseGas(gas uint64) error {
+	if e.gasChecker == nil {
+		return nil
+	}
+	return e.gasChecker(gas)
 }
 
 //GetVM return vm pointer
@@ -320,7 +340,7 @@ func (e *ExecutionEngine) Call(caller common.Address,
 	defer func() {
 		if err := recover(); err != nil {
 			returnbytes = nil
-			er = errors.NewErr("[Call] error happened while call wasmvm")
+			er = errors.NewErr(fmt.Sprintf("[Call] error happened while call wasmvm: %v", err))
 		}
 	}()
 
@@ -343,7 +363,7 @@ func (e *ExecutionEngine) InitCall(caller common.Address,
 	defer func() {
 		if err := recover(); err != nil {
 			returnbytes = nil
-			er = errors.NewErr("[Call] error happened while call wasmvm")
+			er = errors.NewErr(fmt.Sprintf("[Call] error happened while call wasmvm: %v", err))
 		}
 	}()
 
@@ -530,6 +550,27 @@ func (e *ExecutionEngine) call(caller common.Address,
 	}
 }
 
+//VerifyCode check code is a valid wasm module exporting the contract entry method
+func VerifyCode(code []byte) (er error) {
+	defer func() {
+		if err := recover(); err != nil {
+			er = errors.NewErr(fmt.Sprintf("[VerifyCode] read wasm module error: %v", err))
+		}
+	}()
+
+	m, err := wasm.ReadModule(bytes.NewBuffer(code), importer)
+	if err != nil {
+		return errors.NewErr("[VerifyCode]Verify wasm failed!" + err.Error())
+	}
+	if m.Export == nil {
+		return errors.NewErr("[VerifyCode]No export in wasm!")
+	}
+	if _, ok := m.Export.Entries[CONTRACT_METHOD_NAME]; !ok {
+		return errors.NewErr("[VerifyCode]Method:" + CONTRACT_METHOD_NAME + " does not exist!")
+	}
+	return nil
+}
+
 //FIXME NOT IN USE BUT DON'T DELETE IT
 //current we only support the ONX SYSTEM module import
 //other imports will raise an error
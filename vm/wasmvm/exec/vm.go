@@ -387,6 +387,11 @@ outer:
 	for int(vm.ctx.pc) < len(vm.ctx.code) {
 		op := vm.ctx.code[vm.ctx.pc]
 		vm.ctx.pc++
+		if vm.Engine != nil && vm.Engine.gasChecker != nil {
+			if err := vm.Engine.gasChecker(vm.Engine.opcodeGas); err != nil {
+				panic(err)
+			}
+		}
 
 		switch op {
 		case ops.Return: